@@ -0,0 +1,138 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"time"
+)
+
+// redacted replaces text of the redacted elements.
+const redacted = "***"
+
+// AccessLog implements access log entry of the request handled by the server.
+type AccessLog struct {
+	Time       time.Time
+	Duration   time.Duration
+	RemoteAddr string
+	Action     string
+	StatusCode int
+	// FaultCode is code of the sent fault, empty on success.
+	FaultCode    string
+	RequestBytes int
+	// ResponseBytes is size of the written response body.
+	ResponseBytes int
+	// Request and Response are captured envelopes, they are set when ServerLogConfig.Capture is enabled.
+	Request  []byte
+	Response []byte
+}
+
+// ServerLogConfig implements config of the server access log.
+type ServerLogConfig struct {
+	// Log is called with entry of each request after the response is written.
+	Log func(l AccessLog)
+	// Capture passes request and response envelopes to the log.
+	Capture bool
+	// Redact rewrites captured envelopes, e.g. RedactElements hiding passwords.
+	Redact func(envelope []byte) []byte
+}
+
+// RedactElements returns redaction replacing text of the elements with the local names, envelopes
+// which are not well-formed are replaced in full.
+func RedactElements(local ...string) func(envelope []byte) []byte {
+	names := make(map[string]bool, len(local))
+	for _, n := range local {
+		names[n] = true
+	}
+
+	return func(envelope []byte) []byte {
+		d := xml.NewDecoder(bytes.NewReader(envelope))
+		var (
+			out   bytes.Buffer
+			last  int64
+			depth int
+		)
+		for {
+			offset := d.InputOffset()
+			token, err := d.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return []byte(redacted)
+			}
+
+			switch t := token.(type) {
+			case xml.StartElement:
+				if depth > 0 || names[t.Name.Local] {
+					depth++
+				}
+			case xml.EndElement:
+				if depth > 0 {
+					depth--
+				}
+			case xml.CharData:
+				if depth == 0 {
+					continue
+				}
+				// text of the nested elements is redacted as well
+				out.Write(envelope[last:offset])
+				out.WriteString(redacted)
+				last = d.InputOffset()
+			}
+		}
+		out.Write(envelope[last:])
+		return out.Bytes()
+	}
+}
+
+// log passes the entry to the access log, captured envelopes are redacted.
+func (c *ServerLogConfig) log(l *AccessLog, w *logWriter) {
+	l.Duration = time.Since(l.Time)
+	l.Time = l.Time.UTC()
+	l.StatusCode, l.ResponseBytes = w.status, w.n
+	if c.Capture {
+		l.Response = w.body.Bytes()
+		if c.Redact != nil {
+			l.Request, l.Response = c.Redact(l.Request), c.Redact(l.Response)
+		}
+	} else {
+		l.Request = nil
+	}
+	c.Log(*l)
+}
+
+// logWriter implements response writer recording status and body of the response.
+type logWriter struct {
+	http.ResponseWriter
+	status  int
+	n       int
+	capture bool
+	body    bytes.Buffer
+}
+
+func (w *logWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.n += n
+	if w.capture {
+		w.body.Write(b[:n])
+	}
+	return n, err
+}
+
+// Unwrap returns the original response writer for http.ResponseController.
+func (w *logWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,68 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedactElements(t *testing.T) {
+	t.Parallel()
+	redact := RedactElements("Password", "Card")
+	for i, v := range []struct {
+		in   string
+		want string
+	}{
+		{in: `<a><Password>secret</Password><b>x</b></a>`, want: `<a><Password>***</Password><b>x</b></a>`},
+		{in: `<a><w:Password Type="t">secret</w:Password></a>`, want: `<a><w:Password Type="t">***</w:Password></a>`},
+		{in: `<Card><n>1234</n><cvv>1</cvv></Card>`, want: `<Card><n>***</n><cvv>***</cvv></Card>`},
+		{in: `<a><Password/></a>`, want: `<a><Password/></a>`},
+		{in: `<a><Password>secret</a>`, want: `***`},
+	} {
+		if got := string(redact([]byte(v.in))); got != v.want {
+			t.Errorf("#%d got: %s, want: %s", i, got, v.want)
+		}
+	}
+}
+
+func TestServer_AccessLog(t *testing.T) {
+	t.Parallel()
+	logs := make(chan AccessLog, 2)
+	s := NewServer(ServerConfig{AccessLog: &ServerLogConfig{
+		Log:     func(l AccessLog) { logs <- l },
+		Capture: true,
+		Redact:  RedactElements("attr1"),
+	}})
+	s.Handle("get", func(ctx context.Context, r *ServerRequest) (interface{}, error) {
+		return response{Attr3: "value3"}, nil
+	})
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{})
+	if err := client.Call(context.Background(), "get", request{Attr1: "secret"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	l := <-logs
+	if l.Action != "get" || l.StatusCode != http.StatusOK || l.FaultCode != "" || l.RemoteAddr == "" {
+		t.Fatalf("got: %+v, want: successful get", l)
+	}
+
+	if bytes.Contains(l.Request, []byte("secret")) || !bytes.Contains(l.Request, []byte("<attr1>***</attr1>")) {
+		t.Fatalf("got: %s, want: redacted attr1", l.Request)
+	}
+
+	if !bytes.Contains(l.Response, []byte("value3")) || l.ResponseBytes != len(l.Response) {
+		t.Fatalf("got: %s (%d bytes), want: captured response", l.Response, l.ResponseBytes)
+	}
+
+	client.Call(context.Background(), "unknown", request{}, nil)
+	l = <-logs
+	if want := FaultClient; l.FaultCode != want || l.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("got: %s %d, want: %s %d", l.FaultCode, l.StatusCode, want, http.StatusInternalServerError)
+	}
+}
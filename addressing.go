@@ -0,0 +1,251 @@
+package soap
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// AddressingNS is namespace of WS-Addressing 1.0.
+const AddressingNS = "http://www.w3.org/2005/08/addressing"
+
+// AnonymousAddress is WS-Addressing address of the synchronous response.
+const AnonymousAddress = AddressingNS + "/anonymous"
+
+// Addressing implements WS-Addressing message information headers, empty values are omitted.
+type Addressing struct {
+	MessageID string
+	To        string
+	Action    string
+	ReplyTo   string
+	RelatesTo string
+	// MustUnderstand marks To and Action with mustUnderstand="1".
+	MustUnderstand bool
+	// Version is soap version of the mustUnderstand attribute, the middleware uses version of the envelope.
+	Version SOAPVersion
+}
+
+// MarshalXML implements xml.Marshaler interface.
+func (a Addressing) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	for _, v := range []struct {
+		local, value string
+	}{
+		{local: "MessageID", value: a.MessageID},
+		{local: "To", value: a.To},
+		{local: "Action", value: a.Action},
+		{local: "RelatesTo", value: a.RelatesTo},
+	} {
+		if v.value == "" {
+			continue
+		}
+
+		se := xml.StartElement{Name: xml.Name{Space: AddressingNS, Local: v.local}}
+		if a.MustUnderstand && (v.local == "To" || v.local == "Action") {
+			se.Attr = []xml.Attr{{Name: xml.Name{Space: a.Version.namespace(), Local: "mustUnderstand"}, Value: "1"}}
+		}
+
+		if err := encodeText(e, se, v.value); err != nil {
+			return err
+		}
+	}
+
+	if a.ReplyTo != "" {
+		replyTo := xml.StartElement{Name: xml.Name{Space: AddressingNS, Local: "ReplyTo"}}
+		if err := e.EncodeToken(replyTo); err != nil {
+			return err
+		}
+
+		if err := encodeText(e, xml.StartElement{Name: xml.Name{Space: AddressingNS, Local: "Address"}}, a.ReplyTo); err != nil {
+			return err
+		}
+		return e.EncodeToken(replyTo.End())
+	}
+	return nil
+}
+
+// Middleware returns middleware adding the headers to requests without MessageID header,
+// empty MessageID, To and Action are set to new message id, url and soap action of the request.
+func (a Addressing) Middleware() Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			envelope, header, err := withHeader(r.Envelope)
+			if err != nil {
+				return nil, err
+			}
+
+			if header.child("MessageID") != nil {
+				return next(ctx, r)
+			}
+
+			h := a
+			if header.namespace(header.name.Space) == envelope12NS {
+				h.Version = SOAP12
+			}
+			if h.MessageID == "" {
+				h.MessageID = NewMessageID()
+			}
+			if h.To == "" {
+				h.To = r.URL
+			}
+			if h.Action == "" {
+				h.Action = r.Action
+			}
+
+			b, err := xml.Marshal(h)
+			if err != nil {
+				return nil, fmt.Errorf("soap: %s", err)
+			}
+
+			req := *r
+			req.Envelope = insert(envelope, header.contentStart, string(b))
+			return next(ctx, &req)
+		}
+	}
+}
+
+// NewMessageID returns random message id in the urn:uuid form.
+func NewMessageID() string {
+	var u [16]byte
+	rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
+
+// Callback implements http handler receiving asynchronous responses sent to the ReplyTo address,
+// the response is delivered to the waiting call by RelatesTo header.
+type Callback struct {
+	// Address is the public url of the handler used as ReplyTo.
+	Address string
+	// Duplicates enables detection of responses delivered more than once.
+	Duplicates *DuplicateDetector
+	// MaxBodyBytes limits size of the response body, DefaultMaxBodyBytes when zero, negative disables the limit.
+	MaxBodyBytes int64
+	// DecodeLimits protects response decoding against xml bombs.
+	DecodeLimits DecodeLimits
+
+	mu      sync.Mutex
+	waiting map[string]chan []byte
+}
+
+// NewCallback creates callback handler which is reachable by the address.
+func NewCallback(address string) *Callback {
+	return &Callback{Address: address, waiting: make(map[string]chan []byte)}
+}
+
+// ServeHTTP implements http.Handler interface.
+func (c *Callback) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	max := c.MaxBodyBytes
+	if max == 0 {
+		max = DefaultMaxBodyBytes
+	}
+
+	reader := r.Body
+	if max > 0 {
+		reader = http.MaxBytesReader(w, r.Body, max)
+	}
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		if _, ok := err.(*http.MaxBytesError); ok {
+			http.Error(w, fmt.Sprintf("soap: response body exceeds limit %d", max), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n := new(Node)
+	if err := c.DecodeLimits.decoder(trimProlog(body)).Decode(n); err != nil {
+		http.Error(w, fmt.Sprintf("soap: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	if c.Duplicates != nil {
+		dup, err := c.Duplicates.seen(r.Context(), n)
+		switch {
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		case dup && c.Duplicates.Suppress:
+			w.WriteHeader(http.StatusAccepted)
+			return
+		case dup:
+			http.Error(w, "soap: duplicate message", http.StatusConflict)
+			return
+		}
+	}
+
+	c.mu.Lock()
+	ch, ok := c.waiting[n.Value("Envelope/Header/RelatesTo")]
+	c.mu.Unlock()
+	if !ok {
+		http.Error(w, "soap: unknown message", http.StatusNotFound)
+		return
+	}
+
+	select {
+	case ch <- body:
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "soap: duplicate message", http.StatusConflict)
+	}
+}
+
+func (c *Callback) wait(messageID string) chan []byte {
+	ch := make(chan []byte, 1)
+	c.mu.Lock()
+	c.waiting[messageID] = ch
+	c.mu.Unlock()
+	return ch
+}
+
+func (c *Callback) done(messageID string) {
+	c.mu.Lock()
+	delete(c.waiting, messageID)
+	c.mu.Unlock()
+}
+
+// CallAsync sends soap request with ReplyTo set to the callback address and waits
+// for the response delivered to the callback.
+func (s *Client) CallAsync(ctx context.Context, cb *Callback, soapAction string, request, response interface{}) error {
+	if response == nil {
+		response = new(interface{})
+	}
+
+	if s.closed.Err() != nil {
+		return errClosed
+	}
+
+	ctx, cancel := s.withClose(ctx)
+	defer cancel()
+
+	messageID := NewMessageID()
+	ch := cb.wait(messageID)
+	defer cb.done(messageID)
+
+	// the request is sent as by Call, its response only acknowledges the message
+	err := s.call(ctx, soapAction, request, asyncAck{}, Addressing{
+		MessageID: messageID,
+		To:        s.endpoint(),
+		Action:    soapAction,
+		ReplyTo:   cb.Address,
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case body := <-ch:
+		return s.decode(&Response{Status: "200 OK", StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"text/xml"}}, Body: body}, response)
+	case <-ctx.Done():
+		return withCause(ctx, &transportError{err: ctx.Err()})
+	}
+}
+
+// asyncAck is response of the asynchronous request, it is acknowledged by 200 or 202 status without envelope.
+type asyncAck struct{}
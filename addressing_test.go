@@ -0,0 +1,112 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_AddressingMarshal(t *testing.T) {
+	t.Parallel()
+	b, err := xml.Marshal(Header{Items: []interface{}{Addressing{MessageID: "urn:uuid:1", Action: "act", ReplyTo: "http://reply"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<Header xmlns="http://schemas.xmlsoap.org/soap/envelope/">` +
+		`<MessageID xmlns="http://www.w3.org/2005/08/addressing">urn:uuid:1</MessageID>` +
+		`<Action xmlns="http://www.w3.org/2005/08/addressing">act</Action>` +
+		`<ReplyTo xmlns="http://www.w3.org/2005/08/addressing"><Address xmlns="http://www.w3.org/2005/08/addressing">http://reply</Address></ReplyTo>` +
+		`</Header>`
+	if got := string(b); got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+
+	if id := NewMessageID(); !regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Fatalf("invalid message id %s", id)
+	}
+}
+
+func TestClient_CallAsync(t *testing.T) {
+	t.Parallel()
+	cb := NewCallback("")
+	cbSrv := httptest.NewServer(cb)
+	defer cbSrv.Close()
+	cb.Address = cbSrv.URL
+
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		n, err := ParseNode(body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		replyTo, messageID := n.Value("Envelope/Header/ReplyTo/Address"), n.Value("Envelope/Header/MessageID")
+		w.WriteHeader(http.StatusAccepted)
+		go func() {
+			b, _ := xml.Marshal(Envelope{
+				Header: &Header{Items: []interface{}{Addressing{RelatesTo: messageID}}},
+				Body:   Body{Content: response{Attr3: "value3"}},
+			})
+			resp, err := http.Post(replyTo, "text/xml", bytes.NewReader(b))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}))
+	defer srv.Close()
+
+	var (
+		r  response
+		st Stats
+	)
+	c := MustNewClient(srv.URL, Config{
+		Retry: RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond},
+		Stats: func(s Stats) { st = s },
+	})
+	if err := c.CallAsync(context.Background(), cb, "act", request{}, &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "value3"; r.Attr3 != want {
+		t.Fatalf("got: %s, want: %s", r.Attr3, want)
+	}
+
+	if st.Action != "act" || st.Attempts != 2 {
+		t.Fatalf("got: %+v, want: 2 attempts of act", st)
+	}
+}
+
+func TestCallback_Limits(t *testing.T) {
+	t.Parallel()
+	cb := &Callback{MaxBodyBytes: 64, DecodeLimits: DecodeLimits{MaxDepth: 2}}
+
+	w := httptest.NewRecorder()
+	cb.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("<a>", 100))))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("got: %d, want: %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	w = httptest.NewRecorder()
+	cb.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("<a><b><c/></b></a>")))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("got: %d, want: %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,163 @@
+package soap
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var errAuditRecord = fmt.Errorf("soap: audit record is too short")
+
+// AuditRecord implements persisted request and response of the call.
+type AuditRecord struct {
+	Time          time.Time     `json:"time"`
+	Duration      time.Duration `json:"duration"`
+	URL           string        `json:"url"`
+	Action        string        `json:"action"`
+	RequestHeader http.Header   `json:"request_header,omitempty"`
+	Request       []byte        `json:"request"`
+	StatusCode    int           `json:"status_code,omitempty"`
+	Header        http.Header   `json:"header,omitempty"`
+	Response      []byte        `json:"response,omitempty"`
+	Err           string        `json:"error,omitempty"`
+}
+
+// AuditStore implements object storage of audit records, e.g. file system or S3 bucket.
+type AuditStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// AuditConfig implements config of the audit middleware.
+type AuditConfig struct {
+	Store AuditStore
+	// Key enables AES-GCM encryption at rest, it must be 16, 24 or 32 bytes.
+	Key []byte
+	// Required fails the call when the record is not stored, otherwise the error is passed to OnError.
+	Required bool
+	OnError  func(err error)
+}
+
+// NewAudit creates middleware storing every request and response envelope with metadata.
+func NewAudit(c AuditConfig) (Middleware, error) {
+	var aead cipher.AEAD
+	if c.Key != nil {
+		block, err := aes.NewCipher(c.Key)
+		if err != nil {
+			return nil, fmt.Errorf("soap: %s", err)
+		}
+
+		if aead, err = cipher.NewGCM(block); err != nil {
+			return nil, fmt.Errorf("soap: %s", err)
+		}
+	}
+
+	var seq uint64
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			start := time.Now()
+			resp, err := next(ctx, r)
+
+			rec := &AuditRecord{
+				Time:          start.UTC(),
+				Duration:      time.Since(start),
+				URL:           r.URL,
+				Action:        r.Action,
+				RequestHeader: r.Header,
+				Request:       r.Envelope,
+			}
+			if resp != nil {
+				rec.StatusCode, rec.Header, rec.Response = resp.StatusCode, resp.Header, resp.Body
+			}
+			if err != nil {
+				rec.Err = err.Error()
+			}
+
+			key := fmt.Sprintf("%s-%d.json", rec.Time.Format("20060102T150405.000000000Z"), atomic.AddUint64(&seq, 1))
+			if serr := storeAudit(ctx, c.Store, aead, key, rec); serr != nil {
+				if c.Required && err == nil {
+					return nil, serr
+				}
+
+				if c.OnError != nil {
+					c.OnError(serr)
+				}
+			}
+			return resp, err
+		}
+	}, nil
+}
+
+func storeAudit(ctx context.Context, store AuditStore, aead cipher.AEAD, key string, rec *AuditRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("soap: %s", err)
+	}
+
+	if aead != nil {
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return fmt.Errorf("soap: %s", err)
+		}
+		data = aead.Seal(nonce, nonce, data, []byte(key))
+	}
+
+	if err := store.Put(ctx, key, data); err != nil {
+		return fmt.Errorf("soap: audit: %s", err)
+	}
+	return nil
+}
+
+// OpenAuditRecord decodes stored audit record, key is the encryption key or nil.
+func OpenAuditRecord(key []byte, name string, data []byte) (*AuditRecord, error) {
+	if key != nil {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("soap: %s", err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("soap: %s", err)
+		}
+
+		if len(data) < aead.NonceSize() {
+			return nil, errAuditRecord
+		}
+
+		if data, err = aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(name)); err != nil {
+			return nil, fmt.Errorf("soap: %s", err)
+		}
+	}
+
+	rec := &AuditRecord{}
+	if err := json.Unmarshal(data, rec); err != nil {
+		return nil, fmt.Errorf("soap: %s", err)
+	}
+	return rec, nil
+}
+
+// DirStore implements audit store writing each record to a file of the directory.
+type DirStore string
+
+// Put writes the record file.
+func (d DirStore) Put(ctx context.Context, key string, data []byte) error {
+	if strings.ContainsAny(key, `/\`) {
+		return fmt.Errorf("invalid key %q", key)
+	}
+
+	if err := os.MkdirAll(string(d), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(string(d), key), data, 0600)
+}
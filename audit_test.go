@@ -0,0 +1,93 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type failStore struct{}
+
+func (failStore) Put(ctx context.Context, key string, data []byte) error {
+	return errors.New("unavailable")
+}
+
+func TestClient_Audit(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := []byte("0123456789abcdef")
+	audit, err := NewAudit(AuditConfig{Store: DirStore(dir), Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := MustNewClient(srv.URL, Config{Middleware: []Middleware{audit}})
+	if err := client.Call(context.Background(), "get", request{Attr1: "secret"}, &response{}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("got: %v %v, want: 1 file", files, err)
+	}
+
+	data, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), "secret") {
+		t.Fatal("record is not encrypted")
+	}
+
+	rec, err := OpenAuditRecord(key, filepath.Base(files[0]), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Action != "get" || rec.StatusCode != 200 || !strings.Contains(string(rec.Request), "secret") || !strings.Contains(string(rec.Response), "value3") {
+		t.Fatalf("got: %+v", rec)
+	}
+}
+
+func TestClient_AuditRequired(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	var reported error
+	audit, _ := NewAudit(AuditConfig{Store: failStore{}, OnError: func(err error) { reported = err }})
+	if err := MustNewClient(srv.URL, Config{Middleware: []Middleware{audit}}).Call(context.Background(), "get", request{}, &response{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if reported == nil {
+		t.Fatal("got: nil, want: store error")
+	}
+
+	audit, _ = NewAudit(AuditConfig{Store: failStore{}, Required: true})
+	if err := MustNewClient(srv.URL, Config{Middleware: []Middleware{audit}}).Call(context.Background(), "get", request{}, &response{}); err == nil || !strings.Contains(err.Error(), "unavailable") {
+		t.Fatalf("got: %v, want: store error", err)
+	}
+}
@@ -0,0 +1,110 @@
+package soap
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+)
+
+// CredentialStore implements lookup of user passwords, e.g. database or secret manager.
+type CredentialStore interface {
+	// Password returns password of the user, false is returned for unknown user.
+	Password(ctx context.Context, username string) (string, bool, error)
+}
+
+// Principal implements user authenticated by the server middleware.
+type Principal struct {
+	Username string
+}
+
+type principalKey struct{}
+
+// PrincipalFromContext returns principal of the request authenticated by the server middleware.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// UsernameTokenAuth implements server authentication by WS-Security UsernameToken,
+// PasswordText and PasswordDigest tokens are validated against the store.
+type UsernameTokenAuth struct {
+	Store CredentialStore
+	// Replay checks freshness and nonces of the requests, digest tokens may be replayed without it.
+	Replay *ReplayGuard
+	// RequireDigest rejects PasswordText tokens.
+	RequireDigest bool
+}
+
+// Middleware returns server middleware passing principal of the authenticated request in the context.
+func (a *UsernameTokenAuth) Middleware() ServerMiddleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r *ServerRequest) (interface{}, error) {
+			p, err := a.authenticate(ctx, r.Envelope, r.limits)
+			if err != nil {
+				return nil, err
+			}
+			return next(context.WithValue(ctx, principalKey{}, p), r)
+		}
+	}
+}
+
+// authenticate validates the username token, freshness and nonce are checked after the password,
+// so unauthenticated requests do not fill the nonce store.
+func (a *UsernameTokenAuth) authenticate(ctx context.Context, envelope []byte, limits DecodeLimits) (*Principal, error) {
+	n := new(Node)
+	if err := limits.decoder(trimProlog(envelope)).Decode(n); err != nil {
+		return nil, NewClientFault("envelope is invalid")
+	}
+
+	token := n.Find("Envelope/Header/Security/UsernameToken")
+	if token == nil {
+		return nil, &Fault{Code: FaultInvalidSecurity, Text: "security header has no username token"}
+	}
+
+	username := token.Child("Username").Text()
+	password, ok, err := a.Store.Password(ctx, username)
+	if err != nil {
+		return nil, NewServerFault("internal server error")
+	}
+
+	failed := &Fault{Code: FaultFailedAuthentication, Text: "authentication failed"}
+	p := token.Child("Password")
+	if !ok || p == nil {
+		return nil, failed
+	}
+
+	typ, _ := p.Attr("Type")
+	got, want := p.Text(), password
+	switch typ {
+	case PasswordDigest:
+		nonce, err := base64.StdEncoding.DecodeString(token.Child("Nonce").Text())
+		if err != nil {
+			return nil, failed
+		}
+		want = PasswordDigestOf(nonce, token.Child("Created").Text(), password)
+	case PasswordText, "":
+		if a.RequireDigest {
+			return nil, failed
+		}
+	default:
+		return nil, failed
+	}
+
+	if subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+		return nil, failed
+	}
+
+	if a.Replay != nil {
+		switch err := a.Replay.check(ctx, n); err {
+		case nil:
+		case errStale, errTimestamp:
+			return nil, &Fault{Code: FaultMessageExpired, Text: trimSpace(strings.TrimPrefix(err.Error(), "soap: "))}
+		case errReplay:
+			return nil, &Fault{Code: FaultFailedAuthentication, Text: "message is replayed"}
+		default:
+			return nil, NewServerFault("internal server error")
+		}
+	}
+	return &Principal{Username: username}, nil
+}
@@ -0,0 +1,142 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type passwords map[string]string
+
+func (p passwords) Password(ctx context.Context, username string) (string, bool, error) {
+	v, ok := p[username]
+	return v, ok, nil
+}
+
+func TestUsernameTokenAuth(t *testing.T) {
+	t.Parallel()
+	auth := &UsernameTokenAuth{
+		Store:         passwords{"user": "secret"},
+		Replay:        &ReplayGuard{Store: NewMemoryNonceStore(), MaxAge: time.Minute},
+		RequireDigest: true,
+	}
+	s := NewServer(ServerConfig{Middleware: []ServerMiddleware{auth.Middleware()}})
+	s.Handle("whoami", func(ctx context.Context, r *ServerRequest) (interface{}, error) {
+		p, _ := PrincipalFromContext(ctx)
+		return response{Attr3: p.Username}, nil
+	})
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	var sent []byte
+	record := func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			sent = r.Envelope
+			return next(ctx, r)
+		}
+	}
+
+	var resp response
+	wsse := &WSSE{Username: "user", Password: "secret", Digest: true, TTL: time.Minute}
+	if err := MustNewClient(srv.URL, Config{WSSE: wsse, Middleware: []Middleware{record}}).Call(context.Background(), "whoami", request{}, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Attr3 != "user" {
+		t.Fatalf("got: %s, want: %s", resp.Attr3, "user")
+	}
+
+	for i, v := range []struct {
+		wsse *WSSE
+		want string
+	}{
+		{wsse: &WSSE{Username: "user", Password: "wrong", Digest: true}, want: "soap: wsse:FailedAuthentication: authentication failed 500"},
+		{wsse: &WSSE{Username: "unknown", Password: "secret", Digest: true}, want: "soap: wsse:FailedAuthentication: authentication failed 500"},
+		{wsse: &WSSE{Username: "user", Password: "secret", TTL: time.Minute}, want: "soap: wsse:FailedAuthentication: authentication failed 500"},
+		{want: "soap: wsse:InvalidSecurity: security header has no username token 500"},
+	} {
+		err := MustNewClient(srv.URL, Config{WSSE: v.wsse}).Call(context.Background(), "whoami", request{}, &resp)
+		if err == nil || err.Error() != v.want {
+			t.Errorf("#%d got: %v, want: %s", i, err, v.want)
+		}
+	}
+
+	req, _ := http.NewRequest("POST", srv.URL, bytes.NewReader(sent))
+	req.Header.Set("SOAPAction", "whoami")
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	if !bytes.Contains(body, []byte("message is replayed")) {
+		t.Fatalf("got: %s, want: replayed message fault", body)
+	}
+}
+
+func TestUsernameTokenAuth_Order(t *testing.T) {
+	t.Parallel()
+	auth := &UsernameTokenAuth{
+		Store:  passwords{"user": "secret"},
+		Replay: &ReplayGuard{Store: NewMemoryNonceStore(), MaxAge: time.Minute},
+	}
+	newServer := func(limits DecodeLimits) *httptest.Server {
+		s := NewServer(ServerConfig{DecodeLimits: limits, Middleware: []ServerMiddleware{auth.Middleware()}})
+		s.Handle("whoami", func(ctx context.Context, r *ServerRequest) (interface{}, error) {
+			return response{Attr3: "ok"}, nil
+		})
+		return httptest.NewServer(s)
+	}
+
+	srv := newServer(DecodeLimits{})
+	defer srv.Close()
+
+	created := time.Now().UTC().Format(wsuTime)
+	envelope := func(password string) string {
+		return `<soapenv:Envelope xmlns:soapenv="` + envelopeNS + `" xmlns:wsse="` + WSSENS + `" xmlns:wsu="` + WSUNS + `"><soapenv:Header><wsse:Security>` +
+			`<wsse:UsernameToken><wsse:Username>user</wsse:Username><wsse:Password Type="` + PasswordDigest + `">` +
+			PasswordDigestOf([]byte("nonce"), created, password) + `</wsse:Password><wsse:Nonce>bm9uY2U=</wsse:Nonce><wsu:Created>` + created + `</wsu:Created>` +
+			`</wsse:UsernameToken></wsse:Security></soapenv:Header><soapenv:Body><Request xmlns="test:call"/></soapenv:Body></soapenv:Envelope>`
+	}
+
+	post := func(url, envelope string) string {
+		req, _ := http.NewRequest("POST", url, strings.NewReader(envelope))
+		req.Header.Set("SOAPAction", "whoami")
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+
+		body, _ := ioutil.ReadAll(r.Body)
+		return string(body)
+	}
+
+	// the nonce of the rejected request is not stored
+	for i, v := range []struct {
+		password string
+		want     string
+	}{
+		{password: "wrong", want: "authentication failed"},
+		{password: "secret", want: "ok"},
+		{password: "secret", want: "message is replayed"},
+	} {
+		if got := post(srv.URL, envelope(v.password)); !strings.Contains(got, v.want) {
+			t.Errorf("#%d got: %s, want: %s", i, got, v.want)
+		}
+	}
+
+	limited := newServer(DecodeLimits{MaxDepth: 3})
+	defer limited.Close()
+
+	if got := post(limited.URL, envelope("secret")); !strings.Contains(got, "envelope is invalid") {
+		t.Errorf("got: %s, want: envelope is invalid", got)
+	}
+}
@@ -0,0 +1,101 @@
+package soap
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+)
+
+// base64Chunk is size of the raw chunk, it is multiple of 3 so encoded chunks are concatenated without padding.
+const base64Chunk = 3 * 16 * 1024
+
+// Base64Reader implements base64Binary element encoded from the reader during marshal,
+// so file content is not copied into a string of the request. Envelope of the call exceeding
+// SpoolMemory is encoded into the spool file and sent from it, unless middleware, Escape or
+// RawRequestTransformers of the client need the envelope in memory.
+type Base64Reader struct {
+	R io.Reader
+}
+
+// MarshalXML implements xml.Marshaler interface.
+func (b Base64Reader) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	buf := make([]byte, base64Chunk)
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(base64Chunk))
+	for {
+		n, err := io.ReadFull(b.R, buf)
+		if n > 0 {
+			base64.StdEncoding.Encode(encoded, buf[:n])
+			if err := e.EncodeToken(xml.CharData(encoded[:base64.StdEncoding.EncodedLen(n)])); err != nil {
+				return err
+			}
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// Base64Writer implements base64Binary element decoded into the writer during unmarshal,
+// e.g. into the spool of the call for large attachments.
+type Base64Writer struct {
+	W io.Writer
+}
+
+// UnmarshalXML implements xml.Unmarshaler interface.
+func (b Base64Writer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(b.W, base64.NewDecoder(base64.StdEncoding, pr))
+		pr.CloseWithError(err)
+		done <- err
+	}()
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			pw.CloseWithError(err)
+			<-done
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.CharData:
+			if _, err := pw.Write(stripSpace(t)); err != nil {
+				<-done
+				return err
+			}
+		case xml.StartElement:
+			if err := d.Skip(); err != nil {
+				pw.CloseWithError(err)
+				<-done
+				return err
+			}
+		case xml.EndElement:
+			pw.Close()
+			return <-done
+		}
+	}
+}
+
+// stripSpace removes whitespace which is allowed inside base64Binary.
+func stripSpace(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for _, c := range data {
+		switch c {
+		case ' ', '\t', '\r', '\n':
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
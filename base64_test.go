@@ -0,0 +1,97 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+type upload struct {
+	XMLName xml.Name     `xml:"test:call Upload"`
+	Name    string       `xml:"name"`
+	Content Base64Reader `xml:"content"`
+}
+
+type uploaded struct {
+	XMLName xml.Name     `xml:"test:call Upload"`
+	Content Base64Writer `xml:"content"`
+}
+
+type download struct {
+	XMLName xml.Name     `xml:"test:call Download"`
+	Content Base64Writer `xml:"content"`
+}
+
+func TestClient_Base64(t *testing.T) {
+	t.Parallel()
+	file := bytes.Repeat([]byte("0123456789"), base64Chunk/5+1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var got bytes.Buffer
+		if err := xml.Unmarshal(body, &Envelope{Body: Body{Content: &uploaded{Content: Base64Writer{W: &got}}}}); err != nil {
+			t.Error(err)
+		}
+
+		if !bytes.Equal(got.Bytes(), file) {
+			t.Errorf("got: %d bytes, want: %d bytes", got.Len(), len(file))
+		}
+
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Download xmlns="test:call">` +
+			"<content>Z29w\n aGVy</content></Download></Body></Envelope>"))
+	}))
+	defer srv.Close()
+
+	var got bytes.Buffer
+	if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "", upload{Name: "file", Content: Base64Reader{R: bytes.NewReader(file)}}, &download{Content: Base64Writer{W: &got}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "gopher"; got.String() != want {
+		t.Fatalf("got: %s, want: %s", got.String(), want)
+	}
+}
+
+// zeros implements endless reader of zero bytes.
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestClient_Base64Stream(t *testing.T) {
+	// allocations are measured without parallel tests
+	const size = 32 << 20
+	var received int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.Copy(ioutil.Discard, r.Body)
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Response xmlns="test:call"/></Body></Envelope>`))
+	}))
+	defer srv.Close()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	content := Base64Reader{R: io.LimitReader(zeros{}, size)}
+	if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "", upload{Name: "file", Content: content}, nil); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+
+	if received < size*4/3 {
+		t.Fatalf("got: %d bytes, want: more than %d bytes", received, size*4/3)
+	}
+
+	// the envelope is sent from the spool file instead of memory
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > size/4 {
+		t.Fatalf("got: %d bytes allocated, want: less than %d bytes", allocated, size/4)
+	}
+}
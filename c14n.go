@@ -0,0 +1,270 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const xmlNS = "http://www.w3.org/XML/1998/namespace"
+
+// xnode implements element of the parsed document keeping raw prefixes and byte offsets,
+// it is used to edit encoded envelopes and to canonicalize their parts.
+type xnode struct {
+	parent   *xnode
+	name     xml.Name // Space holds the raw prefix
+	attrs    []xml.Attr
+	children []interface{} // *xnode or xml.CharData
+	// start and end are offsets of the element, content is between contentStart and contentEnd
+	start, contentStart, contentEnd, end int
+}
+
+// parseDoc parses document into tree of the root element.
+func parseDoc(data []byte) (*xnode, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var root, cur *xnode
+	for {
+		offset := int(d.InputOffset())
+		token, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("soap: %s", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			n := &xnode{parent: cur, name: t.Name, attrs: t.Attr, start: offset, contentStart: int(d.InputOffset())}
+			if cur == nil {
+				if root != nil {
+					return nil, fmt.Errorf("soap: multiple root elements")
+				}
+				root = n
+			} else {
+				cur.children = append(cur.children, n)
+			}
+			cur = n
+		case xml.EndElement:
+			if cur == nil {
+				return nil, fmt.Errorf("soap: unexpected end element %s", t.Name.Local)
+			}
+			cur.contentEnd, cur.end = offset, int(d.InputOffset())
+			// self-closing element has no end tag
+			if cur.end == cur.contentStart {
+				cur.contentEnd = cur.contentStart
+			}
+			cur = cur.parent
+		case xml.CharData:
+			if cur != nil {
+				cur.children = append(cur.children, t.Copy())
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("soap: document is empty")
+	}
+	return root, nil
+}
+
+// namespace returns namespace bound to the prefix in scope of the element.
+func (n *xnode) namespace(prefix string) string {
+	if prefix == "xml" {
+		return xmlNS
+	}
+
+	for e := n; e != nil; e = e.parent {
+		for _, a := range e.attrs {
+			if prefix == "" && a.Name.Space == "" && a.Name.Local == "xmlns" || a.Name.Space == "xmlns" && a.Name.Local == prefix {
+				return a.Value
+			}
+		}
+	}
+	return ""
+}
+
+// child returns the first child element with the local name.
+func (n *xnode) child(local string) *xnode {
+	if n == nil {
+		return nil
+	}
+
+	for _, c := range n.children {
+		if e, ok := c.(*xnode); ok && e.name.Local == local {
+			return e
+		}
+	}
+	return nil
+}
+
+// find returns element by path of local names below the node.
+func (n *xnode) find(path ...string) *xnode {
+	for _, p := range path {
+		n = n.child(p)
+	}
+	return n
+}
+
+// id returns value of Id attribute of any namespace.
+func (n *xnode) id() string {
+	for _, a := range n.attrs {
+		if a.Name.Local == "Id" || a.Name.Local == "ID" {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// byID returns element with the Id attribute value.
+func (n *xnode) byID(id string) *xnode {
+	if n.id() == id {
+		return n
+	}
+
+	for _, c := range n.children {
+		if e, ok := c.(*xnode); ok {
+			if found := e.byID(id); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// duplicateID returns Id attribute value which occurs more than once below the node.
+func (n *xnode) duplicateID(seen map[string]bool) (string, bool) {
+	if id := n.id(); id != "" {
+		if seen[id] {
+			return id, true
+		}
+		seen[id] = true
+	}
+
+	for _, c := range n.children {
+		if e, ok := c.(*xnode); ok {
+			if id, ok := e.duplicateID(seen); ok {
+				return id, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Canonicalize returns exclusive canonical form (xml-exc-c14n without comments) of the document element.
+func Canonicalize(data []byte) ([]byte, error) {
+	n, err := parseDoc(data)
+	if err != nil {
+		return nil, err
+	}
+	return n.canonical(), nil
+}
+
+// canonical returns exclusive canonical form of the element.
+func (n *xnode) canonical() []byte {
+	var buf bytes.Buffer
+	n.writeCanonical(&buf, map[string]string{})
+	return buf.Bytes()
+}
+
+func (n *xnode) writeCanonical(w *bytes.Buffer, rendered map[string]string) {
+	// namespaces visibly utilized by the element and its attributes
+	used := map[string]bool{n.name.Space: true}
+	var attrs []xml.Attr
+	for _, a := range n.attrs {
+		if a.Name.Space == "xmlns" || a.Name.Space == "" && a.Name.Local == "xmlns" {
+			continue
+		}
+
+		if a.Name.Space != "" {
+			used[a.Name.Space] = true
+		}
+		attrs = append(attrs, a)
+	}
+
+	var prefixes []string
+	scope := make(map[string]string, len(rendered)+len(used))
+	for k, v := range rendered {
+		scope[k] = v
+	}
+
+	for p := range used {
+		if p == "xml" {
+			continue
+		}
+
+		uri := n.namespace(p)
+		if v, ok := rendered[p]; ok && v == uri || !ok && p == "" && uri == "" {
+			continue
+		}
+		scope[p] = uri
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+
+	sort.Slice(attrs, func(i, j int) bool {
+		si, sj := n.namespace(attrs[i].Name.Space), n.namespace(attrs[j].Name.Space)
+		if attrs[i].Name.Space == "" {
+			si = ""
+		}
+		if attrs[j].Name.Space == "" {
+			sj = ""
+		}
+
+		if si != sj {
+			return si < sj
+		}
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+
+	name := rawName(n.name)
+	w.WriteByte('<')
+	w.WriteString(name)
+	for _, p := range prefixes {
+		if p == "" {
+			w.WriteString(` xmlns="`)
+		} else {
+			w.WriteString(` xmlns:` + p + `="`)
+		}
+		w.WriteString(escapeAttr(scope[p]))
+		w.WriteByte('"')
+	}
+
+	for _, a := range attrs {
+		w.WriteString(" " + rawName(a.Name) + `="` + escapeAttr(a.Value) + `"`)
+	}
+	w.WriteByte('>')
+
+	for _, c := range n.children {
+		switch t := c.(type) {
+		case *xnode:
+			t.writeCanonical(w, scope)
+		case xml.CharData:
+			w.WriteString(escapeText(string(t)))
+		}
+	}
+	w.WriteString("</" + name + ">")
+}
+
+func rawName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func escapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
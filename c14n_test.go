@@ -0,0 +1,31 @@
+package soap
+
+import (
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	t.Parallel()
+	for i, v := range []struct {
+		in, want string
+	}{
+		// xml-exc-c14n section 2.2
+		{
+			in:   `<n1:elem2 xmlns:n0="foo:bar" xmlns:n1="http://example.net" xmlns:n3="ftp://example.org" xml:lang="en"><n3:stuff xmlns:n3="ftp://example.org"/></n1:elem2>`,
+			want: `<n1:elem2 xmlns:n1="http://example.net" xml:lang="en"><n3:stuff xmlns:n3="ftp://example.org"></n3:stuff></n1:elem2>`,
+		},
+		{
+			in:   `<?xml version="1.0"?><a xmlns="urn:a" z="1" xmlns:b="urn:b" b:y="2" c="&#x9;"><b:c><d xmlns="">&lt;t&gt;<![CDATA[&]]></d></b:c></a>`,
+			want: `<a xmlns="urn:a" xmlns:b="urn:b" c="&#x9;" z="1" b:y="2"><b:c><d xmlns="">&lt;t&gt;&amp;</d></b:c></a>`,
+		},
+	} {
+		got, err := Canonicalize([]byte(v.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != v.want {
+			t.Errorf("#%d got: %s, want: %s", i, got, v.want)
+		}
+	}
+}
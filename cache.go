@@ -0,0 +1,180 @@
+package soap
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// CacheConfig implements config of the response cache.
+type CacheConfig struct {
+	// TTL is lifetime of the cached response.
+	TTL time.Duration
+	// MaxEntries bounds the cache size, the least recently used response is evicted.
+	MaxEntries int
+	// Actions lists cached soap actions, the operations must be read-only.
+	Actions []string
+}
+
+type cacheEntry struct {
+	key     [sha256.Size]byte
+	resp    *Response
+	expires time.Time
+}
+
+type cache struct {
+	mu      sync.Mutex
+	config  CacheConfig
+	actions map[string]bool
+	lru     *list.List
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+// NewCache creates middleware caching successful responses keyed by url, action, request envelope and
+// identity of the caller: Authorization header of the request and hash of username and password or token
+// of the client, so the cache may be shared by clients of several users. Calls are not cached when
+// the credentials provider fails. WS-Security signed calls, e.g. of SAML or
+// UsernameToken middleware, are not cacheable: identity of the security header is not keyed,
+// so the cache must not precede the signing middleware.
+func NewCache(c CacheConfig) Middleware {
+	cc := &cache{
+		config:  c,
+		actions: make(map[string]bool, len(c.Actions)),
+		lru:     list.New(),
+		entries: make(map[[sha256.Size]byte]*list.Element),
+	}
+	for _, a := range c.Actions {
+		cc.actions[a] = true
+	}
+	return cc.middleware
+}
+
+func (c *cache) middleware(next RoundTripFunc) RoundTripFunc {
+	return func(ctx context.Context, r *Request) (*Response, error) {
+		if !c.actions[r.Action] {
+			return next(ctx, r)
+		}
+
+		key, ok := requestKey(ctx, r)
+		if !ok {
+			return next(ctx, r)
+		}
+
+		if resp, ok := c.get(key); ok {
+			return resp, nil
+		}
+
+		resp, err := next(ctx, r)
+		if err == nil && resp.StatusCode == 200 {
+			c.set(key, resp)
+		}
+		return resp, err
+	}
+}
+
+func (c *cache) get(key [sha256.Size]byte) (*Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := e.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.lru.Remove(e)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(e)
+	return entry.resp, true
+}
+
+func (c *cache) set(key [sha256.Size]byte, resp *Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{key: key, resp: resp, expires: time.Now().Add(c.config.TTL)}
+	if e, ok := c.entries[key]; ok {
+		e.Value = entry
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+	if c.config.MaxEntries > 0 && c.lru.Len() > c.config.MaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// requestKey returns hash of the request url, action, caller identity and envelope,
+// false is returned when the identity is not resolved.
+func requestKey(ctx context.Context, r *Request) ([sha256.Size]byte, bool) {
+	var key [sha256.Size]byte
+	var identity string
+	if f, ok := ctx.Value(identityKey{}).(identityFunc); ok {
+		if identity, ok = f(ctx); !ok {
+			return key, false
+		}
+	}
+
+	h := sha256.New()
+	for _, s := range []string{r.URL, r.Action, identity, r.Header.Get("Authorization")} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	h.Write(r.Envelope)
+	copy(key[:], h.Sum(nil))
+	return key, true
+}
+
+type identityKey struct{}
+
+// identityFunc returns identity of the call, it is resolved only when the call is cached.
+type identityFunc func(ctx context.Context) (string, bool)
+
+// withIdentity returns context with identity of the client authorization keying cached responses.
+func (s *Client) withIdentity(ctx context.Context) context.Context {
+	return context.WithValue(ctx, identityKey{}, identityFunc(s.identity))
+}
+
+// identity returns hash of username and password or token of the client, false is returned
+// when the credentials provider fails.
+func (s *Client) identity(ctx context.Context) (string, bool) {
+	auth, _ := s.current()
+	if p := s.config.Credentials; p != nil {
+		token, err := p.GetToken(ctx)
+		if err != nil {
+			return "", false
+		}
+
+		if token != "" {
+			return credentialHash("token", token), true
+		}
+
+		if auth, err = p.GetBasicAuth(ctx); err != nil {
+			return "", false
+		}
+	}
+
+	if auth == nil {
+		return "", true
+	}
+	return credentialHash("basic", auth.Username, auth.Password), true
+}
+
+// credentialHash returns hash of the credential parts, so secrets are not kept in keys.
+func credentialHash(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return string(h.Sum(nil))
+}
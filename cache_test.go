@@ -0,0 +1,112 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_Cache(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{Middleware: []Middleware{NewCache(CacheConfig{
+		TTL:        time.Minute,
+		MaxEntries: 1,
+		Actions:    []string{"codes"},
+	})}})
+
+	for i, v := range []struct {
+		action string
+		req    request
+		calls  int32
+	}{
+		{action: "codes", req: request{Attr1: "1"}, calls: 1},
+		{action: "codes", req: request{Attr1: "1"}, calls: 1},
+		{action: "codes", req: request{Attr1: "2"}, calls: 2},
+		{action: "codes", req: request{Attr1: "1"}, calls: 3},
+		{action: "update", req: request{Attr1: "1"}, calls: 4},
+		{action: "update", req: request{Attr1: "1"}, calls: 5},
+	} {
+		var r response
+		if err := client.Call(context.Background(), v.action, v.req, &r); err != nil {
+			t.Fatal(err)
+		}
+
+		if r.Attr3 != "value3" {
+			t.Errorf("#%d got: %s, want: %s", i, r.Attr3, "value3")
+		}
+
+		if got := atomic.LoadInt32(&calls); got != v.calls {
+			t.Errorf("#%d got: %d, want: %d", i, got, v.calls)
+		}
+	}
+}
+
+func TestClient_CacheIdentity(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		u, _, _ := r.BasicAuth()
+		b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: u}}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	cache := NewCache(CacheConfig{TTL: time.Minute, Actions: []string{"codes"}})
+	alice := MustNewClient(srv.URL, Config{BasicAuth: &BasicAuth{Username: "alice", Password: "pass"}, Middleware: []Middleware{cache}})
+	bob := MustNewClient(srv.URL, Config{BasicAuth: &BasicAuth{Username: "bob", Password: "pass"}, Middleware: []Middleware{cache}})
+	wrong := MustNewClient(srv.URL, Config{BasicAuth: &BasicAuth{Username: "bob", Password: "wrong"}, Middleware: []Middleware{cache}})
+	token := MustNewClient(srv.URL, Config{Credentials: contextToken{}, Middleware: []Middleware{cache}})
+
+	for i, v := range []struct {
+		client *Client
+		token  string
+		want   string
+		calls  int32
+	}{
+		{client: alice, want: "alice", calls: 1},
+		{client: bob, want: "bob", calls: 2},
+		{client: alice, want: "alice", calls: 2},
+		{client: bob, want: "bob", calls: 2},
+		{client: wrong, want: "bob", calls: 3},
+		{client: token, token: "a", calls: 4},
+		{client: token, token: "b", calls: 5},
+		{client: token, token: "a", calls: 5},
+	} {
+		var r response
+		if err := v.client.Call(context.WithValue(context.Background(), contextToken{}, v.token), "codes", request{}, &r); err != nil {
+			t.Fatal(err)
+		}
+
+		if r.Attr3 != v.want {
+			t.Errorf("#%d got: %s, want: %s", i, r.Attr3, v.want)
+		}
+
+		if got := atomic.LoadInt32(&calls); got != v.calls {
+			t.Errorf("#%d got: %d, want: %d", i, got, v.calls)
+		}
+	}
+}
+
+// contextToken implements credentials of the token passed in the context.
+type contextToken struct{}
+
+func (contextToken) GetBasicAuth(ctx context.Context) (*BasicAuth, error) {
+	return nil, nil
+}
+
+func (contextToken) GetToken(ctx context.Context) (string, error) {
+	return ctx.Value(contextToken{}).(string), nil
+}
@@ -0,0 +1,85 @@
+package soap
+
+import "time"
+
+// CallInfo implements metadata of the failed call carried by faults, transport
+// and http status errors returned by Call, so the errors are self-describing in logs.
+type CallInfo struct {
+	action   string
+	endpoint string
+	attempts int
+	elapsed  time.Duration
+}
+
+// Action returns soap action of the call.
+func (c CallInfo) Action() string {
+	return c.action
+}
+
+// Endpoint returns url the call was sent to.
+func (c CallInfo) Endpoint() string {
+	return c.endpoint
+}
+
+// Attempts returns number of attempts including retries.
+func (c CallInfo) Attempts() int {
+	return c.attempts
+}
+
+// Elapsed returns duration of the call including encoding and retries.
+func (c CallInfo) Elapsed() time.Duration {
+	return c.elapsed
+}
+
+// CallInfoOf returns metadata of the call which returned the error,
+// ok is false when the error carries no metadata.
+func CallInfoOf(err error) (info CallInfo, ok bool) {
+	switch e := err.(type) {
+	case *Fault:
+		return e.CallInfo, e.attempts > 0
+	case *transportError:
+		return e.CallInfo, e.attempts > 0
+	case *statusError:
+		return e.CallInfo, e.attempts > 0
+	case *MappedError:
+		return CallInfoOf(e.Fault)
+	}
+	return CallInfo{}, false
+}
+
+// withCallInfo returns copy of the error with metadata of the call, the error may be shared
+// by concurrent calls, e.g. coalesced ones.
+func withCallInfo(err error, info CallInfo) error {
+	switch e := copyError(err).(type) {
+	case *Fault:
+		e.CallInfo = info
+		return e
+	case *transportError:
+		e.CallInfo = info
+		return e
+	case *statusError:
+		e.CallInfo = info
+		return e
+	}
+	return err
+}
+
+// copyError returns shallow copy of fault, transport and http status errors, other errors are returned as is.
+func copyError(err error) error {
+	switch e := err.(type) {
+	case *Fault:
+		if e == nil {
+			return err
+		}
+
+		c := *e
+		return &c
+	case *transportError:
+		c := *e
+		return &c
+	case *statusError:
+		c := *e
+		return &c
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_CallInfo(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("html") != "" {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html>bad gateway</html>"))
+			return
+		}
+
+		w.WriteHeader(500)
+		b, _ := xml.Marshal(Envelope{Body: Body{Fault: &Fault{Code: "s:ServerBusy", Text: "busy"}}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	err := MustNewClient(srv.URL, Config{Retry: RetryPolicy{
+		MaxAttempts: 2,
+		Backoff:     time.Millisecond,
+		Fault:       RetryFaultCodes("ServerBusy"),
+	}}).Call(context.Background(), "urn:GetUser", request{}, nil)
+
+	f, ok := err.(*Fault)
+	if !ok {
+		t.Fatalf("got: %v, want: fault", err)
+	}
+
+	if f.Action() != "urn:GetUser" || f.Endpoint() != srv.URL || f.Attempts() != 2 || f.Elapsed() <= 0 {
+		t.Fatalf("got: %s %s %d %s", f.Action(), f.Endpoint(), f.Attempts(), f.Elapsed())
+	}
+
+	err = MustNewClient(srv.URL+"?html=1", Config{}).Call(context.Background(), "urn:GetGroup", request{}, nil)
+	info, ok := CallInfoOf(err)
+	if !ok || info.Action() != "urn:GetGroup" || info.Attempts() != 1 {
+		t.Fatalf("got: %v %+v", err, info)
+	}
+
+	if _, ok := CallInfoOf(errClosed); ok {
+		t.Fatal("sentinel error has call info")
+	}
+}
@@ -0,0 +1,70 @@
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// CDATA implements string encoded as CDATA section.
+type CDATA string
+
+// MarshalXML implements xml.Marshaler interface.
+func (c CDATA) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(struct {
+		Value string `xml:",cdata"`
+	}{Value: string(c)}, start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler interface.
+func (c *CDATA) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v string
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+
+	*c = CDATA(v)
+	return nil
+}
+
+// EmbeddedXML implements string element holding escaped or CDATA-wrapped xml document,
+// the document is encoded from Value and decoded into Value which must be a pointer.
+type EmbeddedXML struct {
+	Value interface{}
+	// CDATA wraps the document into CDATA section instead of escaping.
+	CDATA bool
+}
+
+// MarshalXML implements xml.Marshaler interface.
+func (x EmbeddedXML) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	b, err := xml.Marshal(x.Value)
+	if err != nil {
+		return err
+	}
+
+	if x.CDATA {
+		return CDATA(b).MarshalXML(e, start)
+	}
+	return e.EncodeElement(string(b), start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler interface.
+func (x *EmbeddedXML) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v string
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	return UnmarshalEmbedded(v, x.Value)
+}
+
+// UnmarshalEmbedded decodes xml document embedded into string element.
+func UnmarshalEmbedded(s string, v interface{}) error {
+	b := trimProlog([]byte(s))
+	if len(b) == 0 {
+		return nil
+	}
+
+	if err := xml.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("soap: embedded xml %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type order struct {
+	XMLName xml.Name `xml:"Order"`
+	ID      string   `xml:"id,attr"`
+	Item    string   `xml:"Item"`
+}
+
+type embedded struct {
+	XMLName xml.Name    `xml:"test:call Embedded"`
+	Note    CDATA       `xml:"note"`
+	Payload EmbeddedXML `xml:"payload"`
+}
+
+func Test_EmbeddedMarshal(t *testing.T) {
+	t.Parallel()
+	for i, v := range []struct {
+		cdata bool
+		want  string
+	}{
+		{want: `<Embedded xmlns="test:call"><note><![CDATA[a < b]]></note><payload>&lt;Order id=&#34;1&#34;&gt;&lt;Item&gt;book&lt;/Item&gt;&lt;/Order&gt;</payload></Embedded>`},
+		{cdata: true, want: `<Embedded xmlns="test:call"><note><![CDATA[a < b]]></note><payload><![CDATA[<Order id="1"><Item>book</Item></Order>]]></payload></Embedded>`},
+	} {
+		b, err := xml.Marshal(embedded{Note: "a < b", Payload: EmbeddedXML{Value: order{ID: "1", Item: "book"}, CDATA: v.cdata}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := string(b); got != v.want {
+			t.Errorf("#%d got: %s, want: %s", i, got, v.want)
+		}
+	}
+}
+
+func TestClient_Embedded(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Embedded xmlns="test:call">` +
+			`<note>plain</note><payload><![CDATA[<?xml version="1.0"?><Order id="2"><Item>pen</Item></Order>]]></payload>` +
+			`</Embedded></Body></Envelope>`))
+	}))
+	defer srv.Close()
+
+	var o order
+	r := embedded{Payload: EmbeddedXML{Value: &o}}
+	if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "", request{}, &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.ID != "2" || o.Item != "pen" || r.Note != "plain" {
+		t.Fatalf("got: %+v %s", o, r.Note)
+	}
+}
@@ -0,0 +1,57 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+)
+
+// Choice implements response content of one of the candidate body elements, e.g. normal
+// response or business error response. The element is decoded into the candidate keyed
+// by its name, a key without namespace matches any namespace.
+type Choice struct {
+	Candidates Targets
+	// Default is decoded when the element does not match any candidate, ChoiceError is returned when nil.
+	Default interface{}
+	// Name is name of the decoded element.
+	Name xml.Name
+	// Chosen is the candidate the element is decoded into.
+	Chosen interface{}
+}
+
+// ChoiceError is returned when the body element does not match any candidate.
+type ChoiceError struct {
+	Name xml.Name
+}
+
+func (e *ChoiceError) Error() string {
+	return fmt.Sprintf("soap: response element {%s}%s does not match any candidate", e.Name.Space, e.Name.Local)
+}
+
+func (c *Choice) decode(d *xml.Decoder, se xml.StartElement) error {
+	v, ok := c.Candidates.match(se.Name)
+	if !ok {
+		if c.Default == nil {
+			return &ChoiceError{Name: se.Name}
+		}
+		v = c.Default
+	}
+
+	if err := d.DecodeElement(v, &se); err != nil {
+		return err
+	}
+
+	c.Name = se.Name
+	c.Chosen = v
+	return nil
+}
+
+// CallChoice sends soap request and decodes response body element into one of the candidates,
+// the chosen one is returned.
+func (s *Client) CallChoice(ctx context.Context, soapAction string, request interface{}, candidates Targets) (*Choice, error) {
+	c := &Choice{Candidates: candidates}
+	if err := s.Call(ctx, soapAction, request, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,62 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_CallChoice(t *testing.T) {
+	t.Parallel()
+	body := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body>` + body + `</Body></Envelope>`))
+	}))
+	defer srv.Close()
+
+	type businessError struct {
+		Reason string `xml:"Reason"`
+	}
+
+	var (
+		resp response
+		berr businessError
+	)
+	candidates := Targets{
+		{Space: "test:call", Local: "Response"}: &resp,
+		{Local: "BusinessError"}:                &berr,
+	}
+	client := MustNewClient(srv.URL, Config{DecodeMode: Strict})
+
+	body = `<BusinessError xmlns="test:other"><Reason>limit</Reason></BusinessError>`
+	c, err := client.CallChoice(context.Background(), "", request{}, candidates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Chosen != &berr || berr.Reason != "limit" {
+		t.Fatalf("got: %v %+v, want: business error", c.Name, berr)
+	}
+
+	body = `<Response xmlns="test:call"><attr3>value3</attr3></Response>`
+	if c, err = client.CallChoice(context.Background(), "", request{}, candidates); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (xml.Name{Space: "test:call", Local: "Response"}); c.Name != want || resp.Attr3 != "value3" {
+		t.Fatalf("got: %v %+v, want: %v", c.Name, resp, want)
+	}
+
+	body = `<Response xmlns="test:call"><unknown/></Response>`
+	if _, err = client.CallChoice(context.Background(), "", request{}, candidates); err == nil {
+		t.Fatal("got: nil, want: error of unknown element")
+	}
+
+	body = `<Other xmlns="test:call"/>`
+	_, err = client.CallChoice(context.Background(), "", request{}, candidates)
+	if _, ok := err.(*ChoiceError); !ok {
+		t.Fatalf("got: %v, want: choice error", err)
+	}
+}
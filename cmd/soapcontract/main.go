@@ -0,0 +1,49 @@
+// Command soapcontract generates table-driven contract test of the gowsdl generated types.
+// Sample request and response of each operation are built from the wsdl schema,
+// the test checks that the samples round-trip through the types.
+//
+//	soapcontract -wsdl service.wsdl -pkg service -o contract_test.go
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/itcomusic/soap/soaptest"
+)
+
+func main() {
+	var (
+		wsdl = flag.String("wsdl", "", "path to the wsdl file")
+		pkg  = flag.String("pkg", "", "package of the generated types")
+		out  = flag.String("o", "contract_test.go", "output file, - is stdout")
+	)
+	flag.Parse()
+	log.SetFlags(0)
+
+	if *wsdl == "" || *pkg == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := ioutil.ReadFile(*wsdl)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := soaptest.GenerateContract(*pkg, data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *out == "-" {
+		os.Stdout.Write(src)
+		return
+	}
+
+	if err := ioutil.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,71 @@
+// Command soapfmt pretty-prints, canonicalizes and redacts soap envelopes for logs and bug reports.
+// The envelope is read from the file or stdin, secrets of the standard headers are redacted by default.
+//
+//	soapfmt -redact sessionId,{urn:partner}Token request.xml
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/itcomusic/soap/soapfmt"
+)
+
+func main() {
+	var (
+		canonical = flag.Bool("c", false, "write exclusive canonical form")
+		indent    = flag.String("indent", "  ", "indentation of the pretty-printed envelope")
+		redact    = flag.String("redact", "", "comma separated elements to redact in addition to the default ones, {namespace}local or local")
+		noRedact  = flag.Bool("no-redact", false, "do not redact the default elements")
+	)
+	flag.Parse()
+	log.SetFlags(0)
+
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if flag.NArg() == 1 {
+		data, err = ioutil.ReadFile(flag.Arg(0))
+	} else {
+		data, err = ioutil.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	o := soapfmt.Options{Canonical: *canonical, Indent: *indent}
+	if !*noRedact {
+		o.Redact = append(o.Redact, soapfmt.DefaultRedacted...)
+	}
+	for _, v := range strings.Split(*redact, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			o.Redact = append(o.Redact, parseName(v))
+		}
+	}
+
+	out, err := soapfmt.Format(data, o)
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Stdout.Write(out)
+}
+
+// parseName parses name in {namespace}local form.
+func parseName(v string) xml.Name {
+	if strings.HasPrefix(v, "{") {
+		if i := strings.IndexByte(v, '}'); i > 0 {
+			return xml.Name{Space: v[1:i], Local: v[i+1:]}
+		}
+	}
+	return xml.Name{Local: v}
+}
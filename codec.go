@@ -0,0 +1,113 @@
+package soap
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"sync/atomic"
+)
+
+// Codec implements binary encoding of the envelope, e.g. Fast Infoset.
+type Codec interface {
+	// ContentType returns media type of the encoded envelope.
+	ContentType() string
+	Encode(envelope []byte) ([]byte, error)
+	Decode(data []byte) ([]byte, error)
+}
+
+// limitDecoder is implemented by codecs limiting size of the decoded document,
+// e.g. fastinfoset.Codec, since references of the binary encodings repeat decoded strings.
+type limitDecoder interface {
+	DecodeLimit(data []byte, max int) ([]byte, error)
+}
+
+// CodecNegotiation implements negotiation of the codec with the server. The codec is advertised
+// in Accept header of xml requests and requests are encoded with it once the server responds
+// with its content type. Responses of the content type are decoded to xml.
+type CodecNegotiation struct {
+	Codec Codec
+	// Optimistic encodes requests with the codec from the first call.
+	Optimistic bool
+	// MaxSize limits size of the decoded response when positive, *LimitError is returned beyond it.
+	// Codecs implementing DecodeLimit(data []byte, max int) ([]byte, error) stop decoding at the limit.
+	MaxSize int
+
+	// rejected is set when the server rejects encoded request
+	rejected   int32
+	negotiated int32
+}
+
+// Middleware returns middleware negotiating the codec.
+func (n *CodecNegotiation) Middleware() Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			req := *r
+			req.Header = make(http.Header, len(r.Header)+2)
+			for k, v := range r.Header {
+				req.Header[k] = v
+			}
+			req.Header.Set("Accept", n.Codec.ContentType()+", text/xml")
+
+			encoded := atomic.LoadInt32(&n.rejected) == 0 && (n.Optimistic || atomic.LoadInt32(&n.negotiated) == 1)
+			if encoded {
+				data, err := n.Codec.Encode(r.Envelope)
+				if err != nil {
+					return nil, err
+				}
+				req.Header.Set("Content-Type", n.Codec.ContentType())
+				req.Envelope = data
+			}
+
+			resp, err := next(ctx, &req)
+			if err != nil {
+				return resp, err
+			}
+
+			if encoded && resp.StatusCode == http.StatusUnsupportedMediaType {
+				// the server does not support the codec, the request is sent as xml
+				atomic.StoreInt32(&n.rejected, 1)
+				req.Header.Del("Accept")
+				req.Header.Del("Content-Type")
+				if ct := r.Header.Get("Content-Type"); ct != "" {
+					req.Header.Set("Content-Type", ct)
+				}
+				req.Envelope = r.Envelope
+				if resp, err = next(ctx, &req); err != nil {
+					return resp, err
+				}
+			}
+			return n.decode(resp)
+		}
+	}
+}
+
+func (n *CodecNegotiation) decode(resp *Response) (*Response, error) {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != n.Codec.ContentType() {
+		return resp, nil
+	}
+	atomic.StoreInt32(&n.negotiated, 1)
+
+	var body []byte
+	if l, ok := n.Codec.(limitDecoder); ok && n.MaxSize > 0 {
+		body, err = l.DecodeLimit(resp.Body, n.MaxSize)
+	} else {
+		body, err = n.Codec.Decode(resp.Body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if n.MaxSize > 0 && len(body) > n.MaxSize {
+		return nil, &LimitError{Limit: "decoded response size", Max: n.MaxSize}
+	}
+
+	r := *resp
+	r.Header = make(http.Header, len(resp.Header))
+	for k, v := range resp.Header {
+		r.Header[k] = v
+	}
+	r.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	r.Body = body
+	return &r, nil
+}
@@ -0,0 +1,111 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testCodec prefixes xml with the marker.
+type testCodec struct{}
+
+func (testCodec) ContentType() string { return "application/x-test" }
+
+func (testCodec) Encode(envelope []byte) ([]byte, error) {
+	return append([]byte("ENC:"), envelope...), nil
+}
+
+func (testCodec) Decode(data []byte) ([]byte, error) {
+	return bytes.TrimPrefix(data, []byte("ENC:")), nil
+}
+
+func TestCodecNegotiation(t *testing.T) {
+	t.Parallel()
+	var types []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		types = append(types, r.Header.Get("Content-Type"))
+		if r.URL.Query().Get("reject") != "" && bytes.HasPrefix(body, []byte("ENC:")) {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			w.Write([]byte("unsupported"))
+			return
+		}
+
+		b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		if r.Header.Get("Accept") == "application/x-test, text/xml" && r.URL.Query().Get("reject") == "" {
+			w.Header().Set("Content-Type", "application/x-test")
+			b, _ = testCodec{}.Encode(b)
+		}
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{Middleware: []Middleware{(&CodecNegotiation{Codec: testCodec{}}).Middleware()}})
+	for i := 0; i < 2; i++ {
+		var resp response
+		if err := client.Call(context.Background(), "", request{}, &resp); err != nil || resp.Attr3 != "value3" {
+			t.Fatalf("#%d got: %v %s, want: value3", i, err, resp.Attr3)
+		}
+	}
+
+	if len(types) != 2 || types[0] != `text/xml; charset="utf-8"` || types[1] != "application/x-test" {
+		t.Fatalf("got: %v, want: xml then codec request", types)
+	}
+
+	types = nil
+	client = MustNewClient(srv.URL+"?reject=1", Config{Middleware: []Middleware{(&CodecNegotiation{Codec: testCodec{}, Optimistic: true}).Middleware()}})
+	for i := 0; i < 2; i++ {
+		var resp response
+		if err := client.Call(context.Background(), "", request{}, &resp); err != nil || resp.Attr3 != "value3" {
+			t.Fatalf("#%d got: %v %s, want: value3", i, err, resp.Attr3)
+		}
+	}
+
+	if len(types) != 3 || types[0] != "application/x-test" || types[2] != `text/xml; charset="utf-8"` {
+		t.Fatalf("got: %v, want: rejected codec request then xml", types)
+	}
+}
+
+// limitCodec implements codec limiting the decoded size.
+type limitCodec struct {
+	testCodec
+	max int
+}
+
+func (c *limitCodec) DecodeLimit(data []byte, max int) ([]byte, error) {
+	c.max = max
+	return nil, &LimitError{Limit: "decoded test size", Max: max}
+}
+
+func TestCodecNegotiation_MaxSize(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		b, _ = testCodec{}.Encode(b)
+		w.Header().Set("Content-Type", "application/x-test")
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	n := &CodecNegotiation{Codec: testCodec{}, MaxSize: 10}
+	err := MustNewClient(srv.URL, Config{Middleware: []Middleware{n.Middleware()}}).Call(context.Background(), "", request{}, &response{})
+	if e, ok := err.(*LimitError); !ok || e.Limit != "decoded response size" {
+		t.Fatalf("got: %v, want: LimitError", err)
+	}
+
+	c := &limitCodec{}
+	n = &CodecNegotiation{Codec: c, MaxSize: 10}
+	err = MustNewClient(srv.URL, Config{Middleware: []Middleware{n.Middleware()}}).Call(context.Background(), "", request{}, &response{})
+	if e, ok := err.(*LimitError); !ok || e.Limit != "decoded test size" || c.max != 10 {
+		t.Fatalf("got: %v %d, want: LimitError of the codec", err, c.max)
+	}
+
+	n = &CodecNegotiation{Codec: testCodec{}, MaxSize: 1000}
+	if err := MustNewClient(srv.URL, Config{Middleware: []Middleware{n.Middleware()}}).Call(context.Background(), "", request{}, &response{}); err != nil {
+		t.Fatal(err)
+	}
+}
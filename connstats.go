@@ -0,0 +1,171 @@
+package soap
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConnStats implements stats of the connection pool of the client, e.g. for tuning of
+// MaxIdleConnsPerHost. Counters are cumulative since the client is created.
+type ConnStats struct {
+	// Open is number of open connections, InUse of them serve requests and Idle are kept alive.
+	Open  int
+	InUse int
+	Idle  int
+	// Dials counts new connections and DialErrors failed ones.
+	Dials      int64
+	DialErrors int64
+	// Closed counts closed connections, e.g. idle connections above MaxIdleConnsPerHost.
+	Closed int64
+	// Reused counts requests sent over idle connections.
+	Reused int64
+	// Waits counts requests without idle connection, WaitDuration is time they waited for the connection.
+	Waits        int64
+	WaitDuration time.Duration
+}
+
+// connMetrics implements counters of the connection pool shared by http clients of the client.
+type connMetrics struct {
+	open, inUse               int64
+	dials, dialErrors, closed int64
+	reused, waits             int64
+	waitDuration              int64
+}
+
+// ConnStats returns stats of the connection pool.
+func (s *Client) ConnStats() ConnStats {
+	m := &s.conns
+	open, inUse := atomic.LoadInt64(&m.open), atomic.LoadInt64(&m.inUse)
+	idle := open - inUse
+	if idle < 0 {
+		idle = 0
+	}
+
+	return ConnStats{
+		Open:         int(open),
+		InUse:        int(inUse),
+		Idle:         int(idle),
+		Dials:        atomic.LoadInt64(&m.dials),
+		DialErrors:   atomic.LoadInt64(&m.dialErrors),
+		Closed:       atomic.LoadInt64(&m.closed),
+		Reused:       atomic.LoadInt64(&m.reused),
+		Waits:        atomic.LoadInt64(&m.waits),
+		WaitDuration: time.Duration(atomic.LoadInt64(&m.waitDuration)),
+	}
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dial counts connections of the dial function.
+func (m *connMetrics) dial(dial dialFunc) dialFunc {
+	if dial == nil {
+		return nil
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			atomic.AddInt64(&m.dialErrors, 1)
+			return nil, err
+		}
+
+		atomic.AddInt64(&m.dials, 1)
+		atomic.AddInt64(&m.open, 1)
+		return &countedConn{Conn: conn, m: m}, nil
+	}
+}
+
+// countedConn implements connection decrementing open connections on close.
+type countedConn struct {
+	net.Conn
+	m    *connMetrics
+	once sync.Once
+}
+
+// Close implements net.Conn interface.
+func (c *countedConn) Close() error {
+	c.once.Do(func() {
+		atomic.AddInt64(&c.m.open, -1)
+		atomic.AddInt64(&c.m.closed, 1)
+	})
+	return c.Conn.Close()
+}
+
+// connTransport implements transport counting connections in use.
+type connTransport struct {
+	rt *http.Transport
+	m  *connMetrics
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (t *connTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var (
+		mu    sync.Mutex
+		start time.Time
+		got   bool
+	)
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			mu.Lock()
+			start = time.Now()
+			mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = true
+			atomic.AddInt64(&t.m.inUse, 1)
+			if info.Reused && info.WasIdle {
+				atomic.AddInt64(&t.m.reused, 1)
+				return
+			}
+
+			atomic.AddInt64(&t.m.waits, 1)
+			if !start.IsZero() {
+				atomic.AddInt64(&t.m.waitDuration, int64(time.Since(start)))
+			}
+		},
+	}
+
+	release := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if got {
+			got = false
+			atomic.AddInt64(&t.m.inUse, -1)
+		}
+	}
+
+	resp, err := t.rt.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// CloseIdleConnections closes idle connections of the transport.
+func (t *connTransport) CloseIdleConnections() {
+	t.rt.CloseIdleConnections()
+}
+
+// releaseBody implements response body releasing the connection on close.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+// Close implements io.Closer interface.
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,45 @@
+package soap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_ConnStats(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body/></Envelope>`))
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{KeepAlive: true})
+	for i := 0; i < 3; i++ {
+		if err := client.Call(context.Background(), "", request{}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st := client.ConnStats()
+	if st.Open != 1 || st.Idle != 1 || st.InUse != 0 || st.Dials != 1 || st.Reused != 2 || st.Waits != 1 {
+		t.Fatalf("got: %+v, want: one idle connection reused twice", st)
+	}
+
+	client.CloseIdleConnections()
+	if st = client.ConnStats(); st.Open != 0 || st.Closed != 1 {
+		t.Fatalf("got: %+v, want: the connection closed", st)
+	}
+
+	// connection is closed after each call without keep-alive
+	client = MustNewClient(srv.URL, Config{})
+	for i := 0; i < 2; i++ {
+		if err := client.Call(context.Background(), "", request{}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if st = client.ConnStats(); st.Dials != 2 || st.Reused != 0 || st.InUse != 0 {
+		t.Fatalf("got: %+v, want: two dials", st)
+	}
+}
@@ -0,0 +1,102 @@
+// Package ews implements Exchange Web Services interop preset of the soap client:
+// RequestServerVersion header, keep-alive connections for NTLM negotiation
+// and errors reported inside successful responses.
+package ews
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+
+	"github.com/itcomusic/soap"
+)
+
+const (
+	// TypesNS is namespace of EWS types.
+	TypesNS = "http://schemas.microsoft.com/exchange/services/2006/types"
+	// MessagesNS is namespace of EWS messages.
+	MessagesNS = "http://schemas.microsoft.com/exchange/services/2006/messages"
+)
+
+// Exchange versions.
+const (
+	Exchange2010SP2 = "Exchange2010_SP2"
+	Exchange2013    = "Exchange2013"
+	Exchange2016    = "Exchange2016"
+)
+
+// RequestServerVersion implements header of the requested schema version.
+type RequestServerVersion struct {
+	XMLName xml.Name `xml:"http://schemas.microsoft.com/exchange/services/2006/types RequestServerVersion"`
+	Version string   `xml:"Version,attr"`
+}
+
+// Config implements config of the EWS client.
+type Config struct {
+	soap.Config
+	// Version is requested schema version, Exchange2013 by default.
+	Version string
+	// Negotiate wraps transport with NTLM or Kerberos negotiation, e.g. go-ntlmssp Negotiator.
+	Negotiate func(http.RoundTripper) http.RoundTripper
+}
+
+// NewClient creates soap client of the EWS endpoint, usually https://host/EWS/Exchange.asmx.
+func NewClient(url string, c Config) (*soap.Client, error) {
+	if c.Version == "" {
+		c.Version = Exchange2013
+	}
+
+	if c.Negotiate != nil {
+		c.WrapTransport = c.Negotiate
+		c.KeepAlive = true
+	}
+	c.Middleware = append([]soap.Middleware{ResponseErrors}, c.Middleware...)
+
+	client, err := soap.NewClient(url, c.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	client.AddHeader(RequestServerVersion{Version: c.Version})
+	return client, nil
+}
+
+// Action returns soap action of the operation, e.g. "GetItem".
+func Action(operation string) string {
+	return MessagesNS + "/" + operation
+}
+
+// ResponseError implements error reported by response message with ResponseClass="Error"
+// while http status is 200.
+type ResponseError struct {
+	Code string
+	Text string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("ews: %s: %s", e.Code, e.Text)
+}
+
+// ResponseErrors is middleware converting the first response message of error class to ResponseError.
+func ResponseErrors(next soap.RoundTripFunc) soap.RoundTripFunc {
+	return func(ctx context.Context, r *soap.Request) (*soap.Response, error) {
+		resp, err := next(ctx, r)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			return resp, err
+		}
+
+		n, err := soap.ParseNode(resp.Body)
+		if err != nil {
+			// decoding error is reported by the client
+			return resp, nil
+		}
+
+		for _, msg := range n.FindAll("Envelope/Body/*/ResponseMessages/*") {
+			if class, _ := msg.Attr("ResponseClass"); class == "Error" {
+				return nil, &ResponseError{Code: msg.Value(msg.XMLName.Local + "/ResponseCode"), Text: msg.Value(msg.XMLName.Local + "/MessageText")}
+			}
+		}
+		return resp, nil
+	}
+}
@@ -0,0 +1,91 @@
+package ews
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/itcomusic/soap"
+)
+
+type getItem struct {
+	XMLName xml.Name `xml:"http://schemas.microsoft.com/exchange/services/2006/messages GetItem"`
+	ID      struct {
+		ID string `xml:"Id,attr"`
+	} `xml:"ItemIds>ItemId"`
+}
+
+func fixture(t *testing.T, name string) *httptest.Server {
+	b, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		n, err := soap.ParseNode(body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if v, _ := n.Find("Envelope/Header/RequestServerVersion").Attr("Version"); v != Exchange2016 {
+			t.Errorf("got: %s, want: %s", v, Exchange2016)
+		}
+
+		if got, want := r.Header.Get("SOAPAction"), Action("GetItem"); got != want {
+			t.Errorf("got: %s, want: %s", got, want)
+		}
+		w.Write(b)
+	}))
+}
+
+func TestClient_GetItem(t *testing.T) {
+	t.Parallel()
+	srv := fixture(t, "getitem.xml")
+	defer srv.Close()
+
+	client, err := NewClient(srv.URL, Config{Version: Exchange2016})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var resp soap.Node
+	if err := client.Call(context.Background(), Action("GetItem"), getItem{}, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := resp.Value("GetItemResponse/ResponseMessages/GetItemResponseMessage/Items/Message/Subject"), "Quarterly report"; got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+}
+
+func TestClient_ResponseError(t *testing.T) {
+	t.Parallel()
+	srv := fixture(t, "getitem_error.xml")
+	defer srv.Close()
+
+	var negotiated bool
+	client, err := NewClient(srv.URL, Config{Version: Exchange2016, Negotiate: func(rt http.RoundTripper) http.RoundTripper {
+		negotiated = true
+		return rt
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.Call(context.Background(), Action("GetItem"), getItem{}, &soap.Node{})
+
+	e, ok := err.(*ResponseError)
+	if !ok || e.Code != "ErrorItemNotFound" {
+		t.Fatalf("got: %v, want: %s", err, "ErrorItemNotFound")
+	}
+
+	if !negotiated {
+		t.Fatal("transport is not wrapped")
+	}
+}
@@ -0,0 +1,57 @@
+// Package ihe implements IHE ITI interop preset of the soap client for XDS.b, PIX and PDQ
+// transactions: mandatory WS-Addressing headers, XUA SAML assertion and MTOM documents.
+package ihe
+
+import (
+	"github.com/itcomusic/soap"
+)
+
+// Transaction actions.
+const (
+	// ProvideAndRegisterDocumentSet is ITI-41.
+	ProvideAndRegisterDocumentSet = "urn:ihe:iti:2007:ProvideAndRegisterDocumentSet-b"
+	// RegisterDocumentSet is ITI-42.
+	RegisterDocumentSet = "urn:ihe:iti:2007:RegisterDocumentSet-b"
+	// RetrieveDocumentSet is ITI-43.
+	RetrieveDocumentSet = "urn:ihe:iti:2007:RetrieveDocumentSet"
+	// RegistryStoredQuery is ITI-18.
+	RegistryStoredQuery = "urn:ihe:iti:2007:RegistryStoredQuery"
+	// PIXQuery is ITI-45.
+	PIXQuery = "urn:hl7-org:v3:PRPA_IN201309UV02"
+	// PDQQuery is ITI-47.
+	PDQQuery = "urn:hl7-org:v3:PRPA_IN201305UV02"
+)
+
+// Namespaces of XDS.b messages.
+const (
+	XDSNS = "urn:ihe:iti:xds-b:2007"
+	RIMNS = "urn:oasis:names:tc:ebxml-regrep:xsd:rim:3.0"
+	LCMNS = "urn:oasis:names:tc:ebxml-regrep:xsd:lcm:3.0"
+	RSNS  = "urn:oasis:names:tc:ebxml-regrep:xsd:rs:3.0"
+)
+
+// Config implements config of the IHE client.
+type Config struct {
+	soap.Config
+	// SAML adds XUA assertion, holder-of-key assertion signs the message.
+	SAML *soap.SAML
+	// DocumentType is content type of MTOM documents, application/octet-stream when empty.
+	DocumentType string
+}
+
+// NewClient creates soap client of the IHE endpoint. Requests are sent as SOAP 1.2 envelopes carrying
+// WS-Addressing MessageID, To, Action and anonymous ReplyTo with mustUnderstand, Document elements
+// of XDS.b are sent as MTOM attachments.
+func NewClient(endpoint string, c Config) (*soap.Client, error) {
+	middleware := []soap.Middleware{
+		soap.SOAP12.Middleware(),
+		soap.Addressing{ReplyTo: soap.AnonymousAddress, MustUnderstand: true}.Middleware(),
+	}
+	if c.SAML != nil {
+		middleware = append(middleware, c.SAML.Middleware())
+	}
+
+	mtom := &soap.MTOM{Elements: []string{"Document"}, ContentType: c.DocumentType, StartInfo: "application/soap+xml"}
+	c.Middleware = append(append(middleware, mtom.Middleware()), c.Middleware...)
+	return soap.NewClient(endpoint, c.Config)
+}
@@ -0,0 +1,129 @@
+package ihe
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/itcomusic/soap"
+)
+
+const soap12NS = "http://www.w3.org/2003/05/soap-envelope"
+
+type provideRequest struct {
+	XMLName  xml.Name `xml:"urn:ihe:iti:xds-b:2007 ProvideAndRegisterDocumentSetRequest"`
+	Document struct {
+		ID   string `xml:"id,attr"`
+		Data string `xml:",chardata"`
+	} `xml:"Document"`
+}
+
+type retrieveResponse struct {
+	XMLName  xml.Name `xml:"urn:ihe:iti:xds-b:2007 RetrieveDocumentSetResponse"`
+	Document string   `xml:"DocumentResponse>Document"`
+}
+
+const responseEnvelope = `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body>` +
+	`<RetrieveDocumentSetResponse xmlns="urn:ihe:iti:xds-b:2007"><DocumentResponse><Document>` +
+	`<xop:Include xmlns:xop="http://www.w3.org/2004/08/xop/include" href="cid:doc1@server"/>` +
+	`</Document></DocumentResponse></RetrieveDocumentSetResponse></s:Body></s:Envelope>`
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "multipart/related" || params["type"] != "application/xop+xml" || params["start-info"] != "application/soap+xml" {
+			t.Errorf("got: %s, want: multipart/related of soap 1.2", r.Header.Get("Content-Type"))
+			return
+		}
+
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		root, err := mr.NextPart()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		envelope, _ := ioutil.ReadAll(root)
+		n, err := soap.ParseNode(envelope)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if n.XMLName.Space != soap12NS {
+			t.Errorf("got: %s, want: %s", n.XMLName.Space, soap12NS)
+		}
+
+		if got := n.Value("Envelope/Header/Action"); got != ProvideAndRegisterDocumentSet {
+			t.Errorf("got: %s, want: %s", got, ProvideAndRegisterDocumentSet)
+		}
+
+		if attrs := n.Find("Envelope/Header/To").Attrs; len(attrs) != 1 || attrs[0].Name != (xml.Name{Space: soap12NS, Local: "mustUnderstand"}) || attrs[0].Value != "1" {
+			t.Errorf("got: %v, want: soap 1.2 mustUnderstand", attrs)
+		}
+
+		if n.Find("Envelope/Header/Security/Assertion") == nil {
+			t.Errorf("assertion is missing: %s", envelope)
+		}
+
+		if n.Find("Envelope/Body/*/Document/Include") == nil {
+			t.Errorf("document is not included: %s", envelope)
+		}
+
+		attachment, err := mr.NextPart()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if data, _ := ioutil.ReadAll(attachment); string(data) != "<ClinicalDocument/>" {
+			t.Errorf("got: %s, want: <ClinicalDocument/>", data)
+		}
+
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		pw, _ := mw.CreatePart(map[string][]string{"Content-Type": {`application/xop+xml; type="application/soap+xml"`}, "Content-Id": {"<root@server>"}})
+		pw.Write([]byte(responseEnvelope))
+		pw, _ = mw.CreatePart(map[string][]string{"Content-Type": {"text/xml"}, "Content-Id": {"<doc1@server>"}})
+		pw.Write([]byte("<ClinicalDocument/>"))
+		mw.Close()
+
+		w.Header().Set("Content-Type", `multipart/related; type="application/xop+xml"; start="<root@server>"; boundary=`+mw.Boundary())
+		w.Write(body.Bytes())
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(srv.URL, Config{SAML: &soap.SAML{
+		Assertion: []byte(`<saml2:Assertion xmlns:saml2="urn:oasis:names:tc:SAML:2.0:assertion" ID="_1"/>`),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var req provideRequest
+	req.Document.ID = "Document01"
+	req.Document.Data = base64.StdEncoding.EncodeToString([]byte("<ClinicalDocument/>"))
+
+	var resp retrieveResponse
+	if err := client.Call(context.Background(), ProvideAndRegisterDocumentSet, req, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(resp.Document))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "<ClinicalDocument/>" {
+		t.Fatalf("got: %s, want: <ClinicalDocument/>", data)
+	}
+}
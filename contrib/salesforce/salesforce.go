@@ -0,0 +1,209 @@
+// Package salesforce implements login, session header handling and query pagination
+// of Salesforce partner SOAP API built on the soap client.
+package salesforce
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/itcomusic/soap"
+)
+
+const (
+	// DefaultLoginURL is login endpoint of the partner API.
+	DefaultLoginURL = "https://login.salesforce.com/services/Soap/u/52.0"
+	// NS is namespace of the partner API.
+	NS = "urn:partner.soap.sforce.com"
+
+	invalidSession = "INVALID_SESSION_ID"
+)
+
+type login struct {
+	XMLName  xml.Name `xml:"urn:partner.soap.sforce.com login"`
+	Username string   `xml:"username"`
+	Password string   `xml:"password"`
+}
+
+type loginResponse struct {
+	XMLName xml.Name `xml:"urn:partner.soap.sforce.com loginResponse"`
+	Result  Session  `xml:"result"`
+}
+
+// Session implements result of the login.
+type Session struct {
+	ServerURL string `xml:"serverUrl"`
+	SessionID string `xml:"sessionId"`
+	UserID    string `xml:"userId"`
+}
+
+type sessionHeader struct {
+	XMLName   xml.Name `xml:"urn:partner.soap.sforce.com SessionHeader"`
+	SessionID string   `xml:"sessionId"`
+}
+
+type query struct {
+	XMLName     xml.Name `xml:"urn:partner.soap.sforce.com query"`
+	QueryString string   `xml:"queryString"`
+}
+
+type queryMore struct {
+	XMLName      xml.Name `xml:"urn:partner.soap.sforce.com queryMore"`
+	QueryLocator string   `xml:"queryLocator"`
+}
+
+type queryResponse struct {
+	Result QueryResult `xml:"result"`
+}
+
+// QueryResult implements page of the query records.
+type QueryResult struct {
+	Done         bool         `xml:"done"`
+	QueryLocator string       `xml:"queryLocator"`
+	Size         int          `xml:"size"`
+	Records      []*soap.Node `xml:"records"`
+}
+
+// Client implements Salesforce partner API client, the session is created lazily
+// and renewed once when the server reports INVALID_SESSION_ID fault.
+type Client struct {
+	loginURL string
+	username string
+	password string
+	config   soap.Config
+
+	mu      sync.Mutex
+	session *Session
+	service *soap.Client
+}
+
+// New creates client, password must include the security token when it is required.
+func New(loginURL, username, password string, c soap.Config) *Client {
+	if loginURL == "" {
+		loginURL = DefaultLoginURL
+	}
+	return &Client{loginURL: loginURL, username: username, password: password, config: c}
+}
+
+// Login creates new session.
+func (c *Client) Login(ctx context.Context) (*Session, error) {
+	loginClient, err := soap.NewClient(c.loginURL, c.config)
+	if err != nil {
+		return nil, err
+	}
+	defer loginClient.Close()
+
+	var resp loginResponse
+	if err := loginClient.Call(ctx, "login", login{Username: c.username, Password: c.password}, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Result.ServerURL == "" || resp.Result.SessionID == "" {
+		return nil, fmt.Errorf("salesforce: login response has no session")
+	}
+
+	session := resp.Result
+	service, err := soap.NewClient(session.ServerURL, c.config)
+	if err != nil {
+		return nil, err
+	}
+	service.AddHeader(sessionHeader{SessionID: session.SessionID})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.service != nil {
+		// in-flight calls of the previous session are completed
+		c.service.CloseIdleConnections()
+	}
+	c.session, c.service = &session, service
+	return &session, nil
+}
+
+// Session returns current session, nil when the client is not logged in.
+func (c *Client) Session() *Session {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.session
+}
+
+// Call sends request of the partner API with the session header.
+func (c *Client) Call(ctx context.Context, soapAction string, request, response interface{}) error {
+	service, err := c.serviceClient(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = service.Call(ctx, soapAction, request, response)
+	if !isInvalidSession(err) {
+		return err
+	}
+
+	if service, err = c.serviceClient(ctx, service); err != nil {
+		return err
+	}
+	return service.Call(ctx, soapAction, request, response)
+}
+
+// Query returns the first page of the query.
+func (c *Client) Query(ctx context.Context, soql string) (*QueryResult, error) {
+	var resp queryResponse
+	if err := c.Call(ctx, "query", query{QueryString: soql}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Result, nil
+}
+
+// QueryMore returns the next page of the query.
+func (c *Client) QueryMore(ctx context.Context, queryLocator string) (*QueryResult, error) {
+	var resp queryResponse
+	if err := c.Call(ctx, "queryMore", queryMore{QueryLocator: queryLocator}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Result, nil
+}
+
+// QueryAll calls fn for each page of the query until all records are read.
+func (c *Client) QueryAll(ctx context.Context, soql string, fn func(records []*soap.Node) error) error {
+	result, err := c.Query(ctx, soql)
+	for {
+		if err != nil {
+			return err
+		}
+
+		if err := fn(result.Records); err != nil {
+			return err
+		}
+
+		if result.Done || result.QueryLocator == "" {
+			return nil
+		}
+		result, err = c.QueryMore(ctx, result.QueryLocator)
+	}
+}
+
+// serviceClient returns client of the session, it logs in when there is no session
+// or the session of the expired client is still current.
+func (c *Client) serviceClient(ctx context.Context, expired *soap.Client) (*soap.Client, error) {
+	c.mu.Lock()
+	service := c.service
+	c.mu.Unlock()
+
+	if service != nil && service != expired {
+		return service, nil
+	}
+
+	if _, err := c.Login(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.service, nil
+}
+
+func isInvalidSession(err error) bool {
+	f, ok := err.(*soap.Fault)
+	return ok && strings.HasSuffix(f.Code.String(), invalidSession)
+}
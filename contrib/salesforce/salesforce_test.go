@@ -0,0 +1,80 @@
+package salesforce
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/itcomusic/soap"
+)
+
+const envelope = `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns="urn:partner.soap.sforce.com" xmlns:sf="urn:sobject.partner.soap.sforce.com"><soapenv:Body>%s</soapenv:Body></soapenv:Envelope>`
+
+func TestClient_QueryAll(t *testing.T) {
+	t.Parallel()
+	var (
+		logins   int
+		sessions = map[string]bool{}
+		srv      *httptest.Server
+	)
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		n, err := soap.ParseNode(body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if r.URL.Path == "/login" {
+			logins++
+			session := fmt.Sprintf("session%d", logins)
+			sessions[session] = logins > 1
+			fmt.Fprintf(w, envelope, `<loginResponse><result><serverUrl>`+srv.URL+`/service</serverUrl><sessionId>`+session+`</sessionId></result></loginResponse>`)
+			return
+		}
+
+		// the first session expires
+		if !sessions[n.Value("Envelope/Header/SessionHeader/sessionId")] {
+			w.WriteHeader(500)
+			fmt.Fprintf(w, envelope, `<soapenv:Fault><faultcode>sf:INVALID_SESSION_ID</faultcode><faultstring>expired</faultstring></soapenv:Fault>`)
+			return
+		}
+
+		switch r.Header.Get("SOAPAction") {
+		case "query":
+			fmt.Fprintf(w, envelope, `<queryResponse><result><done>false</done><queryLocator>loc1</queryLocator><records><sf:type>Account</sf:type><sf:Id>1</sf:Id></records><size>2</size></result></queryResponse>`)
+		case "queryMore":
+			if got := n.Value("Envelope/Body/queryMore/queryLocator"); got != "loc1" {
+				t.Errorf("got: %s, want: %s", got, "loc1")
+			}
+			fmt.Fprintf(w, envelope, `<queryMoreResponse><result><done>true</done><records><sf:type>Account</sf:type><sf:Id>2</sf:Id></records><size>2</size></result></queryMoreResponse>`)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL+"/login", "user", "pass", soap.Config{})
+	var ids []string
+	if err := c.QueryAll(context.Background(), "SELECT Id FROM Account", func(records []*soap.Node) error {
+		for _, r := range records {
+			ids = append(ids, r.Value("records/Id"))
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fmt.Sprint(ids), "[1 2]"; got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+
+	if logins != 2 {
+		t.Fatalf("got: %d, want: %d", logins, 2)
+	}
+
+	if got := c.Session().SessionID; got != "session2" {
+		t.Fatalf("got: %s, want: %s", got, "session2")
+	}
+}
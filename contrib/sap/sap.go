@@ -0,0 +1,106 @@
+// Package sap implements SAP PI/PO SOAP adapter interop preset of the soap client:
+// sap-client and sap-language headers, session cookies like sap-usercontext
+// and quality of service parameters of the sender channel.
+package sap
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+
+	"github.com/itcomusic/soap"
+)
+
+// QoS implements quality of service of the message.
+type QoS string
+
+// Quality of service values.
+const (
+	// BestEffort is synchronous delivery.
+	BestEffort QoS = "BestEffort"
+	// ExactlyOnce is asynchronous delivery without duplicates.
+	ExactlyOnce QoS = "ExactlyOnce"
+	// ExactlyOnceInOrder is asynchronous delivery keeping order of the queue.
+	ExactlyOnceInOrder QoS = "ExactlyOnceInOrder"
+)
+
+// Config implements config of the SAP client.
+type Config struct {
+	soap.Config
+	// Client and Language are sent as sap-client and sap-language headers.
+	Client   string
+	Language string
+
+	// Sender channel parameters of the XISOAPAdapter message servlet.
+	SenderParty        string
+	SenderService      string
+	ReceiverParty      string
+	ReceiverService    string
+	Interface          string
+	InterfaceNamespace string
+	QoS                QoS
+	// QueueID is required by ExactlyOnceInOrder.
+	QueueID string
+}
+
+// URL returns endpoint url with the sender channel parameters added to the query.
+func URL(endpoint string, c Config) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	for _, v := range []struct {
+		key, value string
+	}{
+		{key: "senderParty", value: c.SenderParty},
+		{key: "senderService", value: c.SenderService},
+		{key: "receiverParty", value: c.ReceiverParty},
+		{key: "receiverService", value: c.ReceiverService},
+		{key: "interface", value: c.Interface},
+		{key: "interfaceNamespace", value: c.InterfaceNamespace},
+		{key: "qos", value: string(c.QoS)},
+		{key: "queueid", value: c.QueueID},
+	} {
+		if v.value != "" {
+			q.Set(v.key, v.value)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// NewClient creates soap client of the SAP endpoint, e.g. http://host:50000/XISOAPAdapter/MessageServlet.
+// Session cookies are kept unless Config.Jar is set.
+func NewClient(endpoint string, c Config) (*soap.Client, error) {
+	u, err := URL(endpoint, c)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Jar == nil {
+		// cookiejar.New never fails without options
+		c.Jar, _ = cookiejar.New(nil)
+	}
+
+	c.Middleware = append([]soap.Middleware{headers(c)}, c.Middleware...)
+	return soap.NewClient(u, c.Config)
+}
+
+func headers(c Config) soap.Middleware {
+	return func(next soap.RoundTripFunc) soap.RoundTripFunc {
+		return func(ctx context.Context, r *soap.Request) (*soap.Response, error) {
+			setHeader(r.Header, "sap-client", c.Client)
+			setHeader(r.Header, "sap-language", c.Language)
+			return next(ctx, r)
+		}
+	}
+}
+
+func setHeader(h http.Header, key, value string) {
+	if value != "" {
+		h.Set(key, value)
+	}
+}
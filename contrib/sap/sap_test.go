@@ -0,0 +1,79 @@
+package sap
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+type materialRequest struct {
+	XMLName  xml.Name `xml:"urn:example.com:material MT_MaterialRequest"`
+	Material string   `xml:"Material"`
+}
+
+type materialResponse struct {
+	XMLName     xml.Name `xml:"urn:example.com:material MT_MaterialResponse"`
+	Material    string   `xml:"Material"`
+	Description string   `xml:"Description"`
+}
+
+func TestClient_Call(t *testing.T) {
+	t.Parallel()
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "response.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		for k, want := range map[string]string{"sap-client": "100", "sap-language": "EN"} {
+			if got := r.Header.Get(k); got != want {
+				t.Errorf("%s got: %s, want: %s", k, got, want)
+			}
+		}
+
+		q := r.URL.Query()
+		if got, want := q.Get("senderService"), "BS_SHOP"; got != want {
+			t.Errorf("got: %s, want: %s", got, want)
+		}
+		if got, want := q.Get("qos"), string(BestEffort); got != want {
+			t.Errorf("got: %s, want: %s", got, want)
+		}
+
+		if calls == 1 {
+			http.SetCookie(w, &http.Cookie{Name: "sap-usercontext", Value: "sap-client=100"})
+		} else if c, err := r.Cookie("sap-usercontext"); err != nil || c.Value != "sap-client=100" {
+			t.Errorf("session cookie is not sent: %v", err)
+		}
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(srv.URL+"/XISOAPAdapter/MessageServlet", Config{
+		Client:             "100",
+		Language:           "EN",
+		SenderService:      "BS_SHOP",
+		Interface:          "SI_Material_Out",
+		InterfaceNamespace: "urn:example.com:material",
+		QoS:                BestEffort,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		var resp materialResponse
+		if err := client.Call(context.Background(), "http://sap.com/xi/WebService/soap1.1", materialRequest{Material: "100-100"}, &resp); err != nil {
+			t.Fatal(err)
+		}
+
+		if want := "Casing"; resp.Description != want {
+			t.Fatalf("got: %s, want: %s", resp.Description, want)
+		}
+	}
+}
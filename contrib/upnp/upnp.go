@@ -0,0 +1,149 @@
+// Package upnp implements UPnP device control preset of the soap client: s: prefixed envelope
+// with encodingStyle, "urn:service#Action" soap action and UPnPError fault detail.
+package upnp
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/itcomusic/soap"
+)
+
+// Namespaces of UPnP control messages.
+const (
+	ControlNS     = "urn:schemas-upnp-org:control-1-0"
+	EncodingStyle = "http://schemas.xmlsoap.org/soap/encoding/"
+	envelopeNS    = "http://schemas.xmlsoap.org/soap/envelope/"
+)
+
+// SOAPAction returns quoted soap action of the service action, e.g. "urn:schemas-upnp-org:service:WANIPConnection:1#GetExternalIPAddress".
+func SOAPAction(service, action string) string {
+	return `"` + service + "#" + action + `"`
+}
+
+// Arg implements argument of the action.
+type Arg struct {
+	Name  string
+	Value string
+}
+
+// Action implements action element of the service with unqualified arguments in order,
+// it is used both as request and response.
+type Action struct {
+	Service string
+	Name    string
+	Args    []Arg
+}
+
+// Arg returns value of the argument.
+func (a *Action) Arg(name string) string {
+	for _, v := range a.Args {
+		if v.Name == name {
+			return v.Value
+		}
+	}
+	return ""
+}
+
+// MarshalXML implements xml.Marshaler interface, the element is written with literal u: prefix.
+func (a Action) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	action := xml.StartElement{
+		Name: xml.Name{Local: "u:" + a.Name},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns:u"}, Value: a.Service}},
+	}
+	if err := e.EncodeToken(action); err != nil {
+		return err
+	}
+
+	for _, v := range a.Args {
+		if err := e.EncodeElement(v.Value, xml.StartElement{Name: xml.Name{Local: v.Name}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(action.End())
+}
+
+// UnmarshalXML implements xml.Unmarshaler interface.
+func (a *Action) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	a.Service, a.Name, a.Args = start.Name.Space, start.Name.Local, nil
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			var v string
+			if err := d.DecodeElement(&v, &t); err != nil {
+				return err
+			}
+			a.Args = append(a.Args, Arg{Name: t.Name.Local, Value: v})
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// Error implements UPnPError of the fault detail.
+type Error struct {
+	Code        int    `xml:"errorCode"`
+	Description string `xml:"errorDescription"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("upnp: error %d: %s", e.Code, e.Description)
+}
+
+// NewClient creates soap client of the service control url.
+func NewClient(controlURL string, c soap.Config) (*soap.Client, error) {
+	c.Middleware = append([]soap.Middleware{control}, c.Middleware...)
+	return soap.NewClient(controlURL, c)
+}
+
+// control rewrites the envelope to s: prefix and returns UPnPError of the fault as error.
+func control(next soap.RoundTripFunc) soap.RoundTripFunc {
+	return func(ctx context.Context, r *soap.Request) (*soap.Response, error) {
+		req := *r
+		req.Envelope = rewriteEnvelope(r.Envelope)
+		resp, err := next(ctx, &req)
+		if err != nil || resp.StatusCode == 200 {
+			return resp, err
+		}
+
+		var envelope struct {
+			Error *Error `xml:"Body>Fault>detail>UPnPError"`
+		}
+		if xml.Unmarshal(resp.Body, &envelope) == nil && envelope.Error != nil {
+			return nil, envelope.Error
+		}
+		return resp, nil
+	}
+}
+
+// rewriteEnvelope replaces default namespace of the envelope with s: prefix, so arguments are unqualified.
+func rewriteEnvelope(envelope []byte) []byte {
+	if !bytes.HasPrefix(envelope, []byte("<Envelope")) {
+		return envelope
+	}
+
+	s := string(envelope)
+	for _, v := range []struct {
+		old, new string
+	}{
+		{old: `<Envelope xmlns="` + envelopeNS + `">`, new: `<s:Envelope xmlns:s="` + envelopeNS + `" s:encodingStyle="` + EncodingStyle + `">`},
+		{old: `<Header xmlns="` + envelopeNS + `">`, new: `<s:Header>`},
+		{old: `</Header><Body xmlns="` + envelopeNS + `">`, new: `</s:Header><s:Body>`},
+		{old: `<Body xmlns="` + envelopeNS + `">`, new: `<s:Body>`},
+	} {
+		s = strings.Replace(s, v.old, v.new, 1)
+	}
+
+	if strings.HasSuffix(s, "</Body></Envelope>") {
+		s = strings.TrimSuffix(s, "</Body></Envelope>") + "</s:Body></s:Envelope>"
+	}
+	return []byte(s)
+}
@@ -0,0 +1,69 @@
+package upnp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/itcomusic/soap"
+)
+
+const wanIP = "urn:schemas-upnp-org:service:WANIPConnection:1"
+
+func TestClient_Call(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if got, want := r.Header.Get("SOAPAction"), `"`+wanIP+`#GetSpecificPortMappingEntry"`; got != want {
+			t.Errorf("got: %s, want: %s", got, want)
+		}
+
+		want := `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+			`<s:Body><u:GetSpecificPortMappingEntry xmlns:u="` + wanIP + `"><NewExternalPort>8080</NewExternalPort><NewProtocol>TCP</NewProtocol>` +
+			`</u:GetSpecificPortMappingEntry></s:Body></s:Envelope>`
+		if string(b) != want {
+			t.Errorf("got: %s, want: %s", b, want)
+		}
+
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(500)
+			w.Write([]byte(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault>` +
+				`<faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail>` +
+				`<UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>714</errorCode><errorDescription>NoSuchEntryInArray</errorDescription></UPnPError>` +
+				`</detail></s:Fault></s:Body></s:Envelope>`))
+			return
+		}
+
+		w.Write([]byte(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>` +
+			`<u:GetSpecificPortMappingEntryResponse xmlns:u="` + wanIP + `"><NewInternalPort>80</NewInternalPort><NewInternalClient>192.168.1.2</NewInternalClient>` +
+			`</u:GetSpecificPortMappingEntryResponse></s:Body></s:Envelope>`))
+	}))
+	defer srv.Close()
+
+	req := Action{Service: wanIP, Name: "GetSpecificPortMappingEntry", Args: []Arg{{Name: "NewExternalPort", Value: "8080"}, {Name: "NewProtocol", Value: "TCP"}}}
+	client, err := NewClient(srv.URL, soap.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var resp Action
+	if err := client.Call(context.Background(), SOAPAction(wanIP, req.Name), req, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Name != "GetSpecificPortMappingEntryResponse" || resp.Arg("NewInternalClient") != "192.168.1.2" {
+		t.Fatalf("got: %+v", resp)
+	}
+
+	failing, err := NewClient(srv.URL+"?fail=1", soap.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = failing.Call(context.Background(), SOAPAction(wanIP, req.Name), req, &resp)
+	if e, ok := err.(*Error); !ok || e.Code != 714 {
+		t.Fatalf("got: %v, want: upnp error 714", err)
+	}
+}
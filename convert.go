@@ -0,0 +1,480 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	// envelope12NS is namespace of SOAP 1.2 envelope.
+	envelope12NS = "http://www.w3.org/2003/05/soap-envelope"
+	// next roles of the header targeted at the next node.
+	actorNext = "http://schemas.xmlsoap.org/soap/actor/next"
+	roleNext  = "http://www.w3.org/2003/05/soap-envelope/role/next"
+)
+
+var errVersion = fmt.Errorf("soap: envelope version is unknown")
+
+// SOAPVersion implements soap version.
+type SOAPVersion int
+
+// Soap versions.
+const (
+	SOAP11 SOAPVersion = iota
+	SOAP12
+)
+
+// namespace returns namespace of the envelope.
+func (v SOAPVersion) namespace() string {
+	if v == SOAP12 {
+		return envelope12NS
+	}
+	return envelopeNS
+}
+
+// contentType returns content type of the envelope, SOAP 1.2 carries soap action as parameter.
+func (v SOAPVersion) contentType(soapAction string) string {
+	if v == SOAP12 {
+		if soapAction == "" {
+			return "application/soap+xml; charset=utf-8"
+		}
+		return mime.FormatMediaType("application/soap+xml", map[string]string{"charset": "utf-8", "action": soapAction})
+	}
+	return `text/xml; charset="utf-8"`
+}
+
+// fault11Codes maps local names of SOAP 1.2 codes to SOAP 1.1 codes.
+var fault11Codes = map[string]string{
+	"VersionMismatch": "VersionMismatch",
+	"MustUnderstand":  "MustUnderstand",
+	"Sender":          "Client",
+	"Receiver":        "Server",
+}
+
+// ConvertEnvelope converts envelope to the soap version. Namespace of the envelope elements, header attributes
+// and fault structure are rewritten, other content is copied token by token with its prefixes, so qname values
+// stay declared. Fault codes which are not standard are converted to Client or Server code by their dotted prefix,
+// envelope of the version is returned as it is.
+func ConvertEnvelope(envelope []byte, to SOAPVersion) ([]byte, error) {
+	b, from, err := convertEnvelope(envelope, to)
+	if err != nil || from == to {
+		return b, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+// Middleware returns client middleware sending request envelopes in the version with its content type,
+// response envelopes of other version are converted to SOAP 1.1 decoded by the client. It must precede
+// middleware signing or packaging the envelope, e.g. SAML and MTOM, since the envelope is encoded again.
+func (v SOAPVersion) Middleware() Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			if v == SOAP11 {
+				return next(ctx, r)
+			}
+
+			envelope, err := ConvertEnvelope(r.Envelope, v)
+			if err != nil {
+				return nil, err
+			}
+
+			req := *r
+			req.Envelope = envelope
+			req.Header = make(http.Header, len(r.Header)+1)
+			for k, h := range r.Header {
+				req.Header[k] = h
+			}
+			req.Header.Set("Content-Type", v.contentType(r.Action))
+
+			resp, err := next(ctx, &req)
+			if err != nil {
+				return resp, err
+			}
+
+			body, from, err := convertEnvelope(trimProlog(resp.Body), SOAP11)
+			if err != nil || from == SOAP11 {
+				// the body is decoded as it is, so the error is reported by decoding
+				return resp, nil
+			}
+
+			converted := *resp
+			converted.Body = append([]byte(xml.Header), body...)
+			return &converted, nil
+		}
+	}
+}
+
+// versionOf returns soap version of the envelope element.
+func versionOf(name xml.Name) (SOAPVersion, error) {
+	if name.Local == "Envelope" {
+		switch name.Space {
+		case envelopeNS:
+			return SOAP11, nil
+		case envelope12NS:
+			return SOAP12, nil
+		}
+	}
+	return 0, errVersion
+}
+
+// envelopeConverter rewrites raw tokens of the envelope, scopes keep namespace declarations of the source.
+type envelopeConverter struct {
+	d        *xml.Decoder
+	e        *xml.Encoder
+	from, to SOAPVersion
+	scopes   []nsScope
+}
+
+// convertEnvelope converts the envelope to the version and returns version of the source,
+// envelope of the version is returned as it is.
+func convertEnvelope(envelope []byte, to SOAPVersion) ([]byte, SOAPVersion, error) {
+	c := &envelopeConverter{d: xml.NewDecoder(bytes.NewReader(envelope)), to: to}
+	var root xml.StartElement
+	for root.Name.Local == "" {
+		token, err := c.d.RawToken()
+		if err != nil {
+			return nil, 0, syntaxError(err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			root = t.Copy()
+		case xml.Directive:
+			if bytes.HasPrefix(bytes.TrimSpace(t), []byte("DOCTYPE")) {
+				return nil, 0, errDTD
+			}
+		}
+	}
+
+	c.push(root)
+	from, err := versionOf(c.resolve(root.Name))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if from == to {
+		return envelope, from, nil
+	}
+
+	var b bytes.Buffer
+	c.from, c.e = from, xml.NewEncoder(&b)
+	if err := c.e.EncodeToken(c.start(root, false)); err != nil {
+		return nil, 0, fmt.Errorf("soap: %s", err)
+	}
+
+	// kinds mark the envelope, header and body elements of the open elements
+	type open struct {
+		name xml.Name
+		kind string
+	}
+	stack := []open{{name: root.Name, kind: "Envelope"}}
+	for len(stack) > 0 {
+		token, err := c.d.RawToken()
+		if err != nil {
+			return nil, 0, syntaxError(err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			parent := stack[len(stack)-1].kind
+			c.push(t)
+			name, kind := c.resolve(t.Name), ""
+			if name.Space == from.namespace() {
+				switch {
+				case parent == "Envelope" && (name.Local == "Header" || name.Local == "Body"):
+					kind = name.Local
+				case parent == "Body" && name.Local == "Fault":
+					if err := c.fault(t); err != nil {
+						return nil, 0, err
+					}
+					continue
+				}
+			}
+
+			stack = append(stack, open{name: t.Name, kind: kind})
+			token = c.start(t, parent == "Header")
+		case xml.EndElement:
+			if name := stack[len(stack)-1].name; t.Name != name {
+				return nil, 0, fmt.Errorf("soap: element <%s> closed by </%s>", rawName(name), rawName(t.Name))
+			}
+			stack = stack[:len(stack)-1]
+			c.pop()
+			token = xml.EndElement{Name: prefixed(t.Name)}
+		}
+
+		if err := c.e.EncodeToken(token); err != nil {
+			return nil, 0, fmt.Errorf("soap: %s", err)
+		}
+	}
+
+	if err := c.e.Flush(); err != nil {
+		return nil, 0, fmt.Errorf("soap: %s", err)
+	}
+	return b.Bytes(), from, nil
+}
+
+// fault converts the fault element which scope is pushed, the detail content is copied as it is.
+func (c *envelopeConverter) fault(start xml.StartElement) error {
+	tokens := []xml.Token{start.Copy()}
+	faultDefault := c.rewrite(c.scope()[""])
+	var (
+		detail        xml.StartElement
+		detailDefault string
+		content       []xml.Token
+	)
+	for depth, inDetail := 1, false; depth > 0; {
+		token, err := c.d.RawToken()
+		if err != nil {
+			return syntaxError(err)
+		}
+
+		token = xml.CopyToken(token)
+		switch t := token.(type) {
+		case xml.StartElement:
+			c.push(t)
+			if depth++; depth == 2 && (t.Name.Local == "detail" || t.Name.Local == "Detail") {
+				detail, detailDefault, inDetail = t, c.rewrite(c.scope()[""]), true
+				tokens = append(tokens, token)
+				continue
+			}
+		case xml.EndElement:
+			c.pop()
+			if depth--; depth == 1 {
+				inDetail = false
+			}
+		}
+
+		if inDetail {
+			content = append(content, token)
+		}
+		tokens = append(tokens, token)
+	}
+
+	f := new(Node)
+	if err := xml.NewTokenDecoder(&tokenSlice{tokens: tokens}).Decode(f); err != nil {
+		return fmt.Errorf("soap: %s", err)
+	}
+
+	converted := fault11(f)
+	if c.to == SOAP12 {
+		converted = fault12(f)
+	}
+
+	if err := c.e.EncodeToken(c.start(start, false)); err != nil {
+		return fmt.Errorf("soap: %s", err)
+	}
+
+	for _, n := range converted.Children {
+		if err := n.encode(c.e, faultDefault, true, nil); err != nil {
+			return err
+		}
+	}
+
+	if detail.Name.Local != "" {
+		if err := c.detail(detail, detailDefault, faultDefault, content); err != nil {
+			return err
+		}
+	}
+
+	if err := c.e.EncodeToken(xml.EndElement{Name: prefixed(start.Name)}); err != nil {
+		return fmt.Errorf("soap: %s", err)
+	}
+	return nil
+}
+
+// detail writes detail element of the version with the content, elements of the content are declared
+// in the default namespace of the source when it differs from default namespace of the converted detail.
+func (c *envelopeConverter) detail(detail xml.StartElement, contentDefault, faultDefault string, content []xml.Token) error {
+	start := xml.StartElement{Name: xml.Name{Local: "detail"}}
+	if c.to == SOAP12 {
+		start.Name = xml.Name{Space: envelope12NS, Local: "Detail"}
+	} else if faultDefault != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns"}})
+	}
+	for _, a := range c.start(detail, false).Attr {
+		if a.Name.Local != "xmlns" {
+			start.Attr = append(start.Attr, a)
+		}
+	}
+
+	if err := c.e.EncodeToken(start); err != nil {
+		return fmt.Errorf("soap: %s", err)
+	}
+
+	redeclare := contentDefault != start.Name.Space
+	depth := 0
+	for _, token := range content {
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth++; depth == 1 && redeclare && !hasDefaultNS(t) {
+				t.Attr = append(t.Attr, xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: contentDefault})
+			}
+			token = c.start(t, false)
+		case xml.EndElement:
+			depth--
+			token = xml.EndElement{Name: prefixed(t.Name)}
+		}
+
+		if err := c.e.EncodeToken(token); err != nil {
+			return fmt.Errorf("soap: %s", err)
+		}
+	}
+
+	if err := c.e.EncodeToken(start.End()); err != nil {
+		return fmt.Errorf("soap: %s", err)
+	}
+	return nil
+}
+
+// start returns raw start element with the envelope namespace declarations rewritten,
+// attributes of the header element are converted.
+func (c *envelopeConverter) start(t xml.StartElement, header bool) xml.StartElement {
+	start := xml.StartElement{Name: prefixed(t.Name), Attr: make([]xml.Attr, 0, len(t.Attr))}
+	for _, a := range t.Attr {
+		switch {
+		case a.Name.Space == "xmlns" || a.Name.Space == "" && a.Name.Local == "xmlns":
+			a.Value = c.rewrite(a.Value)
+		case header && a.Name.Space != "" && c.scope()[a.Name.Space] == c.from.namespace():
+			a = convertHeaderAttr(a, c.to)
+		}
+		a.Name = prefixed(a.Name)
+		start.Attr = append(start.Attr, a)
+	}
+	return start
+}
+
+// rewrite returns namespace of the converted envelope instead of the source envelope namespace.
+func (c *envelopeConverter) rewrite(space string) string {
+	if space == c.from.namespace() {
+		return c.to.namespace()
+	}
+	return space
+}
+
+// push adds scope of the element declarations.
+func (c *envelopeConverter) push(t xml.StartElement) {
+	parent := c.scope()
+	scope := make(nsScope, len(parent))
+	for k, v := range parent {
+		scope[k] = v
+	}
+
+	for _, a := range t.Attr {
+		switch {
+		case a.Name.Space == "xmlns":
+			scope[a.Name.Local] = a.Value
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			scope[""] = a.Value
+		}
+	}
+	c.scopes = append(c.scopes, scope)
+}
+
+func (c *envelopeConverter) pop() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+func (c *envelopeConverter) scope() nsScope {
+	if len(c.scopes) == 0 {
+		return nil
+	}
+	return c.scopes[len(c.scopes)-1]
+}
+
+// resolve returns name of the raw element in the current scope.
+func (c *envelopeConverter) resolve(name xml.Name) xml.Name {
+	return xml.Name{Space: c.scope()[name.Space], Local: name.Local}
+}
+
+// prefixed returns raw name with the prefix which is written by the encoder as it is.
+func prefixed(name xml.Name) xml.Name {
+	return xml.Name{Local: rawName(name)}
+}
+
+func hasDefaultNS(t xml.StartElement) bool {
+	for _, a := range t.Attr {
+		if a.Name.Space == "" && a.Name.Local == "xmlns" {
+			return true
+		}
+	}
+	return false
+}
+
+func syntaxError(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("soap: %s", err)
+}
+
+// convertHeaderAttr converts actor and role and mustUnderstand attribute of the header element.
+func convertHeaderAttr(a xml.Attr, to SOAPVersion) xml.Attr {
+	switch {
+	case to == SOAP12 && a.Name.Local == "actor":
+		a.Name.Local = "role"
+		if a.Value == actorNext {
+			a.Value = roleNext
+		}
+	case to == SOAP11 && a.Name.Local == "role":
+		a.Name.Local = "actor"
+		if a.Value == roleNext {
+			a.Value = actorNext
+		}
+	case to == SOAP11 && a.Name.Local == "mustUnderstand":
+		switch strings.TrimSpace(a.Value) {
+		case "true":
+			a.Value = "1"
+		case "false":
+			a.Value = "0"
+		}
+	}
+	return a
+}
+
+// fault12 converts SOAP 1.1 fault to SOAP 1.2 fault, the detail is converted by the caller.
+func fault12(f *Node) *Node {
+	code := localName(f.Value("Fault/faultcode"))
+	c, ok := fault12Codes[code]
+	if !ok {
+		c = Fault12Receiver
+		if strings.HasPrefix(code, "Client.") {
+			c = Fault12Sender
+		}
+	}
+
+	value := NewNode(envelope12NS, "Value", c)
+	value.Attrs = append(value.Attrs, xml.Attr{Name: xml.Name{Local: "xmlns:env"}, Value: envelope12NS})
+	out := NewNode(envelope12NS, "Fault", "").Add(
+		NewNode(envelope12NS, "Code", "").Add(value),
+		NewNode(envelope12NS, "Reason", "").Add(
+			NewNode(envelope12NS, "Text", f.Value("Fault/faultstring")).SetAttr(xmlNS, "lang", "en")),
+	)
+
+	if actor := f.Value("Fault/faultactor"); actor != "" {
+		out.Add(NewNode(envelope12NS, "Role", actor))
+	}
+	return out
+}
+
+// fault11 converts SOAP 1.2 fault to SOAP 1.1 fault, the detail is converted by the caller.
+func fault11(f *Node) *Node {
+	c, ok := fault11Codes[localName(f.Value("Fault/Code/Value"))]
+	if !ok {
+		c = "Server"
+	}
+
+	code := NewNode("", "faultcode", "soapenv:"+c)
+	code.Attrs = append(code.Attrs, xml.Attr{Name: xml.Name{Local: "xmlns:soapenv"}, Value: envelopeNS})
+	out := NewNode(envelopeNS, "Fault", "").Add(code, NewNode("", "faultstring", f.Value("Fault/Reason/Text")))
+
+	if role := f.Value("Fault/Role"); role != "" {
+		out.Add(NewNode("", "faultactor", role))
+	}
+	return out
+}
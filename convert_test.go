@@ -0,0 +1,137 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConvertEnvelope(t *testing.T) {
+	t.Parallel()
+	in := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">` +
+		`<soapenv:Header><h:Session xmlns:h="urn:h" soapenv:actor="http://schemas.xmlsoap.org/soap/actor/next" soapenv:mustUnderstand="1">1</h:Session></soapenv:Header>` +
+		`<soapenv:Body><soapenv:Fault><faultcode>soapenv:Client.Auth</faultcode><faultstring>denied</faultstring>` +
+		`<faultactor>urn:gw</faultactor><detail><e:Code xmlns:e="urn:e">42</e:Code></detail></soapenv:Fault></soapenv:Body></soapenv:Envelope>`
+
+	b, err := ConvertEnvelope([]byte(in), SOAP12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := ParseNode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n.XMLName.Space != envelope12NS {
+		t.Fatalf("got: %s, want: %s", n.XMLName.Space, envelope12NS)
+	}
+
+	if role, _ := n.Find("Envelope/Header/Session").Attr("role"); role != roleNext {
+		t.Fatalf("got: %s, want: %s", role, roleNext)
+	}
+
+	for path, want := range map[string]string{
+		"Envelope/Body/Fault/Code/Value":  Fault12Sender,
+		"Envelope/Body/Fault/Reason/Text": "denied",
+		"Envelope/Body/Fault/Role":        "urn:gw",
+		"Envelope/Body/Fault/Detail/Code": "42",
+	} {
+		if got := n.Value(path); got != want {
+			t.Errorf("%s got: %s, want: %s", path, got, want)
+		}
+	}
+
+	// the code prefix is declared
+	if want := `xmlns:env="` + envelope12NS + `">env:Sender<`; !bytes.Contains(b, []byte(want)) {
+		t.Fatalf("got: %s, want: %s", b, want)
+	}
+
+	if b, err = ConvertEnvelope(b, SOAP11); err != nil {
+		t.Fatal(err)
+	}
+
+	env := &Envelope{Body: Body{Content: &struct{}{}}}
+	if err := xml.Unmarshal(b, env); err != nil {
+		t.Fatal(err)
+	}
+
+	f := env.Body.Fault
+	if f == nil || f.Code != FaultClient || f.Text != "denied" || f.Actor != "urn:gw" || !bytes.Contains(b, []byte(`<detail><e:Code xmlns:e="urn:e" xmlns="">42</e:Code></detail>`)) {
+		t.Fatalf("got: %+v, want: converted fault", f)
+	}
+
+	if _, err := ConvertEnvelope([]byte(`<Envelope/>`), SOAP12); err != errVersion {
+		t.Fatalf("got: %v, want: %s", err, errVersion)
+	}
+}
+
+func TestConvertEnvelope_Content(t *testing.T) {
+	t.Parallel()
+	body := `<m:Request xmlns:m="urn:m" xmlns:tns="urn:t" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<m:Item xsi:type="tns:Foo">a<b/>c</m:Item><!-- note --></m:Request>`
+	in := `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` + body + `</soap:Body></soap:Envelope>`
+
+	b, err := ConvertEnvelope([]byte(in), SOAP12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the envelope namespace is rewritten, the body is copied token by token
+	want := xml.Header + `<soap:Envelope xmlns:soap="` + envelope12NS + `"><soap:Body>` +
+		`<m:Request xmlns:m="urn:m" xmlns:tns="urn:t" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<m:Item xsi:type="tns:Foo">a<b></b>c</m:Item><!-- note --></m:Request></soap:Body></soap:Envelope>`
+	if got := string(b); got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+
+	if b, err = ConvertEnvelope(b, SOAP11); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `<soap:Envelope xmlns:soap="` + envelopeNS + `"><soap:Body>` + strings.Replace(body, "<b/>", "<b></b>", 1); !strings.Contains(string(b), want) {
+		t.Fatalf("got: %s, want: %s", b, want)
+	}
+
+	if _, err := ConvertEnvelope([]byte(`<soap:Envelope xmlns:soap="`+envelopeNS+`"><soap:Body></soap:Envelope>`), SOAP12); err == nil {
+		t.Fatal("want error of the unclosed element")
+	}
+}
+
+func TestSOAPVersion_Middleware(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/soap+xml" || params["action"] != "get" {
+			t.Errorf("got: %s, want: application/soap+xml of get", r.Header.Get("Content-Type"))
+		}
+
+		b, _ := ioutil.ReadAll(r.Body)
+		if n, err := ParseNode(b); err != nil || n.XMLName.Space != envelope12NS {
+			t.Errorf("got: %s, want: soap 1.2 envelope", b)
+		}
+
+		b = []byte(`<env:Envelope xmlns:env="` + envelope12NS + `"><env:Body><Response xmlns="test:call" xmlns:tns="urn:t" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+			`<attr3 xsi:type="tns:Foo">value<b/>3</attr3></Response></env:Body></env:Envelope>`)
+		w.Header().Set("Content-Type", SOAP12.contentType(""))
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{Middleware: []Middleware{SOAP12.Middleware()}})
+	resp := &Node{}
+	if err := client.Call(context.Background(), "get", request{}, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	// the qname prefix stays declared and mixed content keeps its order
+	want := `<Response xmlns="test:call" xmlns:tns="urn:t" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><attr3 xmlns="test:call" xsi:type="tns:Foo">value<b xmlns="test:call"></b>3</attr3></Response>`
+	if got := string(encodeNode(resp)); got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+}
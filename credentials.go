@@ -0,0 +1,56 @@
+package soap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// CredentialsProvider implements lazily fetched credentials, e.g. secrets kept in Vault or KMS.
+// It is called for each round trip, so rotated credentials are used by the next request,
+// caching is up to the provider. Nil basic auth and empty token are not sent.
+type CredentialsProvider interface {
+	GetBasicAuth(ctx context.Context) (*BasicAuth, error)
+	// GetToken returns bearer token which takes precedence over basic authorization.
+	GetToken(ctx context.Context) (string, error)
+}
+
+// StaticCredentials implements CredentialsProvider of fixed credentials.
+type StaticCredentials struct {
+	BasicAuth *BasicAuth
+	Token     string
+}
+
+// GetBasicAuth returns basic authorization credentials.
+func (c StaticCredentials) GetBasicAuth(ctx context.Context) (*BasicAuth, error) {
+	return c.BasicAuth, nil
+}
+
+// GetToken returns bearer token.
+func (c StaticCredentials) GetToken(ctx context.Context) (string, error) {
+	return c.Token, nil
+}
+
+// authorize sets authorization of the request from the provider or the basic auth.
+func (s *Client) authorize(ctx context.Context, req *http.Request, auth *BasicAuth) error {
+	if p := s.config.Credentials; p != nil {
+		token, err := p.GetToken(ctx)
+		if err != nil {
+			return fmt.Errorf("soap: credentials %s", err)
+		}
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+			return nil
+		}
+
+		if auth, err = p.GetBasicAuth(ctx); err != nil {
+			return fmt.Errorf("soap: credentials %s", err)
+		}
+	}
+
+	if auth != nil {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// rotatingCredentials returns new password for each request.
+type rotatingCredentials struct {
+	n   int
+	err error
+}
+
+func (c *rotatingCredentials) GetBasicAuth(ctx context.Context) (*BasicAuth, error) {
+	c.n++
+	return &BasicAuth{Username: "user", Password: "secret" + strconv.Itoa(c.n)}, c.err
+}
+
+func (c *rotatingCredentials) GetToken(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+func TestClient_Credentials(t *testing.T) {
+	t.Parallel()
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+		b, _ := xml.Marshal(Envelope{Body: Body{}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	rotating := &rotatingCredentials{}
+	for _, p := range []CredentialsProvider{rotating, rotating, StaticCredentials{BasicAuth: &BasicAuth{Username: "user"}, Token: "token"}} {
+		if err := MustNewClient(srv.URL, Config{Credentials: p}).Call(context.Background(), "", request{}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"Basic dXNlcjpzZWNyZXQx", "Basic dXNlcjpzZWNyZXQy", "Bearer token"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+
+	rotating.err = fmt.Errorf("vault is sealed")
+	err := MustNewClient(srv.URL, Config{Credentials: rotating}).Call(context.Background(), "", request{}, nil)
+	if want := "soap: credentials vault is sealed"; err == nil || err.Error() != want {
+		t.Fatalf("got: %v, want: %s", err, want)
+	}
+}
@@ -0,0 +1,234 @@
+package soap
+
+import (
+	"encoding"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimeFormat implements encoding of time.Time fields of the request as xsd:dateTime,
+// instead of RFC 3339 with nanoseconds rejected by some servers.
+type TimeFormat struct {
+	// Location converts the times, e.g. time.UTC, by default location of the time is kept.
+	Location *time.Location
+	// Precision is precision of fraction of seconds, e.g. time.Millisecond, the time is truncated.
+	// By default the time is encoded in whole seconds.
+	Precision time.Duration
+}
+
+// Format returns xsd:dateTime of the time.
+func (f TimeFormat) Format(t time.Time) string {
+	if f.Location != nil {
+		t = t.In(f.Location)
+	}
+
+	layout := "2006-01-02T15:04:05"
+	digits := 0
+	for p := time.Second; p > f.Precision && digits < 9; p /= 10 {
+		digits++
+	}
+	if f.Precision > 0 && digits > 0 {
+		layout += "." + strings.Repeat("0", digits)
+	}
+	return t.Format(layout + "Z07:00")
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	stringType        = reflect.TypeOf("")
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	attrMarshalerType = reflect.TypeOf((*xml.MarshalerAttr)(nil)).Elem()
+)
+
+// timeMirror implements type with time.Time replaced by string, typ is nil when the type has no time fields.
+type timeMirror struct {
+	typ reflect.Type
+	err error
+}
+
+var timeTypes sync.Map // map[reflect.Type]*timeMirror
+
+// timeMirrorOf returns mirror of the type.
+func timeMirrorOf(t reflect.Type) *timeMirror {
+	if v, ok := timeTypes.Load(t); ok {
+		return v.(*timeMirror)
+	}
+
+	m := &timeMirror{}
+	if typ, err := mirrorTimes(t, make(map[reflect.Type]bool)); err != nil {
+		m.err = err
+	} else if typ != t {
+		m.typ = typ
+	}
+
+	v, _ := timeTypes.LoadOrStore(t, m)
+	return v.(*timeMirror)
+}
+
+// mirrorTimes returns type with time.Time replaced by string, the type itself when it has no time fields.
+// Marshalers, interfaces and recursive types are kept.
+func mirrorTimes(t reflect.Type, seen map[reflect.Type]bool) (reflect.Type, error) {
+	if t == timeType {
+		return stringType, nil
+	}
+
+	if seen[t] {
+		return t, nil
+	}
+
+	// marshalers of pointers are checked by the element
+	if t.Kind() != reflect.Ptr && (t.Implements(marshalerType) || t.Implements(attrMarshalerType) || t.Implements(textMarshalerType)) {
+		return t, nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		elem, err := mirrorTimes(t.Elem(), seen)
+		if err != nil || elem == t.Elem() {
+			return t, err
+		}
+
+		switch t.Kind() {
+		case reflect.Ptr:
+			return reflect.PtrTo(elem), nil
+		case reflect.Slice:
+			return reflect.SliceOf(elem), nil
+		}
+		return reflect.ArrayOf(t.Len(), elem), nil
+	case reflect.Struct:
+		if reflect.PtrTo(t).Implements(marshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+			return t, nil
+		}
+
+		var (
+			fields  []reflect.StructField
+			changed bool
+		)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+
+			typ, err := mirrorTimes(f.Type, seen)
+			if err != nil {
+				return t, err
+			}
+
+			if typ != f.Type {
+				if f.Anonymous {
+					return t, fmt.Errorf("soap: embedded field %s of %s must not have time fields", f.Name, t)
+				}
+				changed = true
+			}
+			f.Type = typ
+			fields = append(fields, f)
+		}
+
+		if !changed {
+			return t, nil
+		}
+
+		for _, f := range fields {
+			if f.Anonymous && (f.Type.NumMethod() > 0 || reflect.PtrTo(f.Type).NumMethod() > 0) {
+				return t, fmt.Errorf("soap: embedded field %s of %s must not have methods with time fields", f.Name, t)
+			}
+		}
+		return reflect.StructOf(fields), nil
+	}
+	return t, nil
+}
+
+// mirrorValue copies the value into the mirror type formatting the times.
+func mirrorValue(v reflect.Value, t reflect.Type, f TimeFormat) reflect.Value {
+	if v.Type() == t {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(t)
+		}
+
+		p := reflect.New(t.Elem())
+		p.Elem().Set(mirrorValue(v.Elem(), t.Elem(), f))
+		return p
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(t)
+		}
+
+		s := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s.Index(i).Set(mirrorValue(v.Index(i), t.Elem(), f))
+		}
+		return s
+	case reflect.Array:
+		a := reflect.New(t).Elem()
+		for i := 0; i < v.Len(); i++ {
+			a.Index(i).Set(mirrorValue(v.Index(i), t.Elem(), f))
+		}
+		return a
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return reflect.ValueOf(f.Format(v.Interface().(time.Time)))
+		}
+
+		s := reflect.New(t).Elem()
+		j := 0
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			s.Field(j).Set(mirrorValue(v.Field(i), t.Field(j).Type, f))
+			j++
+		}
+		return s
+	}
+	return v
+}
+
+// formatTimes returns the value with time.Time fields formatted, it is encoded with the name of the original type.
+func formatTimes(value interface{}, f *TimeFormat) (interface{}, error) {
+	if f == nil || value == nil {
+		return value, nil
+	}
+
+	switch v := value.(type) {
+	case namedBody:
+		content, err := formatTimes(v.value, f)
+		return namedBody{name: v.name, value: content}, err
+	case namedHeader:
+		content, err := formatTimes(v.value, f)
+		return namedHeader{name: v.name, value: content}, err
+	}
+
+	rv := reflect.ValueOf(value)
+	m := timeMirrorOf(rv.Type())
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if m.typ == nil {
+		return value, nil
+	}
+
+	mirror := mirrorValue(rv, m.typ, *f).Interface()
+	t := rv.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// name of the mirror is taken from XMLName field, otherwise from the original type
+	if _, ok := t.FieldByName("XMLName"); t.Kind() == reflect.Struct && !ok {
+		return namedBody{name: xml.Name{Local: t.Name()}, value: mirror}, nil
+	}
+	return mirror, nil
+}
@@ -0,0 +1,80 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeFormat_Format(t *testing.T) {
+	t.Parallel()
+	tm := time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.FixedZone("", 3*3600))
+	for i, v := range []struct {
+		f    TimeFormat
+		want string
+	}{
+		{want: "2020-01-02T03:04:05+03:00"},
+		{f: TimeFormat{Location: time.UTC}, want: "2020-01-02T00:04:05Z"},
+		{f: TimeFormat{Precision: time.Millisecond}, want: "2020-01-02T03:04:05.123+03:00"},
+		{f: TimeFormat{Precision: time.Microsecond, Location: time.UTC}, want: "2020-01-02T00:04:05.123456Z"},
+		{f: TimeFormat{Precision: time.Nanosecond}, want: "2020-01-02T03:04:05.123456789+03:00"},
+	} {
+		if got := v.f.Format(tm); got != v.want {
+			t.Errorf("#%d got: %s, want: %s", i, got, v.want)
+		}
+	}
+}
+
+type timeItem struct {
+	At time.Time `xml:"at,attr"`
+}
+
+type timeRequest struct {
+	Created time.Time  `xml:"test:call created"`
+	Expires *time.Time `xml:"expires,omitempty"`
+	Missing *time.Time `xml:"missing,omitempty"`
+	Items   []timeItem `xml:"item"`
+	Name    string     `xml:"name"`
+}
+
+func TestClient_TimeFormat(t *testing.T) {
+	t.Parallel()
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		got = string(b)
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body/></Envelope>`))
+	}))
+	defer srv.Close()
+
+	tm := time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	client := MustNewClient(srv.URL, Config{TimeFormat: &TimeFormat{Precision: time.Millisecond}})
+	req := &timeRequest{Created: tm, Expires: &tm, Items: []timeItem{{At: tm}}, Name: "name"}
+	if err := client.Call(context.Background(), "", req, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<timeRequest><created xmlns="test:call">2020-01-02T03:04:05.123Z</created><expires>2020-01-02T03:04:05.123Z</expires>` +
+		`<item at="2020-01-02T03:04:05.123Z"></item><name>name</name></timeRequest>`
+	if !strings.Contains(got, want) {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+
+	// type with XMLName keeps its name
+	type named struct {
+		XMLName xml.Name  `xml:"test:call Named"`
+		At      time.Time `xml:"at"`
+	}
+	if err := client.Call(context.Background(), "", named{At: tm}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `<Named xmlns="test:call"><at>2020-01-02T03:04:05.123Z</at></Named>`; !strings.Contains(got, want) {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+}
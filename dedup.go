@@ -0,0 +1,69 @@
+package soap
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+)
+
+type flight struct {
+	done chan struct{}
+	resp *Response
+	err  error
+}
+
+type dedup struct {
+	mu      sync.Mutex
+	actions map[string]bool
+	flights map[[sha256.Size]byte]*flight
+}
+
+// NewDedup creates middleware coalescing concurrent identical requests of the read-only
+// soap actions into one round trip, the waiting calls share its response.
+// The round trip is bound to the context of the call which started it.
+func NewDedup(actions ...string) Middleware {
+	d := &dedup{
+		actions: make(map[string]bool, len(actions)),
+		flights: make(map[[sha256.Size]byte]*flight),
+	}
+	for _, a := range actions {
+		d.actions[a] = true
+	}
+	return d.middleware
+}
+
+func (d *dedup) middleware(next RoundTripFunc) RoundTripFunc {
+	return func(ctx context.Context, r *Request) (*Response, error) {
+		if !d.actions[r.Action] {
+			return next(ctx, r)
+		}
+
+		key, ok := requestKey(ctx, r)
+		if !ok {
+			return next(ctx, r)
+		}
+
+		d.mu.Lock()
+		if f, ok := d.flights[key]; ok {
+			d.mu.Unlock()
+			select {
+			case <-f.done:
+				// the error is copied, so metadata is set per call
+				return f.resp, copyError(f.err)
+			case <-ctx.Done():
+				return nil, &transportError{err: ctx.Err()}
+			}
+		}
+
+		f := &flight{done: make(chan struct{})}
+		d.flights[key] = f
+		d.mu.Unlock()
+
+		f.resp, f.err = next(ctx, r)
+		d.mu.Lock()
+		delete(d.flights, key)
+		d.mu.Unlock()
+		close(f.done)
+		return f.resp, f.err
+	}
+}
@@ -0,0 +1,125 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_Dedup(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		atomic.AddInt32(&calls, 1)
+		<-release
+		b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	var started sync.WaitGroup
+	client := MustNewClient(srv.URL, Config{Middleware: []Middleware{
+		func(next RoundTripFunc) RoundTripFunc {
+			return func(ctx context.Context, r *Request) (*Response, error) {
+				started.Done()
+				return next(ctx, r)
+			}
+		},
+		NewDedup("codes"),
+	}})
+
+	const n = 5
+	started.Add(n)
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, n)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var r response
+			if err := client.Call(context.Background(), "codes", request{Attr1: "1"}, &r); err != nil {
+				errs <- err
+				return
+			}
+
+			if r.Attr3 != "value3" {
+				errs <- xml.UnmarshalError(r.Attr3)
+			}
+		}()
+	}
+
+	started.Wait()
+	// let the calls join the flight
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("got: %d, want: %d", got, 1)
+	}
+}
+
+func TestClient_DedupError(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer srv.Close()
+
+	var started sync.WaitGroup
+	client := MustNewClient(srv.URL, Config{Middleware: []Middleware{
+		func(next RoundTripFunc) RoundTripFunc {
+			return func(ctx context.Context, r *Request) (*Response, error) {
+				started.Done()
+				return next(ctx, r)
+			}
+		},
+		NewDedup("codes"),
+	}})
+
+	const n = 5
+	started.Add(n)
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, n)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- client.Call(context.Background(), "codes", request{Attr1: "1"}, &response{})
+		}()
+	}
+
+	started.Wait()
+	// let the calls join the flight
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	seen := make(map[error]bool)
+	for err := range errs {
+		if info, ok := CallInfoOf(err); !ok || info.Action() != "codes" || seen[err] {
+			t.Fatalf("got: %v %+v, want: own transport error of the call", err, info)
+		}
+		seen[err] = true
+	}
+}
@@ -0,0 +1,195 @@
+package soap
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// IPFamily implements address family of endpoint connections.
+type IPFamily string
+
+// IP families.
+const (
+	// DualStack dials IPv6 and IPv4 addresses, the other family is raced after
+	// the fallback delay when the first one does not connect (Happy Eyeballs).
+	DualStack IPFamily = ""
+	// IPv4Only dials only IPv4 addresses, e.g. for firewalls blackholing IPv6.
+	IPv4Only IPFamily = "tcp4"
+	// IPv6Only dials only IPv6 addresses.
+	IPv6Only IPFamily = "tcp6"
+)
+
+// defaultFallbackDelay is the delay of net.Dialer.
+const defaultFallbackDelay = 300 * time.Millisecond
+
+// dial returns dial function of the transport.
+func (c Config) dial() func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Resolver: c.Resolver, FallbackDelay: c.FallbackDelay}
+	dial := dialer.DialContext
+	if c.DNSCacheTTL > 0 {
+		dial = newDNSCache(c.Resolver, c.DNSCacheTTL, dialer).dial
+	}
+
+	if c.IPFamily == DualStack {
+		return dial
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dial(ctx, string(c.IPFamily), addr)
+	}
+}
+
+// dnsCache implements cache of resolved host addresses, stale addresses are used
+// when the resolver fails, e.g. flaky corporate dns.
+type dnsCache struct {
+	ttl    time.Duration
+	lookup func(ctx context.Context, host string) ([]string, error)
+	dialer *net.Dialer
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+func newDNSCache(resolver *net.Resolver, ttl time.Duration, dialer *net.Dialer) *dnsCache {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &dnsCache{ttl: ttl, lookup: resolver.LookupHost, dialer: dialer, entries: make(map[string]dnsEntry)}
+}
+
+func (c *dnsCache) resolve(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	e, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.addrs, nil
+	}
+
+	addrs, err := c.lookup(ctx, host)
+	if err != nil {
+		if ok {
+			return e.addrs, nil
+		}
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return addrs, nil
+}
+
+// dial dials the resolved addresses in order until one succeeds.
+func (c *dnsCache) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if net.ParseIP(host) != nil {
+		return c.dialer.DialContext(ctx, network, addr)
+	}
+
+	addrs, err := c.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	// addresses are partitioned by family of the first one like net.Dialer does
+	var primaries, fallbacks []string
+	for _, a := range addrs {
+		ip := net.ParseIP(a)
+		switch {
+		case ip == nil,
+			network == string(IPv4Only) && ip.To4() == nil,
+			network == string(IPv6Only) && ip.To4() != nil:
+		case len(primaries) == 0 || (ip.To4() == nil) == (net.ParseIP(primaries[0]).To4() == nil):
+			primaries = append(primaries, a)
+		default:
+			fallbacks = append(fallbacks, a)
+		}
+	}
+
+	if len(primaries) == 0 {
+		return nil, fmt.Errorf("soap: no %s address of %s", network, host)
+	}
+
+	if len(fallbacks) == 0 || c.dialer.FallbackDelay < 0 {
+		return c.dialSerial(ctx, network, port, append(primaries, fallbacks...))
+	}
+	return c.dialParallel(ctx, network, port, primaries, fallbacks)
+}
+
+// dialSerial dials the addresses in order until one succeeds.
+func (c *dnsCache) dialSerial(ctx context.Context, network, port string, addrs []string) (net.Conn, error) {
+	var err error
+	for _, ip := range addrs {
+		var conn net.Conn
+		if conn, err = c.dialer.DialContext(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+// dialParallel races fallback addresses after the fallback delay or the failure of primaries.
+func (c *dnsCache) dialParallel(ctx context.Context, network, port string, primaries, fallbacks []string) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, 2)
+	run := func(addrs []string) {
+		conn, err := c.dialSerial(ctx, network, port, addrs)
+		results <- dialResult{conn: conn, err: err}
+	}
+	go run(primaries)
+
+	delay := c.dialer.FallbackDelay
+	if delay == 0 {
+		delay = defaultFallbackDelay
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	pending, fallback := 1, false
+	for {
+		select {
+		case <-timer.C:
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				if pending > 0 {
+					// the connection of the other race is closed
+					go func() {
+						if r := <-results; r.conn != nil {
+							r.conn.Close()
+						}
+					}()
+				}
+				return r.conn, nil
+			}
+
+			if fallback && pending == 0 {
+				return nil, r.err
+			}
+		}
+
+		if !fallback {
+			fallback = true
+			pending++
+			go run(fallbacks)
+		}
+	}
+}
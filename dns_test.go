@@ -0,0 +1,110 @@
+package soap
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDNSCache(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	var lookups int
+	c := newDNSCache(nil, time.Minute, &net.Dialer{})
+	c.lookup = func(ctx context.Context, host string) ([]string, error) {
+		lookups++
+		if host != "soap.test" || lookups > 1 {
+			return nil, fmt.Errorf("no such host %s", host)
+		}
+		return []string{"127.0.0.2", "127.0.0.1"}, nil
+	}
+
+	dial := func() error {
+		conn, err := c.dial(context.Background(), "tcp", net.JoinHostPort("soap.test", port))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := dial(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if lookups != 1 {
+		t.Fatalf("got: %d, want: %d", lookups, 1)
+	}
+
+	// stale addresses are used when the resolver fails
+	e := c.entries["soap.test"]
+	e.expires = time.Now()
+	c.entries["soap.test"] = e
+	if err := dial(); err != nil {
+		t.Fatal(err)
+	}
+
+	if lookups != 2 {
+		t.Fatalf("got: %d, want: %d", lookups, 2)
+	}
+
+	if _, err := c.dial(context.Background(), "tcp", net.JoinHostPort("unknown.test", port)); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestDNSCache_IPFamily(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	for i, v := range []struct {
+		family IPFamily
+		err    bool
+	}{
+		{family: DualStack},
+		{family: IPv4Only},
+		{family: IPv6Only, err: true},
+	} {
+		// server listens only on IPv4 address
+		c := Config{IPFamily: v.family, DNSCacheTTL: time.Minute, FallbackDelay: 10 * time.Millisecond}
+		cache := newDNSCache(nil, time.Minute, &net.Dialer{FallbackDelay: c.FallbackDelay})
+		cache.lookup = func(ctx context.Context, host string) ([]string, error) {
+			return []string{"::1", "127.0.0.1"}, nil
+		}
+
+		network := "tcp"
+		if v.family != DualStack {
+			network = string(v.family)
+		}
+
+		conn, err := cache.dial(context.Background(), network, net.JoinHostPort("soap.test", port))
+		if v.err != (err != nil) {
+			t.Errorf("#%d got: %v, want error: %t", i, err, v.err)
+		}
+
+		if conn != nil {
+			conn.Close()
+		}
+
+		// family is applied to addresses without cache
+		c.DNSCacheTTL = 0
+		conn, err = c.dial()(context.Background(), "tcp", srv.Listener.Addr().String())
+		if v.err != (err != nil) {
+			t.Errorf("#%d got: %v, want error: %t", i, err, v.err)
+		}
+
+		if conn != nil {
+			conn.Close()
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package soap
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+)
+
+// XML-DSig namespace and algorithms.
+const (
+	DSigNS      = "http://www.w3.org/2000/09/xmldsig#"
+	ExcC14N     = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	SHA1Digest  = "http://www.w3.org/2000/09/xmldsig#sha1"
+	SHA256      = "http://www.w3.org/2001/04/xmlenc#sha256"
+	RSASHA1     = "http://www.w3.org/2000/09/xmldsig#rsa-sha1"
+	RSASHA256   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	ECDSASHA256 = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+)
+
+var (
+	errSignature = fmt.Errorf("soap: signature is invalid")
+	errNoSecHdr  = fmt.Errorf("soap: envelope has no security header")
+)
+
+// Signed parts of the envelope, a part without namespace matches any namespace.
+var (
+	BodyPart      = xml.Name{Local: "Body"}
+	TimestampPart = xml.Name{Space: WSUNS, Local: "Timestamp"}
+	// AddressingParts are WS-Addressing message information headers.
+	AddressingParts = []xml.Name{
+		{Space: AddressingNS, Local: "MessageID"},
+		{Space: AddressingNS, Local: "To"},
+		{Space: AddressingNS, Local: "Action"},
+		{Space: AddressingNS, Local: "ReplyTo"},
+		{Space: AddressingNS, Local: "RelatesTo"},
+	}
+	// DefaultSignedParts are signed unless the signed parts are configured.
+	DefaultSignedParts = []xml.Name{TimestampPart, BodyPart}
+)
+
+// withSignedParts returns envelope where the elements of the parts have Id and the ids in order of the parts.
+// Body, elements of the security header and header elements are looked up, missing parts are skipped.
+func withSignedParts(envelope []byte, parts []xml.Name) ([]byte, []string, error) {
+	var ids []string
+	for _, part := range parts {
+		for i := 0; ; i++ {
+			root, err := parseDoc(envelope)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			nodes := signedNodes(root, part)
+			if i >= len(nodes) {
+				break
+			}
+
+			var id string
+			envelope, id = withID(envelope, nodes[i], fmt.Sprintf("%s-%d", part.Local, i+1))
+			if !contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return envelope, ids, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// signedNodes returns elements of the part below the envelope, the header and the security header.
+func signedNodes(root *xnode, part xml.Name) []*xnode {
+	header := root.child("Header")
+	var nodes []*xnode
+	for _, parent := range []*xnode{root, header, header.child("Security")} {
+		if parent == nil {
+			continue
+		}
+
+		for _, c := range parent.children {
+			if n, ok := c.(*xnode); ok && n.name.Local == part.Local && (part.Space == "" || n.namespace(n.name.Space) == part.Space) {
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	return nodes
+}
+
+// signEnvelope signs elements of the envelope by their Id and appends ds:Signature
+// to the security header, keyInfo is the content of ds:KeyInfo.
+func signEnvelope(envelope []byte, key crypto.Signer, keyInfo string, ids []string) ([]byte, error) {
+	root, err := parseDoc(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	security := root.find("Header", "Security")
+	if security == nil {
+		return nil, errNoSecHdr
+	}
+
+	method := RSASHA256
+	if _, ok := key.Public().(*ecdsa.PublicKey); ok {
+		method = ECDSASHA256
+	}
+
+	var si strings.Builder
+	si.WriteString(`<ds:SignedInfo xmlns:ds="` + DSigNS + `">`)
+	si.WriteString(`<ds:CanonicalizationMethod Algorithm="` + ExcC14N + `"></ds:CanonicalizationMethod>`)
+	si.WriteString(`<ds:SignatureMethod Algorithm="` + method + `"></ds:SignatureMethod>`)
+	for _, id := range ids {
+		n := root.byID(id)
+		if n == nil {
+			return nil, fmt.Errorf("soap: element %s is not found", id)
+		}
+
+		digest := sha256.Sum256(n.canonical())
+		si.WriteString(`<ds:Reference URI="#` + escapeAttr(id) + `"><ds:Transforms><ds:Transform Algorithm="` + ExcC14N + `"></ds:Transform></ds:Transforms>`)
+		si.WriteString(`<ds:DigestMethod Algorithm="` + SHA256 + `"></ds:DigestMethod>`)
+		si.WriteString(`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest[:]) + `</ds:DigestValue></ds:Reference>`)
+	}
+	si.WriteString(`</ds:SignedInfo>`)
+
+	signedInfo, err := Canonicalize([]byte(si.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.Sum256(signedInfo)
+	sig, err := key.Sign(rand.Reader, h[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("soap: %s", err)
+	}
+
+	if pub, ok := key.Public().(*ecdsa.PublicKey); ok {
+		if sig, err = rawECDSA(sig, pub); err != nil {
+			return nil, err
+		}
+	}
+
+	signature := `<ds:Signature xmlns:ds="` + DSigNS + `">` + si.String() +
+		`<ds:SignatureValue>` + base64.StdEncoding.EncodeToString(sig) + `</ds:SignatureValue>` +
+		`<ds:KeyInfo>` + keyInfo + `</ds:KeyInfo></ds:Signature>`
+	return insert(envelope, security.contentEnd, signature), nil
+}
+
+// VerifySignature verifies ds:Signature of the security header with the public key
+// and returns Id of the signed elements.
+func VerifySignature(envelope []byte, pub crypto.PublicKey) ([]string, error) {
+	root, err := parseDoc(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	signature := root.find("Header", "Security", "Signature")
+	if signature == nil {
+		return nil, errSignature
+	}
+	return verifySignature(root, signature, pub)
+}
+
+func verifySignature(root, signature *xnode, pub crypto.PublicKey) ([]string, error) {
+	si := signature.child("SignedInfo")
+	if si == nil || algorithm(si.child("CanonicalizationMethod")) != ExcC14N {
+		return nil, errSignature
+	}
+
+	// signed copy of the element hidden before the processed one is not accepted
+	if id, ok := root.duplicateID(make(map[string]bool)); ok {
+		return nil, fmt.Errorf("soap: element id %s is duplicated", id)
+	}
+
+	var ids []string
+	for _, c := range si.children {
+		ref, ok := c.(*xnode)
+		if !ok || ref.name.Local != "Reference" {
+			continue
+		}
+
+		uri, _ := attr(ref, "URI")
+		if !strings.HasPrefix(uri, "#") {
+			return nil, errSignature
+		}
+
+		if transforms := ref.child("Transforms"); transforms != nil {
+			for _, t := range transforms.children {
+				if t, ok := t.(*xnode); ok && algorithm(t) != ExcC14N {
+					return nil, fmt.Errorf("soap: transform %s is not supported", algorithm(t))
+				}
+			}
+		}
+
+		var h hash.Hash
+		switch algorithm(ref.child("DigestMethod")) {
+		case SHA256:
+			h = sha256.New()
+		case SHA1Digest:
+			h = sha1.New()
+		default:
+			return nil, errSignature
+		}
+
+		n := root.byID(uri[1:])
+		if n == nil {
+			return nil, errSignature
+		}
+		h.Write(n.canonical())
+
+		want, err := base64.StdEncoding.DecodeString(ref.child("DigestValue").text())
+		if err != nil || !bytes.Equal(want, h.Sum(nil)) {
+			return nil, errSignature
+		}
+		ids = append(ids, uri[1:])
+	}
+
+	if len(ids) == 0 {
+		return nil, errSignature
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(signature.child("SignatureValue").text()), ""))
+	if err != nil {
+		return nil, errSignature
+	}
+
+	var (
+		alg crypto.Hash
+		h   hash.Hash
+	)
+	switch method := algorithm(si.child("SignatureMethod")); method {
+	case RSASHA256, ECDSASHA256:
+		alg, h = crypto.SHA256, sha256.New()
+	case RSASHA1:
+		alg, h = crypto.SHA1, sha1.New()
+	default:
+		return nil, fmt.Errorf("soap: signature method %s is not supported", method)
+	}
+	h.Write(si.canonical())
+
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(k, alg, h.Sum(nil), sig) != nil {
+			return nil, errSignature
+		}
+	case *ecdsa.PublicKey:
+		size := len(sig) / 2
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, h.Sum(nil), r, s) {
+			return nil, errSignature
+		}
+	default:
+		return nil, fmt.Errorf("soap: key %T is not supported", pub)
+	}
+	return ids, nil
+}
+
+// rawECDSA converts ASN.1 signature to concatenated r and s as required by XML-DSig.
+func rawECDSA(sig []byte, pub *ecdsa.PublicKey) ([]byte, error) {
+	var v struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(sig, &v); err != nil {
+		return nil, fmt.Errorf("soap: %s", err)
+	}
+
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	raw := make([]byte, 2*size)
+	v.R.FillBytes(raw[:size])
+	v.S.FillBytes(raw[size:])
+	return raw, nil
+}
+
+// withHeader returns envelope having header element.
+func withHeader(envelope []byte) ([]byte, *xnode, error) {
+	root, err := parseDoc(envelope)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if root.name.Local != "Envelope" {
+		return nil, nil, fmt.Errorf("soap: root element %s is not envelope", root.name.Local)
+	}
+
+	header := root.child("Header")
+	if header == nil {
+		body := root.child("Body")
+		if body == nil {
+			return nil, nil, fmt.Errorf("soap: envelope has no body")
+		}
+
+		name := rawName(root.name)
+		name = name[:len(name)-len("Envelope")] + "Header"
+		return withHeader(insert(envelope, body.start, "<"+name+"></"+name+">"))
+	}
+
+	if header.contentEnd == header.end {
+		return nil, nil, fmt.Errorf("soap: header element must not be self-closing")
+	}
+	return envelope, header, nil
+}
+
+// withSecurity returns envelope having header with wsse:Security element.
+func withSecurity(envelope []byte) ([]byte, error) {
+	envelope, header, err := withHeader(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.child("Security") != nil {
+		return envelope, nil
+	}
+	return insert(envelope, header.contentEnd, `<wsse:Security xmlns:wsse="`+WSSENS+`" xmlns:wsu="`+WSUNS+`"></wsse:Security>`), nil
+}
+
+// withID returns envelope where the element has Id attribute, id is used when the element has none.
+func withID(envelope []byte, n *xnode, id string) ([]byte, string) {
+	if v := n.id(); v != "" {
+		return envelope, v
+	}
+
+	at := n.contentStart - 1
+	if envelope[at-1] == '/' {
+		at--
+	}
+
+	s := ` wsu:Id="` + escapeAttr(id) + `"`
+	if n.namespace("wsu") != WSUNS {
+		s = ` xmlns:wsu="` + WSUNS + `"` + s
+	}
+	return insert(envelope, at, s), id
+}
+
+func insert(data []byte, at int, s string) []byte {
+	b := make([]byte, 0, len(data)+len(s))
+	b = append(b, data[:at]...)
+	b = append(b, s...)
+	return append(b, data[at:]...)
+}
+
+func attr(n *xnode, local string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Local == local {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+func algorithm(n *xnode) string {
+	if n == nil {
+		return ""
+	}
+
+	v, _ := attr(n, "Algorithm")
+	return v
+}
+
+// text returns trimmed character data of the element.
+func (n *xnode) text() string {
+	if n == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, c := range n.children {
+		if t, ok := c.(xml.CharData); ok {
+			b.Write(t)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
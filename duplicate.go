@@ -0,0 +1,104 @@
+package soap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultDuplicateWindow is period message ids are remembered by the duplicate detector.
+const DefaultDuplicateWindow = 10 * time.Minute
+
+// ErrDuplicate is returned by the call which response is delivered more than once.
+var ErrDuplicate = fmt.Errorf("soap: duplicate response")
+
+// DuplicateDetector implements detection of responses delivered more than once by flaky
+// intermediaries using WS-Addressing headers. The response is identified by its MessageID,
+// or by RelatesTo when it has no MessageID.
+type DuplicateDetector struct {
+	// Store keeps seen ids, it may be shared between instances, in-memory store is used when nil.
+	Store NonceStore
+	// Window is period the ids are remembered, DefaultDuplicateWindow when zero.
+	Window time.Duration
+	// Suppress acknowledges duplicates delivered to the callback without passing them,
+	// otherwise 409 status is sent back.
+	Suppress bool
+	// OnDuplicate is called with id of each duplicate.
+	OnDuplicate func(id string)
+
+	once sync.Once
+}
+
+// seen records the response id and reports whether it is duplicate.
+func (d *DuplicateDetector) seen(ctx context.Context, n *Node) (bool, error) {
+	id := n.Value("Envelope/Header/MessageID")
+	if id == "" {
+		id = n.Value("Envelope/Header/RelatesTo")
+	}
+
+	if id == "" {
+		return false, nil
+	}
+
+	d.once.Do(func() {
+		if d.Store == nil {
+			d.Store = NewMemoryNonceStore()
+		}
+	})
+
+	window := d.Window
+	if window == 0 {
+		window = DefaultDuplicateWindow
+	}
+
+	ok, err := d.Store.Add(ctx, "response:"+id, time.Now().Add(window))
+	if err != nil {
+		return false, fmt.Errorf("soap: %s", err)
+	}
+
+	if !ok && d.OnDuplicate != nil {
+		d.OnDuplicate(id)
+	}
+	return !ok, nil
+}
+
+// Middleware returns middleware failing calls with ErrDuplicate when the response is seen before
+// or relates to another message than MessageID of the request.
+func (d *DuplicateDetector) Middleware() Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			resp, err := next(ctx, r)
+			if err != nil || len(resp.Body) == 0 {
+				return resp, err
+			}
+
+			n, perr := ParseNode(trimProlog(resp.Body))
+			if perr != nil {
+				// the client reports invalid envelope
+				return resp, nil
+			}
+
+			if relatesTo := n.Value("Envelope/Header/RelatesTo"); relatesTo != "" {
+				if req, err := ParseNode(trimProlog(r.Envelope)); err == nil {
+					if id := req.Value("Envelope/Header/MessageID"); id != "" && id != relatesTo {
+						if d.OnDuplicate != nil {
+							d.OnDuplicate(relatesTo)
+						}
+						return nil, ErrDuplicate
+					}
+				}
+			}
+
+			dup, err := d.seen(ctx, n)
+			if err != nil {
+				return nil, err
+			}
+
+			if dup {
+				return nil, ErrDuplicate
+			}
+			return resp, nil
+		}
+	}
+}
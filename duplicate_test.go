@@ -0,0 +1,73 @@
+package soap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDuplicateDetector_Middleware(t *testing.T) {
+	t.Parallel()
+	relatesTo := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Header>` +
+			`<MessageID xmlns="http://www.w3.org/2005/08/addressing">urn:uuid:1</MessageID>` + relatesTo +
+			`</Header><Body><Response xmlns="test:call"/></Body></Envelope>`))
+	}))
+	defer srv.Close()
+
+	var dups []string
+	d := &DuplicateDetector{OnDuplicate: func(id string) { dups = append(dups, id) }}
+	client := MustNewClient(srv.URL, Config{Middleware: []Middleware{Addressing{MessageID: "urn:uuid:req"}.Middleware(), d.Middleware()}})
+	if err := client.Call(context.Background(), "", request{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Call(context.Background(), "", request{}, nil); err != ErrDuplicate {
+		t.Fatalf("got: %v, want: %s", err, ErrDuplicate)
+	}
+
+	// response of another request
+	relatesTo = `<RelatesTo xmlns="http://www.w3.org/2005/08/addressing">urn:uuid:other</RelatesTo>`
+	if err := client.Call(context.Background(), "", request{}, nil); err != ErrDuplicate {
+		t.Fatalf("got: %v, want: %s", err, ErrDuplicate)
+	}
+
+	if got, want := strings.Join(dups, " "), "urn:uuid:1 urn:uuid:other"; got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+}
+
+func TestCallback_Duplicates(t *testing.T) {
+	t.Parallel()
+	for i, v := range []struct {
+		suppress bool
+		want     int
+	}{
+		{suppress: true, want: http.StatusAccepted},
+		{want: http.StatusConflict},
+	} {
+		cb := NewCallback("")
+		cb.Duplicates = &DuplicateDetector{Suppress: v.suppress}
+		ch := cb.wait("urn:uuid:req")
+
+		body := `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Header>` +
+			`<RelatesTo xmlns="http://www.w3.org/2005/08/addressing">urn:uuid:req</RelatesTo></Header><Body/></Envelope>`
+		var codes []int
+		for j := 0; j < 2; j++ {
+			w := httptest.NewRecorder()
+			cb.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+			codes = append(codes, w.Code)
+		}
+
+		if codes[0] != http.StatusAccepted || codes[1] != v.want {
+			t.Errorf("#%d got: %v, want: [202 %d]", i, codes, v.want)
+		}
+
+		if len(ch) != 1 {
+			t.Errorf("#%d got: %d, want: response delivered once", i, len(ch))
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package soap
+
+import (
+	"bytes"
+)
+
+// EscapeFunc rewrites character escaping of the encoded envelope, it is applied after marshaling.
+type EscapeFunc func(envelope []byte) []byte
+
+var (
+	// EscapeNamed replaces numeric references of quotes emitted by encoding/xml with named entities.
+	EscapeNamed EscapeFunc = func(envelope []byte) []byte {
+		return rewriteRefs(envelope, map[string]string{"&#34;": "&quot;", "&#39;": "&apos;"}, map[string]string{"&#34;": "&quot;", "&#39;": "&apos;"})
+	}
+
+	// EscapeMinimal escapes only &, < and > in text and &, < and " in attributes,
+	// quotes, tabs and line breaks of text are written as is.
+	EscapeMinimal EscapeFunc = func(envelope []byte) []byte {
+		return rewriteRefs(envelope,
+			map[string]string{"&#34;": `"`, "&#39;": "'", "&#x9;": "\t", "&#xA;": "\n", "&#xD;": "\r"},
+			map[string]string{"&#34;": "&quot;", "&#39;": "'"})
+	}
+)
+
+// rewriteRefs replaces character references in text and attribute values,
+// CDATA sections, comments and processing instructions are kept.
+func rewriteRefs(data []byte, text, attr map[string]string) []byte {
+	var (
+		buf   bytes.Buffer
+		inTag bool
+	)
+	buf.Grow(len(data))
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '<' && !inTag:
+			if n := special(data[i:]); n > 0 {
+				buf.Write(data[i : i+n])
+				i += n
+				continue
+			}
+			inTag = true
+		case c == '>' && inTag:
+			inTag = false
+		case c == '&':
+			refs := text
+			if inTag {
+				refs = attr
+			}
+
+			if end := bytes.IndexByte(data[i:], ';'); end > 0 {
+				if v, ok := refs[string(data[i:i+end+1])]; ok {
+					buf.WriteString(v)
+					i += end + 1
+					continue
+				}
+			}
+		}
+		buf.WriteByte(c)
+		i++
+	}
+	return buf.Bytes()
+}
+
+// special returns length of CDATA section, comment or processing instruction at the start of data.
+func special(data []byte) int {
+	for _, v := range []struct{ open, close string }{{"<![CDATA[", "]]>"}, {"<!--", "-->"}, {"<?", "?>"}} {
+		if !bytes.HasPrefix(data, []byte(v.open)) {
+			continue
+		}
+
+		if end := bytes.Index(data, []byte(v.close)); end >= 0 {
+			return end + len(v.close)
+		}
+		return len(data)
+	}
+	return 0
+}
@@ -0,0 +1,73 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type escaped struct {
+	XMLName xml.Name `xml:"test:call Escaped"`
+	Attr    string   `xml:"attr,attr"`
+	Text    string   `xml:"Text"`
+	CDATA   CDATA    `xml:"CDATA"`
+}
+
+func TestEscapeFunc(t *testing.T) {
+	t.Parallel()
+	v := escaped{Attr: `a"b'c`, Text: "x \"y\" 'z'\tw\r\n", CDATA: "&#34;"}
+	b, err := xml.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tt := range []struct {
+		escape EscapeFunc
+		want   string
+	}{
+		{
+			escape: EscapeNamed,
+			want:   `<Escaped xmlns="test:call" attr="a&quot;b&apos;c"><Text>x &quot;y&quot; &apos;z&apos;&#x9;w&#xD;&#xA;</Text><CDATA><![CDATA[&#34;]]></CDATA></Escaped>`,
+		},
+		{
+			escape: EscapeMinimal,
+			want:   "<Escaped xmlns=\"test:call\" attr=\"a&quot;b'c\"><Text>x \"y\" 'z'\tw\r\n</Text><CDATA><![CDATA[&#34;]]></CDATA></Escaped>",
+		},
+	} {
+		if got := string(tt.escape(b)); got != tt.want {
+			t.Errorf("#%d got: %s, want: %s", i, got, tt.want)
+		}
+
+		var got escaped
+		if err := xml.Unmarshal(tt.escape(b), &got); err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Attr != v.Attr || got.CDATA != v.CDATA {
+			t.Errorf("#%d got: %+v, want: %+v", i, got, v)
+		}
+	}
+}
+
+func TestClient_Escape(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(b), "&#") {
+			t.Errorf("got: %s, want: no character references", b)
+		}
+
+		b, _ = xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{Escape: EscapeMinimal})
+	if err := client.Call(context.Background(), "get", request{Attr1: `"quoted"`}, &response{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,113 @@
+package exi
+
+import "unicode/utf8"
+
+// bitWriter implements bit-packed stream, bits are written from the most significant one.
+type bitWriter struct {
+	buf []byte
+	n   uint
+}
+
+func (w *bitWriter) bits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		if v>>uint(i)&1 == 1 {
+			w.buf[len(w.buf)-1] |= 0x80 >> (w.n % 8)
+		}
+		w.n++
+	}
+}
+
+// uint writes unsigned integer as 7-bit groups, the least significant group first.
+func (w *bitWriter) uint(v uint64) {
+	for {
+		b := v & 0x7F
+		v >>= 7
+		if v != 0 {
+			b |= 0x80
+		}
+		w.bits(b, 8)
+		if v == 0 {
+			return
+		}
+	}
+}
+
+// chars writes code points of the string.
+func (w *bitWriter) chars(s string) {
+	for _, r := range s {
+		w.uint(uint64(r))
+	}
+}
+
+// string writes length followed by the code points.
+func (w *bitWriter) string(s string) {
+	w.uint(uint64(utf8.RuneCountInString(s)))
+	w.chars(s)
+}
+
+type bitReader struct {
+	data []byte
+	n    uint
+}
+
+func (r *bitReader) bits(n int) (uint64, error) {
+	if uint(n) > uint(len(r.data))*8-r.n {
+		return 0, errTruncated
+	}
+
+	var v uint64
+	for i := 0; i < n; i++ {
+		v = v<<1 | uint64(r.data[r.n/8]>>(7-r.n%8)&1)
+		r.n++
+	}
+	return v, nil
+}
+
+func (r *bitReader) uint() (uint64, error) {
+	var v uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		b, err := r.bits(8)
+		if err != nil {
+			return 0, err
+		}
+
+		v |= (b & 0x7F) << shift
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, errOverflow
+}
+
+// chars reads n code points.
+func (r *bitReader) chars(n uint64) (string, error) {
+	if n > uint64(len(r.data)) {
+		// each code point takes at least one octet
+		return "", errTruncated
+	}
+
+	s := make([]rune, 0, n)
+	for i := uint64(0); i < n; i++ {
+		c, err := r.uint()
+		if err != nil {
+			return "", err
+		}
+
+		if c > utf8.MaxRune {
+			return "", errCodePoint
+		}
+		s = append(s, rune(c))
+	}
+	return string(s), nil
+}
+
+func (r *bitReader) string() (string, error) {
+	n, err := r.uint()
+	if err != nil {
+		return "", err
+	}
+	return r.chars(n)
+}
@@ -0,0 +1,334 @@
+package exi
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+)
+
+type decoder struct {
+	r   bitReader
+	t   *tables
+	out limitBuffer
+
+	frames []*frame
+	// prefixes are generated for namespaces, declared holds counts of in-scope declarations
+	prefixes map[string]string
+	declared map[string]int
+	next     int
+	// tag is start tag written when the first child event is decoded
+	tag *startTag
+}
+
+type startTag struct {
+	name  xml.Name
+	attrs []xml.Attr
+	// uris are namespaces declared by the element
+	uris []string
+}
+
+// Decode decodes EXI stream to xml document limited to DefaultMaxSize.
+func Decode(data []byte) ([]byte, error) {
+	return DecodeLimit(data, DefaultMaxSize)
+}
+
+// DecodeLimit decodes EXI stream to xml document, *soap.LimitError is returned
+// when the document exceeds max bytes, zero or negative max disables the limit.
+func DecodeLimit(data []byte, max int) ([]byte, error) {
+	data = bytes.TrimPrefix(data, []byte("$EXI"))
+	d := &decoder{
+		r:        bitReader{data: data},
+		t:        newTables(),
+		prefixes: map[string]string{"": "", xmlNS: "xml", xsiNS: "xsi"},
+		declared: map[string]int{"": 1, xmlNS: 1},
+	}
+
+	h, err := d.r.bits(8)
+	if err != nil {
+		return nil, err
+	}
+	if h != header {
+		return nil, fmt.Errorf("exi: header 0x%02x is not supported, options and versions other than 1 are not supported", h)
+	}
+
+	// DocContent has only SE(*) production
+	name, err := d.qname()
+	if err != nil {
+		return nil, err
+	}
+	d.out.max = max
+	d.start(name)
+
+	for len(d.frames) > 0 {
+		if err := d.event(d.frames[len(d.frames)-1]); err != nil {
+			return nil, err
+		}
+
+		// value hits may exceed the limit
+		if d.out.err != nil {
+			return nil, d.out.err
+		}
+	}
+	return d.out.Bytes(), nil
+}
+
+// event decodes the next event of the element.
+func (d *decoder) event(f *frame) error {
+	g := f.g
+	var (
+		p   production
+		err error
+	)
+	if !f.content {
+		p, err = d.code(g.start, 2, []event{endElement, attribute, startElement, characters})
+		if err != nil {
+			return err
+		}
+
+		if p.name == (xml.Name{}) && (p.event == attribute || p.event == startElement) {
+			if p.name, err = d.qname(); err != nil {
+				return err
+			}
+		}
+
+		if find(g.start, p.event, p.name) < 0 {
+			g.start = learn(g.start, p)
+		}
+	} else {
+		p, err = d.code(g.content, 1, []event{startElement, characters})
+		if err != nil {
+			return err
+		}
+
+		if p.name == (xml.Name{}) && p.event == startElement {
+			if p.name, err = d.qname(); err != nil {
+				return err
+			}
+		}
+
+		if find(g.content, p.event, p.name) < 0 {
+			g.content = learn(g.content, p)
+		}
+	}
+
+	switch p.event {
+	case attribute:
+		return d.attribute(p.name)
+	case startElement:
+		f.content = true
+		d.writeTag()
+		d.start(p.name)
+	case characters:
+		f.content = true
+		d.writeTag()
+		s, err := d.value(f.name)
+		if err != nil {
+			return err
+		}
+		xml.EscapeText(&d.out, []byte(s))
+	case endElement:
+		d.writeTag()
+		d.end()
+	}
+	return nil
+}
+
+// code reads event code, the generic production of the second level is returned with empty name.
+func (d *decoder) code(list []production, n int, generic []event) (production, error) {
+	v, err := d.r.bits(width(len(list) + 1))
+	if err != nil {
+		return production{}, err
+	}
+
+	if int(v) < len(list) {
+		return list[v], nil
+	}
+
+	if int(v) > len(list) {
+		return production{}, fmt.Errorf("exi: event code %d is invalid", v)
+	}
+
+	v, err = d.r.bits(n)
+	if err != nil {
+		return production{}, err
+	}
+
+	if int(v) >= len(generic) {
+		return production{}, fmt.Errorf("exi: event code %d.%d is not supported", len(list), v)
+	}
+	return production{event: generic[v]}, nil
+}
+
+func (d *decoder) start(name xml.Name) {
+	d.tag = &startTag{name: name}
+	d.declare(d.tag, name.Space)
+	d.frames = append(d.frames, &frame{g: d.t.grammar(name), name: name})
+}
+
+func (d *decoder) end() {
+	f := d.frames[len(d.frames)-1]
+	d.frames = d.frames[:len(d.frames)-1]
+	d.out.WriteString("</" + d.raw(f.name) + ">")
+	for _, uri := range f.scope {
+		d.declared[uri]--
+	}
+}
+
+func (d *decoder) attribute(name xml.Name) error {
+	d.declare(d.tag, name.Space)
+	if name == xsiType {
+		v, err := d.qname()
+		if err != nil {
+			return err
+		}
+
+		d.declare(d.tag, v.Space)
+		d.tag.attrs = append(d.tag.attrs, xml.Attr{Name: name, Value: d.raw(v)})
+		return nil
+	}
+
+	s, err := d.value(name)
+	if err != nil {
+		return err
+	}
+	d.tag.attrs = append(d.tag.attrs, xml.Attr{Name: name, Value: s})
+	return nil
+}
+
+// declare adds namespace declaration to the start tag unless the namespace is in scope.
+func (d *decoder) declare(tag *startTag, uri string) {
+	if d.declared[uri] > 0 {
+		return
+	}
+
+	if _, ok := d.prefixes[uri]; !ok {
+		d.prefixes[uri] = "ns" + strconv.Itoa(d.next)
+		d.next++
+	}
+	d.declared[uri]++
+	tag.uris = append(tag.uris, uri)
+}
+
+// writeTag writes the pending start tag.
+func (d *decoder) writeTag() {
+	tag := d.tag
+	if tag == nil {
+		return
+	}
+	d.tag = nil
+
+	d.out.WriteString("<" + d.raw(tag.name))
+	for _, uri := range tag.uris {
+		d.out.WriteString(" xmlns:" + d.prefixes[uri] + `="`)
+		xml.EscapeText(&d.out, []byte(uri))
+		d.out.WriteByte('"')
+	}
+
+	for _, a := range tag.attrs {
+		d.out.WriteString(" " + d.raw(a.Name) + `="`)
+		xml.EscapeText(&d.out, []byte(a.Value))
+		d.out.WriteByte('"')
+	}
+	d.out.WriteByte('>')
+
+	// scope is released by the end of the element
+	d.frames[len(d.frames)-1].scope = make(map[string]string, len(tag.uris))
+	for _, uri := range tag.uris {
+		d.frames[len(d.frames)-1].scope[d.prefixes[uri]] = uri
+	}
+}
+
+func (d *decoder) raw(n xml.Name) string {
+	if p := d.prefixes[n.Space]; p != "" {
+		return p + ":" + n.Local
+	}
+	return n.Local
+}
+
+// qname reads uri and local name using the string table.
+func (d *decoder) qname() (xml.Name, error) {
+	t := d.t
+	i, err := d.r.bits(width(len(t.uris) + 1))
+	if err != nil {
+		return xml.Name{}, err
+	}
+
+	var name xml.Name
+	switch {
+	case i == 0:
+		if name.Space, err = d.r.string(); err != nil {
+			return name, err
+		}
+		t.uris = append(t.uris, name.Space)
+	case int(i) <= len(t.uris):
+		name.Space = t.uris[i-1]
+	default:
+		return name, fmt.Errorf("exi: uri %d is not defined", i)
+	}
+
+	n, err := d.r.uint()
+	if err != nil {
+		return name, err
+	}
+
+	locals := t.locals[name.Space]
+	if n > 0 {
+		if name.Local, err = d.r.chars(n - 1); err != nil {
+			return name, err
+		}
+		t.locals[name.Space] = append(locals, name.Local)
+		return name, nil
+	}
+
+	j, err := d.r.bits(width(len(locals)))
+	if err != nil {
+		return name, err
+	}
+
+	if int(j) >= len(locals) {
+		return name, fmt.Errorf("exi: local name %d is not defined", j)
+	}
+	name.Local = locals[j]
+	return name, nil
+}
+
+// value reads string value of the element or attribute using local and global value partitions.
+func (d *decoder) value(name xml.Name) (string, error) {
+	t := d.t
+	n, err := d.r.uint()
+	if err != nil {
+		return "", err
+	}
+
+	switch n {
+	case 0:
+		local := t.values[name]
+		i, err := d.r.bits(width(len(local)))
+		if err != nil {
+			return "", err
+		}
+
+		if int(i) >= len(local) {
+			return "", fmt.Errorf("exi: local value %d is not defined", i)
+		}
+		return local[i], nil
+	case 1:
+		i, err := d.r.bits(width(len(t.global)))
+		if err != nil {
+			return "", err
+		}
+
+		if int(i) >= len(t.global) {
+			return "", fmt.Errorf("exi: global value %d is not defined", i)
+		}
+		return t.global[i], nil
+	}
+
+	s, err := d.r.chars(n - 2)
+	if err != nil {
+		return "", err
+	}
+	t.addValue(name, s)
+	return s, nil
+}
@@ -0,0 +1,230 @@
+package exi
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// header is distinguishing bits, no options and final version 1.
+const header = 0x80
+
+// second are event codes of the second level of StartTagContent grammar.
+var second = map[event]uint64{endElement: 0, attribute: 1, startElement: 2, characters: 3}
+
+type frame struct {
+	g       *grammar
+	name    xml.Name
+	content bool
+	// scope is prefix bindings declared by the element
+	scope map[string]string
+}
+
+type encoder struct {
+	w      bitWriter
+	t      *tables
+	frames []*frame
+	text   strings.Builder
+}
+
+// Encode encodes xml document to EXI stream.
+func Encode(data []byte) ([]byte, error) {
+	e := &encoder{t: newTables()}
+	e.w.bits(header, 8)
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	root := false
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("exi: %s", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if len(e.frames) == 0 {
+				if root {
+					return nil, fmt.Errorf("exi: document has several root elements")
+				}
+				root = true
+			}
+
+			if err := e.start(t); err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			e.flush()
+			f := e.frames[len(e.frames)-1]
+			e.code(f, endElement, xml.Name{})
+			e.frames = e.frames[:len(e.frames)-1]
+		case xml.CharData:
+			if len(e.frames) > 0 {
+				e.text.Write(t)
+			}
+		}
+	}
+
+	if !root {
+		return nil, fmt.Errorf("exi: document has no root element")
+	}
+	// DocEnd has only ED production
+	return e.w.buf, nil
+}
+
+func (e *encoder) start(t xml.StartElement) error {
+	e.flush()
+
+	scope := make(map[string]string)
+	var attrs []xml.Attr
+	for _, a := range t.Attr {
+		switch {
+		case a.Name.Space == "xmlns":
+			scope[a.Name.Local] = a.Value
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			scope[""] = a.Value
+		default:
+			attrs = append(attrs, a)
+		}
+	}
+
+	if len(e.frames) > 0 {
+		e.code(e.frames[len(e.frames)-1], startElement, t.Name)
+	} else {
+		// DocContent has only SE(*) production
+		e.qname(t.Name)
+	}
+
+	f := &frame{g: e.t.grammar(t.Name), name: t.Name, scope: scope}
+	e.frames = append(e.frames, f)
+	for _, a := range attrs {
+		e.code(f, attribute, a.Name)
+		if a.Name == xsiType {
+			name, err := e.resolve(a.Value)
+			if err != nil {
+				return err
+			}
+			e.qname(name)
+			continue
+		}
+		e.value(a.Name, a.Value)
+	}
+	return nil
+}
+
+// flush writes buffered character data of the current element.
+func (e *encoder) flush() {
+	if e.text.Len() == 0 {
+		return
+	}
+
+	f := e.frames[len(e.frames)-1]
+	e.code(f, characters, xml.Name{})
+	e.value(f.name, e.text.String())
+	e.text.Reset()
+}
+
+// code writes event code of the production and learns it when matched by the generic production,
+// qualified name of generic SE and AT is written as well.
+func (e *encoder) code(f *frame, ev event, name xml.Name) {
+	g := f.g
+	if !f.content {
+		if i := find(g.start, ev, name); i >= 0 {
+			e.w.bits(uint64(i), width(len(g.start)+1))
+		} else {
+			e.w.bits(uint64(len(g.start)), width(len(g.start)+1))
+			e.w.bits(second[ev], 2)
+			if ev == startElement || ev == attribute {
+				e.qname(name)
+			}
+			g.start = learn(g.start, production{event: ev, name: name})
+		}
+	} else {
+		if i := find(g.content, ev, name); i >= 0 {
+			e.w.bits(uint64(i), width(len(g.content)+1))
+		} else {
+			e.w.bits(uint64(len(g.content)), width(len(g.content)+1))
+			if ev == characters {
+				e.w.bits(1, 1)
+			} else {
+				e.w.bits(0, 1)
+				e.qname(name)
+			}
+			g.content = learn(g.content, production{event: ev, name: name})
+		}
+	}
+
+	if ev == startElement || ev == characters {
+		f.content = true
+	}
+}
+
+// qname writes uri and local name using the string table.
+func (e *encoder) qname(n xml.Name) {
+	t := e.t
+	if i := index(t.uris, n.Space); i >= 0 {
+		e.w.bits(uint64(i+1), width(len(t.uris)+1))
+	} else {
+		e.w.bits(0, width(len(t.uris)+1))
+		e.w.string(n.Space)
+		t.uris = append(t.uris, n.Space)
+	}
+
+	locals := t.locals[n.Space]
+	if i := index(locals, n.Local); i >= 0 {
+		e.w.uint(0)
+		e.w.bits(uint64(i), width(len(locals)))
+		return
+	}
+
+	e.w.uint(uint64(len([]rune(n.Local))) + 1)
+	e.w.chars(n.Local)
+	t.locals[n.Space] = append(locals, n.Local)
+}
+
+// value writes string value of the element or attribute using local and global value partitions.
+func (e *encoder) value(name xml.Name, s string) {
+	t := e.t
+	if i, ok := t.valueIndex[name][s]; ok {
+		e.w.uint(0)
+		e.w.bits(uint64(i), width(len(t.values[name])))
+		return
+	}
+
+	if i, ok := t.globalIndex[s]; ok {
+		e.w.uint(1)
+		e.w.bits(uint64(i), width(len(t.global)))
+		return
+	}
+
+	e.w.uint(uint64(len([]rune(s))) + 2)
+	e.w.chars(s)
+	t.addValue(name, s)
+}
+
+// resolve returns qualified name of xsi:type value.
+func (e *encoder) resolve(v string) (xml.Name, error) {
+	prefix, local := "", strings.TrimSpace(v)
+	if i := strings.IndexByte(local, ':'); i >= 0 {
+		prefix, local = local[:i], local[i+1:]
+	}
+
+	if prefix == "xml" {
+		return xml.Name{Space: xmlNS, Local: local}, nil
+	}
+
+	for i := len(e.frames) - 1; i >= 0; i-- {
+		if ns, ok := e.frames[i].scope[prefix]; ok {
+			return xml.Name{Space: ns, Local: local}, nil
+		}
+	}
+
+	if prefix != "" {
+		return xml.Name{}, fmt.Errorf("exi: prefix %s of xsi:type is not declared", prefix)
+	}
+	return xml.Name{Local: local}, nil
+}
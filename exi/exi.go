@@ -0,0 +1,209 @@
+// Package exi implements experimental Efficient XML Interchange (EXI 1.0) codec of soap
+// envelopes for bandwidth-constrained devices, see soap.CodecNegotiation.
+//
+// Only schema-less streams with default options are supported: bit-packed alignment,
+// built-in grammars, no options in the header. Prefixes, comments and processing instructions
+// are not preserved, the decoder generates prefixes of the namespaces.
+package exi
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math/bits"
+
+	"github.com/itcomusic/soap"
+)
+
+// ContentType is media type of EXI documents.
+const ContentType = "application/exi"
+
+const (
+	xmlNS = "http://www.w3.org/XML/1998/namespace"
+	xsiNS = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+var (
+	errTruncated = fmt.Errorf("exi: stream is truncated")
+	errOverflow  = fmt.Errorf("exi: unsigned integer overflows")
+	errCodePoint = fmt.Errorf("exi: code point is invalid")
+	xsiType      = xml.Name{Space: xsiNS, Local: "type"}
+)
+
+// DefaultMaxSize is size limit of the decoded document, values of the string tables
+// are repeated by hits of a few bits.
+const DefaultMaxSize = 64 << 20
+
+// Codec implements soap.Codec.
+type Codec struct {
+	// MaxSize limits size of the decoded document, DefaultMaxSize when zero, negative disables the limit.
+	MaxSize int
+}
+
+// ContentType returns media type of the encoded envelope.
+func (Codec) ContentType() string {
+	return ContentType
+}
+
+// Encode encodes xml envelope to EXI stream.
+func (Codec) Encode(envelope []byte) ([]byte, error) {
+	return Encode(envelope)
+}
+
+// Decode decodes EXI stream to xml envelope.
+func (c Codec) Decode(data []byte) ([]byte, error) {
+	max := c.MaxSize
+	if max == 0 {
+		max = DefaultMaxSize
+	}
+	return DecodeLimit(data, max)
+}
+
+// DecodeLimit decodes EXI stream to xml envelope, *soap.LimitError is returned
+// when the document exceeds max bytes, zero or negative max disables the limit.
+func (Codec) DecodeLimit(data []byte, max int) ([]byte, error) {
+	return DecodeLimit(data, max)
+}
+
+// limitBuffer implements buffer of the decoded document, writes beyond max bytes are dropped
+// and the error is kept.
+type limitBuffer struct {
+	bytes.Buffer
+	max int
+	err error
+}
+
+func (b *limitBuffer) grow(n int) bool {
+	if b.err == nil && b.max > 0 && b.Len()+n > b.max {
+		b.err = &soap.LimitError{Limit: "decoded exi size", Max: b.max}
+	}
+	return b.err == nil
+}
+
+func (b *limitBuffer) Write(p []byte) (int, error) {
+	if !b.grow(len(p)) {
+		return 0, b.err
+	}
+	return b.Buffer.Write(p)
+}
+
+func (b *limitBuffer) WriteString(s string) (int, error) {
+	if !b.grow(len(s)) {
+		return 0, b.err
+	}
+	return b.Buffer.WriteString(s)
+}
+
+func (b *limitBuffer) WriteByte(c byte) error {
+	if !b.grow(1) {
+		return b.err
+	}
+	return b.Buffer.WriteByte(c)
+}
+
+// event implements production of the built-in element grammar.
+type event int
+
+const (
+	endElement event = iota
+	attribute
+	startElement
+	characters
+)
+
+type production struct {
+	event event
+	name  xml.Name
+}
+
+// grammar implements built-in element grammar of the qualified name, learned productions
+// are prepended so the most recent one has event code 0.
+type grammar struct {
+	// start is StartTagContent, content is ElementContent which initially has EE
+	start   []production
+	content []production
+}
+
+func newGrammar() *grammar {
+	return &grammar{content: []production{{event: endElement}}}
+}
+
+func learn(list []production, p production) []production {
+	return append([]production{p}, list...)
+}
+
+func find(list []production, e event, name xml.Name) int {
+	for i, p := range list {
+		if p.event == e && p.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// tables implements string table partitions and element grammars shared by encoder and decoder.
+type tables struct {
+	uris   []string
+	locals map[string][]string
+	// global and local value partitions, indexes are used by the encoder
+	global      []string
+	values      map[xml.Name][]string
+	globalIndex map[string]int
+	valueIndex  map[xml.Name]map[string]int
+	grammars    map[xml.Name]*grammar
+}
+
+func newTables() *tables {
+	return &tables{
+		uris: []string{"", xmlNS, xsiNS},
+		locals: map[string][]string{
+			xmlNS: {"base", "id", "lang", "space"},
+			xsiNS: {"nil", "type"},
+		},
+		values:      make(map[xml.Name][]string),
+		globalIndex: make(map[string]int),
+		valueIndex:  make(map[xml.Name]map[string]int),
+		grammars:    make(map[xml.Name]*grammar),
+	}
+}
+
+// addValue adds value of the element or attribute to the global and local partitions.
+func (t *tables) addValue(name xml.Name, s string) {
+	if s == "" {
+		return
+	}
+
+	if t.valueIndex[name] == nil {
+		t.valueIndex[name] = make(map[string]int)
+	}
+	t.globalIndex[s] = len(t.global)
+	t.valueIndex[name][s] = len(t.values[name])
+	t.global = append(t.global, s)
+	t.values[name] = append(t.values[name], s)
+}
+
+func (t *tables) grammar(name xml.Name) *grammar {
+	g, ok := t.grammars[name]
+	if !ok {
+		g = newGrammar()
+		t.grammars[name] = g
+	}
+	return g
+}
+
+// width returns number of bits of n distinct values.
+func width(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	return bits.Len(uint(n - 1))
+}
+
+func index(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,100 @@
+package exi
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/itcomusic/soap"
+)
+
+func TestEncode(t *testing.T) {
+	t.Parallel()
+	// header, SE(*) with uri "" and local name "a", CH 0.3 with value "x", EE 0
+	want := "804098703780"
+	data, err := Encode([]byte(`<a>x</a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := hex.EncodeToString(data); got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != `<a>x</a>` {
+		t.Fatalf("got: %s, want: %s", got, `<a>x</a>`)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+	envelope := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body>` +
+		`<m:GetUsers xmlns:m="urn:users" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<m:User xsi:type="m:Admin" id="1">alice &amp; bob</m:User><m:User xsi:type="m:Admin" id="2" empty="">alice &amp; bob</m:User>` +
+		`<m:User id="1">Ωmega</m:User><!-- dropped --><m:Note>` + strings.Repeat("x", 300) + `</m:Note></m:GetUsers></soapenv:Body></soapenv:Envelope>`
+	want := `<ns0:Envelope xmlns:ns0="http://schemas.xmlsoap.org/soap/envelope/"><ns0:Body>` +
+		`<ns1:GetUsers xmlns:ns1="urn:users">` +
+		`<ns1:User xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="ns1:Admin" id="1">alice &amp; bob</ns1:User>` +
+		`<ns1:User xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="ns1:Admin" id="2" empty="">alice &amp; bob</ns1:User>` +
+		`<ns1:User id="1">Ωmega</ns1:User><ns1:Note>` + strings.Repeat("x", 300) + `</ns1:Note></ns1:GetUsers></ns0:Body></ns0:Envelope>`
+
+	data, err := Encode([]byte(envelope))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) >= len(envelope) {
+		t.Fatalf("got: %d bytes, want less than %d", len(data), len(envelope))
+	}
+
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+
+	if _, err := Decode(data[:len(data)/2]); err != errTruncated {
+		t.Fatalf("got: %v, want: %s", err, errTruncated)
+	}
+}
+
+func TestDecodeLimit(t *testing.T) {
+	t.Parallel()
+	// the value is sent once and repeated by global value hits
+	value := "<b>" + strings.Repeat("x", 10000) + "</b>"
+	data, err := Encode([]byte("<a>" + strings.Repeat(value, 200) + "</a>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) > 20000 {
+		t.Fatalf("got: %d, want: value hits", len(data))
+	}
+
+	got, err := DecodeLimit(data, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := 200*len(value) + len("<a></a>"); len(got) != want {
+		t.Fatalf("got: %d, want: %d", len(got), want)
+	}
+
+	for _, decode := range []func([]byte) ([]byte, error){Codec{MaxSize: 1 << 20}.Decode, func(data []byte) ([]byte, error) {
+		return DecodeLimit(data, 100000)
+	}} {
+		if _, err := decode(data); err == nil {
+			t.Fatal("got: nil, want: limit error")
+		} else if _, ok := err.(*soap.LimitError); !ok {
+			t.Fatalf("got: %v, want: LimitError", err)
+		}
+	}
+}
@@ -0,0 +1,767 @@
+package fastinfoset
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Built-in restricted alphabets, each character is encoded in four bits.
+var alphabets = map[int]string{
+	1: "0123456789-+.E ",
+	2: "0123456789-:TZ ",
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+	out  limitBuffer
+
+	prefixes     []string
+	namespaces   []string
+	locals       []string
+	otherNCNames []string
+	values       []string
+	chunks       []string
+	others       []string
+	elements     []qname
+	attributes   []qname
+	// open are names of the elements which children are decoded
+	open []qname
+}
+
+// Decode decodes Fast Infoset document to xml document limited to DefaultMaxSize.
+func Decode(data []byte) ([]byte, error) {
+	return DecodeLimit(data, DefaultMaxSize)
+}
+
+// DecodeLimit decodes Fast Infoset document to xml document, *soap.LimitError is returned
+// when the document exceeds max bytes, zero or negative max disables the limit.
+func DecodeLimit(data []byte, max int) ([]byte, error) {
+	d := &decoder{
+		data:       skipDeclaration(data),
+		prefixes:   []string{"xml"},
+		namespaces: []string{xmlNS},
+	}
+	d.out.max = max
+	err := d.document()
+	if d.out.err != nil {
+		return nil, d.out.err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.out.Bytes(), nil
+}
+
+// skipDeclaration skips optional xml declaration preceding the document, e.g. encoding='finf'.
+func skipDeclaration(data []byte) []byte {
+	if bytes.HasPrefix(data, []byte("<?xml")) {
+		if i := bytes.Index(data, []byte("?>")); i >= 0 {
+			return data[i+2:]
+		}
+	}
+	return data
+}
+
+func (d *decoder) document() error {
+	id, err := d.read(len(header))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(id, header) {
+		return fmt.Errorf("fastinfoset: document is not fast infoset")
+	}
+
+	if err := d.components(); err != nil {
+		return err
+	}
+
+	for {
+		// repeated references may exceed the limit
+		if d.out.err != nil {
+			return d.out.err
+		}
+
+		b, err := d.byte()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case b < 0x80:
+			if err := d.element(b); err != nil {
+				return err
+			}
+		case b == piItem:
+			if err := d.pi(); err != nil {
+				return err
+			}
+		case b == commentItem:
+			if err := d.comment(); err != nil {
+				return err
+			}
+		case b == terminator || b == doubleTerminator:
+			count := 1
+			if b == doubleTerminator {
+				count = 2
+			}
+
+			for ; count > 0 && len(d.open) > 0; count-- {
+				d.end()
+			}
+
+			// the remaining terminator ends the document
+			if count > 0 {
+				if d.pos != len(d.data) {
+					return fmt.Errorf("fastinfoset: data after the end of document")
+				}
+				return nil
+			}
+		case b&0xC0 == 0x80:
+			if len(d.open) == 0 {
+				return fmt.Errorf("fastinfoset: character data outside of the document element")
+			}
+			if err := d.chunk(b); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("fastinfoset: item 0x%02x is not supported", b)
+		}
+	}
+}
+
+// components skips optional components of the document header.
+func (d *decoder) components() error {
+	b, err := d.byte()
+	if err != nil {
+		return err
+	}
+
+	if b&0xF8 != 0 {
+		return fmt.Errorf("fastinfoset: additional data, vocabularies, notations and unparsed entities are not supported")
+	}
+
+	if b&0x04 != 0 {
+		// character encoding scheme
+		c, err := d.byte()
+		if err != nil {
+			return err
+		}
+		if _, err := d.octets2(c); err != nil {
+			return err
+		}
+	}
+
+	if b&0x02 != 0 {
+		// standalone
+		if _, err := d.byte(); err != nil {
+			return err
+		}
+	}
+
+	if b&0x01 != 0 {
+		// version
+		if _, err := d.nonIdentifying(&d.others); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) element(b byte) error {
+	attrs := b&0x40 != 0
+	var decls []xml.Attr
+	if b&0x3F == 0x38 {
+		for {
+			c, err := d.byte()
+			if err != nil {
+				return err
+			}
+
+			if c == terminator {
+				break
+			}
+
+			if c&0xFC != 0xCC {
+				return fmt.Errorf("fastinfoset: namespace attribute 0x%02x is invalid", c)
+			}
+
+			var prefix, ns string
+			if c&0x02 != 0 {
+				if prefix, err = d.identifying(&d.prefixes); err != nil {
+					return err
+				}
+			}
+			if c&0x01 != 0 {
+				if ns, err = d.identifying(&d.namespaces); err != nil {
+					return err
+				}
+			}
+
+			name := xml.Name{Local: "xmlns"}
+			if prefix != "" {
+				name = xml.Name{Space: "xmlns", Local: prefix}
+			}
+			decls = append(decls, xml.Attr{Name: name, Value: ns})
+		}
+
+		var err error
+		if b, err = d.byte(); err != nil {
+			return err
+		}
+	}
+
+	name, err := d.elementName(b)
+	if err != nil {
+		return err
+	}
+
+	d.out.WriteString("<" + rawName(name))
+	for _, a := range decls {
+		d.attr(rawName(qname{prefix: a.Name.Space, local: a.Name.Local}), a.Value)
+	}
+	d.open = append(d.open, name)
+
+	if !attrs {
+		d.out.WriteByte('>')
+		return nil
+	}
+
+	for {
+		c, err := d.byte()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case c == terminator:
+			d.out.WriteByte('>')
+			return nil
+		case c == doubleTerminator:
+			// the element has no children
+			d.out.WriteByte('>')
+			d.end()
+			return nil
+		case c&0x80 != 0:
+			return fmt.Errorf("fastinfoset: attribute 0x%02x is invalid", c)
+		}
+
+		name, err := d.attributeName(c)
+		if err != nil {
+			return err
+		}
+
+		value, err := d.nonIdentifying(&d.values)
+		if err != nil {
+			return err
+		}
+		d.attr(rawName(name), value)
+	}
+}
+
+func (d *decoder) end() {
+	name := d.open[len(d.open)-1]
+	d.open = d.open[:len(d.open)-1]
+	d.out.WriteString("</" + rawName(name) + ">")
+}
+
+func (d *decoder) attr(name, value string) {
+	d.out.WriteString(" " + name + `="`)
+	xml.EscapeText(&d.out, []byte(value))
+	d.out.WriteByte('"')
+}
+
+// elementName reads qualified name of the element starting on the third bit.
+func (d *decoder) elementName(b byte) (qname, error) {
+	if b&0x3C == 0x3C {
+		name, err := d.literalName(b)
+		if err != nil {
+			return qname{}, err
+		}
+		return name, appendName(&d.elements, name)
+	}
+
+	i, err := d.index3(b)
+	if err != nil {
+		return qname{}, err
+	}
+
+	if i > len(d.elements) {
+		return qname{}, fmt.Errorf("fastinfoset: element name %d is not defined", i)
+	}
+	return d.elements[i-1], nil
+}
+
+// attributeName reads qualified name of the attribute starting on the second bit.
+func (d *decoder) attributeName(b byte) (qname, error) {
+	if b&0xFC == 0x78 {
+		name, err := d.literalName(b)
+		if err != nil {
+			return qname{}, err
+		}
+		return name, appendName(&d.attributes, name)
+	}
+
+	i, err := d.index2(b)
+	if err != nil {
+		return qname{}, err
+	}
+
+	if i > len(d.attributes) {
+		return qname{}, fmt.Errorf("fastinfoset: attribute name %d is not defined", i)
+	}
+	return d.attributes[i-1], nil
+}
+
+// literalName reads literal qualified name, the last two bits of b tell presence of prefix and namespace.
+func (d *decoder) literalName(b byte) (qname, error) {
+	var (
+		name qname
+		err  error
+	)
+	if b&0x02 != 0 {
+		if name.prefix, err = d.identifying(&d.prefixes); err != nil {
+			return name, err
+		}
+	}
+
+	if b&0x01 != 0 {
+		if name.space, err = d.identifying(&d.namespaces); err != nil {
+			return name, err
+		}
+	}
+
+	name.local, err = d.identifying(&d.locals)
+	return name, err
+}
+
+func (d *decoder) pi() error {
+	target, err := d.identifying(&d.otherNCNames)
+	if err != nil {
+		return err
+	}
+
+	content, err := d.nonIdentifying(&d.others)
+	if err != nil {
+		return err
+	}
+
+	d.out.WriteString("<?" + target)
+	if content != "" {
+		d.out.WriteString(" " + content)
+	}
+	d.out.WriteString("?>")
+	return nil
+}
+
+func (d *decoder) comment() error {
+	s, err := d.nonIdentifying(&d.others)
+	if err != nil {
+		return err
+	}
+
+	d.out.WriteString("<!--" + s + "-->")
+	return nil
+}
+
+// chunk reads character chunk starting on the third bit.
+func (d *decoder) chunk(b byte) error {
+	var s string
+	if b&0x20 != 0 {
+		i, err := d.index4(b)
+		if err != nil {
+			return err
+		}
+
+		if i > len(d.chunks) {
+			return fmt.Errorf("fastinfoset: character chunk %d is not defined", i)
+		}
+		s = d.chunks[i-1]
+	} else {
+		var (
+			id  int
+			c   = b
+			err error
+		)
+		format := b >> 2 & 0x03
+		if format >= 2 {
+			if c, err = d.byte(); err != nil {
+				return err
+			}
+			id = int(b&0x03)<<6 | int(c>>2) + 1
+		}
+
+		data, err := d.octets7(c)
+		if err != nil {
+			return err
+		}
+
+		if s, err = decodeString(format, id, data); err != nil {
+			return err
+		}
+
+		if b&0x10 != 0 {
+			d.chunks = append(d.chunks, s)
+		}
+	}
+
+	xml.EscapeText(&d.out, []byte(s))
+	return nil
+}
+
+// identifying reads identifying string or index starting on the first bit, literal is added to the table.
+func (d *decoder) identifying(table *[]string) (string, error) {
+	b, err := d.byte()
+	if err != nil {
+		return "", err
+	}
+
+	if b&0x80 == 0 {
+		data, err := d.octets2(b)
+		if err != nil {
+			return "", err
+		}
+
+		s := string(data)
+		return s, appendString(table, s)
+	}
+
+	i, err := d.index2(b)
+	if err != nil {
+		return "", err
+	}
+
+	if i > len(*table) {
+		return "", fmt.Errorf("fastinfoset: string %d is not defined", i)
+	}
+	return (*table)[i-1], nil
+}
+
+// nonIdentifying reads non-identifying string or index starting on the first bit.
+func (d *decoder) nonIdentifying(table *[]string) (string, error) {
+	b, err := d.byte()
+	if err != nil {
+		return "", err
+	}
+
+	if b == emptyString {
+		return "", nil
+	}
+
+	if b&0x80 != 0 {
+		i, err := d.index2(b)
+		if err != nil {
+			return "", err
+		}
+
+		if i > len(*table) {
+			return "", fmt.Errorf("fastinfoset: string %d is not defined", i)
+		}
+		return (*table)[i-1], nil
+	}
+
+	var (
+		id int
+		c  = b
+	)
+	format := b >> 4 & 0x03
+	if format >= 2 {
+		if c, err = d.byte(); err != nil {
+			return "", err
+		}
+		id = int(b&0x0F)<<4 | int(c>>4) + 1
+	}
+
+	data, err := d.octets5(c)
+	if err != nil {
+		return "", err
+	}
+
+	s, err := decodeString(format, id, data)
+	if err != nil {
+		return "", err
+	}
+
+	if b&0x40 != 0 {
+		return s, appendString(table, s)
+	}
+	return s, nil
+}
+
+// decodeString decodes utf-8, utf-16, restricted alphabet or encoding algorithm octets.
+func decodeString(format byte, id int, data []byte) (string, error) {
+	switch format {
+	case 0:
+		return string(data), nil
+	case 1:
+		if len(data)%2 != 0 {
+			return "", fmt.Errorf("fastinfoset: utf-16 string has odd length")
+		}
+		u := make([]uint16, len(data)/2)
+		for i := range u {
+			u[i] = binary.BigEndian.Uint16(data[2*i:])
+		}
+		return string(utf16.Decode(u)), nil
+	case 2:
+		alphabet, ok := alphabets[id]
+		if !ok {
+			return "", fmt.Errorf("fastinfoset: restricted alphabet %d is not supported", id)
+		}
+
+		var b strings.Builder
+		for _, c := range data {
+			for _, n := range []byte{c >> 4, c & 0x0F} {
+				if n == 0x0F {
+					break
+				}
+				if int(n) >= len(alphabet) {
+					return "", fmt.Errorf("fastinfoset: character %d is not in the alphabet", n)
+				}
+				b.WriteByte(alphabet[n])
+			}
+		}
+		return b.String(), nil
+	}
+	return decodeAlgorithm(id, data)
+}
+
+// decodeAlgorithm returns lexical form of the data encoded with built-in encoding algorithm.
+func decodeAlgorithm(id int, data []byte) (string, error) {
+	size := map[int]int{3: 2, 4: 4, 5: 8, 7: 4, 8: 8, 9: 16}[id]
+	if size > 0 && len(data)%size != 0 {
+		return "", fmt.Errorf("fastinfoset: length %d of encoding algorithm %d data is invalid", len(data), id)
+	}
+
+	var items []string
+	switch id {
+	case 1:
+		return strings.ToUpper(hex.EncodeToString(data)), nil
+	case 2:
+		return base64.StdEncoding.EncodeToString(data), nil
+	case 6:
+		if len(data) == 0 {
+			return "", nil
+		}
+		unused := int(data[0] >> 4)
+		bits := len(data)*8 - 4 - unused
+		for i := 0; i < bits; i++ {
+			bit := i + 4
+			items = append(items, strconv.FormatBool(data[bit/8]&(0x80>>(bit%8)) != 0))
+		}
+	case 10:
+		return string(data), nil
+	default:
+		if size == 0 {
+			return "", fmt.Errorf("fastinfoset: encoding algorithm %d is not supported", id)
+		}
+
+		for i := 0; i < len(data); i += size {
+			v := data[i : i+size]
+			switch id {
+			case 3:
+				items = append(items, strconv.Itoa(int(int16(binary.BigEndian.Uint16(v)))))
+			case 4:
+				items = append(items, strconv.Itoa(int(int32(binary.BigEndian.Uint32(v)))))
+			case 5:
+				items = append(items, strconv.FormatInt(int64(binary.BigEndian.Uint64(v)), 10))
+			case 7:
+				items = append(items, strconv.FormatFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(v))), 'g', -1, 32))
+			case 8:
+				items = append(items, strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(v)), 'g', -1, 64))
+			case 9:
+				h := hex.EncodeToString(v)
+				items = append(items, h[:8]+"-"+h[8:12]+"-"+h[12:16]+"-"+h[16:20]+"-"+h[20:])
+			}
+		}
+	}
+	return strings.Join(items, " "), nil
+}
+
+// octets2 reads non-empty octet string which length starts on the second bit of b.
+func (d *decoder) octets2(b byte) ([]byte, error) {
+	switch {
+	case b&0x40 == 0:
+		return d.read(int(b&0x3F) + 1)
+	case b&0x7F == 0x40:
+		n, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		return d.read(int(n) + 65)
+	case b&0x7F == 0x60:
+		n, err := d.uint32()
+		if err != nil {
+			return nil, err
+		}
+		return d.read(n + 321)
+	}
+	return nil, fmt.Errorf("fastinfoset: octet string length 0x%02x is invalid", b)
+}
+
+// octets5 reads non-empty octet string which length starts on the fifth bit of b.
+func (d *decoder) octets5(b byte) ([]byte, error) {
+	switch {
+	case b&0x08 == 0:
+		return d.read(int(b&0x07) + 1)
+	case b&0x0F == 0x08:
+		n, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		return d.read(int(n) + 9)
+	case b&0x0F == 0x0C:
+		n, err := d.uint32()
+		if err != nil {
+			return nil, err
+		}
+		return d.read(n + 265)
+	}
+	return nil, fmt.Errorf("fastinfoset: octet string length 0x%02x is invalid", b)
+}
+
+// octets7 reads non-empty octet string which length starts on the seventh bit of b.
+func (d *decoder) octets7(b byte) ([]byte, error) {
+	switch b & 0x03 {
+	case 0x00, 0x01:
+		return d.read(int(b&0x01) + 1)
+	case 0x02:
+		n, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		return d.read(int(n) + 3)
+	}
+
+	n, err := d.uint32()
+	if err != nil {
+		return nil, err
+	}
+	return d.read(n + 259)
+}
+
+// index2 reads index starting on the second bit of b.
+func (d *decoder) index2(b byte) (int, error) {
+	switch {
+	case b&0x40 == 0:
+		return int(b&0x3F) + 1, nil
+	case b&0x20 == 0:
+		n, err := d.byte()
+		return int(b&0x1F)<<8 | int(n) + 65, err
+	case b&0x10 == 0:
+		n, err := d.uint16()
+		return int(b&0x0F)<<16 | n + 8257, err
+	}
+	return 0, fmt.Errorf("fastinfoset: index 0x%02x is invalid", b)
+}
+
+// index3 reads index starting on the third bit of b.
+func (d *decoder) index3(b byte) (int, error) {
+	switch {
+	case b&0x20 == 0:
+		return int(b&0x1F) + 1, nil
+	case b&0x38 == 0x20:
+		n, err := d.byte()
+		return int(b&0x07)<<8 | int(n) + 33, err
+	case b&0x38 == 0x28:
+		n, err := d.uint16()
+		return int(b&0x07)<<16 | n + 2081, err
+	case b&0x3F == 0x30:
+		n, err := d.byte()
+		if err != nil {
+			return 0, err
+		}
+		m, err := d.uint16()
+		return int(n&0x0F)<<16 | m + 526369, err
+	}
+	return 0, fmt.Errorf("fastinfoset: index 0x%02x is invalid", b)
+}
+
+// index4 reads index starting on the fourth bit of b.
+func (d *decoder) index4(b byte) (int, error) {
+	switch {
+	case b&0x10 == 0:
+		return int(b&0x0F) + 1, nil
+	case b&0x1C == 0x10:
+		n, err := d.byte()
+		return int(b&0x03)<<8 | int(n) + 17, err
+	case b&0x1C == 0x14:
+		n, err := d.uint16()
+		return int(b&0x03)<<16 | n + 1041, err
+	case b&0x1F == 0x18:
+		n, err := d.byte()
+		if err != nil {
+			return 0, err
+		}
+		m, err := d.uint16()
+		return int(n&0x0F)<<16 | m + 263185, err
+	}
+	return 0, fmt.Errorf("fastinfoset: index 0x%02x is invalid", b)
+}
+
+func (d *decoder) byte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, errTruncated
+	}
+	d.pos++
+	return d.data[d.pos-1], nil
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, errTruncated
+	}
+	d.pos += n
+	return d.data[d.pos-n : d.pos], nil
+}
+
+func (d *decoder) uint16() (int, error) {
+	b, err := d.read(2)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint16(b)), nil
+}
+
+func (d *decoder) uint32() (int, error) {
+	b, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+
+	n := binary.BigEndian.Uint32(b)
+	if n > math.MaxInt32 {
+		return 0, fmt.Errorf("fastinfoset: length %d is too large", n)
+	}
+	return int(n), nil
+}
+
+func appendString(table *[]string, s string) error {
+	if len(*table) >= limit {
+		return errFull
+	}
+	*table = append(*table, s)
+	return nil
+}
+
+func appendName(table *[]qname, n qname) error {
+	if len(*table) >= limit {
+		return errFull
+	}
+	*table = append(*table, n)
+	return nil
+}
+
+func rawName(n qname) string {
+	if n.prefix == "" {
+		return n.local
+	}
+	return n.prefix + ":" + n.local
+}
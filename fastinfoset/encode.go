@@ -0,0 +1,372 @@
+package fastinfoset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+type encoder struct {
+	buf bytes.Buffer
+	// pending is terminator written in the high bits of the next octet
+	pending bool
+
+	prefixes   map[string]int
+	namespaces map[string]int
+	locals     map[string]int
+	values     map[string]int
+	elements   map[qname]int
+	attributes map[qname]int
+	// scopes are prefix bindings of the open elements
+	scopes []map[string]string
+}
+
+// Encode encodes xml document to Fast Infoset document.
+func Encode(data []byte) ([]byte, error) {
+	e := &encoder{
+		prefixes:   map[string]int{"xml": 1},
+		namespaces: map[string]int{xmlNS: 1},
+		locals:     make(map[string]int),
+		values:     make(map[string]int),
+		elements:   make(map[qname]int),
+		attributes: make(map[qname]int),
+	}
+	e.buf.Write(header)
+	// no optional components
+	e.buf.WriteByte(0x00)
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		t, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fastinfoset: %s", err)
+		}
+
+		switch t := t.(type) {
+		case xml.StartElement:
+			if err := e.element(t); err != nil {
+				return nil, err
+			}
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				return nil, fmt.Errorf("fastinfoset: unexpected end element %s", t.Name.Local)
+			}
+			e.scopes = e.scopes[:len(e.scopes)-1]
+			e.terminate()
+			depth--
+		case xml.CharData:
+			if depth > 0 && len(t) > 0 {
+				e.chunk(t)
+			}
+		case xml.Comment:
+			e.align()
+			e.buf.WriteByte(commentItem)
+			e.nonIdentifying(string(t), false)
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				continue
+			}
+			e.align()
+			e.buf.WriteByte(piItem)
+			e.literal(t.Target)
+			e.nonIdentifying(string(t.Inst), false)
+		case xml.Directive:
+			return nil, fmt.Errorf("fastinfoset: directives are not supported")
+		}
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("fastinfoset: unexpected end of document")
+	}
+	// document termination
+	e.terminate()
+	e.align()
+	return e.buf.Bytes(), nil
+}
+
+// terminate appends terminator, two terminators share one octet.
+func (e *encoder) terminate() {
+	if e.pending {
+		e.buf.WriteByte(doubleTerminator)
+		e.pending = false
+		return
+	}
+	e.pending = true
+}
+
+// align writes pending terminator padded to the octet.
+func (e *encoder) align() {
+	if e.pending {
+		e.buf.WriteByte(terminator)
+		e.pending = false
+	}
+}
+
+func (e *encoder) element(t xml.StartElement) error {
+	e.align()
+
+	scope := make(map[string]string)
+	var (
+		decls []xml.Attr
+		attrs []xml.Attr
+	)
+	for _, a := range t.Attr {
+		switch {
+		case a.Name.Space == "xmlns":
+			scope[a.Name.Local] = a.Value
+			decls = append(decls, a)
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			scope[""] = a.Value
+			decls = append(decls, a)
+		default:
+			attrs = append(attrs, a)
+		}
+	}
+	e.scopes = append(e.scopes, scope)
+
+	b := byte(0)
+	if len(attrs) > 0 {
+		b |= 0x40
+	}
+
+	if len(decls) > 0 {
+		e.buf.WriteByte(b | 0x38)
+		for _, a := range decls {
+			prefix := a.Name.Local
+			if a.Name.Space == "" {
+				prefix = ""
+			}
+
+			item := byte(0xCC)
+			if prefix != "" {
+				item |= 0x02
+			}
+			if a.Value != "" {
+				item |= 0x01
+			}
+			e.buf.WriteByte(item)
+			if prefix != "" {
+				e.identifying(e.prefixes, prefix)
+			}
+			if a.Value != "" {
+				e.identifying(e.namespaces, a.Value)
+			}
+		}
+		e.buf.WriteByte(terminator)
+		b = 0
+	}
+
+	name, err := e.resolve(t.Name, true)
+	if err != nil {
+		return err
+	}
+
+	if i, ok := e.elements[name]; ok {
+		e.index3(b, i)
+	} else {
+		e.qname(b|0x3C, name)
+		if err := addName(e.elements, name); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range attrs {
+		name, err := e.resolve(a.Name, false)
+		if err != nil {
+			return err
+		}
+
+		if i, ok := e.attributes[name]; ok {
+			e.index2(0, i)
+		} else {
+			e.qname(0x78, name)
+			if err := addName(e.attributes, name); err != nil {
+				return err
+			}
+		}
+		e.nonIdentifying(a.Value, true)
+	}
+
+	if len(attrs) > 0 {
+		e.terminate()
+	}
+	return nil
+}
+
+// resolve returns qualified name of the raw name, unprefixed attributes have no namespace.
+func (e *encoder) resolve(n xml.Name, element bool) (qname, error) {
+	if n.Space == "" && !element {
+		return qname{local: n.Local}, nil
+	}
+
+	if n.Space == "xml" {
+		return qname{prefix: "xml", space: xmlNS, local: n.Local}, nil
+	}
+
+	for i := len(e.scopes) - 1; i >= 0; i-- {
+		if ns, ok := e.scopes[i][n.Space]; ok {
+			if ns == "" {
+				break
+			}
+			return qname{prefix: n.Space, space: ns, local: n.Local}, nil
+		}
+	}
+
+	if n.Space != "" {
+		return qname{}, fmt.Errorf("fastinfoset: prefix %s is not declared", n.Space)
+	}
+	return qname{local: n.Local}, nil
+}
+
+// qname writes literal qualified name, b has identification bits of the item.
+func (e *encoder) qname(b byte, n qname) {
+	if n.prefix != "" {
+		b |= 0x02
+	}
+	if n.space != "" {
+		b |= 0x01
+	}
+	e.buf.WriteByte(b)
+
+	if n.prefix != "" {
+		e.identifying(e.prefixes, n.prefix)
+	}
+	if n.space != "" {
+		e.identifying(e.namespaces, n.space)
+	}
+	e.identifying(e.locals, n.local)
+}
+
+// identifying writes identifying string or its index starting on the first bit.
+func (e *encoder) identifying(table map[string]int, s string) {
+	if i, ok := table[s]; ok {
+		e.index2(0x80, i)
+		return
+	}
+	// the decoder adds literal to its table even when this one is full
+	add(table, s)
+	e.literal(s)
+}
+
+// literal writes non-empty octet string starting on the second bit.
+func (e *encoder) literal(s string) {
+	switch n := len(s); {
+	case n <= 64:
+		e.buf.WriteByte(byte(n - 1))
+	case n <= 320:
+		e.buf.Write([]byte{0x40, byte(n - 65)})
+	default:
+		e.buf.WriteByte(0x60)
+		e.uint32(n - 321)
+	}
+	e.buf.WriteString(s)
+}
+
+// nonIdentifying writes attribute value, comment or instruction starting on the first bit.
+// Short values are added to the table when indexed is set.
+func (e *encoder) nonIdentifying(s string, indexed bool) {
+	if s == "" {
+		e.buf.WriteByte(emptyString)
+		return
+	}
+
+	if i, ok := e.values[s]; ok && indexed {
+		e.index2(0x80, i)
+		return
+	}
+
+	b := byte(0)
+	if indexed && len(s) < 32 && add(e.values, s) == nil {
+		b |= 0x40
+	}
+
+	switch n := len(s); {
+	case n <= 8:
+		e.buf.WriteByte(b | byte(n-1))
+	case n <= 264:
+		e.buf.Write([]byte{b | 0x08, byte(n - 9)})
+	default:
+		e.buf.WriteByte(b | 0x0C)
+		e.uint32(n - 265)
+	}
+	e.buf.WriteString(s)
+}
+
+// chunk writes character chunk as utf-8 literal.
+func (e *encoder) chunk(s []byte) {
+	e.align()
+	switch n := len(s); {
+	case n <= 2:
+		e.buf.WriteByte(0x80 | byte(n-1))
+	case n <= 258:
+		e.buf.Write([]byte{0x82, byte(n - 3)})
+	default:
+		e.buf.WriteByte(0x83)
+		e.uint32(n - 259)
+	}
+	e.buf.Write(s)
+}
+
+// index2 writes index starting on the second bit, b has the first bit.
+func (e *encoder) index2(b byte, i int) {
+	switch {
+	case i <= 64:
+		e.buf.WriteByte(b | byte(i-1))
+	case i <= 8256:
+		i -= 65
+		e.buf.Write([]byte{b | 0x40 | byte(i>>8), byte(i)})
+	default:
+		i -= 8257
+		e.buf.Write([]byte{b | 0x60 | byte(i>>16), byte(i >> 8), byte(i)})
+	}
+}
+
+// index3 writes index starting on the third bit, b has the first two bits.
+func (e *encoder) index3(b byte, i int) {
+	switch {
+	case i <= 32:
+		e.buf.WriteByte(b | byte(i-1))
+	case i <= 2080:
+		i -= 33
+		e.buf.Write([]byte{b | 0x20 | byte(i>>8), byte(i)})
+	case i <= 526368:
+		i -= 2081
+		e.buf.Write([]byte{b | 0x28 | byte(i>>16), byte(i >> 8), byte(i)})
+	default:
+		i -= 526369
+		e.buf.Write([]byte{b | 0x30, byte(i >> 16), byte(i >> 8), byte(i)})
+	}
+}
+
+func (e *encoder) uint32(n int) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(n))
+	e.buf.Write(b[:])
+}
+
+var errFull = fmt.Errorf("fastinfoset: vocabulary table is full")
+
+// add adds the string to the table, its index is size of the table plus one.
+func add(table map[string]int, s string) error {
+	if len(table) >= limit {
+		return errFull
+	}
+	table[s] = len(table) + 1
+	return nil
+}
+
+// addName adds the qualified name to the table.
+func addName(table map[qname]int, n qname) error {
+	if len(table) >= limit {
+		return errFull
+	}
+	table[n] = len(table) + 1
+	return nil
+}
@@ -0,0 +1,119 @@
+// Package fastinfoset implements Fast Infoset (ITU-T X.891) encoding of soap envelopes
+// supported by Metro and WebLogic stacks, see soap.CodecNegotiation.
+//
+// Documents referring to external vocabularies are not supported. Element, attribute
+// and namespace names are indexed by the encoder, values are sent as literal strings.
+package fastinfoset
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/itcomusic/soap"
+)
+
+// Content types of Fast Infoset soap messages.
+const (
+	ContentType       = "application/fastinfoset"
+	SOAP12ContentType = "application/soap+fastinfoset"
+)
+
+const xmlNS = "http://www.w3.org/XML/1998/namespace"
+
+// header is identification and version of the document without optional components.
+var header = []byte{0xE0, 0x00, 0x00, 0x01}
+
+const (
+	terminator       = 0xF0
+	doubleTerminator = 0xFF
+	// emptyString is non-identifying string index zero
+	emptyString = 0xFF
+
+	piItem      = 0xE1
+	commentItem = 0xE2
+)
+
+// limit is maximum index of the vocabulary tables.
+const limit = 1 << 20
+
+// DefaultMaxSize is size limit of the decoded document, indexed strings and chunks
+// may be repeated by references of a few bytes.
+const DefaultMaxSize = 64 << 20
+
+var errTruncated = fmt.Errorf("fastinfoset: document is truncated")
+
+// Codec implements soap.Codec, the zero value uses SOAP 1.1 content type.
+type Codec struct {
+	// SOAP12 uses application/soap+fastinfoset content type.
+	SOAP12 bool
+	// MaxSize limits size of the decoded document, DefaultMaxSize when zero, negative disables the limit.
+	MaxSize int
+}
+
+// ContentType returns media type of the encoded envelope.
+func (c Codec) ContentType() string {
+	if c.SOAP12 {
+		return SOAP12ContentType
+	}
+	return ContentType
+}
+
+// Encode encodes xml envelope to Fast Infoset document.
+func (c Codec) Encode(envelope []byte) ([]byte, error) {
+	return Encode(envelope)
+}
+
+// Decode decodes Fast Infoset document to xml envelope.
+func (c Codec) Decode(data []byte) ([]byte, error) {
+	max := c.MaxSize
+	if max == 0 {
+		max = DefaultMaxSize
+	}
+	return DecodeLimit(data, max)
+}
+
+// DecodeLimit decodes Fast Infoset document to xml envelope, *soap.LimitError is returned
+// when the document exceeds max bytes, zero or negative max disables the limit.
+func (c Codec) DecodeLimit(data []byte, max int) ([]byte, error) {
+	return DecodeLimit(data, max)
+}
+
+// limitBuffer implements buffer of the decoded document, writes beyond max bytes are dropped
+// and the error is kept.
+type limitBuffer struct {
+	bytes.Buffer
+	max int
+	err error
+}
+
+func (b *limitBuffer) grow(n int) bool {
+	if b.err == nil && b.max > 0 && b.Len()+n > b.max {
+		b.err = &soap.LimitError{Limit: "decoded fast infoset size", Max: b.max}
+	}
+	return b.err == nil
+}
+
+func (b *limitBuffer) Write(p []byte) (int, error) {
+	if !b.grow(len(p)) {
+		return 0, b.err
+	}
+	return b.Buffer.Write(p)
+}
+
+func (b *limitBuffer) WriteString(s string) (int, error) {
+	if !b.grow(len(s)) {
+		return 0, b.err
+	}
+	return b.Buffer.WriteString(s)
+}
+
+func (b *limitBuffer) WriteByte(c byte) error {
+	if !b.grow(1) {
+		return b.err
+	}
+	return b.Buffer.WriteByte(c)
+}
+
+type qname struct {
+	prefix, space, local string
+}
@@ -0,0 +1,116 @@
+package fastinfoset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/itcomusic/soap"
+)
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+	for i, v := range []struct {
+		data string
+		want string
+	}{
+		{
+			// namespace attribute, literal and indexed names, double terminator
+			data: "e0000001 00 78 cd 0475726e3a78 f0 3d 81 0061 78 016964 4031 f0 3d 81 0062 816869 f0 01 ff f0",
+			want: `<a xmlns="urn:x" id="1"><b>hi</b><b></b></a>`,
+		},
+		{
+			// base64 encoding algorithm and numeric restricted alphabet
+			data: "e0000001 00 7c 0063 78 0076 3012616263 f0 880112c5 ff",
+			want: `<c v="YWJj">12.5</c>`,
+		},
+		{
+			// xml declaration, utf-16 chunk and comment
+			data: hex.EncodeToString([]byte("<?xml version='1.0' encoding='finf'?>")) + "e0000001 00 3c 0064 8601 00410042 e2 0278797a ff",
+			want: `<d>AB<!--xyz--></d>`,
+		},
+	} {
+		data, err := hex.DecodeString(strings.Replace(v.data, " ", "", -1))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := Decode(data)
+		if err != nil {
+			t.Errorf("#%d %s", i, err)
+			continue
+		}
+
+		if string(got) != v.want {
+			t.Errorf("#%d got: %s, want: %s", i, got, v.want)
+		}
+	}
+}
+
+func TestDecodeLimit(t *testing.T) {
+	t.Parallel()
+	// chunk of 100 KB added to the table and referenced many times
+	document := func(refs int) []byte {
+		data, _ := hex.DecodeString("e0000001003c006493")
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], 100000-259)
+		data = append(data, n[:]...)
+		data = append(data, bytes.Repeat([]byte("x"), 100000)...)
+		data = append(data, bytes.Repeat([]byte{0xA0}, refs)...)
+		return append(data, 0xFF)
+	}
+
+	got, err := DecodeLimit(document(10), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := 100000*11 + len("<d></d>"); len(got) != want {
+		t.Fatalf("got: %d, want: %d", len(got), want)
+	}
+
+	data := document(10000)
+	for _, decode := range []func([]byte) ([]byte, error){Decode, Codec{}.Decode, Codec{MaxSize: 1 << 20}.Decode} {
+		if _, err := decode(data); err == nil {
+			t.Fatal("got: nil, want: limit error")
+		} else if _, ok := err.(*soap.LimitError); !ok {
+			t.Fatalf("got: %v, want: LimitError", err)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	t.Parallel()
+	envelope := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Header></soapenv:Header>` +
+		`<soapenv:Body><m:GetUsers xmlns:m="urn:users" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<m:User xsi:type="m:Admin" id="1">alice &amp; bob</m:User><m:User xsi:type="m:Admin" id="2" empty=""></m:User>` +
+		`<!-- comment --><m:Note>` + strings.Repeat("x", 300) + `</m:Note></m:GetUsers></soapenv:Body></soapenv:Envelope>`
+
+	data, err := Encode([]byte(envelope))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(data, []byte{0xE0, 0x00, 0x00, 0x01}) || len(data) >= len(envelope) {
+		t.Fatalf("got: %x", data)
+	}
+
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != envelope {
+		t.Fatalf("got: %s, want: %s", got, envelope)
+	}
+
+	if got, err := Encode([]byte(`<a><b></a>`)); err == nil {
+		t.Fatalf("got: %x, want: error", got)
+	}
+
+	if _, err := Decode(data[:len(data)-3]); err != errTruncated {
+		t.Fatalf("got: %v, want: %s", err, errTruncated)
+	}
+}
@@ -0,0 +1,88 @@
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Fault codes of SOAP 1.1, the soapenv prefix is declared by the encoded fault.
+const (
+	FaultVersionMismatch = "soapenv:VersionMismatch"
+	FaultMustUnderstand  = "soapenv:MustUnderstand"
+	FaultClient          = "soapenv:Client"
+	FaultServer          = "soapenv:Server"
+)
+
+// Fault codes of WS-Security, the wsse prefix is declared by the encoded fault.
+const (
+	FaultInvalidSecurity      = "wsse:InvalidSecurity"
+	FaultFailedAuthentication = "wsse:FailedAuthentication"
+	FaultMessageExpired       = "wsse:MessageExpired"
+)
+
+// faultPrefixes are namespaces of the prefixes of the standard codes.
+var faultPrefixes = map[string]string{"soapenv": envelopeNS, "wsse": WSSENS}
+
+// Fault codes of SOAP 1.2.
+const (
+	Fault12VersionMismatch     = "env:VersionMismatch"
+	Fault12MustUnderstand      = "env:MustUnderstand"
+	Fault12DataEncodingUnknown = "env:DataEncodingUnknown"
+	Fault12Sender              = "env:Sender"
+	Fault12Receiver            = "env:Receiver"
+)
+
+// fault12Codes maps local names of SOAP 1.1 codes to SOAP 1.2 codes.
+var fault12Codes = map[string]string{
+	"VersionMismatch": Fault12VersionMismatch,
+	"MustUnderstand":  Fault12MustUnderstand,
+	"Client":          Fault12Sender,
+	"Server":          Fault12Receiver,
+}
+
+// NewClientFault returns fault of the invalid request.
+func NewClientFault(text string) *Fault {
+	return &Fault{Code: FaultClient, Text: trimSpace(text)}
+}
+
+// NewServerFault returns fault of the request failed by the server.
+func NewServerFault(text string) *Fault {
+	return &Fault{Code: FaultServer, Text: trimSpace(text)}
+}
+
+// NewMustUnderstandFault returns fault of the mandatory header which is not understood.
+func NewMustUnderstandFault(header xml.Name) *Fault {
+	name := header.Local
+	if header.Space != "" {
+		name = "{" + header.Space + "}" + name
+	}
+	return &Fault{Code: FaultMustUnderstand, Text: trimSpace(fmt.Sprintf("header %s is not understood", name))}
+}
+
+// WithDetail sets detail of the fault.
+func (f *Fault) WithDetail(detail string) *Fault {
+	f.Detail = trimSpace(detail)
+	return f
+}
+
+// SOAP12Code returns SOAP 1.2 code of the standard SOAP 1.1 code, other codes are returned as is.
+func (f *Fault) SOAP12Code() string {
+	code := f.Code.String()
+	if c, ok := fault12Codes[localName(code)]; ok && (code == localName(code) || strings.HasPrefix(code, "soapenv:")) {
+		return c
+	}
+	return code
+}
+
+// MarshalXML implements xml.Marshaler interface, prefix of the standard codes is declared.
+func (f Fault) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type fault Fault
+	start.Name = xml.Name{Space: envelopeNS, Local: "Fault"}
+	if i := strings.IndexByte(f.Code.String(), ':'); i >= 0 {
+		if ns, ok := faultPrefixes[f.Code.String()[:i]]; ok {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + f.Code.String()[:i]}, Value: ns})
+		}
+	}
+	return e.EncodeElement(fault(f), start)
+}
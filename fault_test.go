@@ -0,0 +1,75 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewFault(t *testing.T) {
+	t.Parallel()
+	for i, v := range []struct {
+		fault *Fault
+		want  string
+		code  string
+	}{
+		{
+			fault: NewClientFault("invalid id").WithDetail("id must be positive"),
+			want: `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body xmlns="http://schemas.xmlsoap.org/soap/envelope/">` +
+				`<Fault xmlns="http://schemas.xmlsoap.org/soap/envelope/" xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">` +
+				`<faultcode>soapenv:Client</faultcode><faultstring>invalid id</faultstring><detail>id must be positive</detail></Fault></Body></Envelope>`,
+			code: Fault12Sender,
+		},
+		{
+			fault: NewServerFault("database is down"),
+			want: `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body xmlns="http://schemas.xmlsoap.org/soap/envelope/">` +
+				`<Fault xmlns="http://schemas.xmlsoap.org/soap/envelope/" xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">` +
+				`<faultcode>soapenv:Server</faultcode><faultstring>database is down</faultstring></Fault></Body></Envelope>`,
+			code: Fault12Receiver,
+		},
+		{
+			fault: NewMustUnderstandFault(xml.Name{Space: "urn:session", Local: "SessionHeader"}),
+			want: `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body xmlns="http://schemas.xmlsoap.org/soap/envelope/">` +
+				`<Fault xmlns="http://schemas.xmlsoap.org/soap/envelope/" xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">` +
+				`<faultcode>soapenv:MustUnderstand</faultcode><faultstring>header {urn:session}SessionHeader is not understood</faultstring></Fault></Body></Envelope>`,
+			code: Fault12MustUnderstand,
+		},
+		{
+			fault: &Fault{Code: "s:ServerBusy", Text: "busy"},
+			want: `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body xmlns="http://schemas.xmlsoap.org/soap/envelope/">` +
+				`<Fault xmlns="http://schemas.xmlsoap.org/soap/envelope/"><faultcode>s:ServerBusy</faultcode><faultstring>busy</faultstring></Fault></Body></Envelope>`,
+			code: "s:ServerBusy",
+		},
+	} {
+		b, err := xml.Marshal(Envelope{Body: Body{Fault: v.fault}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(b) != v.want {
+			t.Errorf("#%d got: %s, want: %s", i, b, v.want)
+		}
+
+		if got := v.fault.SOAP12Code(); got != v.code {
+			t.Errorf("#%d got: %s, want: %s", i, got, v.code)
+		}
+	}
+}
+
+func TestClient_BuiltFault(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := xml.Marshal(Envelope{Body: Body{Fault: NewClientFault("invalid id")}})
+		w.WriteHeader(500)
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "", request{}, nil)
+	f, ok := err.(*Fault)
+	if !ok || f.Code != FaultClient || f.Text != "invalid id" {
+		t.Fatalf("got: %v, want: client fault", err)
+	}
+}
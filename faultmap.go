@@ -0,0 +1,135 @@
+package soap
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+)
+
+// FaultMapFunc maps fault to application error, nil keeps the fault.
+type FaultMapFunc func(f *Fault) error
+
+// MappedError implements application error mapped from the fault returned by Call,
+// errors.Is matches the application error and errors.As the fault.
+type MappedError struct {
+	Err   error
+	Fault *Fault
+}
+
+func (e *MappedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the application error and the fault.
+func (e *MappedError) Unwrap() []error {
+	return []error{e.Err, e.Fault}
+}
+
+// MapFault maps faults matching the code and text to the error. Code is matched by the local name,
+// text is matched as substring of the fault string, empty code or text matches any fault.
+func (s *Client) MapFault(code, text string, err error) {
+	code = localName(code)
+	s.MapFaultFunc(func(f *Fault) error {
+		if code != "" && localName(f.Code.String()) != code {
+			return nil
+		}
+
+		if !strings.Contains(f.Text.String(), text) {
+			return nil
+		}
+		return err
+	})
+}
+
+// MapFaultFunc adds fault mapping, mappings are evaluated in order until the first error.
+func (s *Client) MapFaultFunc(fn FaultMapFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faultMaps = append(s.faultMaps, fn)
+}
+
+// mapFault returns application error of the fault, other errors are returned as is.
+func (s *Client) mapFault(err error) error {
+	f, ok := err.(*Fault)
+	if !ok {
+		return err
+	}
+
+	s.mu.RLock()
+	maps := s.faultMaps
+	s.mu.RUnlock()
+
+	for _, fn := range maps {
+		if e := fn(f); e != nil {
+			return &MappedError{Err: e, Fault: f}
+		}
+	}
+	return err
+}
+
+// errorElement implements business error returned as regular body element.
+type errorElement struct {
+	action string
+	name   xml.Name
+	fn     func() error
+}
+
+// MapErrorElement maps body element of the action response to the error, the element is decoded
+// into the error returned by fn and it is returned from Call, so fn must return a pointer.
+// Empty action matches any action, name without namespace matches any namespace.
+// Responses decoded into Targets, Stream, Choice or Selection are not mapped.
+func (s *Client) MapErrorElement(action string, name xml.Name, fn func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errorElements = append(s.errorElements, errorElement{action: action, name: name, fn: fn})
+}
+
+// decodeErrors decodes the response, error elements of the action are returned as errors.
+func (s *Client) decodeErrors(r *Response, action string, response interface{}) error {
+	switch response.(type) {
+	case Targets, Stream, *Choice, *Selection:
+		return s.decode(r, response)
+	case asyncAck:
+		// acknowledgement of the asynchronous request has no envelope
+		if len(r.Body) > 0 {
+			return s.decode(r, Targets{})
+		}
+		if r.StatusCode != http.StatusOK && r.StatusCode != http.StatusAccepted {
+			return s.statusError(r)
+		}
+		return nil
+	}
+
+	s.mu.RLock()
+	elements := s.errorElements
+	s.mu.RUnlock()
+
+	c := &Choice{Candidates: make(Targets), Default: response}
+	for _, e := range elements {
+		if e.action != "" && e.action != action {
+			continue
+		}
+
+		if _, ok := c.Candidates[e.name]; !ok {
+			c.Candidates[e.name] = e.fn()
+		}
+	}
+
+	if len(c.Candidates) == 0 {
+		return s.decode(r, response)
+	}
+
+	if err := s.decode(r, c); err != nil {
+		return err
+	}
+
+	if c.Chosen == nil {
+		return nil
+	}
+
+	// the default is chosen by unmatched element
+	if _, ok := c.Candidates.match(c.Name); !ok {
+		return nil
+	}
+	return c.Chosen.(error)
+}
@@ -0,0 +1,105 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_MapFault(t *testing.T) {
+	t.Parallel()
+	errNotFound := fmt.Errorf("account is not found")
+	errDenied := fmt.Errorf("access is denied")
+
+	s := NewServer(ServerConfig{})
+	s.Handle("missing", func(ctx context.Context, r *ServerRequest) (interface{}, error) {
+		return nil, NewClientFault("account 42 is not found")
+	})
+	s.Handle("denied", func(ctx context.Context, r *ServerRequest) (interface{}, error) {
+		return nil, &Fault{Code: FaultFailedAuthentication, Text: "bad token", HTTPStatus: http.StatusForbidden}
+	})
+	s.Handle("fail", func(ctx context.Context, r *ServerRequest) (interface{}, error) {
+		return nil, NewServerFault("database is down")
+	})
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{})
+	client.MapFault("Client", "is not found", errNotFound)
+	client.MapFaultFunc(func(f *Fault) error {
+		if f.HTTPStatus == http.StatusForbidden {
+			return errDenied
+		}
+		return nil
+	})
+
+	for i, v := range []struct {
+		action string
+		want   error
+	}{
+		{action: "missing", want: errNotFound},
+		{action: "denied", want: errDenied},
+	} {
+		err := client.Call(context.Background(), v.action, request{}, nil)
+		if !errors.Is(err, v.want) {
+			t.Fatalf("#%d got: %v, want: %s", i, err, v.want)
+		}
+
+		var f *Fault
+		if !errors.As(err, &f) {
+			t.Fatalf("#%d got: %v, want: fault", i, err)
+		}
+
+		if info, ok := CallInfoOf(err); !ok || info.Action() != v.action {
+			t.Fatalf("#%d got: %v, want: call info of %s", i, info, v.action)
+		}
+	}
+
+	err := client.Call(context.Background(), "fail", request{}, nil)
+	if _, ok := err.(*Fault); !ok {
+		t.Fatalf("got: %v, want: unmapped fault", err)
+	}
+}
+
+type limitError struct {
+	Reason string `xml:"Reason"`
+}
+
+func (e *limitError) Error() string {
+	return "limit: " + e.Reason
+}
+
+func TestClient_MapErrorElement(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := `<Response xmlns="test:call"><attr3>value3</attr3></Response>`
+		if r.Header.Get("SOAPAction") == "transfer" {
+			body = `<LimitError xmlns="test:call"><Reason>daily</Reason></LimitError>`
+		}
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body>` + body + `</Body></Envelope>`))
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{})
+	client.MapErrorElement("transfer", xml.Name{Local: "LimitError"}, func() error { return &limitError{} })
+
+	var resp response
+	err := client.Call(context.Background(), "transfer", request{}, &resp)
+	var le *limitError
+	if !errors.As(err, &le) || le.Reason != "daily" {
+		t.Fatalf("got: %v, want: limit error", err)
+	}
+
+	if err := client.Call(context.Background(), "other", request{}, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "value3"; resp.Attr3 != want {
+		t.Fatalf("got: %s, want: %s", resp.Attr3, want)
+	}
+}
@@ -0,0 +1,229 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Transform rewrites envelope forwarded by the gateway.
+type Transform func(envelope *Node) error
+
+// InjectHeader returns transform appending the header element, the header is added when missing.
+func InjectHeader(v interface{}) Transform {
+	return func(envelope *Node) error {
+		b, err := xml.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("soap: %s", err)
+		}
+
+		n, err := ParseNode(b)
+		if err != nil {
+			return err
+		}
+
+		h := envelope.Child("Header")
+		if h == nil {
+			h = NewNode(envelope.XMLName.Space, "Header", "")
+			envelope.Children = append([]*Node{h}, envelope.Children...)
+		}
+		h.Add(n)
+		return nil
+	}
+}
+
+// StripHeaders returns transform removing the header elements, name without namespace matches any namespace.
+func StripHeaders(names ...xml.Name) Transform {
+	return func(envelope *Node) error {
+		h := envelope.Child("Header")
+		if h == nil {
+			return nil
+		}
+
+		children := h.Children[:0]
+		for _, c := range h.Children {
+			if !matchName(c.XMLName, names) {
+				children = append(children, c)
+			}
+		}
+		h.Children = children
+		return nil
+	}
+}
+
+func matchName(name xml.Name, names []xml.Name) bool {
+	for _, n := range names {
+		if n.Local == name.Local && (n.Space == "" || n.Space == name.Space) {
+			return true
+		}
+	}
+	return false
+}
+
+// RewriteNamespace returns transform replacing namespace of the elements, attributes and prefix declarations.
+func RewriteNamespace(old, new string) Transform {
+	var rewrite func(n *Node)
+	rewrite = func(n *Node) {
+		if n.XMLName.Space == old {
+			n.XMLName.Space = new
+		}
+		for i, a := range n.Attrs {
+			if a.Name.Space == old {
+				n.Attrs[i].Name.Space = new
+			}
+		}
+		for i, a := range n.ns {
+			if a.Value == old {
+				n.ns[i].Value = new
+			}
+		}
+		for _, c := range n.Children {
+			rewrite(c)
+		}
+	}
+
+	return func(envelope *Node) error {
+		rewrite(envelope)
+		return nil
+	}
+}
+
+// GatewayConfig implements config of the gateway.
+type GatewayConfig struct {
+	// Version is soap version of the backend, envelopes of the other version are converted.
+	Version SOAPVersion
+	// Request transforms rewrite the received envelope, Response transforms rewrite the backend response,
+	// both are applied in order to envelopes of the backend version.
+	Request  []Transform
+	Response []Transform
+	// MaxBodyBytes limits size of the request body, DefaultMaxBodyBytes when zero, negative disables the limit.
+	MaxBodyBytes int64
+}
+
+// Gateway implements reverse proxy of soap requests forwarding them via the client,
+// e.g. for wrapping legacy services.
+type Gateway struct {
+	client *Client
+	config GatewayConfig
+}
+
+// NewGateway creates gateway forwarding requests to the client endpoint.
+func NewGateway(client *Client, c GatewayConfig) *Gateway {
+	if c.MaxBodyBytes == 0 {
+		c.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	return &Gateway{client: client, config: c}
+}
+
+// ServeHTTP implements http.Handler interface, errors of the gateway are sent as SOAP 1.1 fault.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method is not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body := r.Body
+	if g.config.MaxBodyBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, g.config.MaxBodyBytes)
+	}
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		if e, ok := err.(*http.MaxBytesError); ok {
+			writeFault(w, &Fault{Code: FaultClient, Text: trimSpace(fmt.Sprintf("request body exceeds limit %d", e.Limit)), HTTPStatus: http.StatusRequestEntityTooLarge})
+			return
+		}
+		writeFault(w, NewClientFault("request body is not read"))
+		return
+	}
+
+	data, version, err := convertEnvelope(trimProlog(data), g.config.Version)
+	if err == errVersion {
+		writeFault(w, &Fault{Code: FaultVersionMismatch, Text: "envelope version is unknown"})
+		return
+	}
+
+	var env *Node
+	if err == nil {
+		env, err = ParseNode(data)
+	}
+	if err != nil {
+		writeFault(w, NewClientFault("envelope is invalid"))
+		return
+	}
+
+	action := soapAction(r.Header)
+	if err := apply(env, g.config.Request); err != nil {
+		writeFault(w, NewClientFault(fmt.Sprintf("request is not transformed: %s", err)))
+		return
+	}
+
+	req := g.client.newRequest(action, encodeNode(env))
+	req.Header.Set("Content-Type", g.config.Version.contentType(action))
+	resp, err := g.client.transport(r.Context(), req)
+	if err != nil {
+		writeFault(w, &Fault{Code: FaultServer, Text: "backend is unavailable", HTTPStatus: http.StatusBadGateway})
+		return
+	}
+
+	data, _, err = convertEnvelope(trimProlog(resp.Body), g.config.Version)
+	if err == nil {
+		env, err = ParseNode(data)
+	}
+	if err != nil {
+		writeFault(w, &Fault{Code: FaultServer, Text: "backend response is invalid", HTTPStatus: http.StatusBadGateway})
+		return
+	}
+
+	if err := apply(env, g.config.Response); err != nil {
+		writeFault(w, NewServerFault(fmt.Sprintf("response is not transformed: %s", err)))
+		return
+	}
+
+	if data, _, err = convertEnvelope(encodeNode(env), version); err != nil {
+		writeFault(w, NewServerFault(fmt.Sprintf("response is not converted: %s", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", version.contentType(""))
+	w.WriteHeader(resp.StatusCode)
+	w.Write(data)
+}
+
+func apply(env *Node, transforms []Transform) error {
+	for _, t := range transforms {
+		if err := t(env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// soapAction returns soap action of SOAPAction header or action parameter of SOAP 1.2 content type.
+func soapAction(h http.Header) string {
+	if v := h.Get("SOAPAction"); v != "" {
+		return strings.Trim(v, `"`)
+	}
+
+	_, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return params["action"]
+}
+
+// encodeNode encodes the node, unqualified elements undeclare default namespace of the parent
+// since the encoder declares default namespace of the qualified ones.
+func encodeNode(n *Node) []byte {
+	var b bytes.Buffer
+	e := xml.NewEncoder(&b)
+	// node has no values failing the encoder
+	n.encode(e, "", true, nil)
+	e.Flush()
+	return b.Bytes()
+}
@@ -0,0 +1,140 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type gatewayHeader struct {
+	XMLName xml.Name `xml:"urn:gw Tenant"`
+	ID      string   `xml:"id"`
+}
+
+func TestGateway(t *testing.T) {
+	t.Parallel()
+	var (
+		got         *Node
+		raw         []byte
+		action      string
+		contentType string
+	)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ = ioutil.ReadAll(r.Body)
+		got, _ = ParseNode(raw)
+		action, contentType = r.Header.Get("SOAPAction"), r.Header.Get("Content-Type")
+		w.Write([]byte(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><Response xmlns="urn:v2"><attr3>value3</attr3></Response></soapenv:Body></soapenv:Envelope>`))
+	}))
+	defer backend.Close()
+
+	gw := httptest.NewServer(NewGateway(MustNewClient(backend.URL, Config{}), GatewayConfig{
+		Request: []Transform{
+			StripHeaders(xml.Name{Local: "Secret"}),
+			InjectHeader(gatewayHeader{ID: "1"}),
+			RewriteNamespace("urn:v1", "urn:v2"),
+		},
+		Response: []Transform{RewriteNamespace("urn:v2", "urn:v1")},
+	}))
+	defer gw.Close()
+
+	req := `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Header><Secret xmlns="urn:x">1</Secret></env:Header>` +
+		`<env:Body><v:Request xmlns:v="urn:v1" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="v:Get">` +
+		`a<attr1>value1</attr1>b</v:Request></env:Body></env:Envelope>`
+	resp, err := http.Post(gw.URL, `application/soap+xml; charset=utf-8; action="get"`, strings.NewReader(req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if action != "get" || !strings.HasPrefix(contentType, "text/xml") {
+		t.Fatalf("got: %s %s, want: get text/xml", action, contentType)
+	}
+
+	if got.XMLName.Space != envelopeNS || got.Find("Envelope/Header/Secret") != nil || got.Value("Envelope/Header/Tenant/id") != "1" {
+		t.Fatalf("got: %s, want: SOAP 1.1 envelope with Tenant header only", encodeNode(got))
+	}
+
+	if n := got.Find("Envelope/Body/Request"); n.XMLName.Space != "urn:v2" || n.Child("attr1").XMLName.Space != "" {
+		t.Fatalf("got: %s, want: urn:v2 request with unqualified attr1", encodeNode(got))
+	}
+
+	// qname value keeps its rewritten prefix declaration, mixed content keeps its order
+	if !bytes.Contains(raw, []byte(`xmlns:v="urn:v2"`)) || !bytes.Contains(raw, []byte(`xsi:type="v:Get">a<attr1 xmlns="">value1</attr1>b<`)) {
+		t.Fatalf("got: %s, want: declared qname prefix and mixed content", raw)
+	}
+
+	b, _ := ioutil.ReadAll(resp.Body)
+	n, err := ParseNode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/soap+xml") || n.XMLName.Space != envelope12NS {
+		t.Fatalf("got: %s %s, want: SOAP 1.2 envelope", resp.Header.Get("Content-Type"), b)
+	}
+
+	if r := n.Find("Envelope/Body/Response"); r.XMLName.Space != "urn:v1" || r.Value("Response/attr3") != "value3" {
+		t.Fatalf("got: %s, want: urn:v1 response", b)
+	}
+
+	// backend is down
+	backend.Close()
+	err = MustNewClient(gw.URL, Config{}).Call(context.Background(), "get", request{}, nil)
+	if err == nil || err.Error() != "soap: soapenv:Server: backend is unavailable 502" {
+		t.Fatalf("got: %v, want: backend is unavailable", err)
+	}
+}
+
+func TestGateway_ConvertError(t *testing.T) {
+	t.Parallel()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		w.Write(b)
+	}))
+	defer backend.Close()
+
+	gw := httptest.NewServer(NewGateway(MustNewClient(backend.URL, Config{}), GatewayConfig{
+		Response: []Transform{func(env *Node) error {
+			env.XMLName.Space = "urn:x"
+			return nil
+		}},
+	}))
+	defer gw.Close()
+
+	err := MustNewClient(gw.URL, Config{}).Call(context.Background(), "get", request{}, &response{})
+	if f, ok := err.(*Fault); !ok || f.Code != FaultServer || f.Text != "response is not converted: soap: envelope version is unknown" {
+		t.Fatalf("got: %v, want: Server fault", err)
+	}
+}
+
+func TestEncodeNode(t *testing.T) {
+	t.Parallel()
+	n := NewNode("urn:a", "a", "").Add(NewNode("", "b", "x"), NewNode("urn:a", "c", ""))
+	want := `<a xmlns="urn:a"><b xmlns="">x</b><c xmlns="urn:a"></c></a>`
+	if got := encodeNode(n); !bytes.Equal(got, []byte(want)) {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+
+	// prefixes of qname values stay declared, mixed content keeps its order
+	n, err := ParseNode([]byte(`<m xmlns="urn:m" xmlns:tns="urn:t" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><v xsi:type="tns:Foo">a<b/>c</v></m>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = `<m xmlns="urn:m" xmlns:tns="urn:t" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><v xmlns="urn:m" xsi:type="tns:Foo">a<b xmlns="urn:m"></b>c</v></m>`
+	if got := encodeNode(n); !bytes.Equal(got, []byte(want)) {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+
+	// changed character data is encoded before the children
+	n.Child("v").CharData = "x"
+	want = `<m xmlns="urn:m" xmlns:tns="urn:t" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><v xmlns="urn:m" xsi:type="tns:Foo">x<b xmlns="urn:m"></b></v></m>`
+	if got := encodeNode(n); !bytes.Equal(got, []byte(want)) {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+}
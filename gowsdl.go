@@ -0,0 +1,167 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+	"time"
+)
+
+// Types and methods below mirror hooklift/gowsdl soap package, so the code generated
+// by gowsdl is reused with this client by changing the import path.
+
+// CallContext sends soap request, it is equal to Call.
+func (s *Client) CallContext(ctx context.Context, soapAction string, request, response interface{}) error {
+	return s.Call(ctx, soapAction, request, response)
+}
+
+const (
+	xsdDateTimeLayout = "2006-01-02T15:04:05.999999999"
+	xsdDateLayout     = "2006-01-02"
+	xsdTimeLayout     = "15:04:05.999999999"
+	xsdTimezoneLayout = "Z07:00"
+)
+
+// XSDDateTime implements xsd:dateTime.
+type XSDDateTime struct {
+	innerTime time.Time
+	hasTz     bool
+}
+
+// CreateXsdDateTime creates xsd:dateTime, the timezone is emitted when hasTz is true.
+func CreateXsdDateTime(dt time.Time, hasTz bool) XSDDateTime {
+	return XSDDateTime{innerTime: dt, hasTz: hasTz}
+}
+
+// ToGoTime returns time.Time value.
+func (xdt XSDDateTime) ToGoTime() time.Time {
+	return xdt.innerTime
+}
+
+// MarshalXML implements xml.Marshaler interface.
+func (xdt XSDDateTime) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if xdt.innerTime.IsZero() {
+		return nil
+	}
+	return e.EncodeElement(formatXSD(xdt.innerTime, xsdDateTimeLayout, xdt.hasTz), start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler interface.
+func (xdt *XSDDateTime) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var err error
+	xdt.innerTime, xdt.hasTz, err = decodeXSD(d, start, xsdDateTimeLayout)
+	return err
+}
+
+// XSDDate implements xsd:date.
+type XSDDate struct {
+	innerDate time.Time
+	hasTz     bool
+}
+
+// CreateXsdDate creates xsd:date, the timezone is emitted when hasTz is true.
+func CreateXsdDate(date time.Time, hasTz bool) XSDDate {
+	return XSDDate{innerDate: date, hasTz: hasTz}
+}
+
+// ToGoTime returns time.Time value.
+func (xd XSDDate) ToGoTime() time.Time {
+	return xd.innerDate
+}
+
+// MarshalXML implements xml.Marshaler interface.
+func (xd XSDDate) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if xd.innerDate.IsZero() {
+		return nil
+	}
+	return e.EncodeElement(formatXSD(xd.innerDate, xsdDateLayout, xd.hasTz), start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler interface.
+func (xd *XSDDate) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var err error
+	xd.innerDate, xd.hasTz, err = decodeXSD(d, start, xsdDateLayout)
+	return err
+}
+
+// XSDTime implements xsd:time.
+type XSDTime struct {
+	innerTime time.Time
+	hasTz     bool
+}
+
+// CreateXsdTime creates xsd:time, the timezone is emitted when loc is not nil.
+func CreateXsdTime(hour int, min int, sec int, nsec int, loc *time.Location) XSDTime {
+	hasTz := loc != nil
+	if loc == nil {
+		loc = time.UTC
+	}
+	return XSDTime{innerTime: time.Date(1, 1, 1, hour, min, sec, nsec, loc), hasTz: hasTz}
+}
+
+// Hour returns hour of the time.
+func (xt XSDTime) Hour() int {
+	return xt.innerTime.Hour()
+}
+
+// Minute returns minute of the time.
+func (xt XSDTime) Minute() int {
+	return xt.innerTime.Minute()
+}
+
+// Second returns second of the time.
+func (xt XSDTime) Second() int {
+	return xt.innerTime.Second()
+}
+
+// Nanosecond returns nanosecond of the time.
+func (xt XSDTime) Nanosecond() int {
+	return xt.innerTime.Nanosecond()
+}
+
+// Location returns location of the time, nil when timezone is absent.
+func (xt XSDTime) Location() *time.Location {
+	if !xt.hasTz {
+		return nil
+	}
+	return xt.innerTime.Location()
+}
+
+// MarshalXML implements xml.Marshaler interface.
+func (xt XSDTime) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(formatXSD(xt.innerTime, xsdTimeLayout, xt.hasTz), start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler interface.
+func (xt *XSDTime) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var err error
+	xt.innerTime, xt.hasTz, err = decodeXSD(d, start, xsdTimeLayout)
+	return err
+}
+
+func formatXSD(t time.Time, layout string, hasTz bool) string {
+	if hasTz {
+		layout += xsdTimezoneLayout
+	}
+	return t.Format(layout)
+}
+
+// decodeXSD decodes date or time value with optional timezone.
+func decodeXSD(d *xml.Decoder, start xml.StartElement, layout string) (time.Time, bool, error) {
+	var v string
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return time.Time{}, false, err
+	}
+
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, false, nil
+	}
+
+	if t, err := time.Parse(layout+xsdTimezoneLayout, v); err == nil {
+		return t, true, nil
+	}
+
+	t, err := time.Parse(layout, v)
+	return t, false, err
+}
@@ -0,0 +1,47 @@
+package soap
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+type gowsdlTimes struct {
+	XMLName  xml.Name    `xml:"test:call Times"`
+	DateTime XSDDateTime `xml:"dateTime,omitempty" json:"dateTime,omitempty"`
+	Date     XSDDate     `xml:"date,omitempty" json:"date,omitempty"`
+	Time     XSDTime     `xml:"time,omitempty" json:"time,omitempty"`
+}
+
+func Test_GowsdlTypes(t *testing.T) {
+	t.Parallel()
+	loc := time.FixedZone("", 3*60*60)
+	v := gowsdlTimes{
+		DateTime: CreateXsdDateTime(time.Date(2020, 1, 2, 3, 4, 5, 600000000, loc), true),
+		Date:     CreateXsdDate(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), false),
+		Time:     CreateXsdTime(3, 4, 5, 0, nil),
+	}
+
+	b, err := xml.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<Times xmlns="test:call"><dateTime>2020-01-02T03:04:05.6+03:00</dateTime><date>2020-01-02</date><time>03:04:05</time></Times>`
+	if got := string(b); got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+
+	var got gowsdlTimes
+	if err := xml.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if !got.DateTime.ToGoTime().Equal(v.DateTime.ToGoTime()) || !got.Date.ToGoTime().Equal(v.Date.ToGoTime()) {
+		t.Fatalf("got: %v, want: %v", got, v)
+	}
+
+	if got.Time.Hour() != 3 || got.Time.Minute() != 4 || got.Time.Second() != 5 || got.Time.Location() != nil {
+		t.Fatalf("got: %v, want: %v", got.Time, v.Time)
+	}
+}
@@ -0,0 +1,123 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// HeaderLimits implements validation of soap header elements of the envelope, zero value disables the checks.
+type HeaderLimits struct {
+	// MaxItems limits number of header elements.
+	MaxItems int
+	// MaxItemBytes limits size of each header element.
+	MaxItemBytes int
+	// MaxBytes limits size of the header.
+	MaxBytes int
+	// MustUnderstand rejects header elements with mustUnderstand="1" or "true" missing in Understood,
+	// name without namespace matches any namespace.
+	MustUnderstand bool
+	Understood     []xml.Name
+}
+
+// MustUnderstandError is returned when the mandatory header element is not understood.
+type MustUnderstandError struct {
+	Header xml.Name
+}
+
+func (e *MustUnderstandError) Error() string {
+	return fmt.Sprintf("soap: header {%s}%s is not understood", e.Header.Space, e.Header.Local)
+}
+
+func (l HeaderLimits) enabled() bool {
+	return l.MaxItems > 0 || l.MaxItemBytes > 0 || l.MaxBytes > 0 || l.MustUnderstand
+}
+
+// check validates header elements of the envelope, the body is not read.
+func (l HeaderLimits) check(envelope []byte) error {
+	if !l.enabled() {
+		return nil
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(envelope))
+	var (
+		depth int
+		items int
+		// start is offset of the header and of the current header element
+		start, itemStart int64
+	)
+	for {
+		offset := d.InputOffset()
+		token, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// the envelope is decoded as it is, so the error is reported by decoding
+			return nil
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 2 && t.Name.Local == "Header":
+				start = offset
+			case depth == 2:
+				// the body is not checked
+				return nil
+			case depth == 3:
+				itemStart = offset
+				if items++; l.MaxItems > 0 && items > l.MaxItems {
+					return &LimitError{Limit: "header items", Max: l.MaxItems}
+				}
+
+				if l.MustUnderstand && mustUnderstand(t) && !l.understood(t.Name) {
+					return &MustUnderstandError{Header: t.Name}
+				}
+			}
+		case xml.EndElement:
+			depth--
+			switch {
+			case depth == 2 && l.MaxItemBytes > 0 && d.InputOffset()-itemStart > int64(l.MaxItemBytes):
+				return &LimitError{Limit: "header item size", Max: l.MaxItemBytes}
+			case depth == 1 && l.MaxBytes > 0 && d.InputOffset()-start > int64(l.MaxBytes):
+				return &LimitError{Limit: "header size", Max: l.MaxBytes}
+			case depth == 1:
+				return nil
+			}
+		}
+	}
+}
+
+func (l HeaderLimits) understood(name xml.Name) bool {
+	for _, n := range l.Understood {
+		if n.Local == name.Local && (n.Space == "" || n.Space == name.Space) {
+			return true
+		}
+	}
+	return false
+}
+
+// mustUnderstand reports whether the header element is mandatory in soap 1.1 or 1.2.
+func mustUnderstand(se xml.StartElement) bool {
+	for _, a := range se.Attr {
+		if a.Name.Local == "mustUnderstand" && (a.Name.Space == envelopeNS || a.Name.Space == envelope12NS) {
+			return a.Value == "1" || a.Value == "true"
+		}
+	}
+	return false
+}
+
+// headerFault returns fault of the rejected request header.
+func headerFault(err error) *Fault {
+	if e, ok := err.(*MustUnderstandError); ok {
+		return NewMustUnderstandFault(e.Header)
+	}
+
+	if e, ok := err.(*LimitError); ok {
+		return NewClientFault(fmt.Sprintf("request %s exceeds limit %d", e.Limit, e.Max))
+	}
+	return NewClientFault(err.Error())
+}
@@ -0,0 +1,94 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const headerLimitsEnvelope = `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Header>` +
+	`<Token xmlns="test:auth">abc</Token>` +
+	`<Trace xmlns="test:trace" Envelope:mustUnderstand="1" xmlns:Envelope="http://schemas.xmlsoap.org/soap/envelope/">0123456789</Trace>` +
+	`</Header><Body><Response xmlns="test:call"><attr3>value3</attr3></Response></Body></Envelope>`
+
+func TestHeaderLimits(t *testing.T) {
+	t.Parallel()
+	for i, v := range []struct {
+		limits HeaderLimits
+		want   string
+	}{
+		{limits: HeaderLimits{}},
+		{limits: HeaderLimits{MaxItems: 2, MaxItemBytes: 200, MaxBytes: 300}},
+		{limits: HeaderLimits{MaxItems: 1}, want: "soap: header items exceeds limit 1"},
+		{limits: HeaderLimits{MaxItemBytes: 100}, want: "soap: header item size exceeds limit 100"},
+		{limits: HeaderLimits{MaxBytes: 150}, want: "soap: header size exceeds limit 150"},
+		{limits: HeaderLimits{MustUnderstand: true, Understood: []xml.Name{{Local: "Token"}}}, want: "soap: header {test:trace}Trace is not understood"},
+		{limits: HeaderLimits{MustUnderstand: true, Understood: []xml.Name{{Space: "test:trace", Local: "Trace"}}}},
+	} {
+		var got string
+		if err := v.limits.check([]byte(headerLimitsEnvelope)); err != nil {
+			got = err.Error()
+		}
+
+		if got != v.want {
+			t.Errorf("#%d got: %s, want: %s", i, got, v.want)
+		}
+	}
+}
+
+func TestClient_HeaderLimits(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(headerLimitsEnvelope))
+	}))
+	defer srv.Close()
+
+	err := MustNewClient(srv.URL, Config{HeaderLimits: HeaderLimits{MustUnderstand: true}}).Call(context.Background(), "get", request{}, &response{})
+	if e, ok := err.(*MustUnderstandError); !ok || e.Header.Local != "Trace" {
+		t.Fatalf("got: %v, want: MustUnderstandError", err)
+	}
+
+	err = MustNewClient(srv.URL, Config{HeaderLimits: HeaderLimits{MaxItems: 1}}).Call(context.Background(), "get", request{}, &response{})
+	if e, ok := err.(*LimitError); !ok || e.Max != 1 {
+		t.Fatalf("got: %v, want: LimitError", err)
+	}
+}
+
+func TestServer_HeaderLimits(t *testing.T) {
+	t.Parallel()
+	s := NewServer(ServerConfig{HeaderLimits: HeaderLimits{MaxItems: 2, MustUnderstand: true, Understood: []xml.Name{{Space: "test:trace", Local: "Trace"}}}})
+	s.Handle("get", func(ctx context.Context, r *ServerRequest) (interface{}, error) {
+		return response{Attr3: "value3"}, nil
+	})
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	for i, v := range []struct {
+		header string
+		want   string
+	}{
+		{header: `<Trace xmlns="test:trace" soapenv:mustUnderstand="1">1</Trace>`, want: "value3"},
+		{header: `<a/><b/><c/>`, want: "request header items exceeds limit 2"},
+		{header: `<Lock xmlns="test:lock" soapenv:mustUnderstand="true"/>`, want: "header {test:lock}Lock is not understood"},
+	} {
+		body := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Header>` + v.header +
+			`</soapenv:Header><soapenv:Body><Request xmlns="test:call"/></soapenv:Body></soapenv:Envelope>`
+		req, _ := http.NewRequest("POST", srv.URL, strings.NewReader(body))
+		req.Header.Set("SOAPAction", "get")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(b), v.want) {
+			t.Errorf("#%d got: %s, want: %s", i, b, v.want)
+		}
+	}
+}
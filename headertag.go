@@ -0,0 +1,140 @@
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// headerFields implements request type with fields tagged soap:"header".
+type headerFields struct {
+	// body is the type without header fields, nil when the type has no header fields.
+	body    reflect.Type
+	name    xml.Name
+	headers []int
+	fields  []int
+	err     error
+}
+
+var headerTypes sync.Map // map[reflect.Type]*headerFields
+
+// headersOf returns header fields of the struct type.
+func headersOf(t reflect.Type) *headerFields {
+	if v, ok := headerTypes.Load(t); ok {
+		return v.(*headerFields)
+	}
+
+	h := &headerFields{name: xml.Name{Local: t.Name()}}
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("soap") == "header" {
+			h.headers = append(h.headers, i)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if f.Anonymous && (f.Type.NumMethod() > 0 || reflect.PtrTo(f.Type).NumMethod() > 0) {
+			h.err = fmt.Errorf("soap: embedded field %s of %s must not have methods with soap header fields", f.Name, t)
+		}
+
+		if f.Name == "XMLName" {
+			if i := strings.LastIndexByte(f.Tag.Get("xml"), ' '); i >= 0 {
+				h.name = xml.Name{Space: f.Tag.Get("xml")[:i], Local: strings.Split(f.Tag.Get("xml")[i+1:], ",")[0]}
+			} else if name := strings.Split(f.Tag.Get("xml"), ",")[0]; name != "" {
+				h.name = xml.Name{Local: name}
+			}
+		}
+
+		h.fields = append(h.fields, i)
+		fields = append(fields, f)
+	}
+
+	if len(h.headers) == 0 {
+		h.err = nil
+	} else if h.err == nil {
+		h.body = reflect.StructOf(fields)
+	}
+
+	v, _ := headerTypes.LoadOrStore(t, h)
+	return v.(*headerFields)
+}
+
+// namedHeader implements header item encoded with the name of the xml tag.
+type namedHeader struct {
+	name  xml.Name
+	value interface{}
+}
+
+// MarshalXML implements xml.Marshaler interface.
+func (h namedHeader) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(h.value, xml.StartElement{Name: h.name})
+}
+
+// namedBody implements request content encoded with the name of the original type.
+type namedBody struct {
+	name  xml.Name
+	value interface{}
+}
+
+// MarshalXML implements xml.Marshaler interface.
+func (b namedBody) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(b.value, xml.StartElement{Name: b.name})
+}
+
+// liftHeaders returns request without fields tagged soap:"header" and values of the fields,
+// nil values are skipped. The header element name is taken from the xml tag of the field if set.
+func liftHeaders(request interface{}) (interface{}, []interface{}, error) {
+	v := reflect.ValueOf(request)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return request, nil, nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct || v.Type().Implements(marshalerType) || reflect.PtrTo(v.Type()).Implements(marshalerType) {
+		return request, nil, nil
+	}
+
+	h := headersOf(v.Type())
+	if h.err != nil {
+		return nil, nil, h.err
+	}
+
+	if h.body == nil {
+		return request, nil, nil
+	}
+
+	var headers []interface{}
+	for _, i := range h.headers {
+		f := v.Field(i)
+		if (f.Kind() == reflect.Ptr || f.Kind() == reflect.Interface) && f.IsNil() {
+			continue
+		}
+
+		value := f.Interface()
+		tag := v.Type().Field(i).Tag.Get("xml")
+		if name := strings.Split(tag, ",")[0]; name != "" && name != "-" {
+			n := xml.Name{Local: name}
+			if i := strings.LastIndexByte(name, ' '); i >= 0 {
+				n = xml.Name{Space: name[:i], Local: name[i+1:]}
+			}
+			value = namedHeader{name: n, value: value}
+		}
+		headers = append(headers, value)
+	}
+
+	body := reflect.New(h.body).Elem()
+	for i, j := range h.fields {
+		body.Field(i).Set(v.Field(j))
+	}
+	return namedBody{name: h.name, value: body.Interface()}, headers, nil
+}
+
+var marshalerType = reflect.TypeOf((*xml.Marshaler)(nil)).Elem()
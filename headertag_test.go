@@ -0,0 +1,45 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type sessionHeader struct {
+	XMLName   xml.Name `xml:"urn:session SessionHeader"`
+	SessionID string   `xml:"sessionId"`
+}
+
+type taggedRequest struct {
+	XMLName xml.Name       `xml:"test:call Request"`
+	Session *sessionHeader `soap:"header"`
+	Locale  string         `xml:"urn:session Locale" soap:"header"`
+	Missing *sessionHeader `soap:"header"`
+	Attr1   string         `xml:"attr1,omitempty"`
+}
+
+func TestClient_HeaderTag(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		want := `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Header xmlns="http://schemas.xmlsoap.org/soap/envelope/">` +
+			`<SessionHeader xmlns="urn:session"><sessionId>42</sessionId></SessionHeader><Locale xmlns="urn:session">en</Locale></Header>` +
+			`<Body xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Request xmlns="test:call"><attr1>value1</attr1></Request></Body></Envelope>`
+		if string(b) != want {
+			t.Errorf("got: %s, want: %s", b, want)
+		}
+
+		b, _ = xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	req := &taggedRequest{Session: &sessionHeader{SessionID: "42"}, Locale: "en", Attr1: "value1"}
+	if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "get", req, &response{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,74 @@
+package soap
+
+import (
+	"context"
+	"time"
+)
+
+// HedgePolicy implements hedging of idempotent calls: when the primary endpoint
+// does not respond within Delay, a duplicate request is sent to URL and the first
+// response is used, the other request is canceled.
+type HedgePolicy struct {
+	// URL is the secondary endpoint, the primary one is used when empty.
+	URL   string
+	Delay time.Duration
+	// Actions lists hedged soap actions, calls are hedged only when they are idempotent.
+	Actions []string
+}
+
+func (p *HedgePolicy) hedged(soapAction string) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, a := range p.Actions {
+		if a == soapAction {
+			return true
+		}
+	}
+	return false
+}
+
+type hedgeResult struct {
+	resp *Response
+	err  error
+}
+
+// do runs round trip to the url, hedging it to the secondary endpoint for the hedged actions.
+func (p *HedgePolicy) do(ctx context.Context, url, soapAction string, fn func(ctx context.Context, url string) (*Response, error)) (*Response, error) {
+	if !p.hedged(soapAction) {
+		return fn(ctx, url)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult, 2)
+	run := func(url string) {
+		resp, err := fn(ctx, url)
+		results <- hedgeResult{resp: resp, err: err}
+	}
+	go run(url)
+
+	secondary := p.URL
+	if secondary == "" {
+		secondary = url
+	}
+
+	timer := time.NewTimer(p.Delay)
+	defer timer.Stop()
+
+	pending := 1
+	for {
+		select {
+		case <-timer.C:
+			pending++
+			go run(secondary)
+		case r := <-results:
+			pending--
+			if r.err == nil || pending == 0 {
+				return r.resp, r.err
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_Hedge(t *testing.T) {
+	t.Parallel()
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+		b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "primary"}}})
+		w.Write(b)
+	}))
+	defer primary.Close()
+
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "secondary"}}})
+		w.Write(b)
+	}))
+	defer secondary.Close()
+
+	client := MustNewClient(primary.URL, Config{Hedge: &HedgePolicy{
+		URL:     secondary.URL,
+		Delay:   10 * time.Millisecond,
+		Actions: []string{"get"},
+	}})
+
+	var r response
+	start := time.Now()
+	if err := client.Call(context.Background(), "get", request{}, &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "secondary"; r.Attr3 != want {
+		t.Fatalf("got: %s, want: %s", r.Attr3, want)
+	}
+
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Fatalf("call is not hedged, elapsed %s", elapsed)
+	}
+}
@@ -0,0 +1,76 @@
+package soap
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestInterop_Layouts signs envelopes in the layouts of WCF, Metro and Axis2 (prefixes, whitespace, Id
+// conventions and xml declarations) with the fixed RSA key and compares them with the fixed golden
+// signed envelopes byte-for-byte, PKCS #1 v1.5 signatures are deterministic. The golden envelopes are
+// not captured from the vendor stacks, so the test guards the layouts against regressions only:
+// signatures of real WCF, Metro and Axis2 messages are not covered, encrypted envelopes are not
+// covered since the package does not implement XML encryption.
+func TestInterop_Layouts(t *testing.T) {
+	t.Parallel()
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "interop", "key.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode(b)
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := key.(crypto.Signer)
+
+	keyInfo := `<wsse:SecurityTokenReference xmlns:wsse="` + WSSENS + `"><wsse:Reference URI="#X509-1"></wsse:Reference></wsse:SecurityTokenReference>`
+	for _, v := range []struct {
+		name string
+		ids  []string
+	}{
+		{name: "wcf", ids: []string{"_0", "Body-1", "MessageID-1", "To-1", "Action-1"}},
+		{name: "metro", ids: []string{"_1", "_5002", "MessageID-1", "To-1", "Action-1"}},
+		{name: "axis2", ids: []string{"Timestamp-1", "Body-1", "MessageID-1", "To-1", "Action-1"}},
+	} {
+		envelope, err := ioutil.ReadFile(filepath.Join("testdata", "interop", v.name+".xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		envelope, ids, err := withSignedParts(envelope, append(append([]xml.Name{}, DefaultSignedParts...), AddressingParts...))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(ids, v.ids) {
+			t.Errorf("%s got: %v, want: %v", v.name, ids, v.ids)
+		}
+
+		got, err := signEnvelope(envelope, signer, keyInfo, ids)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want, err := ioutil.ReadFile(filepath.Join("testdata", "interop", v.name+".signed.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s got: %s, want: %s", v.name, got, want)
+		}
+
+		if ids, err = VerifySignature(want, signer.Public()); err != nil || !reflect.DeepEqual(ids, v.ids) {
+			t.Errorf("%s got: %v %v, want: %v", v.name, ids, err, v.ids)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+const (
+	jsonAttrPrefix = "@"
+	jsonTextKey    = "#text"
+)
+
+// JSON implements bridging between json documents and soap content.
+//
+// Object keys become child elements, keys prefixed with "@" become attributes
+// and the "#text" key becomes character data. Arrays are encoded as repeated
+// elements. On decode elements holding only character data become strings
+// and repeated elements become arrays.
+type JSON struct {
+	XMLName xml.Name
+	Data    json.RawMessage
+}
+
+// NewJSON creates json content with the root element name.
+func NewJSON(name xml.Name, data []byte) *JSON {
+	return &JSON{XMLName: name, Data: data}
+}
+
+// MarshalXML implements xml.Marshaler interface.
+func (j JSON) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if j.XMLName.Local != "" {
+		start.Name = j.XMLName
+	}
+
+	data := j.Data
+	if len(data) == 0 {
+		data = []byte("null")
+	}
+	return encodeJSON(e, data, start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler interface.
+func (j *JSON) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	v, err := decodeElement(d, start)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	j.XMLName = start.Name
+	j.Data = data
+	return nil
+}
+
+// JSONToXML converts json document into xml element with the name.
+func JSONToXML(name xml.Name, data []byte) ([]byte, error) {
+	b, err := xml.Marshal(JSON{XMLName: name, Data: data})
+	if err != nil {
+		return nil, encodeError(err)
+	}
+	return b, nil
+}
+
+// XMLToJSON converts xml element into json document.
+func XMLToJSON(data []byte) ([]byte, error) {
+	var j JSON
+	if err := xml.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("soap: %s", err)
+	}
+	return j.Data, nil
+}
+
+type jsonField struct {
+	key   string
+	value json.RawMessage
+}
+
+func encodeJSON(e *xml.Encoder, data []byte, start xml.StartElement) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	token, err := d.Token()
+	if err != nil {
+		return fmt.Errorf("soap: %s", err)
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		if t == '[' {
+			for d.More() {
+				var item json.RawMessage
+				if err := d.Decode(&item); err != nil {
+					return fmt.Errorf("soap: %s", err)
+				}
+
+				if err := encodeJSON(e, item, start); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		var fields []jsonField
+		for d.More() {
+			key, err := d.Token()
+			if err != nil {
+				return fmt.Errorf("soap: %s", err)
+			}
+
+			var value json.RawMessage
+			if err := d.Decode(&value); err != nil {
+				return fmt.Errorf("soap: %s", err)
+			}
+			fields = append(fields, jsonField{key: key.(string), value: value})
+		}
+		return encodeJSONObject(e, fields, start)
+	case nil:
+		// null is omitted
+		return nil
+	default:
+		return encodeText(e, start, jsonScalar(t))
+	}
+}
+
+func encodeJSONObject(e *xml.Encoder, fields []jsonField, start xml.StartElement) error {
+	var (
+		text     string
+		children []jsonField
+	)
+
+	for _, f := range fields {
+		switch {
+		case f.key == jsonTextKey:
+			v, err := scalarJSON(f.value)
+			if err != nil {
+				return err
+			}
+			text = v
+		case strings.HasPrefix(f.key, jsonAttrPrefix):
+			name := strings.TrimPrefix(f.key, jsonAttrPrefix)
+			if !isXMLName(name) {
+				return fmt.Errorf("soap: json key %q is not xml name", f.key)
+			}
+
+			v, err := scalarJSON(f.value)
+			if err != nil {
+				return err
+			}
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: name}, Value: v})
+		case !isXMLName(f.key):
+			return fmt.Errorf("soap: json key %q is not xml name", f.key)
+		default:
+			children = append(children, f)
+		}
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	if text != "" {
+		if err := e.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range children {
+		if err := encodeJSON(e, f.value, xml.StartElement{Name: xml.Name{Local: f.key}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// isXMLName reports whether the string matches Name production of XML 1.0.
+func isXMLName(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i, r := range s {
+		if !isNameStartChar(r) && (i == 0 || !isNameChar(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameStartChar(r rune) bool {
+	return r == ':' || r == '_' || 'A' <= r && r <= 'Z' || 'a' <= r && r <= 'z' ||
+		0xC0 <= r && r <= 0xD6 || 0xD8 <= r && r <= 0xF6 || 0xF8 <= r && r <= 0x2FF ||
+		0x370 <= r && r <= 0x37D || 0x37F <= r && r <= 0x1FFF || 0x200C <= r && r <= 0x200D ||
+		0x2070 <= r && r <= 0x218F || 0x2C00 <= r && r <= 0x2FEF || 0x3001 <= r && r <= 0xD7FF ||
+		0xF900 <= r && r <= 0xFDCF || 0xFDF0 <= r && r <= 0xFFFD || 0x10000 <= r && r <= 0xEFFFF
+}
+
+func isNameChar(r rune) bool {
+	return r == '-' || r == '.' || '0' <= r && r <= '9' || r == 0xB7 ||
+		0x300 <= r && r <= 0x36F || 0x203F <= r && r <= 0x2040
+}
+
+func encodeText(e *xml.Encoder, start xml.StartElement, text string) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	if err := e.EncodeToken(xml.CharData(text)); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+func scalarJSON(data json.RawMessage) (string, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	token, err := d.Token()
+	if err != nil {
+		return "", fmt.Errorf("soap: %s", err)
+	}
+
+	if _, ok := token.(json.Delim); ok {
+		return "", fmt.Errorf("soap: json attribute and text values must be scalar")
+	}
+	return jsonScalar(token), nil
+}
+
+func jsonScalar(token json.Token) string {
+	switch v := token.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// decodeElement decodes element into string or map[string]interface{},
+// repeated child elements are collected into []interface{}.
+func decodeElement(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	var (
+		text     strings.Builder
+		children = make(map[string]interface{})
+	)
+
+	for _, a := range start.Attr {
+		if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" {
+			continue
+		}
+		children[jsonAttrPrefix+a.Name.Local] = a.Value
+	}
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			v, err := decodeElement(d, t)
+			if err != nil {
+				return nil, err
+			}
+
+			switch prev := children[t.Name.Local].(type) {
+			case nil:
+				children[t.Name.Local] = v
+			case []interface{}:
+				children[t.Name.Local] = append(prev, v)
+			default:
+				children[t.Name.Local] = []interface{}{prev, v}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if len(children) == 0 {
+				return s, nil
+			}
+
+			if s != "" {
+				children[jsonTextKey] = s
+			}
+			return children, nil
+		}
+	}
+}
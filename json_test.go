@@ -0,0 +1,87 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_JSONToXML(t *testing.T) {
+	t.Parallel()
+	b, err := JSONToXML(xml.Name{Space: "test:call", Local: "Request"}, []byte(`{"@id":"1","attr1":"value1","items":[1,2],"empty":null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<Request xmlns="test:call" id="1"><attr1>value1</attr1><items>1</items><items>2</items></Request>`
+	if got := string(b); got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+}
+
+func Test_JSONToXMLInjection(t *testing.T) {
+	t.Parallel()
+	for _, in := range []string{
+		`{"a><Admin>true</Admin><b":"x"}`,
+		`{"@c=\"1\" d":"2"}`,
+		`{"1a":"x"}`,
+		`{"":"x"}`,
+	} {
+		if b, err := JSONToXML(xml.Name{Local: "Request"}, []byte(in)); err == nil || !strings.HasPrefix(err.Error(), "soap: json key ") {
+			t.Errorf("%s got: %s %v, want: invalid key", in, b, err)
+		}
+	}
+
+	if _, err := JSONToXML(xml.Name{Local: "Request"}, []byte(`{"ns:a-b.c_1":"x","@xml:lang":"en","é":"x"}`)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_XMLToJSON(t *testing.T) {
+	t.Parallel()
+	b, err := XMLToJSON([]byte(`<Response xmlns="test:call" id="1"><attr3>value3</attr3><items>1</items><items>2</items><note lang="en">text</note></Response>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"@id":"1","attr3":"value3","items":["1","2"],"note":{"#text":"text","@lang":"en"}}`
+	if got := string(b); got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+}
+
+func TestClient_CallJSON(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var req request
+		if err := xml.Unmarshal(body, &Envelope{Body: Body{Content: &req}}); err != nil {
+			t.Fatal(err)
+		}
+
+		if want := "value1"; req.Attr1 != want {
+			t.Fatalf("got: %s, want: %s", req.Attr1, want)
+		}
+
+		b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	var resp JSON
+	if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "", NewJSON(xml.Name{Space: "test:call", Local: "Request"}, []byte(`{"attr1":"value1"}`)), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"attr3":"value3"}`; string(resp.Data) != want {
+		t.Fatalf("got: %s, want: %s", resp.Data, want)
+	}
+}
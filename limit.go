@@ -0,0 +1,126 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LimitError implements error of the exceeded limit.
+type LimitError struct {
+	Limit string
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("soap: %s exceeds limit %d", e.Limit, e.Max)
+}
+
+// limitWriter fails when more than max bytes are written, zero max disables the limit.
+type limitWriter struct {
+	w   io.Writer
+	max int
+	n   int
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if l.max > 0 && l.n+len(p) > l.max {
+		return 0, &LimitError{Limit: "request size", Max: l.max}
+	}
+
+	l.n += len(p)
+	return l.w.Write(p)
+}
+
+// encodeError keeps limit errors and errors of the package marshalers, e.g. JSON, and prefixes others.
+func encodeError(err error) error {
+	if e, ok := err.(*LimitError); ok {
+		return e
+	}
+
+	if strings.HasPrefix(err.Error(), "soap: ") {
+		return err
+	}
+	return fmt.Errorf("soap: %s", err)
+}
+
+// checkDepth checks element depth of the encoded envelope, zero max disables the check.
+func checkDepth(r io.Reader, max int) error {
+	if max <= 0 {
+		return nil
+	}
+
+	d := xml.NewDecoder(r)
+	depth := 0
+	for {
+		token, err := d.RawToken()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("soap: %s", err)
+		}
+
+		switch token.(type) {
+		case xml.StartElement:
+			if depth++; depth > max {
+				return &LimitError{Limit: "request depth", Max: max}
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// DecodeLimits implements limits of the response decoding, zero value disables the limit.
+type DecodeLimits struct {
+	MaxDepth     int
+	MaxTokens    int
+	MaxAttrBytes int
+}
+
+// decoder returns xml decoder of the data enforcing the limits.
+func (l DecodeLimits) decoder(data []byte) *xml.Decoder {
+	return xml.NewTokenDecoder(&limitTokenReader{d: xml.NewDecoder(bytes.NewReader(data)), limits: l})
+}
+
+type limitTokenReader struct {
+	d      *xml.Decoder
+	limits DecodeLimits
+	depth  int
+	tokens int
+}
+
+// Token implements xml.TokenReader interface.
+func (r *limitTokenReader) Token() (xml.Token, error) {
+	token, err := r.d.Token()
+	if err != nil {
+		return token, err
+	}
+
+	if r.tokens++; r.limits.MaxTokens > 0 && r.tokens > r.limits.MaxTokens {
+		return nil, &LimitError{Limit: "response tokens", Max: r.limits.MaxTokens}
+	}
+
+	switch t := token.(type) {
+	case xml.StartElement:
+		if r.depth++; r.limits.MaxDepth > 0 && r.depth > r.limits.MaxDepth {
+			return nil, &LimitError{Limit: "response depth", Max: r.limits.MaxDepth}
+		}
+
+		for _, a := range t.Attr {
+			if r.limits.MaxAttrBytes > 0 && len(a.Value) > r.limits.MaxAttrBytes {
+				return nil, &LimitError{Limit: "response attribute size", Max: r.limits.MaxAttrBytes}
+			}
+		}
+	case xml.EndElement:
+		r.depth--
+	case xml.Directive:
+		if bytes.HasPrefix(bytes.TrimSpace(t), []byte("DOCTYPE")) {
+			return nil, errDTD
+		}
+	}
+	return token, nil
+}
@@ -0,0 +1,83 @@
+package soap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_RequestLimits(t *testing.T) {
+	t.Parallel()
+	for i, v := range []struct {
+		config Config
+		want   string
+	}{
+		{config: Config{MaxRequestBytes: 64}, want: "soap: request size exceeds limit 64"},
+		{config: Config{MaxRequestDepth: 3}, want: "soap: request depth exceeds limit 3"},
+	} {
+		err := MustNewClient("http://127.0.0.1:0", v.config).Call(context.Background(), "", request{Attr1: "value1"}, nil)
+		if _, ok := err.(*LimitError); !ok || err.Error() != v.want {
+			t.Errorf("#%d got: %v, want: %s", i, err, v.want)
+		}
+	}
+}
+
+func Test_CheckDepth(t *testing.T) {
+	t.Parallel()
+	if err := checkDepth(strings.NewReader(`<a><b><c/></b><b/></a>`), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkDepth(strings.NewReader(`<a><b><c><d/></c></b></a>`), 3); err == nil {
+		t.Fatal("want limit error")
+	}
+}
+
+func TestClient_DecodeLimits(t *testing.T) {
+	t.Parallel()
+	for i, v := range []struct {
+		limits DecodeLimits
+		body   string
+		want   string
+	}{
+		{
+			limits: DecodeLimits{MaxDepth: 3},
+			body:   `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Response xmlns="test:call"><attr3>value3</attr3></Response></Body></Envelope>`,
+			want:   "soap: response depth exceeds limit 3",
+		},
+		{
+			limits: DecodeLimits{MaxTokens: 5},
+			body:   `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Response xmlns="test:call"><attr3>value3</attr3></Response></Body></Envelope>`,
+			want:   "soap: response tokens exceeds limit 5",
+		},
+		{
+			limits: DecodeLimits{MaxAttrBytes: 4},
+			body:   `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Response xmlns="test:call" id="12345"/></Body></Envelope>`,
+			want:   "soap: response attribute size exceeds limit 4",
+		},
+		{
+			body: `<?xml version="1.0"?><!DOCTYPE lolz [<!ENTITY lol "lol">]><Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Response xmlns="test:call"><attr3>&lol;</attr3></Response></Body></Envelope>`,
+			want: errDTD.Error(),
+		},
+		{
+			limits: DecodeLimits{MaxDepth: 4, MaxTokens: 20, MaxAttrBytes: 64},
+			body:   `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Response xmlns="test:call"><attr3>value3</attr3></Response></Body></Envelope>`,
+		},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(v.body))
+		}))
+
+		var got string
+		if err := MustNewClient(srv.URL, Config{DecodeLimits: v.limits}).Call(context.Background(), "", request{}, &response{}); err != nil {
+			got = err.Error()
+		}
+		srv.Close()
+
+		if got != v.want {
+			t.Errorf("#%d got: %s, want: %s", i, got, v.want)
+		}
+	}
+}
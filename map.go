@@ -0,0 +1,29 @@
+package soap
+
+import "encoding/xml"
+
+// Map implements generic response content decoded like json into interface{}, e.g. for
+// exploratory tools and gateways forwarding data. Child elements become keys, repeated
+// elements become []interface{} and elements holding only character data become strings,
+// attributes are keyed with "@" prefix and character data next to children with "#text" key.
+// Namespaces are dropped, nested elements are map[string]interface{}.
+type Map map[string]interface{}
+
+// UnmarshalXML implements xml.Unmarshaler interface.
+func (m *Map) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	v, err := decodeElement(d, start)
+	if err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		*m = v
+	case string:
+		*m = Map{}
+		if v != "" {
+			(*m)[jsonTextKey] = v
+		}
+	}
+	return nil
+}
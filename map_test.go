@@ -0,0 +1,35 @@
+package soap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClient_Map(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body>` +
+			`<Response xmlns="test:call" id="1"><attr3>value3</attr3><Item>a</Item><Item>b</Item>` +
+			`<Price currency="EUR">10</Price><Empty/></Response></Body></Envelope>`))
+	}))
+	defer srv.Close()
+
+	var m Map
+	if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "", request{}, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Map{
+		"@id":   "1",
+		"attr3": "value3",
+		"Item":  []interface{}{"a", "b"},
+		"Price": map[string]interface{}{"@currency": "EUR", "#text": "10"},
+		"Empty": "",
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("got: %v, want: %v", m, want)
+	}
+}
@@ -0,0 +1,40 @@
+package soap
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Request implements encoded soap request passed through the middleware chain.
+type Request struct {
+	URL    string
+	Action string
+	// Header is sent as http headers, SOAPAction and Content-Type unless present are set by the client.
+	Header   http.Header
+	Envelope []byte
+	// Body is sent instead of Envelope when set, e.g. by middleware streaming large packages.
+	// It is spooled on the first read, so hedged requests and redirects replay it in full.
+	Body io.Reader
+}
+
+// Response implements raw soap response passed through the middleware chain.
+type Response struct {
+	Status     string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// RoundTripFunc sends request and returns raw response.
+type RoundTripFunc func(ctx context.Context, r *Request) (*Response, error)
+
+// Middleware wraps round trip, e.g. for caching or auditing.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+func chain(rt RoundTripFunc, middleware []Middleware) RoundTripFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		rt = middleware[i](rt)
+	}
+	return rt
+}
@@ -0,0 +1,304 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// XOPNS is namespace of XOP include element.
+const XOPNS = "http://www.w3.org/2004/08/xop/include"
+
+const mtomRoot = "<root.message@soap>"
+
+// MTOM implements MTOM/XOP packaging, content of base64 elements is sent as binary MIME parts.
+// Multipart responses are unpacked, xop:Include elements are replaced with base64 content.
+type MTOM struct {
+	// Elements lists local names of base64 elements sent as attachments, e.g. "Document" of IHE XDS.b.
+	Elements []string
+	// ContentType is type of the attachments, application/octet-stream when empty.
+	ContentType string
+	// StartInfo is type of the soap part, text/xml when empty.
+	StartInfo string
+	// Sniff detects type of the attachments by the content, ContentType is used when it is not detected.
+	// Type of the received attachments is detected when the part has no type or application/octet-stream.
+	Sniff bool
+	// MaxSize limits size of each sent and received attachment, unlimited when zero.
+	MaxSize int
+	// AllowedTypes lists media types of the sent and received attachments, e.g. "application/pdf"
+	// or "image/*", any type is allowed when empty.
+	AllowedTypes []string
+	// Validate is called with each sent and received attachment, the error fails the call.
+	Validate func(a Attachment) error
+}
+
+// Attachment implements binary MIME part of the message.
+type Attachment struct {
+	// ID is content id without angle brackets.
+	ID          string
+	ContentType string
+	Data        []byte
+	// Received is set for attachments of the response.
+	Received bool
+}
+
+// attachmentType returns type of the attachment, the declared one is replaced by the sniffed one if enabled.
+func (m *MTOM) attachmentType(declared string, data []byte) string {
+	if !m.Sniff {
+		return declared
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(declared); declared != "" && mediaType != "application/octet-stream" {
+		return declared
+	}
+
+	if detected := http.DetectContentType(data); !strings.HasPrefix(detected, "application/octet-stream") {
+		return detected
+	}
+	return declared
+}
+
+// check validates the attachment.
+func (m *MTOM) check(a Attachment) error {
+	if m.MaxSize > 0 && len(a.Data) > m.MaxSize {
+		return fmt.Errorf("soap: attachment %s exceeds limit %d", a.ID, m.MaxSize)
+	}
+
+	if len(m.AllowedTypes) > 0 {
+		mediaType, _, _ := mime.ParseMediaType(a.ContentType)
+		allowed := false
+		for _, t := range m.AllowedTypes {
+			if t == mediaType || t == "*/*" || strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, t[:len(t)-1]) {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return fmt.Errorf("soap: attachment %s type %q is not allowed", a.ID, a.ContentType)
+		}
+	}
+
+	if m.Validate != nil {
+		if err := m.Validate(a); err != nil {
+			return fmt.Errorf("soap: attachment %s: %s", a.ID, err)
+		}
+	}
+	return nil
+}
+
+// Middleware returns middleware packaging requests and unpacking responses.
+func (m *MTOM) Middleware() Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			req, err := m.pack(r)
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := next(ctx, req)
+			if err != nil {
+				return resp, err
+			}
+			return m.unpack(resp)
+		}
+	}
+}
+
+func (m *MTOM) pack(r *Request) (*Request, error) {
+	root, err := parseDoc(r.Envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	elements := make(map[string]bool, len(m.Elements))
+	for _, e := range m.Elements {
+		elements[e] = true
+	}
+
+	var found []*xnode
+	var walk func(n *xnode)
+	walk = func(n *xnode) {
+		for _, c := range n.children {
+			if c, ok := c.(*xnode); ok {
+				if elements[c.name.Local] && c.contentEnd > c.contentStart {
+					found = append(found, c)
+					continue
+				}
+				walk(c)
+			}
+		}
+	}
+	walk(root)
+
+	if len(found) == 0 {
+		return r, nil
+	}
+
+	// replace from the end to keep offsets
+	sort.Slice(found, func(i, j int) bool { return found[i].start > found[j].start })
+	envelope := r.Envelope
+	parts := make([][]byte, len(found))
+	for i, n := range found {
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(n.text()), ""))
+		if err != nil {
+			return nil, fmt.Errorf("soap: element %s: %s", n.name.Local, err)
+		}
+		parts[len(found)-1-i] = data
+
+		include := `<xop:Include xmlns:xop="` + XOPNS + `" href="cid:` + strconv.Itoa(len(found)-i) + `@soap"></xop:Include>`
+		envelope = append(append(append([]byte(nil), envelope[:n.contentStart]...), include...), envelope[n.contentEnd:]...)
+	}
+
+	startInfo := m.StartInfo
+	if startInfo == "" {
+		startInfo = "text/xml"
+	}
+
+	contentType := m.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	pw, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {`application/xop+xml; charset=UTF-8; type="` + startInfo + `"`},
+		"Content-Transfer-Encoding": {"binary"},
+		"Content-Id":                {mtomRoot},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("soap: %s", err)
+	}
+	pw.Write(envelope)
+
+	for i, data := range parts {
+		a := Attachment{ID: strconv.Itoa(i+1) + "@soap", ContentType: m.attachmentType(contentType, data), Data: data}
+		if err := m.check(a); err != nil {
+			return nil, err
+		}
+
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"binary"},
+			"Content-Id":                {"<" + strconv.Itoa(i+1) + "@soap>"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("soap: %s", err)
+		}
+		pw.Write(data)
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("soap: %s", err)
+	}
+
+	req := *r
+	req.Header = make(http.Header, len(r.Header)+1)
+	for k, v := range r.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", mime.FormatMediaType("multipart/related", map[string]string{
+		"type":       "application/xop+xml",
+		"start":      mtomRoot,
+		"start-info": startInfo,
+		"boundary":   w.Boundary(),
+	}))
+	req.Envelope = buf.Bytes()
+	return &req, nil
+}
+
+// unpack returns response with the soap part of multipart response as the body.
+func (m *MTOM) unpack(resp *Response) (*Response, error) {
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/related" {
+		return resp, nil
+	}
+
+	var (
+		envelope []byte
+		parts    = make(map[string][]byte)
+	)
+	mr := multipart.NewReader(bytes.NewReader(resp.Body), params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("soap: %s", err)
+		}
+
+		data, err := ioutil.ReadAll(p)
+		if err != nil {
+			return nil, fmt.Errorf("soap: %s", err)
+		}
+
+		id := p.Header.Get("Content-Id")
+		if envelope == nil && (params["start"] == "" || id == params["start"]) {
+			envelope = data
+			continue
+		}
+
+		a := Attachment{ID: strings.Trim(id, "<>"), ContentType: m.attachmentType(p.Header.Get("Content-Type"), data), Data: data, Received: true}
+		if err := m.check(a); err != nil {
+			return nil, err
+		}
+		parts[a.ID] = data
+	}
+
+	if envelope == nil {
+		return nil, fmt.Errorf("soap: multipart response has no soap part")
+	}
+
+	if bytes.Contains(envelope, []byte("Include")) {
+		root, err := parseDoc(trimProlog(envelope))
+		if err != nil {
+			return nil, err
+		}
+		envelope = inlineXOP(trimProlog(envelope), root, parts)
+	}
+
+	r := *resp
+	r.Body = envelope
+	return &r, nil
+}
+
+// inlineXOP replaces xop:Include elements with base64 content of the referenced parts.
+func inlineXOP(envelope []byte, root *xnode, parts map[string][]byte) []byte {
+	var includes []*xnode
+	var walk func(n *xnode)
+	walk = func(n *xnode) {
+		for _, c := range n.children {
+			if c, ok := c.(*xnode); ok {
+				if c.name.Local == "Include" && c.namespace(c.name.Space) == XOPNS {
+					includes = append(includes, c)
+					continue
+				}
+				walk(c)
+			}
+		}
+	}
+	walk(root)
+
+	for i := len(includes) - 1; i >= 0; i-- {
+		n := includes[i]
+		href, _ := attr(n, "href")
+		data, ok := parts[strings.TrimPrefix(href, "cid:")]
+		if !ok {
+			continue
+		}
+		envelope = append(append(append([]byte(nil), envelope[:n.start]...), base64.StdEncoding.EncodeToString(data)...), envelope[n.end:]...)
+	}
+	return envelope
+}
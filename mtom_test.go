@@ -0,0 +1,87 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"testing"
+)
+
+type mtomUpload struct {
+	XMLName xml.Name `xml:"test:call Upload"`
+	Data    string   `xml:"Data"`
+}
+
+func TestMTOM(t *testing.T) {
+	t.Parallel()
+	envelope, err := xml.Marshal(Envelope{Body: Body{Content: mtomUpload{Data: "Z29waGVy"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &MTOM{Elements: []string{"Data"}}
+	echo := func(ctx context.Context, r *Request) (*Response, error) {
+		return &Response{StatusCode: 200, Header: http.Header{"Content-Type": r.Header["Content-Type"]}, Body: r.Envelope}, nil
+	}
+
+	var sent []byte
+	rt := chain(echo, []Middleware{m.Middleware(), func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			sent = r.Envelope
+			return next(ctx, r)
+		}
+	}})
+
+	resp, err := rt(context.Background(), &Request{Header: make(http.Header), Envelope: envelope})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(sent, []byte("\r\n\r\ngopher\r\n")) || bytes.Contains(sent, []byte("Z29waGVy")) {
+		t.Fatalf("got: %s, want: binary part", sent)
+	}
+
+	if !bytes.Equal(resp.Body, envelope) {
+		t.Fatalf("got: %s, want: %s", resp.Body, envelope)
+	}
+}
+
+func TestMTOM_Validation(t *testing.T) {
+	t.Parallel()
+	pdf := base64.StdEncoding.EncodeToString([]byte("%PDF-1.4 document"))
+	envelope, err := xml.Marshal(Envelope{Body: Body{Content: mtomUpload{Data: pdf}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	echo := func(ctx context.Context, r *Request) (*Response, error) {
+		return &Response{StatusCode: 200, Header: http.Header{"Content-Type": r.Header["Content-Type"]}, Body: r.Envelope}, nil
+	}
+
+	var validated []Attachment
+	for i, v := range []struct {
+		m   *MTOM
+		err bool
+	}{
+		{m: &MTOM{Sniff: true, AllowedTypes: []string{"application/pdf"}}},
+		{m: &MTOM{Sniff: true, AllowedTypes: []string{"image/*"}}, err: true},
+		{m: &MTOM{AllowedTypes: []string{"application/pdf"}}, err: true},
+		{m: &MTOM{MaxSize: 8}, err: true},
+		{m: &MTOM{Sniff: true, Validate: func(a Attachment) error {
+			validated = append(validated, a)
+			return nil
+		}}},
+	} {
+		v.m.Elements = []string{"Data"}
+		_, err := chain(echo, []Middleware{v.m.Middleware()})(context.Background(), &Request{Header: make(http.Header), Envelope: envelope})
+		if (err != nil) != v.err {
+			t.Errorf("#%d got: %v, want error: %t", i, err, v.err)
+		}
+	}
+
+	if len(validated) != 2 || validated[0].Received || !validated[1].Received || validated[1].ContentType != "application/pdf" {
+		t.Fatalf("got: %+v, want: sent and received pdf", validated)
+	}
+}
@@ -0,0 +1,212 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ResolveMultiRef is response transformer resolving multi-ref references of rpc/encoded
+// responses, e.g. of Axis 1.x services: element with href="#id" is replaced by content and
+// attributes of the element with the id, referenced elements are removed from the body,
+// so response types decode the body as it is inlined. Prefixes of the envelope are kept.
+// Resolved body is limited to 16 times size of the response, *LimitError is returned beyond it,
+// since shared references expand exponentially.
+var ResolveMultiRef RawResponseTransformer = func(ctx context.Context, action string, body []byte) ([]byte, error) {
+	return resolveMultiRef(body, multiRefFactor*len(body))
+}
+
+// multiRefFactor limits growth of the resolved body.
+const multiRefFactor = 16
+
+// ResolveMultiRefLimit returns ResolveMultiRef transformer limiting size of the resolved body to max bytes.
+func ResolveMultiRefLimit(max int) RawResponseTransformer {
+	return func(ctx context.Context, action string, body []byte) ([]byte, error) {
+		return resolveMultiRef(body, max)
+	}
+}
+
+// refElement implements element of the body with id or href.
+type refElement struct {
+	name  xml.Name
+	attr  []xml.Attr
+	id    string
+	href  string
+	depth int
+	// start and end are offsets of the element, inner ones of its content
+	start, innerStart, innerEnd, end int64
+}
+
+func resolveMultiRef(data []byte, max int) ([]byte, error) {
+	if !bytes.Contains(data, []byte("href")) {
+		return data, nil
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var (
+		depth    int
+		body     bool
+		stack    []*refElement
+		elements []*refElement
+		ids      = make(map[string]*refElement)
+	)
+	for {
+		offset := d.InputOffset()
+		token, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// the body is decoded as it is, so the error is reported by decoding
+			return data, nil
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 {
+				body = t.Name.Local == "Body"
+			}
+
+			e := &refElement{name: t.Name, attr: t.Copy().Attr, depth: depth, start: offset, innerStart: d.InputOffset()}
+			if body && depth > 2 {
+				for _, a := range t.Attr {
+					switch {
+					case a.Name.Space == "" && a.Name.Local == "id":
+						e.id = a.Value
+					case a.Name.Space == "" && a.Name.Local == "href" && strings.HasPrefix(a.Value, "#"):
+						e.href = a.Value[1:]
+					}
+				}
+			}
+			stack = append(stack, e)
+		case xml.EndElement:
+			depth--
+			if len(stack) == 0 {
+				return data, nil
+			}
+
+			e := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			e.innerEnd, e.end = offset, d.InputOffset()
+			if e.id != "" {
+				ids[e.id] = e
+			}
+			if e.id != "" || e.href != "" {
+				elements = append(elements, e)
+			}
+		}
+	}
+
+	r := &multiRefResolver{data: data, ids: ids, visiting: make(map[string]bool), max: max}
+	for _, e := range elements {
+		if e.href == "" {
+			continue
+		}
+
+		target, ok := ids[e.href]
+		if !ok {
+			return nil, fmt.Errorf("soap: multi-ref #%s is not found", e.href)
+		}
+		r.edits = append(r.edits, e)
+		if target.depth == 3 {
+			r.edits = append(r.edits, target)
+		}
+	}
+
+	if len(r.edits) == 0 {
+		return data, nil
+	}
+
+	// outer elements go first, so nested ones are skipped
+	sort.Slice(r.edits, func(i, j int) bool {
+		a, b := r.edits[i], r.edits[j]
+		return a.start < b.start || a.start == b.start && a.end > b.end
+	})
+
+	var out bytes.Buffer
+	out.Grow(len(data))
+	if err := r.write(&out, 0, int64(len(data))); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// multiRefResolver writes the data with references inlined.
+type multiRefResolver struct {
+	data []byte
+	ids  map[string]*refElement
+	// edits are references and removed elements sorted by offset
+	edits    []*refElement
+	visiting map[string]bool
+	// max limits size of the written data
+	max int
+}
+
+// write writes the data of the range, references are replaced and referenced body elements are skipped.
+func (r *multiRefResolver) write(out *bytes.Buffer, from, to int64) error {
+	cur := from
+	for _, e := range r.edits {
+		// edits out of the range or nested in the written edit
+		if e.start < cur || e.end > to {
+			continue
+		}
+
+		out.Write(r.data[cur:e.start])
+		cur = e.end
+		if e.href == "" {
+			continue
+		}
+
+		target := r.ids[e.href]
+		if r.visiting[e.href] {
+			return fmt.Errorf("soap: multi-ref #%s is cyclic", e.href)
+		}
+
+		r.visiting[e.href] = true
+		r.writeStart(out, e, target)
+		if err := r.write(out, target.innerStart, target.innerEnd); err != nil {
+			return err
+		}
+		out.WriteString("</" + rawName(e.name) + ">")
+		delete(r.visiting, e.href)
+
+		if err := r.check(out); err != nil {
+			return err
+		}
+	}
+	out.Write(r.data[cur:to])
+	return r.check(out)
+}
+
+// check fails when the written data exceeds the limit.
+func (r *multiRefResolver) check(out *bytes.Buffer) error {
+	if out.Len() > r.max {
+		return &LimitError{Limit: "resolved multi-ref size", Max: r.max}
+	}
+	return nil
+}
+
+// writeStart writes start element of the reference with attributes of the target,
+// href, id and root attributes are dropped.
+func (r *multiRefResolver) writeStart(out *bytes.Buffer, ref, target *refElement) {
+	out.WriteString("<" + rawName(ref.name))
+	seen := make(map[xml.Name]bool)
+	for _, attrs := range [][]xml.Attr{ref.attr, target.attr} {
+		for _, a := range attrs {
+			if seen[a.Name] || a.Name.Space == "" && (a.Name.Local == "href" || a.Name.Local == "id") || a.Name.Space != "" && a.Name.Space != "xmlns" && a.Name.Local == "root" {
+				continue
+			}
+
+			seen[a.Name] = true
+			out.WriteString(" " + rawName(a.Name) + `="`)
+			xml.EscapeText(out, []byte(a.Value))
+			out.WriteByte('"')
+		}
+	}
+	out.WriteByte('>')
+}
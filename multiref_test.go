@@ -0,0 +1,126 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const multiRefResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+<soapenv:Body>
+<ns1:getOrderResponse soapenv:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/" xmlns:ns1="test:call">
+<getOrderReturn href="#id0"/>
+</ns1:getOrderResponse>
+<multiRef id="id0" soapenc:root="0" soapenv:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/" xsi:type="ns2:Order" xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/" xmlns:ns2="test:types">
+<id>7</id>
+<customer href="#id1"/>
+<lines href="#id2"/>
+<lines href="#id2"/>
+</multiRef>
+<multiRef id="id1" soapenc:root="0" xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/"><name>John</name></multiRef>
+<multiRef id="id2" soapenc:root="0" xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/"><sku>A1</sku></multiRef>
+</soapenv:Body>
+</soapenv:Envelope>`
+
+type multiRefOrder struct {
+	XMLName xml.Name `xml:"test:call getOrderResponse"`
+	Order   struct {
+		Type     string `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr"`
+		ID       int    `xml:"id"`
+		Customer string `xml:"customer>name"`
+		Lines    []struct {
+			SKU string `xml:"sku"`
+		} `xml:"lines"`
+	} `xml:"getOrderReturn"`
+}
+
+func TestResolveMultiRef(t *testing.T) {
+	t.Parallel()
+	got, err := ResolveMultiRef(context.Background(), "", []byte(multiRefResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var env struct {
+		Body struct {
+			Content []byte `xml:",innerxml"`
+		}
+	}
+	if err := xml.Unmarshal(got, &env); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `
+<ns1:getOrderResponse soapenv:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/" xmlns:ns1="test:call">
+<getOrderReturn soapenv:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/" xsi:type="ns2:Order" xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/" xmlns:ns2="test:types">
+<id>7</id>
+<customer xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/"><name>John</name></customer>
+<lines xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/"><sku>A1</sku></lines>
+<lines xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/"><sku>A1</sku></lines>
+</getOrderReturn>
+</ns1:getOrderResponse>
+
+
+
+`
+	if string(env.Body.Content) != want {
+		t.Fatalf("got: %s, want: %s", env.Body.Content, want)
+	}
+
+	for _, data := range []string{
+		`<Envelope><Body><a href="#id0"/></Body></Envelope>`,
+		`<Envelope><Body><a href="#id0"/><b id="id0"><c href="#id0"/></b></Body></Envelope>`,
+	} {
+		if _, err := ResolveMultiRef(context.Background(), "", []byte(data)); err == nil {
+			t.Errorf("got: nil, want: error of %s", data)
+		}
+	}
+}
+
+func TestClient_ResolveMultiRef(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(multiRefResponse))
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{RawResponseTransformers: []RawResponseTransformer{ResolveMultiRef}})
+	resp := &multiRefOrder{}
+	if err := client.Call(context.Background(), "getOrder", request{}, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	o := resp.Order
+	if o.Type != "ns2:Order" || o.ID != 7 || o.Customer != "John" || len(o.Lines) != 2 || o.Lines[1].SKU != "A1" {
+		t.Fatalf("got: %+v, want: inlined order", o)
+	}
+}
+
+func TestResolveMultiRef_Bomb(t *testing.T) {
+	t.Parallel()
+	var b strings.Builder
+	b.WriteString(`<Envelope><Body><r href="#a0"/>`)
+	for i := 0; i < 22; i++ {
+		fmt.Fprintf(&b, `<m id="a%d"><p href="#a%d"/><q href="#a%d"/></m>`, i, i+1, i+1)
+	}
+	b.WriteString(`<m id="a22">x</m></Body></Envelope>`)
+
+	_, err := ResolveMultiRef(context.Background(), "", []byte(b.String()))
+	if e, ok := err.(*LimitError); !ok || e.Max != 16*b.Len() {
+		t.Fatalf("got: %v, want: LimitError", err)
+	}
+
+	_, err = ResolveMultiRefLimit(1000)(context.Background(), "", []byte(b.String()))
+	if e, ok := err.(*LimitError); !ok || e.Max != 1000 {
+		t.Fatalf("got: %v, want: LimitError", err)
+	}
+
+	if _, err := ResolveMultiRefLimit(1000)(context.Background(), "", []byte(multiRefResponse)); err != nil {
+		t.Fatal(err)
+	}
+}
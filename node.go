@@ -0,0 +1,252 @@
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Node implements generic xml element, it may be used as body or header content
+// when the structure is not known at compile time.
+type Node struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr
+	Children []*Node
+	CharData string
+
+	// ns keeps prefixed namespace declarations of the parsed element, so prefixes
+	// used by qname values, e.g. xsi:type="tns:Foo", stay declared
+	ns []xml.Attr
+	// lead is character data before the first child and tail after the element, they keep
+	// order of mixed content while CharData is not changed since parsing
+	lead, tail, parsed string
+}
+
+// NewNode creates node with the name and character data.
+func NewNode(space, local, chardata string) *Node {
+	return &Node{XMLName: xml.Name{Space: space, Local: local}, CharData: chardata}
+}
+
+// Add appends children nodes and returns the node.
+func (n *Node) Add(children ...*Node) *Node {
+	n.Children = append(n.Children, children...)
+	return n
+}
+
+// SetAttr sets attribute value and returns the node.
+func (n *Node) SetAttr(space, local, value string) *Node {
+	for i, a := range n.Attrs {
+		if a.Name.Space == space && a.Name.Local == local {
+			n.Attrs[i].Value = value
+			return n
+		}
+	}
+
+	n.Attrs = append(n.Attrs, xml.Attr{Name: xml.Name{Space: space, Local: local}, Value: value})
+	return n
+}
+
+// Attr returns attribute value by the local name.
+func (n *Node) Attr(local string) (string, bool) {
+	for _, a := range n.Attrs {
+		if a.Name.Local == local {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// Child returns the first child node by the local name.
+func (n *Node) Child(local string) *Node {
+	for _, c := range n.Children {
+		if c.XMLName.Local == local {
+			return c
+		}
+	}
+	return nil
+}
+
+// Text returns trimmed character data.
+func (n *Node) Text() string {
+	if n == nil {
+		return ""
+	}
+	return strings.TrimSpace(n.CharData)
+}
+
+// MarshalXML implements xml.Marshaler interface.
+func (n Node) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return n.encode(e, "", false, nil)
+}
+
+// encode encodes the node with namespace declarations and order of mixed content kept since parsing.
+// Unqualified element undeclares default namespace of the parent when undeclare is set, since
+// the encoder declares default namespace of the qualified ones.
+func (n *Node) encode(e *xml.Encoder, parent string, undeclare bool, prefixes map[string]string) error {
+	start := xml.StartElement{Name: n.XMLName, Attr: make([]xml.Attr, 0, len(n.ns)+len(n.Attrs)+1)}
+	if undeclare && n.XMLName.Space == "" && parent != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns"}})
+	}
+
+	if len(n.ns) > 0 {
+		// declared prefixes are used by the attributes, so the encoder does not declare the same prefix again
+		scope := make(map[string]string, len(prefixes)+len(n.ns))
+		for space, prefix := range prefixes {
+			scope[space] = prefix
+		}
+		for _, a := range n.ns {
+			prefix := strings.TrimPrefix(a.Name.Local, "xmlns:")
+			for space, p := range scope {
+				if p == prefix {
+					delete(scope, space)
+				}
+			}
+			scope[a.Value] = prefix
+		}
+		prefixes = scope
+		start.Attr = append(start.Attr, n.ns...)
+	}
+
+	for _, a := range n.Attrs {
+		if prefix, ok := prefixes[a.Name.Space]; ok && a.Name.Space != "" {
+			a.Name = xml.Name{Local: prefix + ":" + a.Name.Local}
+		}
+		start.Attr = append(start.Attr, a)
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	mixed := len(n.Children) > 0 && n.CharData == n.parsed
+	text := n.CharData
+	if mixed {
+		text = n.lead
+	}
+	if text != "" {
+		if err := e.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range n.Children {
+		if err := c.encode(e, n.XMLName.Space, undeclare, prefixes); err != nil {
+			return err
+		}
+
+		if mixed && c.tail != "" {
+			if err := e.EncodeToken(xml.CharData(c.tail)); err != nil {
+				return err
+			}
+		}
+	}
+	return e.EncodeToken(xml.EndElement{Name: n.XMLName})
+}
+
+// UnmarshalXML implements xml.Unmarshaler interface.
+func (n *Node) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	n.XMLName = start.Name
+	// default namespace is declared by the encoder, prefixed declarations are kept for qname values
+	n.Attrs = stripNS(start).Attr
+	n.ns = n.ns[:0]
+	for _, a := range start.Attr {
+		if a.Name.Space == "xmlns" {
+			n.ns = append(n.ns, xml.Attr{Name: xml.Name{Local: "xmlns:" + a.Name.Local}, Value: a.Value})
+		}
+	}
+	n.Children = n.Children[:0]
+
+	var text, segment strings.Builder
+	// flush assigns the text segment before the child or the end of the node
+	flush := func() {
+		if len(n.Children) == 0 {
+			n.lead = segment.String()
+		} else {
+			n.Children[len(n.Children)-1].tail = segment.String()
+		}
+		segment.Reset()
+	}
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			flush()
+			c := new(Node)
+			if err := c.UnmarshalXML(d, t); err != nil {
+				return err
+			}
+			n.Children = append(n.Children, c)
+		case xml.CharData:
+			text.Write(t)
+			segment.Write(t)
+		case xml.EndElement:
+			flush()
+			n.CharData = text.String()
+			// indentation between children is not content
+			if len(n.Children) > 0 && strings.TrimSpace(n.CharData) == "" {
+				n.CharData = ""
+			}
+			n.parsed = n.CharData
+			return nil
+		}
+	}
+}
+
+// ParseNode parses xml document into node.
+func ParseNode(data []byte) (*Node, error) {
+	n := new(Node)
+	if err := xml.Unmarshal(data, n); err != nil {
+		return nil, fmt.Errorf("soap: %s", err)
+	}
+	return n, nil
+}
+
+// Find returns the first node matched by the path, nil if nothing is found.
+// The path is a slash separated list of local names starting with the node itself,
+// "*" matches any element, e.g. "Envelope/Body/*/Result".
+func (n *Node) Find(path string) *Node {
+	if found := n.FindAll(path); len(found) > 0 {
+		return found[0]
+	}
+	return nil
+}
+
+// FindAll returns all nodes matched by the path.
+func (n *Node) FindAll(path string) []*Node {
+	if n == nil || path == "" {
+		return nil
+	}
+
+	steps := strings.Split(strings.Trim(path, "/"), "/")
+	if !matchStep(n, steps[0]) {
+		return nil
+	}
+
+	found := []*Node{n}
+	for _, step := range steps[1:] {
+		var next []*Node
+		for _, f := range found {
+			for _, c := range f.Children {
+				if matchStep(c, step) {
+					next = append(next, c)
+				}
+			}
+		}
+		found = next
+	}
+	return found
+}
+
+// Value returns trimmed character data of the first node matched by the path.
+func (n *Node) Value(path string) string {
+	return n.Find(path).Text()
+}
+
+func matchStep(n *Node, step string) bool {
+	return step == "*" || n.XMLName.Local == step
+}
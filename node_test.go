@@ -0,0 +1,74 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_NodeMarshal(t *testing.T) {
+	t.Parallel()
+	n := NewNode("test:call", "Request", "").SetAttr("", "id", "1").Add(NewNode("", "attr1", "value1"))
+	b, err := xml.Marshal(Envelope{Body: Body{Content: n}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Request xmlns="test:call" id="1"><attr1>value1</attr1></Request></Body></Envelope>`
+	if got := string(b); got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+}
+
+func TestClient_CallNode(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body>
+			<Response xmlns="test:call" id="2">
+				<attr3> value3 </attr3>
+			</Response>
+		</Body></Envelope>`))
+	}))
+	defer srv.Close()
+
+	var resp Node
+	if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "", NewNode("test:call", "Request", ""), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (xml.Name{Space: "test:call", Local: "Response"}); resp.XMLName != want {
+		t.Fatalf("got: %v, want: %v", resp.XMLName, want)
+	}
+
+	if id, _ := resp.Attr("id"); id != "2" {
+		t.Fatalf("got: %s, want: %s", id, "2")
+	}
+
+	if got, want := resp.Child("attr3").Text(), "value3"; got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+}
+
+func Test_NodeFind(t *testing.T) {
+	t.Parallel()
+	n, err := ParseNode([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Response xmlns="test:call"><Result>one</Result><Result>two</Result></Response></Body></Envelope>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := n.Value("Envelope/Body/*/Result"), "one"; got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+
+	if got, want := len(n.FindAll("Envelope/Body/Response/Result")), 2; got != want {
+		t.Fatalf("got: %d, want: %d", got, want)
+	}
+
+	for _, path := range []string{"Body/Response", "Envelope/Header", ""} {
+		if got := n.Find(path); got != nil {
+			t.Errorf("%q got: %v, want: nil", path, got)
+		}
+	}
+}
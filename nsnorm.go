@@ -0,0 +1,77 @@
+package soap
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// namespaces maps keys of equivalent namespace uris to the uris expected by the response types.
+type namespaces map[string]string
+
+func newNamespaces(uris []string) namespaces {
+	if len(uris) == 0 {
+		return nil
+	}
+
+	ns := namespaces{namespaceKey(envelopeNS): envelopeNS}
+	for _, uri := range uris {
+		ns[namespaceKey(uri)] = uri
+	}
+	return ns
+}
+
+// namespaceKey returns key of the uri ignoring trailing slashes and https scheme,
+// e.g. https://example.com/ns/ is equivalent to http://example.com/ns.
+func namespaceKey(uri string) string {
+	key := strings.TrimRight(uri, "/")
+	if len(key) > len("https://") && strings.EqualFold(key[:len("https://")], "https://") {
+		return "http://" + key[len("https://"):]
+	}
+	if len(key) > len("http://") && strings.EqualFold(key[:len("http://")], "http://") {
+		return "http://" + key[len("http://"):]
+	}
+	return key
+}
+
+// normalize returns expected uri of the equivalent one.
+func (ns namespaces) normalize(uri string) string {
+	if v, ok := ns[namespaceKey(uri)]; ok {
+		return v
+	}
+	return uri
+}
+
+// nsTokenReader rewrites equivalent namespaces of the elements and attributes.
+type nsTokenReader struct {
+	r  xml.TokenReader
+	ns namespaces
+}
+
+// Token implements xml.TokenReader interface.
+func (r *nsTokenReader) Token() (xml.Token, error) {
+	token, err := r.r.Token()
+	if err != nil {
+		return token, err
+	}
+
+	switch t := token.(type) {
+	case xml.StartElement:
+		t.Name.Space = r.ns.normalize(t.Name.Space)
+		attrs := make([]xml.Attr, len(t.Attr))
+		for i, a := range t.Attr {
+			switch {
+			case a.Name.Space == "xmlns" || a.Name.Space == "" && a.Name.Local == "xmlns":
+				a.Value = r.ns.normalize(a.Value)
+			case a.Name.Space != "":
+				a.Name.Space = r.ns.normalize(a.Name.Space)
+			}
+			attrs[i] = a
+		}
+		t.Attr = attrs
+		return t, nil
+	case xml.EndElement:
+		t.Name.Space = r.ns.normalize(t.Name.Space)
+		return t, nil
+	}
+	return token, nil
+}
@@ -0,0 +1,56 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNamespaceKey(t *testing.T) {
+	t.Parallel()
+	for i, v := range []struct {
+		a, b string
+		want bool
+	}{
+		{a: "http://example.com/ns", b: "http://example.com/ns/", want: true},
+		{a: "http://example.com/ns", b: "https://example.com/ns", want: true},
+		{a: "http://example.com/ns", b: "HTTPS://example.com/ns//", want: true},
+		{a: "http://example.com/ns", b: "http://example.com/ns2"},
+		{a: "urn:test", b: "urn:test/", want: true},
+	} {
+		if got := namespaceKey(v.a) == namespaceKey(v.b); got != v.want {
+			t.Errorf("#%d got: %t, want: %t", i, got, v.want)
+		}
+	}
+}
+
+func TestClient_Namespaces(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<soap:Envelope xmlns:soap="https://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` +
+			`<m:Response xmlns:m="https://example.com/ns/" m:id="1"><m:Value>ok</m:Value></m:Response></soap:Body></soap:Envelope>`))
+	}))
+	defer srv.Close()
+
+	type resp struct {
+		XMLName xml.Name `xml:"http://example.com/ns Response"`
+		ID      string   `xml:"http://example.com/ns id,attr"`
+		Value   string   `xml:"http://example.com/ns Value"`
+	}
+
+	var v resp
+	if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "", request{}, &v); err == nil {
+		t.Fatal("got: nil, want: error of unexpected namespace")
+	}
+
+	client := MustNewClient(srv.URL, Config{Namespaces: []string{"http://example.com/ns"}})
+	if err := client.Call(context.Background(), "", request{}, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.ID != "1" || v.Value != "ok" {
+		t.Fatalf("got: %+v, want: id 1 and value ok", v)
+	}
+}
@@ -0,0 +1,275 @@
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// nsField implements field of the struct, namespace is taken from soapns:"prefix=uri" tag
+// or from the xml tag with soapns:"prefix", an element without namespace matches any namespace on decode.
+type nsField struct {
+	index     int
+	name      xml.Name
+	prefix    string
+	attr      bool
+	chardata  bool
+	omitempty bool
+}
+
+// qname returns name of the encoded element or attribute.
+func (f nsField) qname() xml.Name {
+	if f.prefix != "" {
+		return xml.Name{Local: f.prefix + ":" + f.name.Local}
+	}
+	return f.name
+}
+
+// nsStruct implements struct type with fields tagged soapns.
+type nsStruct struct {
+	// self is the element name of XMLName field
+	self   *nsField
+	fields []nsField
+	// uris are declared prefixes of the element, prefixes are sorted
+	uris     map[string]string
+	prefixes []string
+	err      error
+}
+
+var nsTypes sync.Map // map[reflect.Type]*nsStruct
+
+func nsOf(t reflect.Type) *nsStruct {
+	if v, ok := nsTypes.Load(t); ok {
+		return v.(*nsStruct)
+	}
+
+	s := &nsStruct{uris: make(map[string]string)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("xml")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+
+		opts := strings.Split(tag, ",")
+		field := nsField{index: i, name: xml.Name{Local: f.Name}}
+		if i := strings.LastIndexByte(opts[0], ' '); i >= 0 {
+			field.name = xml.Name{Space: opts[0][:i], Local: opts[0][i+1:]}
+		} else if opts[0] != "" {
+			field.name.Local = opts[0]
+		}
+
+		for _, o := range opts[1:] {
+			switch o {
+			case "attr":
+				field.attr = true
+			case "chardata":
+				field.chardata = true
+			case "omitempty":
+				field.omitempty = true
+			default:
+				s.err = fmt.Errorf("soap: option %s of field %s of %s is not supported", o, f.Name, t)
+			}
+		}
+
+		if ns := f.Tag.Get("soapns"); ns != "" {
+			field.prefix = ns
+			if i := strings.IndexByte(ns, '='); i >= 0 {
+				field.prefix, field.name.Space = ns[:i], ns[i+1:]
+			}
+
+			if field.prefix == "" || field.name.Space == "" {
+				s.err = fmt.Errorf("soap: soapns tag %q of field %s of %s must be prefix=uri or prefix of the xml tag namespace", ns, f.Name, t)
+				continue
+			}
+
+			if uri, ok := s.uris[field.prefix]; ok && uri != field.name.Space {
+				s.err = fmt.Errorf("soap: prefix %s of %s is bound to several namespaces", field.prefix, t)
+			}
+			s.uris[field.prefix] = field.name.Space
+		}
+
+		if (field.attr || field.chardata) && f.Type.Kind() != reflect.String {
+			s.err = fmt.Errorf("soap: field %s of %s must be string", f.Name, t)
+		}
+
+		if f.Name == "XMLName" {
+			if opts[0] == "" {
+				field.name.Local = ""
+			}
+			s.self = &field
+			continue
+		}
+		s.fields = append(s.fields, field)
+	}
+
+	for p := range s.uris {
+		s.prefixes = append(s.prefixes, p)
+	}
+	sort.Strings(s.prefixes)
+
+	v, _ := nsTypes.LoadOrStore(t, s)
+	return v.(*nsStruct)
+}
+
+func nsStructOf(v reflect.Value) (reflect.Value, *nsStruct, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return v, nil, fmt.Errorf("soap: %s is not a struct", v.Type())
+	}
+
+	s := nsOf(v.Type())
+	return v, s, s.err
+}
+
+// MarshalNS encodes struct with precise namespace control, fields tagged soapns:"prefix=uri"
+// are encoded as prefixed elements or attributes of the namespace. Fields of the same local name
+// in different namespaces take the namespace from the xml tag and only prefix from soapns tag,
+// e.g. `xml:"urn:b Item" soapns:"b"`, since go vet rejects repeated xml tags. Prefixes are declared on the struct element. Only element, attr,
+// chardata and omitempty options of xml tags are supported. Generated types call it from MarshalXML:
+//
+//	func (r Request) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+//		return soap.MarshalNS(e, start, r)
+//	}
+func MarshalNS(e *xml.Encoder, start xml.StartElement, v interface{}) error {
+	rv, s, err := nsStructOf(reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+
+	se := xml.StartElement{Name: start.Name}
+	if s.self != nil && s.self.name.Local != "" {
+		se.Name = s.self.qname()
+	}
+
+	for _, p := range s.prefixes {
+		se.Attr = append(se.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + p}, Value: s.uris[p]})
+	}
+
+	var text string
+	for _, f := range s.fields {
+		fv := rv.Field(f.index)
+		switch {
+		case f.attr:
+			if !f.omitempty || fv.String() != "" {
+				se.Attr = append(se.Attr, xml.Attr{Name: f.qname(), Value: fv.String()})
+			}
+		case f.chardata:
+			text += fv.String()
+		}
+	}
+
+	if err := e.EncodeToken(se); err != nil {
+		return err
+	}
+
+	if text != "" {
+		if err := e.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range s.fields {
+		fv := rv.Field(f.index)
+		if f.attr || f.chardata || f.omitempty && fv.IsZero() {
+			continue
+		}
+
+		if (fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface) && fv.IsNil() {
+			continue
+		}
+
+		if err := e.EncodeElement(fv.Interface(), xml.StartElement{Name: f.qname()}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(se.End())
+}
+
+// UnmarshalNS decodes struct encoded by MarshalNS, child elements and attributes match fields
+// by local name and namespace, fields without namespace match any namespace.
+//
+//	func (r *Response) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+//		return soap.UnmarshalNS(d, start, r)
+//	}
+func UnmarshalNS(d *xml.Decoder, start xml.StartElement, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("soap: UnmarshalNS requires non-nil pointer")
+	}
+
+	rv, s, err := nsStructOf(rv)
+	if err != nil {
+		return err
+	}
+
+	if s.self != nil && rv.Field(s.self.index).Type() == reflect.TypeOf(xml.Name{}) {
+		rv.Field(s.self.index).Set(reflect.ValueOf(start.Name))
+	}
+
+	for _, a := range start.Attr {
+		if f, ok := s.match(a.Name, true); ok {
+			rv.Field(f.index).SetString(a.Value)
+		}
+	}
+
+	var text strings.Builder
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			f, ok := s.match(t.Name, false)
+			if !ok {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := d.DecodeElement(rv.Field(f.index).Addr().Interface(), &t); err != nil {
+				return err
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			for _, f := range s.fields {
+				if f.chardata {
+					rv.Field(f.index).SetString(text.String())
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// match returns field of the element or attribute, the field of the same namespace takes precedence.
+func (s *nsStruct) match(name xml.Name, attr bool) (nsField, bool) {
+	var (
+		found nsField
+		ok    bool
+	)
+	for _, f := range s.fields {
+		if f.attr != attr || f.chardata || f.name.Local != name.Local {
+			continue
+		}
+
+		if f.name.Space == name.Space {
+			return f, true
+		}
+
+		if f.name.Space == "" && !ok {
+			found, ok = f, true
+		}
+	}
+	return found, ok
+}
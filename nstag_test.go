@@ -0,0 +1,71 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type nsRequest struct {
+	XMLName xml.Name `xml:"Request" soapns:"m=urn:message"`
+	ID      string   `xml:"id,attr" soapns:"m=urn:message"`
+	Item    string   `xml:"Item" soapns:"a=urn:a"`
+	Other   string   `xml:"OtherItem" soapns:"b=urn:b"`
+	Plain   string   `xml:"plain,omitempty"`
+}
+
+func (r nsRequest) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return MarshalNS(e, start, r)
+}
+
+type nsResponse struct {
+	XMLName xml.Name
+	ID      string `xml:"id,attr"`
+	A       string `xml:"Item" soapns:"a=urn:a"`
+	B       string `xml:"urn:b Item" soapns:"b"`
+	Any     []int  `xml:"Value"`
+}
+
+func (r *nsResponse) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return UnmarshalNS(d, start, r)
+}
+
+func TestClient_NS(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		want := `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body xmlns="http://schemas.xmlsoap.org/soap/envelope/">` +
+			`<m:Request xmlns:a="urn:a" xmlns:b="urn:b" xmlns:m="urn:message" m:id="1"><a:Item>x</a:Item><b:OtherItem>y</b:OtherItem></m:Request></Body></Envelope>`
+		if string(b) != want {
+			t.Errorf("got: %s, want: %s", b, want)
+		}
+
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body>` +
+			`<r:Response xmlns:r="urn:message" xmlns:a="urn:a" xmlns:b="urn:b" id="2"><b:Item>b</b:Item><a:Item>a</a:Item>` +
+			`<Value>1</Value><a:Value>2</a:Value><Unknown/></r:Response></Body></Envelope>`))
+	}))
+	defer srv.Close()
+
+	var resp nsResponse
+	if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "", nsRequest{ID: "1", Item: "x", Other: "y"}, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.XMLName.Space != "urn:message" || resp.ID != "2" || resp.A != "a" || resp.B != "b" || len(resp.Any) != 2 {
+		t.Fatalf("got: %+v, want: items of both namespaces", resp)
+	}
+}
+
+func TestMarshalNS_Invalid(t *testing.T) {
+	t.Parallel()
+	type invalid struct {
+		A string `xml:"A" soapns:"a="`
+	}
+
+	if err := MarshalNS(xml.NewEncoder(ioutil.Discard), xml.StartElement{}, invalid{}); err == nil {
+		t.Fatal("want error")
+	}
+}
@@ -0,0 +1,121 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+)
+
+// XSINS is namespace of xml schema instance attributes.
+const XSINS = "http://www.w3.org/2001/XMLSchema-instance"
+
+// Presence implements how the element is sent, servers often treat omitted, empty
+// and nil elements differently, e.g. as "keep", "clear" and "unset" of the field.
+type Presence int
+
+const (
+	// ElementOmitted element is not sent, it is the zero value.
+	ElementOmitted Presence = iota
+	// ElementPresent element is sent with the value.
+	ElementPresent
+	// ElementEmpty element is sent without content.
+	ElementEmpty
+	// ElementNil element is sent with xsi:nil="true".
+	ElementNil
+)
+
+// Optional implements element which is omitted, sent empty, sent with xsi:nil or sent with the value.
+// On decode the presence is set and the content is decoded into Value, which must be a pointer
+// when set, otherwise the content is decoded as string.
+type Optional struct {
+	Value    interface{}
+	Presence Presence
+}
+
+// Some returns element sent with the value.
+func Some(v interface{}) Optional {
+	return Optional{Value: v, Presence: ElementPresent}
+}
+
+// MarshalXML implements xml.Marshaler interface.
+func (o Optional) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	switch o.Presence {
+	case ElementPresent:
+		return e.EncodeElement(o.Value, start)
+	case ElementEmpty:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		return e.EncodeToken(start.End())
+	case ElementNil:
+		start.Attr = append(start.Attr,
+			xml.Attr{Name: xml.Name{Local: "xmlns:xsi"}, Value: XSINS},
+			xml.Attr{Name: xml.Name{Local: "xsi:nil"}, Value: "true"})
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		return e.EncodeToken(start.End())
+	}
+	// nothing is written, so the element is omitted
+	return nil
+}
+
+// UnmarshalXML implements xml.Unmarshaler interface.
+func (o *Optional) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, a := range start.Attr {
+		if a.Name.Space == XSINS && a.Name.Local == "nil" && (a.Value == "true" || a.Value == "1") {
+			o.Presence = ElementNil
+			return d.Skip()
+		}
+	}
+
+	// content is buffered to tell empty element
+	tokens := []xml.Token{start.Copy()}
+	empty := true
+	for depth := 1; depth > 0; {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			empty = false
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				empty = false
+			}
+		}
+		tokens = append(tokens, xml.CopyToken(token))
+	}
+
+	if empty {
+		o.Presence = ElementEmpty
+		return nil
+	}
+
+	o.Presence = ElementPresent
+	if o.Value == nil {
+		o.Value = new(string)
+	}
+	return xml.NewTokenDecoder(&tokenSlice{tokens: tokens}).Decode(o.Value)
+}
+
+// tokenSlice implements reader of the buffered tokens.
+type tokenSlice struct {
+	tokens []xml.Token
+}
+
+// Token implements xml.TokenReader interface.
+func (s *tokenSlice) Token() (xml.Token, error) {
+	if len(s.tokens) == 0 {
+		return nil, io.EOF
+	}
+
+	token := s.tokens[0]
+	s.tokens = s.tokens[1:]
+	return token, nil
+}
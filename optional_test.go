@@ -0,0 +1,70 @@
+package soap
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+type optionalRequest struct {
+	XMLName xml.Name `xml:"test:call Update"`
+	Name    Optional `xml:"name"`
+	Phone   Optional `xml:"phone"`
+	Email   Optional `xml:"email"`
+	Age     Optional `xml:"age"`
+}
+
+func TestOptional_Marshal(t *testing.T) {
+	t.Parallel()
+	out, err := xml.Marshal(optionalRequest{
+		Phone: Optional{Presence: ElementEmpty},
+		Email: Optional{Presence: ElementNil},
+		Age:   Some(42),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<Update xmlns="test:call"><phone></phone><email xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:nil="true"></email><age>42</age></Update>`
+	if string(out) != want {
+		t.Fatalf("got: %s, want: %s", out, want)
+	}
+}
+
+func TestOptional_Unmarshal(t *testing.T) {
+	t.Parallel()
+	var age int
+	v := optionalRequest{Age: Optional{Value: &age}}
+	in := `<Update xmlns="test:call" xmlns:i="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<phone> </phone><email i:nil="true"/><age>42</age></Update>`
+	if err := xml.Unmarshal([]byte(in), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, p := range []struct {
+		got, want Presence
+	}{
+		{got: v.Name.Presence, want: ElementOmitted},
+		{got: v.Phone.Presence, want: ElementEmpty},
+		{got: v.Email.Presence, want: ElementNil},
+		{got: v.Age.Presence, want: ElementPresent},
+	} {
+		if p.got != p.want {
+			t.Errorf("#%d got: %d, want: %d", i, p.got, p.want)
+		}
+	}
+
+	if age != 42 {
+		t.Fatalf("got: %d, want: 42", age)
+	}
+
+	var s struct {
+		Name Optional `xml:"name"`
+	}
+	if err := xml.Unmarshal([]byte(`<v><name>value</name></v>`), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, ok := s.Name.Value.(*string); !ok || *got != "value" {
+		t.Fatalf("got: %v, want: value", s.Name.Value)
+	}
+}
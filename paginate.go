@@ -0,0 +1,51 @@
+package soap
+
+import (
+	"context"
+	"time"
+)
+
+// PageConfig implements limits of the pagination.
+type PageConfig struct {
+	// MaxPages stops the pagination after the pages when positive.
+	MaxPages int
+	// Interval is the minimal delay between page calls to respect rate limits of the server.
+	Interval time.Duration
+}
+
+// Paginate calls page with the token of the next page starting with the empty token,
+// consume handles the page response and next extracts the token of the next page from it.
+// The pagination ends on the empty token, an error, the page limit or the done context.
+func Paginate(ctx context.Context, page func(ctx context.Context, token string) (interface{}, error),
+	next func(resp interface{}) (string, error), consume func(resp interface{}) error, c PageConfig) error {
+	var (
+		token string
+		last  time.Time
+	)
+	for n := 1; c.MaxPages <= 0 || n <= c.MaxPages; n++ {
+		if wait := c.Interval - time.Since(last); n > 1 && wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return &transportError{err: ctx.Err()}
+			case <-t.C:
+			}
+		}
+
+		last = time.Now()
+		resp, err := page(ctx, token)
+		if err != nil {
+			return err
+		}
+
+		if err := consume(resp); err != nil {
+			return err
+		}
+
+		if token, err = next(resp); err != nil || token == "" {
+			return err
+		}
+	}
+	return nil
+}
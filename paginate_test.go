@@ -0,0 +1,69 @@
+package soap
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPaginate(t *testing.T) {
+	t.Parallel()
+	pages := map[string][]int{"": {1, 2}, "p2": {3, 4}, "p3": {5}}
+	nextToken := map[string]string{"": "p2", "p2": "p3"}
+
+	type page struct {
+		items []int
+		next  string
+	}
+
+	for i, v := range []struct {
+		config PageConfig
+		want   int
+	}{
+		{config: PageConfig{}, want: 15},
+		{config: PageConfig{MaxPages: 2, Interval: 10 * time.Millisecond}, want: 10},
+	} {
+		var sum int
+		start := time.Now()
+		err := Paginate(context.Background(),
+			func(ctx context.Context, token string) (interface{}, error) {
+				return &page{items: pages[token], next: nextToken[token]}, nil
+			},
+			func(resp interface{}) (string, error) {
+				return resp.(*page).next, nil
+			},
+			func(resp interface{}) error {
+				for _, item := range resp.(*page).items {
+					sum += item
+				}
+				return nil
+			}, v.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if sum != v.want {
+			t.Errorf("#%d got: %d, want: %d", i, sum, v.want)
+		}
+
+		if elapsed := time.Since(start); elapsed < v.config.Interval {
+			t.Errorf("#%d got: %s, want: interval %s", i, elapsed, v.config.Interval)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int
+	err := Paginate(ctx,
+		func(ctx context.Context, token string) (interface{}, error) {
+			calls++
+			cancel()
+			return strconv.Itoa(calls), nil
+		},
+		func(resp interface{}) (string, error) { return "next", nil },
+		func(resp interface{}) error { return nil },
+		PageConfig{Interval: time.Minute})
+	if err == nil || calls != 1 {
+		t.Fatalf("got: %v %d, want: context error", err, calls)
+	}
+}
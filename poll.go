@@ -0,0 +1,59 @@
+package soap
+
+import (
+	"context"
+	"time"
+)
+
+// Backoff returns delay before the attempt, attempts are counted from 1.
+type Backoff func(attempt int) time.Duration
+
+// ConstantBackoff returns backoff with the same delay.
+func ConstantBackoff(d time.Duration) Backoff {
+	return func(int) time.Duration {
+		return d
+	}
+}
+
+// ExponentialBackoff returns backoff doubling the initial delay up to the max one.
+func ExponentialBackoff(initial, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		d := initial
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+
+		if d > max {
+			return max
+		}
+		return d
+	}
+}
+
+// PollUntil implements "submit then poll" pattern: submit is called once, then status is
+// called with the backoff delay until isDone reports true, an error or the context is done.
+// The job id returned by submit is usually shared by the closures.
+func PollUntil(ctx context.Context, submit, status func(ctx context.Context) error, isDone func() (bool, error), backoff Backoff) error {
+	if err := submit(ctx); err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		t := time.NewTimer(backoff(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return &transportError{err: ctx.Err()}
+		case <-t.C:
+		}
+
+		if err := status(ctx); err != nil {
+			return err
+		}
+
+		done, err := isDone()
+		if err != nil || done {
+			return err
+		}
+	}
+}
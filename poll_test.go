@@ -0,0 +1,70 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type job struct {
+	XMLName xml.Name `xml:"test:call Job"`
+	ID      string   `xml:"id,omitempty"`
+	State   string   `xml:"state,omitempty"`
+}
+
+func TestPollUntil(t *testing.T) {
+	t.Parallel()
+	var polls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		resp := job{ID: "1", State: "running"}
+		if r.Header.Get("SOAPAction") == "status" {
+			if polls++; polls == 3 {
+				resp.State = "done"
+			}
+		}
+
+		b, _ := xml.Marshal(Envelope{Body: Body{Content: resp}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{})
+	var submitted, status job
+	if err := PollUntil(context.Background(),
+		func(ctx context.Context) error {
+			return client.Call(ctx, "submit", job{}, &submitted)
+		},
+		func(ctx context.Context) error {
+			return client.Call(ctx, "status", job{ID: submitted.ID}, &status)
+		},
+		func() (bool, error) {
+			return status.State == "done", nil
+		},
+		ConstantBackoff(time.Millisecond),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if polls != 3 {
+		t.Fatalf("got: %d, want: %d", polls, 3)
+	}
+}
+
+func Test_ExponentialBackoff(t *testing.T) {
+	t.Parallel()
+	b := ExponentialBackoff(time.Second, 5*time.Second)
+	for attempt, want := range []time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		if want == 0 {
+			continue
+		}
+
+		if got := b(attempt); got != want {
+			t.Errorf("#%d got: %s, want: %s", attempt, got, want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package soap
+
+import (
+	"container/list"
+	"sync"
+)
+
+// PoolConfigFunc returns endpoint url and config of the tenant client.
+type PoolConfigFunc func(tenant string) (url string, c Config, err error)
+
+type poolEntry struct {
+	tenant string
+	client *Client
+}
+
+// ClientPool implements clients keyed by tenant which are created lazily and cached,
+// the least recently used client is evicted when the pool is full.
+type ClientPool struct {
+	mu      sync.Mutex
+	max     int
+	config  PoolConfigFunc
+	lru     *list.List
+	clients map[string]*list.Element
+}
+
+// NewClientPool creates pool of at most max clients, zero max means unbounded pool.
+func NewClientPool(max int, config PoolConfigFunc) *ClientPool {
+	return &ClientPool{
+		max:     max,
+		config:  config,
+		lru:     list.New(),
+		clients: make(map[string]*list.Element),
+	}
+}
+
+// Get returns client of the tenant, creating it when needed. Config of the tenant is resolved
+// without holding the pool, so slow lookups of one tenant do not block the others.
+func (p *ClientPool) Get(tenant string) (*Client, error) {
+	if client, ok := p.cached(tenant); ok {
+		return client, nil
+	}
+
+	url, c, err := p.config(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(url, c)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// client created concurrently is kept
+	if e, ok := p.clients[tenant]; ok {
+		client.Close()
+		p.lru.MoveToFront(e)
+		return e.Value.(*poolEntry).client, nil
+	}
+
+	p.clients[tenant] = p.lru.PushFront(&poolEntry{tenant: tenant, client: client})
+	if p.max > 0 && p.lru.Len() > p.max {
+		p.remove(p.lru.Back())
+	}
+	return client, nil
+}
+
+// cached returns client of the tenant when it is in the pool.
+func (p *ClientPool) cached(tenant string) (*Client, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e, ok := p.clients[tenant]
+	if !ok {
+		return nil, false
+	}
+
+	p.lru.MoveToFront(e)
+	return e.Value.(*poolEntry).client, true
+}
+
+// Remove evicts client of the tenant, e.g. when its credentials are changed.
+func (p *ClientPool) Remove(tenant string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e, ok := p.clients[tenant]; ok {
+		p.remove(e)
+	}
+}
+
+// Len returns number of cached clients.
+func (p *ClientPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lru.Len()
+}
+
+// Close closes all clients of the pool.
+func (p *ClientPool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, e := range p.clients {
+		e.Value.(*poolEntry).client.Close()
+	}
+	p.lru.Init()
+	p.clients = make(map[string]*list.Element)
+	return nil
+}
+
+// remove evicts the client, its in-flight calls are completed, idle connections are closed
+// now and after the last in-flight call.
+func (p *ClientPool) remove(e *list.Element) {
+	entry := e.Value.(*poolEntry)
+	p.lru.Remove(e)
+	delete(p.clients, entry.tenant)
+	entry.client.drain()
+}
@@ -0,0 +1,160 @@
+package soap
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientPool(t *testing.T) {
+	t.Parallel()
+	var created []string
+	p := NewClientPool(2, func(tenant string) (string, Config, error) {
+		if tenant == "" {
+			return "", Config{}, fmt.Errorf("unknown tenant")
+		}
+
+		created = append(created, tenant)
+		return "http://" + tenant, Config{BasicAuth: &BasicAuth{Username: tenant}}, nil
+	})
+	defer p.Close()
+
+	for _, tenant := range []string{"a", "b", "a", "c", "b"} {
+		c, err := p.Get(tenant)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if c.url != "http://"+tenant {
+			t.Fatalf("got: %s, want: %s", c.url, "http://"+tenant)
+		}
+	}
+
+	// b is evicted by c as the least recently used
+	if got, want := fmt.Sprint(created), "[a b c b]"; got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+
+	if got := p.Len(); got != 2 {
+		t.Fatalf("got: %d, want: %d", got, 2)
+	}
+
+	if _, err := p.Get(""); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestClientPool_SlowConfig(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	p := NewClientPool(0, func(tenant string) (string, Config, error) {
+		if tenant == "slow" {
+			<-release
+		}
+		return "http://" + tenant, Config{}, nil
+	})
+	defer p.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Get("slow")
+		done <- err
+	}()
+
+	got := make(chan error, 1)
+	go func() {
+		_, err := p.Get("a")
+		got <- err
+	}()
+
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("tenant is blocked by config of the other tenant")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Len() != 2 {
+		t.Fatalf("got: %d, want: 2", p.Len())
+	}
+}
+
+func TestClientPool_Evicted(t *testing.T) {
+	t.Parallel()
+	var (
+		mu     sync.Mutex
+		states []http.ConnState
+	)
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Response/></Body></Envelope>`))
+	}))
+	srv.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		mu.Lock()
+		states = append(states, state)
+		mu.Unlock()
+	}
+	srv.Start()
+	defer srv.Close()
+
+	p := NewClientPool(1, func(tenant string) (string, Config, error) { return srv.URL, Config{KeepAlive: true}, nil })
+	defer p.Close()
+
+	c, err := p.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 2)
+	go func() { done <- c.Call(context.Background(), "", request{}, nil) }()
+	<-started
+
+	// a is evicted while its call is in flight and the holder of the client starts another call
+	if _, err := p.Get("b"); err != nil {
+		t.Fatal(err)
+	}
+	go func() { done <- c.Call(context.Background(), "", request{}, nil) }()
+	<-started
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		closed := 0
+		for _, state := range states {
+			if state == http.StateClosed {
+				closed++
+			}
+		}
+		mu.Unlock()
+		if closed == 2 {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("connection of the evicted client is not closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,50 @@
+package soap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+var errPreconnect = fmt.Errorf("soap: preconnect requires keep-alive")
+
+// Preconnect establishes connections to the endpoint ahead of the first call and keeps them idle,
+// Config.PreconnectConns connections are opened at once. HEAD requests are sent with the client
+// authorization through the wrapped transport, so connection-bound NTLM or Kerberos handshakes
+// are primed as well. Status of the responses is ignored, the connections must be kept alive.
+func (s *Client) Preconnect(ctx context.Context) error {
+	if !s.config.KeepAlive {
+		return errPreconnect
+	}
+
+	n := s.config.PreconnectConns
+	if n < 1 {
+		n = 1
+	}
+
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			errs <- s.preconnect(ctx)
+		}()
+	}
+
+	var err error
+	for i := 0; i < n; i++ {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (s *Client) preconnect(ctx context.Context) error {
+	resp, err := s.do(ctx, "HEAD", s.endpoint(), true)
+	if err != nil {
+		return err
+	}
+	// connection is reused only when the body is drained
+	io.Copy(ioutil.Discard, resp.Body)
+	return resp.Body.Close()
+}
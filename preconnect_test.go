@@ -0,0 +1,55 @@
+package soap
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClient_Preconnect(t *testing.T) {
+	t.Parallel()
+	var conns, heads int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			if u, p, _ := r.BasicAuth(); u != "user" || p != "pass" {
+				t.Errorf("got: %s:%s, want: user:pass", u, p)
+			}
+			atomic.AddInt32(&heads, 1)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Response xmlns="test:call"/></Body></Envelope>`))
+	}))
+	srv.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	if err := MustNewClient(srv.URL, Config{}).Preconnect(context.Background()); err != errPreconnect {
+		t.Fatalf("got: %v, want: %s", err, errPreconnect)
+	}
+
+	client := MustNewClient(srv.URL, Config{KeepAlive: true, BasicAuth: &BasicAuth{Username: "user", Password: "pass"}})
+	if err := client.Preconnect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Call(context.Background(), "", request{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := atomic.LoadInt32(&heads), int32(1); got != want {
+		t.Fatalf("got: %d, want: %d", got, want)
+	}
+
+	// the call reuses preconnected connection
+	if got, want := atomic.LoadInt32(&conns), int32(1); got != want {
+		t.Fatalf("got: %d, want: %d", got, want)
+	}
+}
@@ -0,0 +1,195 @@
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// defaultQNamePrefix is prefix of the encoded qname without prefix.
+const defaultQNamePrefix = "qn"
+
+// QName implements xsd:QName value, e.g. wst:Issue. The prefix of the element value is resolved
+// into namespace on decode by declarations in scope of the element, and it is declared on encode.
+// Attributes are encoded with the prefix which must be declared by the ancestors,
+// they are decoded with the prefix kept unresolved since the scope is not known.
+type QName struct {
+	Space string
+	Local string
+	// Prefix is prefix of the decoded value, the preferred one on encode.
+	Prefix string
+}
+
+// String returns {space}local name of the qname.
+func (q QName) String() string {
+	if q.Space == "" {
+		return q.Local
+	}
+	return "{" + q.Space + "}" + q.Local
+}
+
+// value returns prefixed value.
+func (q QName) value() string {
+	if q.Space == "" && q.Prefix == "" {
+		return q.Local
+	}
+
+	prefix := q.Prefix
+	if prefix == "" {
+		prefix = defaultQNamePrefix
+	}
+	return prefix + ":" + q.Local
+}
+
+// MarshalXML implements xml.Marshaler interface.
+func (q QName) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if q.Space != "" {
+		prefix := q.Prefix
+		if prefix == "" {
+			prefix = defaultQNamePrefix
+		}
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: q.Space})
+	}
+	return e.EncodeElement(q.value(), start)
+}
+
+// MarshalXMLAttr implements xml.MarshalerAttr interface.
+func (q QName) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: q.value()}, nil
+}
+
+// UnmarshalXML implements xml.Unmarshaler interface.
+func (q *QName) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	// scope of the element is left when the value is decoded
+	lookup := func(prefix string) (string, bool) {
+		return lookupAttr(start.Attr, prefix)
+	}
+	if s, ok := decoderScopes.Load(d); ok {
+		lookup = s.(*scopeReader).scope().lookup
+	}
+
+	var v string
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	return q.parse(v, lookup)
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr interface.
+func (q *QName) UnmarshalXMLAttr(attr xml.Attr) error {
+	return q.parse(attr.Value, nil)
+}
+
+// parse resolves the value, unprefixed value is in the default namespace.
+// The prefix is kept unresolved when the scope is not known.
+func (q *QName) parse(v string, lookup func(prefix string) (string, bool)) error {
+	v = strings.TrimSpace(v)
+	prefix, local := "", v
+	if i := strings.IndexByte(v, ':'); i >= 0 {
+		prefix, local = v[:i], v[i+1:]
+	}
+
+	if local == "" || strings.IndexByte(local, ':') >= 0 || strings.ContainsAny(v, " \t\r\n") {
+		return fmt.Errorf("soap: qname %q is invalid", v)
+	}
+
+	if lookup == nil {
+		*q = QName{Local: local, Prefix: prefix}
+		return nil
+	}
+
+	space, ok := lookup(prefix)
+	if !ok {
+		return fmt.Errorf("soap: qname %q prefix is not declared", v)
+	}
+
+	*q = QName{Space: space, Local: local, Prefix: prefix}
+	return nil
+}
+
+// lookupAttr resolves prefix by declarations of the element.
+func lookupAttr(attrs []xml.Attr, prefix string) (string, bool) {
+	for _, a := range attrs {
+		if prefix == "" && a.Name.Space == "" && a.Name.Local == "xmlns" || prefix != "" && a.Name.Space == "xmlns" && a.Name.Local == prefix {
+			return a.Value, true
+		}
+	}
+	return "", prefix == ""
+}
+
+var decoderScopes sync.Map // map[*xml.Decoder]*scopeReader
+
+// scopeReader tracks namespace declarations in scope of the current element.
+type scopeReader struct {
+	r     xml.TokenReader
+	stack []nsScope
+}
+
+// Token implements xml.TokenReader interface.
+func (r *scopeReader) Token() (xml.Token, error) {
+	token, err := r.r.Token()
+	if err != nil {
+		return token, err
+	}
+
+	switch t := token.(type) {
+	case xml.StartElement:
+		var parent nsScope
+		if len(r.stack) > 0 {
+			parent = r.stack[len(r.stack)-1]
+		}
+
+		scope, copied := parent, false
+		for _, a := range t.Attr {
+			prefix := ""
+			switch {
+			case a.Name.Space == "xmlns":
+				prefix = a.Name.Local
+			case a.Name.Space == "" && a.Name.Local == "xmlns":
+			default:
+				continue
+			}
+
+			if !copied {
+				copied = true
+				scope = make(nsScope, len(parent)+1)
+				for k, v := range parent {
+					scope[k] = v
+				}
+			}
+			scope[prefix] = a.Value
+		}
+		r.stack = append(r.stack, scope)
+	case xml.EndElement:
+		if len(r.stack) > 0 {
+			r.stack = r.stack[:len(r.stack)-1]
+		}
+	}
+	return token, nil
+}
+
+// scope returns declarations in scope of the current element.
+func (r *scopeReader) scope() nsScope {
+	if len(r.stack) == 0 {
+		return nil
+	}
+	return r.stack[len(r.stack)-1]
+}
+
+// nsScope maps prefixes to namespaces, empty prefix is the default namespace.
+type nsScope map[string]string
+
+// lookup resolves the prefix, the default namespace is empty unless declared.
+func (s nsScope) lookup(prefix string) (string, bool) {
+	space, ok := s[prefix]
+	return space, ok || prefix == ""
+}
+
+// scopedDecoder returns decoder tracking namespace scope for qnames, release must be called after decoding.
+func scopedDecoder(tr xml.TokenReader) (d *xml.Decoder, release func()) {
+	s := &scopeReader{r: tr}
+	d = xml.NewTokenDecoder(s)
+	decoderScopes.Store(d, s)
+	return d, func() { decoderScopes.Delete(d) }
+}
@@ -0,0 +1,86 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type qnameResponse struct {
+	XMLName xml.Name `xml:"test:call Response"`
+	Type    QName    `xml:"type"`
+	Default QName    `xml:"default"`
+	Attr    QName    `xml:"kind,attr"`
+}
+
+func TestClient_QName(t *testing.T) {
+	t.Parallel()
+	body := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:wst="urn:trust"><soap:Body>` +
+			body + `</soap:Body></soap:Envelope>`))
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{})
+	body = `<Response xmlns="test:call" kind="wst:Renew"><type>wst:Issue</type><default xmlns="urn:default"> Local </default></Response>`
+	var v qnameResponse
+	if err := client.Call(context.Background(), "", request{}, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (QName{Space: "urn:trust", Local: "Issue", Prefix: "wst"}); v.Type != want {
+		t.Fatalf("got: %+v, want: %+v", v.Type, want)
+	}
+
+	if want := (QName{Space: "urn:default", Local: "Local"}); v.Default != want {
+		t.Fatalf("got: %+v, want: %+v", v.Default, want)
+	}
+
+	if want := (QName{Local: "Renew", Prefix: "wst"}); v.Attr != want {
+		t.Fatalf("got: %+v, want: %+v", v.Attr, want)
+	}
+
+	body = `<Response xmlns="test:call"><type>undeclared:Issue</type></Response>`
+	if err := client.Call(context.Background(), "", request{}, &v); err == nil {
+		t.Fatal("got: nil, want: error of undeclared prefix")
+	}
+}
+
+func TestQName_Marshal(t *testing.T) {
+	t.Parallel()
+	type item struct {
+		XMLName xml.Name `xml:"item"`
+		Type    QName    `xml:"type"`
+		Other   QName    `xml:"other"`
+		Plain   QName    `xml:"plain"`
+		Attr    QName    `xml:"kind,attr"`
+	}
+
+	out, err := xml.Marshal(item{
+		Type:  QName{Space: "urn:trust", Local: "Issue", Prefix: "wst"},
+		Other: QName{Space: "urn:other", Local: "Value"},
+		Plain: QName{Local: "plain"},
+		Attr:  QName{Local: "Renew", Prefix: "wst"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<item kind="wst:Renew"><type xmlns:wst="urn:trust">wst:Issue</type><other xmlns:qn="urn:other">qn:Value</other><plain>plain</plain></item>`
+	if string(out) != want {
+		t.Fatalf("got: %s, want: %s", out, want)
+	}
+
+	// decoded with declarations of the element
+	var v item
+	if err := xml.Unmarshal(out, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Type.String() != "{urn:trust}Issue" || v.Other.String() != "{urn:other}Value" {
+		t.Fatalf("got: %s %s, want: {urn:trust}Issue {urn:other}Value", v.Type, v.Other)
+	}
+}
@@ -0,0 +1,268 @@
+package soap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Default settings of the queue.
+const (
+	DefaultQueueInterval = time.Second
+	DefaultQueueBatch    = 100
+)
+
+// QueuedMessage implements one-way call persisted by the queue.
+type QueuedMessage struct {
+	ID       string    `json:"id"`
+	Action   string    `json:"action"`
+	Envelope []byte    `json:"envelope"`
+	Created  time.Time `json:"created"`
+	Attempts int       `json:"attempts"`
+	// Next is time of the next attempt, Err is error of the last attempt.
+	Next time.Time `json:"next"`
+	Err  string    `json:"error,omitempty"`
+}
+
+// QueueStore implements persistent storage of the queue, e.g. file system or database.
+type QueueStore interface {
+	// Put adds or replaces the message by its id.
+	Put(ctx context.Context, m *QueuedMessage) error
+	// Due returns at most limit messages which next attempt is not after now, the earliest first,
+	// the messages are sent even when error is returned with them.
+	Due(ctx context.Context, now time.Time, limit int) ([]*QueuedMessage, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// QueueConfig implements config of the queue.
+type QueueConfig struct {
+	Store QueueStore
+	// Backoff is delay before the next attempt, by default it is exponential from 1s up to 5m.
+	Backoff Backoff
+	// Interval is period of polling the store for due messages, DefaultQueueInterval when zero.
+	Interval time.Duration
+	// Batch limits messages sent by one poll, DefaultQueueBatch when zero.
+	Batch int
+	// MaxAttempts drops the message after the attempts when positive.
+	MaxAttempts int
+	// OnDrop is called with the message which is dropped after not retryable error or the last attempt.
+	OnDrop func(m *QueuedMessage, err error)
+	// OnError is called with errors of the store returned by Run polls.
+	OnError func(err error)
+}
+
+// Queue implements store-and-forward queue of one-way calls with at-least-once delivery.
+// The message is deleted after the partner accepts it, transport failures, 5xx responses without
+// envelope and faults retryable by the client retry policy are retried later.
+type Queue struct {
+	client *Client
+	config QueueConfig
+}
+
+// NewQueue creates queue sending messages via the client.
+func NewQueue(client *Client, c QueueConfig) *Queue {
+	if c.Backoff == nil {
+		c.Backoff = ExponentialBackoff(time.Second, 5*time.Minute)
+	}
+
+	if c.Interval == 0 {
+		c.Interval = DefaultQueueInterval
+	}
+
+	if c.Batch == 0 {
+		c.Batch = DefaultQueueBatch
+	}
+	return &Queue{client: client, config: c}
+}
+
+// Enqueue encodes the request and persists it, the message id is returned. Headers of the client
+// are evaluated at once, so headers expiring in time, e.g. WS-Security timestamps, must not be used.
+func (q *Queue) Enqueue(ctx context.Context, soapAction string, request interface{}) (string, error) {
+	m, err := q.NewMessage(ctx, soapAction, request)
+	if err != nil {
+		return "", err
+	}
+
+	if err := q.config.Store.Put(ctx, m); err != nil {
+		return "", fmt.Errorf("soap: queue: %s", err)
+	}
+	return m.ID, nil
+}
+
+// NewMessage encodes the request into message which is due at once without persisting it.
+// It implements transactional outbox: the application inserts the message into the outbox table
+// within its database transaction, the store of the queue reads the table and Run dispatches it.
+func (q *Queue) NewMessage(ctx context.Context, soapAction string, request interface{}) (*QueuedMessage, error) {
+	envelope, err := q.client.encode(ctx, soapAction, request)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	return &QueuedMessage{
+		ID:       strings.TrimPrefix(NewMessageID(), "urn:uuid:"),
+		Action:   soapAction,
+		Envelope: envelope,
+		Created:  now,
+		Next:     now,
+	}, nil
+}
+
+// Run sends due messages until the context is done.
+func (q *Queue) Run(ctx context.Context) error {
+	t := time.NewTicker(q.config.Interval)
+	defer t.Stop()
+	for {
+		// the store may recover until the next poll
+		if err := q.Flush(ctx); err != nil && ctx.Err() == nil && q.config.OnError != nil {
+			q.config.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Flush sends one batch of due messages, error of the store is returned.
+func (q *Queue) Flush(ctx context.Context) error {
+	due, dueErr := q.config.Store.Due(ctx, time.Now(), q.config.Batch)
+	for _, m := range due {
+		if err := q.send(ctx, m); err != nil {
+			return err
+		}
+	}
+
+	if dueErr != nil {
+		return fmt.Errorf("soap: queue: %s", dueErr)
+	}
+	return nil
+}
+
+// send sends the message and deletes it or schedules the next attempt.
+func (q *Queue) send(ctx context.Context, m *QueuedMessage) error {
+	m.Attempts++
+	resp, err := q.client.transport(ctx, q.client.newRequest(m.Action, m.Envelope))
+	switch {
+	case err != nil:
+	case len(resp.Body) == 0 && resp.StatusCode < 300:
+		// one-way calls are usually accepted without envelope
+	case len(resp.Body) == 0:
+		err = &statusError{status: resp.Status, code: resp.StatusCode}
+	default:
+		err = q.client.decode(resp, new(interface{}))
+	}
+
+	if err != nil && ctx.Err() != nil {
+		// the attempt is interrupted by the caller
+		return nil
+	}
+
+	switch {
+	case err == nil:
+	case q.client.config.Retry.retryable(err) && (q.config.MaxAttempts <= 0 || m.Attempts < q.config.MaxAttempts):
+		m.Next, m.Err = time.Now().Add(q.config.Backoff(m.Attempts)).UTC(), err.Error()
+		if err := q.config.Store.Put(ctx, m); err != nil {
+			return fmt.Errorf("soap: queue: %s", err)
+		}
+		return nil
+	default:
+		if q.config.OnDrop != nil {
+			q.config.OnDrop(m, err)
+		}
+	}
+
+	if err := q.config.Store.Delete(ctx, m.ID); err != nil {
+		return fmt.Errorf("soap: queue: %s", err)
+	}
+	return nil
+}
+
+// DirQueue implements queue store keeping each message in a file of the directory.
+type DirQueue string
+
+// Put writes the message file atomically.
+func (d DirQueue) Put(ctx context.Context, m *QueuedMessage) error {
+	if strings.ContainsAny(m.ID, `/\`) {
+		return fmt.Errorf("invalid id %q", m.ID)
+	}
+
+	if err := os.MkdirAll(string(d), 0700); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(string(d), m.ID+".tmp")
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(string(d), m.ID+".json"))
+}
+
+// Due reads the message files. File which is not valid message is renamed to .bad
+// and reported by the error returned with the due messages.
+func (d DirQueue) Due(ctx context.Context, now time.Time, limit int) ([]*QueuedMessage, error) {
+	files, err := filepath.Glob(filepath.Join(string(d), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		due []*QueuedMessage
+		bad []string
+	)
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		m := &QueuedMessage{}
+		if err := json.Unmarshal(b, m); err != nil {
+			// the file is quarantined, so it does not block the queue
+			bad = append(bad, fmt.Sprintf("%s: %s", filepath.Base(f), err))
+			if err := os.Rename(f, strings.TrimSuffix(f, ".json")+".bad"); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			continue
+		}
+
+		if !m.Next.After(now) {
+			due = append(due, m)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool { return due[i].Next.Before(due[j].Next) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	if len(bad) > 0 {
+		return due, fmt.Errorf("invalid messages moved to .bad: %s", strings.Join(bad, "; "))
+	}
+	return due, nil
+}
+
+// Delete removes the message file.
+func (d DirQueue) Delete(ctx context.Context, id string) error {
+	err := os.Remove(filepath.Join(string(d), id+".json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,255 @@
+package soap
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		down     int32 = 1
+		received int32
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		atomic.AddInt32(&received, 1)
+		if r.Header.Get("SOAPAction") == "reject" {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Fault><faultcode>Client</faultcode><faultstring>invalid</faultstring></Fault></Body></Envelope>`))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	var dropped []string
+	store := DirQueue(dir)
+	q := NewQueue(MustNewClient(srv.URL, Config{}), QueueConfig{
+		Store:   store,
+		Backoff: ConstantBackoff(0),
+		OnDrop:  func(m *QueuedMessage, err error) { dropped = append(dropped, m.Action) },
+	})
+
+	ctx := context.Background()
+	for _, action := range []string{"notify", "reject"} {
+		if _, err := q.Enqueue(ctx, action, request{Attr1: "value1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// partner is down, the messages are kept
+	if err := q.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	due, err := store.Due(ctx, time.Now(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(due) != 2 || due[0].Attempts != 1 || due[0].Err == "" {
+		t.Fatalf("got: %+v, want: 2 messages after the failed attempt", due)
+	}
+
+	atomic.StoreInt32(&down, 0)
+	if err := q.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if due, _ = store.Due(ctx, time.Now(), 10); len(due) != 0 {
+		t.Fatalf("got: %d, want: empty queue", len(due))
+	}
+
+	if got := atomic.LoadInt32(&received); got != 2 {
+		t.Fatalf("got: %d, want: 2", got)
+	}
+
+	if len(dropped) != 1 || dropped[0] != "reject" {
+		t.Fatalf("got: %v, want: [reject]", dropped)
+	}
+}
+
+func TestQueue_BadFile(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var received int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := DirQueue(dir)
+	q := NewQueue(MustNewClient(srv.URL, Config{}), QueueConfig{Store: store})
+	ctx := context.Background()
+	if _, err := q.Enqueue(ctx, "notify", request{Attr1: "value1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the broken file is reported, the valid message is sent anyway
+	if err := q.Flush(ctx); err == nil || !strings.Contains(err.Error(), "broken.json") {
+		t.Fatalf("got: %v, want: error of broken.json", err)
+	}
+
+	if got := atomic.LoadInt32(&received); got != 1 {
+		t.Fatalf("got: %d, want: 1", got)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "broken.bad")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueue_MaxAttempts(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := DirQueue(dir)
+	var dropped int32
+	q := NewQueue(MustNewClient(srv.URL, Config{}), QueueConfig{
+		Store:       store,
+		Backoff:     ConstantBackoff(0),
+		Interval:    time.Millisecond,
+		MaxAttempts: 3,
+		OnDrop:      func(m *QueuedMessage, err error) { atomic.AddInt32(&dropped, int32(m.Attempts)) },
+	})
+
+	if _, err := q.Enqueue(context.Background(), "notify", request{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(done)
+	}()
+
+	for start := time.Now(); atomic.LoadInt32(&dropped) == 0 && time.Since(start) < time.Second; {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if got := atomic.LoadInt32(&dropped); got != 3 {
+		t.Fatalf("got: %d, want: dropped after 3 attempts", got)
+	}
+}
+
+// outbox implements outbox table of the application, messages are visible after the commit.
+type outbox struct {
+	mu       sync.Mutex
+	messages map[string]*QueuedMessage
+}
+
+func (o *outbox) commit(m ...*QueuedMessage) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, v := range m {
+		o.messages[v.ID] = v
+	}
+}
+
+func (o *outbox) Put(ctx context.Context, m *QueuedMessage) error {
+	o.commit(m)
+	return nil
+}
+
+func (o *outbox) Due(ctx context.Context, now time.Time, limit int) ([]*QueuedMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var due []*QueuedMessage
+	for _, m := range o.messages {
+		if !m.Next.After(now) && len(due) < limit {
+			due = append(due, m)
+		}
+	}
+	return due, nil
+}
+
+func (o *outbox) Delete(ctx context.Context, id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.messages, id)
+	return nil
+}
+
+func TestQueue_Outbox(t *testing.T) {
+	t.Parallel()
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		n, _ := ParseNode(b)
+		got = append(got, n.Value("Envelope/Body/Request/attr1"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	store := &outbox{messages: make(map[string]*QueuedMessage)}
+	q := NewQueue(MustNewClient(srv.URL, Config{}), QueueConfig{Store: store})
+
+	committed, err := q.NewMessage(context.Background(), "notify", request{Attr1: "committed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the rolled back transaction does not insert its message
+	if _, err := q.NewMessage(context.Background(), "notify", request{Attr1: "rolled back"}); err != nil {
+		t.Fatal(err)
+	}
+
+	store.commit(committed)
+	if err := q.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 1 || got[0] != "committed" {
+		t.Fatalf("got: %v, want: [committed]", got)
+	}
+
+	if len(store.messages) != 0 {
+		t.Fatalf("got: %d, want: empty outbox", len(store.messages))
+	}
+}
@@ -0,0 +1,88 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+)
+
+// RawElement implements element captured as self-contained raw xml, namespaces
+// of the ancestors are declared inside. Used with `xml:",any"` tag on a slice field
+// it captures unmapped subtrees of the response, so they can be persisted or sent back.
+type RawElement struct {
+	XMLName xml.Name
+	Raw     []byte
+}
+
+// UnmarshalXML implements xml.Unmarshaler interface.
+func (r *RawElement) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	buf := new(bytes.Buffer)
+	e := xml.NewEncoder(buf)
+	if err := e.EncodeToken(stripNS(start)); err != nil {
+		return err
+	}
+
+	for depth := 1; depth > 0; {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			token = stripNS(t)
+		case xml.EndElement:
+			depth--
+		case xml.ProcInst, xml.Directive:
+			continue
+		}
+
+		if err := e.EncodeToken(token); err != nil {
+			return err
+		}
+	}
+
+	if err := e.Flush(); err != nil {
+		return err
+	}
+
+	r.XMLName = start.Name
+	r.Raw = buf.Bytes()
+	return nil
+}
+
+// MarshalXML implements xml.Marshaler interface.
+func (r RawElement) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	d := xml.NewDecoder(bytes.NewReader(r.Raw))
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if t, ok := token.(xml.StartElement); ok {
+			token = stripNS(t)
+		}
+
+		if err := e.EncodeToken(token); err != nil {
+			return err
+		}
+	}
+}
+
+// stripNS removes namespace declarations, the encoder declares namespaces of the resolved names.
+func stripNS(start xml.StartElement) xml.StartElement {
+	attrs := make([]xml.Attr, 0, len(start.Attr))
+	for _, a := range start.Attr {
+		if a.Name.Space == "xmlns" || a.Name.Space == "" && a.Name.Local == "xmlns" {
+			continue
+		}
+		attrs = append(attrs, a)
+	}
+	start.Attr = attrs
+	return start
+}
@@ -0,0 +1,53 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type restResponse struct {
+	XMLName xml.Name     `xml:"test:call Response"`
+	Attr3   string       `xml:"attr3"`
+	Rest    []RawElement `xml:",any"`
+}
+
+func TestClient_RawElement(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" xmlns:x="test:ext"><s:Body>` +
+			`<Response xmlns="test:call"><attr3>value3</attr3><x:new id="1"><x:v>1</x:v></x:new><attr4>2</attr4></Response>` +
+			`</s:Body></s:Envelope>`))
+	}))
+	defer srv.Close()
+
+	var r restResponse
+	if err := MustNewClient(srv.URL, Config{DecodeMode: Strict}).Call(context.Background(), "", request{}, &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.Rest) != 2 {
+		t.Fatalf("got: %d, want: %d", len(r.Rest), 2)
+	}
+
+	for i, want := range []string{
+		`<new xmlns="test:ext" id="1"><v xmlns="test:ext">1</v></new>`,
+		`<attr4 xmlns="test:call">2</attr4>`,
+	} {
+		if got := string(r.Rest[i].Raw); got != want {
+			t.Errorf("#%d got: %s, want: %s", i, got, want)
+		}
+	}
+
+	b, err := xml.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<Response xmlns="test:call"><attr3>value3</attr3><new xmlns="test:ext" id="1"><v xmlns="test:ext">1</v></new><attr4 xmlns="test:call">2</attr4></Response>`
+	if got := string(b); got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package soap
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RecordFormat implements format of the recorded requests.
+type RecordFormat int
+
+const (
+	// RecordCurl writes the request as curl command.
+	RecordCurl RecordFormat = iota
+	// RecordHTTP writes the request as .http file of the editor rest clients.
+	RecordHTTP
+)
+
+// redactedHeaders are headers carrying credentials.
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// redactPassword redacts password of WS-Security username token.
+var redactPassword = RedactElements("Password")
+
+// Recorder implements debug recording of each request sent by the client, so issues can be
+// reproduced outside of go. Headers set by the client are included, cookies of the jar are not.
+type Recorder struct {
+	W      io.Writer
+	Format RecordFormat
+	// Credentials keeps values of authorization and cookie headers and text of Password elements
+	// of the envelope, by default they are redacted.
+	Credentials bool
+	// Headers lists other headers carrying secrets, e.g. api keys of Config.Headers, they are redacted
+	// unless Credentials is set.
+	Headers []string
+
+	mu sync.Mutex
+}
+
+// record writes the request, errors of the writer are ignored.
+func (rec *Recorder) record(req *http.Request) {
+	if rec == nil || rec.W == nil {
+		return
+	}
+
+	var body []byte
+	if req.GetBody != nil {
+		if r, err := req.GetBody(); err == nil {
+			body, _ = ioutil.ReadAll(r)
+			r.Close()
+		}
+	}
+
+	header := req.Header.Clone()
+	if !rec.Credentials {
+		for _, k := range append(redactedHeaders, rec.Headers...) {
+			if header.Get(k) != "" {
+				header.Set(k, redacted)
+			}
+		}
+
+		// bodies without password, e.g. mtom packages, are kept as is
+		if bytes.Contains(body, []byte("Password")) {
+			body = redactPassword(body)
+		}
+	}
+
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf := new(bytes.Buffer)
+	switch rec.Format {
+	case RecordHTTP:
+		fmt.Fprintf(buf, "%s %s\n", req.Method, req.URL)
+		for _, k := range keys {
+			for _, v := range header[k] {
+				fmt.Fprintf(buf, "%s: %s\n", k, v)
+			}
+		}
+		fmt.Fprintf(buf, "\n%s\n\n###\n", body)
+	default:
+		fmt.Fprintf(buf, "curl -X %s %s", req.Method, shellQuote(req.URL.String()))
+		for _, k := range keys {
+			for _, v := range header[k] {
+				fmt.Fprintf(buf, " \\\n  -H %s", shellQuote(k+": "+v))
+			}
+		}
+		fmt.Fprintf(buf, " \\\n  --data-binary %s\n", shellQuote(string(body)))
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.W.Write(buf.Bytes())
+}
+
+// shellQuote quotes the string for posix shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,58 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body/></Envelope>`))
+	}))
+	defer srv.Close()
+
+	for i, v := range []struct {
+		format RecordFormat
+		want   []string
+	}{
+		{format: RecordCurl, want: []string{
+			"curl -X POST '" + srv.URL + "'",
+			`-H 'Authorization: ***'`,
+			`-H 'Soapaction: it'\''s'`,
+			`--data-binary '<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/">`,
+		}},
+		{format: RecordHTTP, want: []string{
+			"POST " + srv.URL + "\n",
+			"Authorization: ***\n",
+			"Soapaction: it's\n",
+			"\n\n<Envelope",
+			"\n\n###\n",
+		}},
+	} {
+		buf := new(bytes.Buffer)
+		client := MustNewClient(srv.URL, Config{
+			BasicAuth: &BasicAuth{Username: "user", Password: "secret"},
+			WSSE:      NewWSSE("user", "wssepass"),
+			Headers:   http.Header{"X-Api-Key": {"apikey"}},
+			Recorder:  &Recorder{W: buf, Format: v.format, Headers: []string{"X-Api-Key"}},
+		})
+		if err := client.Call(context.Background(), "it's", request{}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, want := range v.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("#%d got: %s, want: %s", i, buf, want)
+			}
+		}
+
+		if strings.Contains(buf.String(), "dXNlcjpzZWNyZXQ") || strings.Contains(buf.String(), "apikey") || strings.Contains(buf.String(), "wssepass") {
+			t.Errorf("#%d got: %s, want: redacted credentials", i, buf)
+		}
+	}
+}
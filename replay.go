@@ -0,0 +1,186 @@
+package soap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	errReplay    = fmt.Errorf("soap: replayed message")
+	errStale     = fmt.Errorf("soap: message is not fresh")
+	errTimestamp = fmt.Errorf("soap: message has no timestamp")
+)
+
+// NonceStore implements storage of seen nonces, it may be shared between instances, e.g. by redis.
+type NonceStore interface {
+	// Add stores nonce until expires, false is returned when the nonce is already stored.
+	Add(ctx context.Context, nonce string, expires time.Time) (bool, error)
+}
+
+// nonceSweep is interval of removing expired nonces of the memory store.
+const nonceSweep = time.Minute
+
+// MemoryNonceStore implements in-memory nonce store.
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	// sweep is time of the next removal of expired nonces
+	sweep time.Time
+}
+
+// NewMemoryNonceStore creates in-memory nonce store.
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[string]time.Time)}
+}
+
+// Add implements NonceStore interface, expired nonces are removed once a minute.
+func (s *MemoryNonceStore) Add(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.After(s.sweep) {
+		for k, v := range s.nonces {
+			if now.After(v) {
+				delete(s.nonces, k)
+			}
+		}
+		s.sweep = now.Add(nonceSweep)
+	}
+
+	if v, ok := s.nonces[nonce]; ok && !now.After(v) {
+		return false, nil
+	}
+	s.nonces[nonce] = expires
+	return true, nil
+}
+
+// DefaultNonceLifetime is lifetime of nonces when freshness is not checked.
+const DefaultNonceLifetime = 24 * time.Hour
+
+// ReplayGuard implements freshness and replay checks of received envelopes.
+// Timestamp is taken from wsu:Timestamp or UsernameToken Created,
+// nonce is taken from UsernameToken Nonce or wsa:MessageID.
+// UsernameToken Created is always checked and keys its nonce, since Timestamp is not
+// covered by password digest and may be replaced by replaying client.
+type ReplayGuard struct {
+	// Store enables nonce checks when set.
+	Store NonceStore
+	// MaxAge enables freshness checks of Created when positive, it is also the nonce lifetime.
+	// Nonces are kept DefaultNonceLifetime when zero, so replays older than it are not detected,
+	// UsernameToken Created is limited by DefaultNonceLifetime then.
+	MaxAge time.Duration
+	// Skew is the allowed clock difference.
+	Skew time.Duration
+}
+
+// Check checks the envelope, errors are returned for stale or replayed messages.
+func (g *ReplayGuard) Check(ctx context.Context, envelope []byte) error {
+	n, err := ParseNode(envelope)
+	if err != nil {
+		return err
+	}
+	return g.check(ctx, n)
+}
+
+func (g *ReplayGuard) check(ctx context.Context, n *Node) error {
+	tokenCreated := n.Value("Envelope/Header/Security/UsernameToken/Created")
+	created := n.Value("Envelope/Header/Security/Timestamp/Created")
+	if created == "" {
+		created = tokenCreated
+	}
+
+	now := time.Now()
+	expires := now.Add(DefaultNonceLifetime + g.Skew)
+	if g.MaxAge > 0 {
+		if created == "" {
+			return errTimestamp
+		}
+
+		t, err := g.fresh(created, g.MaxAge, now)
+		if err != nil {
+			return err
+		}
+		expires = t.Add(g.MaxAge + g.Skew)
+	}
+
+	if v := n.Value("Envelope/Header/Security/Timestamp/Expires"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil || now.After(t.Add(g.Skew)) {
+			return errStale
+		}
+	}
+
+	nonce := n.Value("Envelope/Header/Security/UsernameToken/Nonce")
+	if tokenCreated != "" {
+		maxAge := g.MaxAge
+		if maxAge <= 0 {
+			maxAge = DefaultNonceLifetime
+		}
+
+		t, err := g.fresh(tokenCreated, maxAge, now)
+		if err != nil {
+			return err
+		}
+
+		// nonce of the token is kept while the token is fresh
+		if nonce != "" {
+			nonce += " " + tokenCreated
+			expires = t.Add(maxAge + g.Skew)
+		}
+	}
+
+	if g.Store == nil {
+		return nil
+	}
+
+	if nonce == "" {
+		nonce = n.Value("Envelope/Header/MessageID")
+	}
+
+	if nonce == "" {
+		return nil
+	}
+
+	ok, err := g.Store.Add(ctx, nonce, expires)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return errReplay
+	}
+	return nil
+}
+
+// fresh returns time of Created, errStale is returned when it is older than max age or in the future.
+func (g *ReplayGuard) fresh(created string, maxAge time.Duration, now time.Time) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, created)
+	if err != nil {
+		return t, errStale
+	}
+
+	if t.After(now.Add(g.Skew)) || now.Sub(t) > maxAge+g.Skew {
+		return t, errStale
+	}
+	return t, nil
+}
+
+// Middleware returns middleware checking successful responses.
+func (g *ReplayGuard) Middleware() Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			resp, err := next(ctx, r)
+			if err != nil || resp.StatusCode != 200 {
+				return resp, err
+			}
+
+			if err := g.Check(ctx, trimProlog(resp.Body)); err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package soap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func replayEnvelope(created, nonce string) []byte {
+	return []byte(fmt.Sprintf(`<soapenv:Envelope xmlns:soapenv="%s" xmlns:wsse="%s" xmlns:wsu="%s"><soapenv:Header><wsse:Security>`+
+		`<wsu:Timestamp><wsu:Created>%s</wsu:Created></wsu:Timestamp>`+
+		`<wsse:UsernameToken><wsse:Nonce>%s</wsse:Nonce></wsse:UsernameToken>`+
+		`</wsse:Security></soapenv:Header><soapenv:Body><Response xmlns="test:call"/></soapenv:Body></soapenv:Envelope>`,
+		envelopeNS, WSSENS, WSUNS, created, nonce))
+}
+
+func TestReplayGuard_Check(t *testing.T) {
+	t.Parallel()
+	g := &ReplayGuard{Store: NewMemoryNonceStore(), MaxAge: time.Minute, Skew: time.Second}
+	now := time.Now().UTC()
+
+	for i, v := range []struct {
+		envelope []byte
+		err      error
+	}{
+		{envelope: replayEnvelope(now.Format(wsuTime), "n1"), err: nil},
+		{envelope: replayEnvelope(now.Format(wsuTime), "n1"), err: errReplay},
+		{envelope: replayEnvelope(now.Format(wsuTime), "n2"), err: nil},
+		{envelope: replayEnvelope(now.Add(-time.Hour).Format(wsuTime), "n3"), err: errStale},
+		{envelope: replayEnvelope(now.Add(time.Hour).Format(wsuTime), "n4"), err: errStale},
+		{envelope: replayEnvelope("", "n5"), err: errTimestamp},
+	} {
+		if err := g.Check(context.Background(), v.envelope); err != v.err {
+			t.Errorf("#%d got: %v, want: %v", i, err, v.err)
+		}
+	}
+}
+
+func TestReplayGuard_TokenCreated(t *testing.T) {
+	t.Parallel()
+	now := time.Now().UTC()
+	envelope := func(created, tokenCreated, nonce string) []byte {
+		return []byte(fmt.Sprintf(`<soapenv:Envelope xmlns:soapenv="%s" xmlns:wsse="%s" xmlns:wsu="%s"><soapenv:Header><wsse:Security>`+
+			`<wsu:Timestamp><wsu:Created>%s</wsu:Created></wsu:Timestamp>`+
+			`<wsse:UsernameToken><wsse:Nonce>%s</wsse:Nonce><wsu:Created>%s</wsu:Created></wsse:UsernameToken>`+
+			`</wsse:Security></soapenv:Header><soapenv:Body><Response xmlns="test:call"/></soapenv:Body></soapenv:Envelope>`,
+			envelopeNS, WSSENS, WSUNS, created, nonce, tokenCreated))
+	}
+
+	for i, v := range []struct {
+		maxAge       time.Duration
+		tokenCreated time.Time
+		err          error
+	}{
+		{maxAge: time.Minute, tokenCreated: now},
+		// old token with new timestamp
+		{maxAge: time.Minute, tokenCreated: now.Add(-time.Hour), err: errStale},
+		{tokenCreated: now.Add(-time.Hour)},
+		{tokenCreated: now.Add(-2 * DefaultNonceLifetime), err: errStale},
+	} {
+		store := NewMemoryNonceStore()
+		g := &ReplayGuard{Store: store, MaxAge: v.maxAge, Skew: time.Second}
+		created := v.tokenCreated.Format(wsuTime)
+		if err := g.Check(context.Background(), envelope(now.Format(wsuTime), created, "n1")); err != v.err {
+			t.Fatalf("#%d got: %v, want: %v", i, err, v.err)
+		}
+
+		if v.err != nil {
+			continue
+		}
+
+		maxAge := v.maxAge
+		if maxAge == 0 {
+			maxAge = DefaultNonceLifetime
+		}
+
+		store.mu.Lock()
+		expires, ok := store.nonces["n1 "+created]
+		store.mu.Unlock()
+		if want := v.tokenCreated.Add(maxAge + time.Second).Truncate(time.Millisecond); !ok || !expires.Equal(want) {
+			t.Fatalf("#%d got: %s, want: %s", i, expires, want)
+		}
+	}
+}
+
+func TestReplayGuard_NonceLifetime(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryNonceStore()
+	g := &ReplayGuard{Store: store, Skew: time.Second}
+	envelope := replayEnvelope("", "n1")
+	if err := g.Check(context.Background(), envelope); err != nil {
+		t.Fatal(err)
+	}
+
+	store.mu.Lock()
+	expires := store.nonces["n1"]
+	store.mu.Unlock()
+	if d := time.Until(expires); d < DefaultNonceLifetime {
+		t.Fatalf("got: %s, want: %s", d, DefaultNonceLifetime)
+	}
+
+	if err := g.Check(context.Background(), envelope); err != errReplay {
+		t.Fatalf("got: %v, want: %s", err, errReplay)
+	}
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	t.Parallel()
+	s := NewMemoryNonceStore()
+	ctx := context.Background()
+	if ok, _ := s.Add(ctx, "expired", time.Now().Add(-time.Second)); !ok {
+		t.Fatal("got: false, want: true")
+	}
+
+	// the expired nonce is not swept yet, but it is accepted again
+	if ok, _ := s.Add(ctx, "expired", time.Now().Add(time.Minute)); !ok {
+		t.Fatal("got: false, want: true")
+	}
+
+	if ok, _ := s.Add(ctx, "expired", time.Now().Add(time.Minute)); ok {
+		t.Fatal("got: true, want: false")
+	}
+
+	s.mu.Lock()
+	s.nonces["old"], s.sweep = time.Now().Add(-time.Second), time.Time{}
+	s.mu.Unlock()
+	s.Add(ctx, "new", time.Now().Add(time.Minute))
+	if _, ok := s.nonces["old"]; ok || len(s.nonces) != 2 {
+		t.Fatalf("got: %v, want: expired nonce swept", s.nonces)
+	}
+}
+
+func TestClient_ReplayGuard(t *testing.T) {
+	t.Parallel()
+	body := replayEnvelope(time.Now().UTC().Format(wsuTime), "nonce")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	g := &ReplayGuard{Store: NewMemoryNonceStore(), MaxAge: time.Minute}
+	client := MustNewClient(srv.URL, Config{Middleware: []Middleware{g.Middleware()}})
+	if err := client.Call(context.Background(), "get", request{}, &response{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Call(context.Background(), "get", request{}, &response{}); err != errReplay {
+		t.Fatalf("got: %v, want: %s", err, errReplay)
+	}
+}
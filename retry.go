@@ -0,0 +1,146 @@
+package soap
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// transportError implements failure of the http round trip.
+type transportError struct {
+	CallInfo
+	err error
+	// cause is ErrDeadline, ErrCanceled or ErrTimeout, it is set when the call fails
+	cause error
+}
+
+func (e *transportError) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s", e.cause, e.err)
+	}
+	return fmt.Sprintf("soap: %s", e.err)
+}
+
+// Unwrap returns the cause and the failure.
+func (e *transportError) Unwrap() []error {
+	if e.cause != nil {
+		return []error{e.cause, e.err}
+	}
+	return []error{e.err}
+}
+
+// statusError implements http response without valid envelope.
+type statusError struct {
+	CallInfo
+	status string
+	code   int
+	// contentType is reported when it is not xml
+	contentType string
+}
+
+func (e *statusError) Error() string {
+	if e.contentType != "" {
+		return fmt.Sprintf("soap: %s (%d) unexpected content type %s", e.status, e.code, e.contentType)
+	}
+	return fmt.Sprintf("soap: %s (%d)", e.status, e.code)
+}
+
+// RetryPolicy implements retrying of failed calls.
+// Transport failures and 5xx responses without envelope are retried,
+// faults are retried when Fault predicate reports true.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt, values less than 2 disable retries.
+	MaxAttempts int
+	// Backoff is delay before the second attempt, it is doubled for each next attempt.
+	Backoff time.Duration
+	// Fault reports whether the call failed with the fault must be retried.
+	Fault func(f *Fault) bool
+	// DeadlineMargin enables retry budget of calls with context deadline when positive: the time left
+	// until the deadline minus the margin is split evenly between the remaining attempts as their timeouts,
+	// and retry is not started when the budget does not cover its backoff.
+	DeadlineMargin time.Duration
+}
+
+// RetryFaultCodes returns fault predicate matching any of the fault codes,
+// the namespace prefix of the code is ignored, e.g. "ServerBusy" matches "s:ServerBusy".
+func RetryFaultCodes(codes ...string) func(f *Fault) bool {
+	return func(f *Fault) bool {
+		code := localName(f.Code.String())
+		for _, c := range codes {
+			if localName(c) == code {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	switch e := err.(type) {
+	case *transportError:
+		return true
+	case *statusError:
+		return e.code >= 500
+	case *Fault:
+		return p.Fault != nil && p.Fault(e)
+	}
+	return false
+}
+
+// do calls fn until it succeeds, fails with not retryable error or attempts are exhausted.
+func (p RetryPolicy) do(ctx context.Context, fn func(ctx context.Context) error) error {
+	deadline, budget := ctx.Deadline()
+	budget = budget && p.DeadlineMargin > 0
+	deadline = deadline.Add(-p.DeadlineMargin)
+
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		var err error
+		if left := time.Until(deadline); budget && left > 0 {
+			err = p.attempt(ctx, left/time.Duration(p.attempts()-attempt+1), fn)
+		} else {
+			// the first attempt is sent when the budget is spent
+			err = fn(ctx)
+		}
+
+		if err == nil || attempt >= p.MaxAttempts || !p.retryable(err) {
+			return err
+		}
+
+		if budget && time.Until(deadline) <= backoff {
+			return err
+		}
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		backoff *= 2
+	}
+}
+
+// attempt calls fn with the attempt timeout.
+func (p RetryPolicy) attempt(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// attempts returns number of attempts including the first one.
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+func localName(qname string) string {
+	if i := strings.IndexByte(qname, ':'); i >= 0 {
+		return qname[i+1:]
+	}
+	return qname
+}
@@ -0,0 +1,136 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_RetryFault(t *testing.T) {
+	t.Parallel()
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		env := Envelope{Body: Body{Content: response{Attr3: "value3"}}}
+		if calls < 3 {
+			w.WriteHeader(500)
+			env = Envelope{Body: Body{Fault: &Fault{Code: "s:ServerBusy", Text: "busy"}}}
+		}
+
+		b, _ := xml.Marshal(env)
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	var r response
+	if err := MustNewClient(srv.URL, Config{Retry: RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		Fault:       RetryFaultCodes("ServerBusy"),
+	}}).Call(context.Background(), "", request{}, &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := 3; calls != want {
+		t.Fatalf("got: %d, want: %d", calls, want)
+	}
+
+	if want := "value3"; r.Attr3 != want {
+		t.Fatalf("got: %s, want: %s", r.Attr3, want)
+	}
+}
+
+func TestClient_RetryPlainText(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("Service Unavailable"))
+	}))
+	defer srv.Close()
+
+	err := MustNewClient(srv.URL, Config{Retry: RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}}).Call(context.Background(), "", request{}, &response{})
+	if want := `soap: 503 Service Unavailable (503) unexpected content type "text/plain"`; err == nil || err.Error() != want {
+		t.Fatalf("got: %v, want: %s", err, want)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Fatalf("got: %d, want: 3", got)
+	}
+}
+
+func TestClient_RetryBudget(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// the first attempt hangs until its timeout
+			<-r.Context().Done()
+			return
+		}
+
+		b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{Retry: RetryPolicy{MaxAttempts: 3, DeadlineMargin: 100 * time.Millisecond}})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var r response
+	if err := client.Call(ctx, "", request{}, &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "value3"; r.Attr3 != want {
+		t.Fatalf("got: %s, want: %s", r.Attr3, want)
+	}
+
+	// backoff is not covered by the budget
+	atomic.StoreInt32(&calls, 0)
+	client = MustNewClient(srv.URL, Config{Retry: RetryPolicy{MaxAttempts: 3, Backoff: time.Second, DeadlineMargin: 100 * time.Millisecond}})
+	ctx, cancel = context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := client.Call(ctx, "", request{}, &r); err == nil {
+		t.Fatal("want: error of the timed out attempt")
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("got: %d, want: 1", got)
+	}
+
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Fatalf("got: %s, want: attempt timeout of the budget", elapsed)
+	}
+}
+
+func Test_RetryPolicy(t *testing.T) {
+	t.Parallel()
+	p := RetryPolicy{Fault: RetryFaultCodes("ServerBusy")}
+	for i, v := range []struct {
+		err  error
+		want bool
+	}{
+		{err: &transportError{}, want: true},
+		{err: &statusError{code: 503}, want: true},
+		{err: &statusError{code: 400}},
+		{err: &Fault{Code: "ServerBusy"}, want: true},
+		{err: &Fault{Code: "soap:Client"}},
+		{err: errUnauthorized},
+	} {
+		if got := p.retryable(v.err); got != v.want {
+			t.Errorf("#%d got: %t, want: %t", i, got, v.want)
+		}
+	}
+}
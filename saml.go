@@ -0,0 +1,123 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// SAML token profile value types.
+const (
+	SAMLTokenType = "http://docs.oasis-open.org/wss/oasis-wss-saml-token-profile-1.1#SAMLV2.0"
+	SAMLID        = "http://docs.oasis-open.org/wss/oasis-wss-saml-token-profile-1.1#SAMLID"
+	WSSE11NS      = "http://docs.oasis-open.org/wss/oasis-wss-wssecurity-secext-1.1.xsd"
+)
+
+// SAML implements WS-Security header carrying SAML 2.0 assertion issued by an identity provider,
+// e.g. IHE XUA. The header is added to the encoded envelope by the middleware.
+type SAML struct {
+	// Assertion is inserted verbatim to keep the issuer signature valid.
+	Assertion []byte
+	// Key is the subject confirmation key of holder-of-key assertion, Body and Timestamp
+	// are signed with it referencing the assertion. Bearer assertion is sent unsigned when nil.
+	Key crypto.Signer
+	// TTL adds Timestamp expiring after TTL when positive.
+	TTL time.Duration
+	// SignedParts lists signed elements, e.g. AddressingParts or custom headers by name,
+	// DefaultSignedParts are signed when nil.
+	SignedParts []xml.Name
+}
+
+// Middleware returns middleware adding the security header to every request.
+func (s *SAML) Middleware() Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			envelope, err := s.apply(r.Envelope)
+			if err != nil {
+				return nil, err
+			}
+
+			req := *r
+			req.Envelope = envelope
+			return next(ctx, &req)
+		}
+	}
+}
+
+func (s *SAML) apply(envelope []byte) ([]byte, error) {
+	assertion := trimDecl(s.Assertion)
+	a, err := parseDoc(assertion)
+	if err != nil {
+		return nil, err
+	}
+
+	if envelope, err = withSecurity(envelope); err != nil {
+		return nil, err
+	}
+
+	if s.TTL > 0 {
+		if envelope, err = withTimestamp(envelope, s.TTL); err != nil {
+			return nil, err
+		}
+	}
+
+	root, err := parseDoc(envelope)
+	if err != nil {
+		return nil, err
+	}
+	envelope = insert(envelope, root.find("Header", "Security").contentEnd, string(assertion))
+
+	if s.Key == nil {
+		return envelope, nil
+	}
+
+	parts := s.SignedParts
+	if parts == nil {
+		parts = DefaultSignedParts
+	}
+
+	envelope, ids, err := withSignedParts(envelope, parts)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := `<wsse:SecurityTokenReference xmlns:wsse="` + WSSENS + `" xmlns:wsse11="` + WSSE11NS + `" wsse11:TokenType="` + SAMLTokenType + `">` +
+		`<wsse:KeyIdentifier ValueType="` + SAMLID + `">` + escapeText(a.id()) + `</wsse:KeyIdentifier></wsse:SecurityTokenReference>`
+	return signEnvelope(envelope, s.Key, keyInfo, ids)
+}
+
+// withTimestamp adds wsu:Timestamp as the first child of the security header unless present.
+func withTimestamp(envelope []byte, ttl time.Duration) ([]byte, error) {
+	root, err := parseDoc(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	security := root.find("Header", "Security")
+	if security == nil {
+		return nil, errNoSecHdr
+	}
+
+	if security.child("Timestamp") != nil {
+		return envelope, nil
+	}
+
+	now := time.Now().UTC()
+	ts := fmt.Sprintf(`<wsu:Timestamp xmlns:wsu="%s" wsu:Id="TS-1"><wsu:Created>%s</wsu:Created><wsu:Expires>%s</wsu:Expires></wsu:Timestamp>`,
+		WSUNS, now.Format(wsuTime), now.Add(ttl).Format(wsuTime))
+	return insert(envelope, security.contentStart, ts), nil
+}
+
+// trimDecl removes xml declaration of the document.
+func trimDecl(data []byte) []byte {
+	data = trimProlog(data)
+	if bytes.HasPrefix(data, []byte("<?xml")) {
+		if i := bytes.Index(data, []byte("?>")); i >= 0 {
+			data = trimProlog(data[i+2:])
+		}
+	}
+	return data
+}
@@ -0,0 +1,195 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const samlAssertion = `<?xml version="1.0" encoding="UTF-8"?>
+<saml2:Assertion xmlns:saml2="urn:oasis:names:tc:SAML:2.0:assertion" ID="_a75adf55" IssueInstant="2020-01-02T03:04:05Z" Version="2.0">` +
+	`<saml2:Issuer>idp</saml2:Issuer><saml2:Subject><saml2:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:holder-of-key"/></saml2:Subject></saml2:Assertion>`
+
+func TestSAML_HolderOfKey(t *testing.T) {
+	t.Parallel()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, key := range []crypto.Signer{rsaKey, ecKey} {
+		envelopes := make(chan []byte, 1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			envelopes <- b
+			b, _ = xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+			w.Write(b)
+		}))
+
+		saml := &SAML{Assertion: []byte(samlAssertion), Key: key, TTL: time.Minute}
+		client := MustNewClient(srv.URL, Config{Middleware: []Middleware{saml.Middleware()}})
+		if err := client.Call(context.Background(), "get", request{Attr1: "value1"}, &response{}); err != nil {
+			t.Fatal(err)
+		}
+		srv.Close()
+
+		envelope := <-envelopes
+		ids, err := VerifySignature(envelope, key.Public())
+		if err != nil {
+			t.Fatalf("#%d %s: %s", i, err, envelope)
+		}
+
+		if want := []string{"TS-1", "Body-1"}; !reflect.DeepEqual(ids, want) {
+			t.Errorf("#%d got: %v, want: %v", i, ids, want)
+		}
+
+		n, err := ParseNode(envelope)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := n.Value("Envelope/Header/Security/Signature/KeyInfo/SecurityTokenReference/KeyIdentifier"); got != "_a75adf55" {
+			t.Errorf("#%d got: %s, want: %s", i, got, "_a75adf55")
+		}
+
+		if n.Find("Envelope/Header/Security/Assertion/Subject") == nil {
+			t.Errorf("#%d assertion is missing: %s", i, envelope)
+		}
+
+		tampered := bytes.Replace(envelope, []byte("value1"), []byte("value2"), 1)
+		if _, err := VerifySignature(tampered, key.Public()); err != errSignature {
+			t.Errorf("#%d got: %v, want: %s", i, err, errSignature)
+		}
+	}
+}
+
+func TestSAML_Bearer(t *testing.T) {
+	t.Parallel()
+	envelope, err := xml.Marshal(Envelope{Body: Body{Content: request{Attr1: "value1"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelope, err = (&SAML{Assertion: []byte(samlAssertion)}).apply(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := ParseNode(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n.Find("Envelope/Header/Security/Assertion") == nil || n.Find("Envelope/Header/Security/Signature") != nil {
+		t.Fatalf("got: %s, want: unsigned assertion", envelope)
+	}
+}
+
+func TestSAML_SignedParts(t *testing.T) {
+	t.Parallel()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelopes := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		envelopes <- b
+		b, _ = xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	parts := append(append([]xml.Name{}, AddressingParts...), BodyPart, xml.Name{Space: "urn:session", Local: "SessionHeader"})
+	saml := &SAML{Assertion: []byte(samlAssertion), Key: key, TTL: time.Minute, SignedParts: parts}
+	client := MustNewClient(srv.URL, Config{Middleware: []Middleware{Addressing{}.Middleware(), saml.Middleware()}})
+	client.AddHeader(sessionHeader{SessionID: "42"})
+	if err := client.Call(context.Background(), "get", request{Attr1: "value1"}, &response{}); err != nil {
+		t.Fatal(err)
+	}
+
+	envelope := <-envelopes
+	ids, err := VerifySignature(envelope, key.Public())
+	if err != nil {
+		t.Fatalf("%s: %s", err, envelope)
+	}
+
+	if want := []string{"MessageID-1", "To-1", "Action-1", "Body-1", "SessionHeader-1"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("got: %v, want: %v", ids, want)
+	}
+
+	tampered := bytes.Replace(envelope, []byte(">42<"), []byte(">43<"), 1)
+	if _, err := VerifySignature(tampered, key.Public()); err != errSignature {
+		t.Fatalf("got: %v, want: %s", err, errSignature)
+	}
+}
+
+func TestVerifySignature_Wrapping(t *testing.T) {
+	t.Parallel()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelope, err := withSecurity([]byte(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Header></soapenv:Header>` +
+		`<soapenv:Body><Transfer xmlns="test:bank">10</Transfer></soapenv:Body></soapenv:Envelope>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelope, ids, err := withSignedParts(envelope, []xml.Name{BodyPart})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := signEnvelope(envelope, key, "", ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := VerifySignature(signed, key.Public()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the signed body is hidden in the header and the forged body is processed
+	root, err := parseDoc(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := root.child("Body")
+	original := string(signed[body.start:body.end])
+	forged := bytes.Replace(signed, []byte(">10<"), []byte(">1000<"), 1)
+	root, _ = parseDoc(forged)
+	forged = insert(forged, root.child("Header").contentStart, `<Wrapper xmlns="test:wrap">`+original+`</Wrapper>`)
+
+	if _, err := VerifySignature(forged, key.Public()); err == nil || err.Error() != "soap: element id Body-1 is duplicated" {
+		t.Fatalf("got: %v, want: duplicated id error", err)
+	}
+
+	// signature without references signs nothing
+	unsigned, err := signEnvelope(envelope, key, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := VerifySignature(unsigned, key.Public()); err != errSignature {
+		t.Fatalf("got: %v, want: %s", err, errSignature)
+	}
+}
@@ -0,0 +1,75 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+)
+
+// ScrubInvalidChars is response transformer removing control characters illegal in xml,
+// e.g. 0x00-0x08 emitted by mainframe-backed services, and character references to them.
+// Tab, line feed and carriage return are kept, bytes beyond ASCII are kept as is,
+// so the body may use any ASCII-compatible encoding.
+var ScrubInvalidChars RawResponseTransformer = func(ctx context.Context, action string, body []byte) ([]byte, error) {
+	return scrubInvalidChars(body), nil
+}
+
+func scrubInvalidChars(data []byte) []byte {
+	if !needsScrub(data) {
+		return data
+	}
+
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if invalidControl(c) {
+			continue
+		}
+
+		if c == '&' && i+1 < len(data) && data[i+1] == '#' {
+			if n, ok := invalidRef(data[i:]); ok {
+				i += n - 1
+				continue
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// needsScrub reports whether the data has illegal control characters or references.
+func needsScrub(data []byte) bool {
+	for _, c := range data {
+		if invalidControl(c) {
+			return true
+		}
+	}
+	return bytes.Contains(data, []byte("&#"))
+}
+
+func invalidControl(c byte) bool {
+	return c < 0x20 && c != '\t' && c != '\n' && c != '\r'
+}
+
+// invalidRef returns length of the character reference at the start of the data
+// when the referenced character is illegal in xml.
+func invalidRef(data []byte) (int, bool) {
+	end := bytes.IndexByte(data, ';')
+	if end < 3 || end > 12 {
+		return 0, false
+	}
+
+	ref, base := string(data[2:end]), 10
+	if ref[0] == 'x' {
+		ref, base = ref[1:], 16
+	}
+
+	v, err := strconv.ParseUint(ref, base, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	valid := v == 0x09 || v == 0x0A || v == 0x0D ||
+		v >= 0x20 && v <= 0xD7FF || v >= 0xE000 && v <= 0xFFFD || v >= 0x10000 && v <= 0x10FFFF
+	return end + 1, !valid
+}
@@ -0,0 +1,56 @@
+package soap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScrubInvalidChars(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "<a>text</a>", want: "<a>text</a>"},
+		{in: "<a>te\x00x\x08t\x1f</a>", want: "<a>text</a>"},
+		{in: "<a>\ttext\r\n</a>", want: "<a>\ttext\r\n</a>"},
+		{in: "<a>te&#0;x&#x1F;t&#xFFFE;</a>", want: "<a>text</a>"},
+		{in: "<a>&#x9;&#65;&amp;&#x10FFFF;&#bad;</a>", want: "<a>&#x9;&#65;&amp;&#x10FFFF;&#bad;</a>"},
+		{in: "<a>\xc8\xe2\xe0\xed</a>", want: "<a>\xc8\xe2\xe0\xed</a>"},
+	}
+
+	for _, tt := range tests {
+		got, err := ScrubInvalidChars(context.Background(), "", []byte(tt.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != tt.want {
+			t.Errorf("got: %q, want: %q", got, tt.want)
+		}
+	}
+}
+
+func TestClient_ScrubInvalidChars(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<Envelope xmlns=\"http://schemas.xmlsoap.org/soap/envelope/\"><Body><Response xmlns=\"test:call\"><attr3>value\x003&#x1;</attr3></Response></Body></Envelope>"))
+	}))
+	defer srv.Close()
+
+	if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "get", request{}, &response{}); err == nil {
+		t.Fatal("got: nil, want: error")
+	}
+
+	resp := &response{}
+	client := MustNewClient(srv.URL, Config{RawResponseTransformers: []RawResponseTransformer{ScrubInvalidChars}})
+	if err := client.Call(context.Background(), "get", request{}, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Attr3 != "value3" {
+		t.Fatalf("got: %s, want: value3", resp.Attr3)
+	}
+}
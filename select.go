@@ -0,0 +1,85 @@
+package soap
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+)
+
+// Selection implements response content decoded from the selected subtree of the body only,
+// other elements are skipped token by token without decoding, e.g. a few fields of a huge report.
+// Path is a slash separated list of local names starting with the body element,
+// "*" matches any element, e.g. "GetReportResponse/Report/*/Total".
+// Target must be a pointer, every matched element is appended when it is a pointer to a slice,
+// otherwise the first matched element is decoded.
+type Selection struct {
+	Path   string
+	Target interface{}
+	// Matched is number of the decoded elements.
+	Matched int
+}
+
+// Select returns selection of the path decoded into the target.
+func Select(path string, target interface{}) *Selection {
+	return &Selection{Path: path, Target: target}
+}
+
+// many reports whether every matched element is decoded.
+func (s *Selection) many() bool {
+	t := reflect.TypeOf(s.Target)
+	return t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Slice
+}
+
+func (s *Selection) decode(d *xml.Decoder, se xml.StartElement) error {
+	steps := strings.Split(strings.Trim(s.Path, "/"), "/")
+	if s.Path == "" || !matchLocal(se.Name, steps[0]) {
+		return d.Skip()
+	}
+
+	if len(steps) == 1 {
+		return s.decodeMatched(d, se)
+	}
+
+	// depth is depth of the current element, steps[:depth] are matched by its ancestors
+	depth := 1
+	for depth > 0 {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if !matchLocal(t.Name, steps[depth]) || s.Matched > 0 && !s.many() {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if depth == len(steps)-1 {
+				if err := s.decodeMatched(d, t); err != nil {
+					return err
+				}
+				continue
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return nil
+}
+
+func (s *Selection) decodeMatched(d *xml.Decoder, se xml.StartElement) error {
+	if err := d.DecodeElement(s.Target, &se); err != nil {
+		return err
+	}
+
+	s.Matched++
+	return nil
+}
+
+func matchLocal(name xml.Name, step string) bool {
+	return step == "*" || name.Local == step
+}
@@ -0,0 +1,62 @@
+package soap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_Select(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var items strings.Builder
+		for i := 1; i <= 100; i++ {
+			fmt.Fprintf(&items, "<item><id>%d</id><data><blob>%s</blob></data></item>", i, strings.Repeat("x", 100))
+		}
+		fmt.Fprintf(w, `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><ListResponse xmlns="test:call"><items>%s</items><summary><total>100</total></summary></ListResponse></Body></Envelope>`, items.String())
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{DecodeMode: Strict})
+	var total int
+	sel := Select("ListResponse/summary/total", &total)
+	if err := client.Call(context.Background(), "list", request{}, sel); err != nil {
+		t.Fatal(err)
+	}
+
+	if total != 100 || sel.Matched != 1 {
+		t.Fatalf("got: %d %d, want: 100 1", total, sel.Matched)
+	}
+
+	var ids []int
+	sel = Select("ListResponse/items/*/id", &ids)
+	if err := client.Call(context.Background(), "list", request{}, sel); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 100 || ids[0] != 1 || ids[99] != 100 || sel.Matched != 100 {
+		t.Fatalf("got: %d %d, want: 100 100", len(ids), sel.Matched)
+	}
+
+	var first streamItem
+	sel = Select("/ListResponse/items/item/", &first)
+	if err := client.Call(context.Background(), "list", request{}, sel); err != nil {
+		t.Fatal(err)
+	}
+
+	if first.ID != 1 || sel.Matched != 1 {
+		t.Fatalf("got: %d %d, want: 1 1", first.ID, sel.Matched)
+	}
+
+	sel = Select("OtherResponse/total", &total)
+	if err := client.Call(context.Background(), "list", request{}, sel); err != nil {
+		t.Fatal(err)
+	}
+
+	if sel.Matched != 0 {
+		t.Fatalf("got: %d, want: 0", sel.Matched)
+	}
+}
@@ -0,0 +1,328 @@
+package soap
+
+import (
+	"context"
+	"crypto"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default limits of the server.
+const (
+	DefaultMaxBodyBytes = 10 << 20
+	DefaultReadTimeout  = 30 * time.Second
+)
+
+// ServerRequest implements received soap request.
+type ServerRequest struct {
+	Action   string
+	Header   http.Header
+	Envelope []byte
+
+	limits DecodeLimits
+}
+
+// Decode decodes body element of the request into v, Client fault is returned on invalid envelope.
+func (r *ServerRequest) Decode(v interface{}) error {
+	env := &Envelope{Body: Body{Content: v}}
+	d, release := scopedDecoder(r.limits.decoder(trimProlog(r.Envelope)))
+	defer release()
+
+	if err := d.Decode(env); err != nil {
+		return NewClientFault(fmt.Sprintf("envelope is invalid: %s", err))
+	}
+	return nil
+}
+
+// HandlerFunc handles soap request, the response is encoded into the body. *Fault error is sent
+// as is, other errors are sent as Server fault without details.
+type HandlerFunc func(ctx context.Context, r *ServerRequest) (interface{}, error)
+
+// ServerMiddleware wraps handlers, e.g. for authentication.
+type ServerMiddleware func(next HandlerFunc) HandlerFunc
+
+// ServerConfig implements config of the soap server.
+type ServerConfig struct {
+	// MaxBodyBytes limits size of the request body, DefaultMaxBodyBytes when zero, negative disables the limit.
+	// Client fault with 413 status is sent when exceeded.
+	MaxBodyBytes int64
+	// ReadTimeout limits reading of the request body, DefaultReadTimeout when zero, negative disables the limit.
+	// Client fault with 408 status is sent when exceeded.
+	ReadTimeout time.Duration
+	// Timeout limits the handler by its context when positive, Server fault with 503 status is sent when exceeded.
+	Timeout time.Duration
+	// MaxConcurrent limits requests handled at once when positive, Server fault with 503 status is sent to the others.
+	MaxConcurrent int
+	// DecodeLimits protects request decoding against xml bombs.
+	DecodeLimits DecodeLimits
+	// SignatureConfirmation adds WS-Security 1.1 header to responses confirming signatures of the request,
+	// the body and the confirmations are signed with SignatureKey, so NewSignatureConfirmation of the client
+	// verifies them, the header is not signed when the key is nil.
+	SignatureConfirmation bool
+	SignatureKey          crypto.Signer
+	// HeaderLimits validates request header elements, Client or MustUnderstand fault is sent when violated.
+	HeaderLimits HeaderLimits
+	// Middleware wraps every handler, the first one is the outermost.
+	Middleware []ServerMiddleware
+	// AccessLog enables access logging of the requests.
+	AccessLog *ServerLogConfig
+	// HealthPath enables health endpoint answering GET requests with 200 status, 503 status is sent
+	// when Health fails or the server is shutting down.
+	HealthPath string
+	Health     func(ctx context.Context) error
+}
+
+// Server implements http handler of soap service dispatching requests by soap action.
+type Server struct {
+	config   ServerConfig
+	handlers map[string]HandlerFunc
+	sem      chan struct{}
+
+	mu       sync.Mutex
+	active   int
+	shutdown bool
+	// idle is closed when the last request is handled after shutdown
+	idle chan struct{}
+}
+
+// NewServer creates soap server.
+func NewServer(c ServerConfig) *Server {
+	if c.MaxBodyBytes == 0 {
+		c.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = DefaultReadTimeout
+	}
+
+	s := &Server{config: c, handlers: make(map[string]HandlerFunc), idle: make(chan struct{})}
+	if c.MaxConcurrent > 0 {
+		s.sem = make(chan struct{}, c.MaxConcurrent)
+	}
+	return s
+}
+
+// Handle registers handler of the soap action, it must not be called while serving.
+func (s *Server) Handle(soapAction string, h HandlerFunc) {
+	for i := len(s.config.Middleware) - 1; i >= 0; i-- {
+		h = s.config.Middleware[i](h)
+	}
+	s.handlers[soapAction] = h
+}
+
+// ServeHTTP implements http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.config.HealthPath != "" && r.URL.Path == s.config.HealthPath {
+		s.health(w, r)
+		return
+	}
+
+	c := s.config.AccessLog
+	if c == nil || c.Log == nil {
+		s.serve(w, r, &AccessLog{})
+		return
+	}
+
+	l := &AccessLog{Time: time.Now(), RemoteAddr: r.RemoteAddr}
+	lw := &logWriter{ResponseWriter: w, capture: c.Capture}
+	s.serve(lw, r, l)
+	c.log(l, lw)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, l *AccessLog) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method is not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !s.enter() {
+		l.fault(w, &Fault{Code: FaultServer, Text: "server is shutting down", HTTPStatus: http.StatusServiceUnavailable})
+		return
+	}
+	defer s.leave()
+
+	if s.sem != nil {
+		select {
+		case s.sem <- struct{}{}:
+			defer func() { <-s.sem }()
+		default:
+			l.fault(w, &Fault{Code: FaultServer, Text: "server is busy", HTTPStatus: http.StatusServiceUnavailable})
+			return
+		}
+	}
+
+	envelope, f := s.read(w, r)
+	if f != nil {
+		l.fault(w, f)
+		return
+	}
+
+	action := strings.Trim(r.Header.Get("SOAPAction"), `"`)
+	l.Action, l.Request, l.RequestBytes = action, envelope, len(envelope)
+	if err := s.config.HeaderLimits.check(trimProlog(envelope)); err != nil {
+		l.fault(w, headerFault(err))
+		return
+	}
+
+	h, ok := s.handlers[action]
+	if !ok {
+		l.fault(w, NewClientFault(fmt.Sprintf("soap action %q is not supported", action)))
+		return
+	}
+
+	ctx := r.Context()
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+
+	resp, err := h(ctx, &ServerRequest{Action: action, Header: r.Header, Envelope: envelope, limits: s.config.DecodeLimits})
+	if err != nil {
+		f, ok := err.(*Fault)
+		switch {
+		case ok:
+		case ctx.Err() == context.DeadlineExceeded:
+			f = &Fault{Code: FaultServer, Text: "request is not handled in time", HTTPStatus: http.StatusServiceUnavailable}
+		default:
+			f = NewServerFault("internal server error")
+		}
+		l.fault(w, f)
+		return
+	}
+	env := Envelope{Body: Body{Content: resp}}
+	if s.config.SignatureConfirmation {
+		env.Header = &Header{Items: []interface{}{signatureConfirmations(signatureValues(envelope))}}
+		if s.config.SignatureKey != nil {
+			writeConfirmation(w, env, s.config.SignatureKey)
+			return
+		}
+	}
+	writeEnvelope(w, http.StatusOK, env)
+}
+
+// Shutdown rejects new requests with Server fault and waits until in-flight requests are handled
+// or the context is done. The http server is shut down by the caller.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.shutdown {
+		s.shutdown = true
+		if s.active == 0 {
+			close(s.idle)
+		}
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("soap: %s", ctx.Err())
+	}
+}
+
+// enter registers in-flight request, false is returned after shutdown.
+func (s *Server) enter() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shutdown {
+		return false
+	}
+
+	s.active++
+	return true
+}
+
+func (s *Server) leave() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+	if s.shutdown && s.active == 0 {
+		close(s.idle)
+	}
+}
+
+// health writes status of the health endpoint.
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method is not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	shutdown := s.shutdown
+	s.mu.Unlock()
+
+	switch {
+	case shutdown:
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+	case s.config.Health != nil && s.config.Health(r.Context()) != nil:
+		http.Error(w, "unhealthy", http.StatusServiceUnavailable)
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("ok\n"))
+	}
+}
+
+// read reads the request body enforcing size and time limits.
+func (s *Server) read(w http.ResponseWriter, r *http.Request) ([]byte, *Fault) {
+	if s.config.ReadTimeout > 0 {
+		// not every response writer supports deadlines, e.g. httptest.ResponseRecorder
+		http.NewResponseController(w).SetReadDeadline(time.Now().Add(s.config.ReadTimeout))
+	}
+
+	body := r.Body
+	if s.config.MaxBodyBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, s.config.MaxBodyBytes)
+	}
+
+	envelope, err := ioutil.ReadAll(body)
+	if err == nil {
+		return envelope, nil
+	}
+
+	switch e := err.(type) {
+	case *http.MaxBytesError:
+		return nil, &Fault{Code: FaultClient, Text: trimSpace(fmt.Sprintf("request body exceeds limit %d", e.Limit)), HTTPStatus: http.StatusRequestEntityTooLarge}
+	case net.Error:
+		if e.Timeout() {
+			return nil, &Fault{Code: FaultClient, Text: "request body is not read in time", HTTPStatus: http.StatusRequestTimeout}
+		}
+	}
+	return nil, NewClientFault("request body is not read")
+}
+
+// fault writes the fault and records its code.
+func (l *AccessLog) fault(w http.ResponseWriter, f *Fault) {
+	l.FaultCode = f.Code.String()
+	writeFault(w, f)
+}
+
+// writeFault writes the fault with its http status, 500 when not set.
+func writeFault(w http.ResponseWriter, f *Fault) {
+	status := f.HTTPStatus
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	writeEnvelope(w, status, Envelope{Body: Body{Fault: f}})
+}
+
+func writeEnvelope(w http.ResponseWriter, status int, env Envelope) {
+	b, err := xml.Marshal(env)
+	if err != nil {
+		b, _ = xml.Marshal(Envelope{Body: Body{Fault: NewServerFault("response is not encoded")}})
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.WriteHeader(status)
+	w.Write(b)
+}
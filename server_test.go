@@ -0,0 +1,203 @@
+package soap
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+	s := NewServer(ServerConfig{})
+	s.Handle("get", func(ctx context.Context, r *ServerRequest) (interface{}, error) {
+		var req request
+		if err := r.Decode(&req); err != nil {
+			return nil, err
+		}
+		return response{Attr3: req.Attr1}, nil
+	})
+	s.Handle("fail", func(ctx context.Context, r *ServerRequest) (interface{}, error) {
+		return nil, fmt.Errorf("database password is wrong")
+	})
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{})
+	var resp response
+	if err := client.Call(context.Background(), "get", request{Attr1: "value1"}, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "value1"; resp.Attr3 != want {
+		t.Fatalf("got: %s, want: %s", resp.Attr3, want)
+	}
+
+	for i, v := range []struct {
+		action string
+		want   string
+	}{
+		{action: "unknown", want: `soap: soapenv:Client: soap action "unknown" is not supported 500`},
+		{action: "fail", want: "soap: soapenv:Server: internal server error 500"},
+	} {
+		if err := client.Call(context.Background(), v.action, request{}, nil); err == nil || err.Error() != v.want {
+			t.Errorf("#%d got: %v, want: %s", i, err, v.want)
+		}
+	}
+
+	resp2, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp2.Body.Close()
+
+	if resp2.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("got: %d, want: %d", resp2.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestServer_Limits(t *testing.T) {
+	t.Parallel()
+	block, entered := make(chan struct{}), make(chan struct{}, 1)
+	s := NewServer(ServerConfig{MaxBodyBytes: 300, ReadTimeout: 50 * time.Millisecond, Timeout: 20 * time.Millisecond, MaxConcurrent: 1})
+	s.Handle("slow", func(ctx context.Context, r *ServerRequest) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	s.Handle("block", func(ctx context.Context, r *ServerRequest) (interface{}, error) {
+		entered <- struct{}{}
+		<-block
+		return nil, nil
+	})
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{})
+	for i, v := range []struct {
+		action  string
+		request interface{}
+		status  int
+	}{
+		{action: "slow", request: request{Attr1: string(make([]byte, 300))}, status: http.StatusRequestEntityTooLarge},
+		{action: "slow", request: request{}, status: http.StatusServiceUnavailable},
+	} {
+		err := client.Call(context.Background(), v.action, v.request, nil)
+		if f, ok := err.(*Fault); !ok || f.HTTPStatus != v.status {
+			t.Errorf("#%d got: %v, want: fault with status %d", i, err, v.status)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		client.Call(context.Background(), "block", request{}, nil)
+		close(done)
+	}()
+
+	// the blocked request holds the only slot
+	<-entered
+	err := client.Call(context.Background(), "slow", request{}, nil)
+	close(block)
+	<-done
+
+	if err == nil || err.Error() != "soap: soapenv:Server: server is busy 503" {
+		t.Fatalf("got: %v, want: server is busy", err)
+	}
+
+	// body is not sent in full
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	fmt.Fprint(conn, "POST / HTTP/1.1\r\nHost: soap\r\nSOAPAction: slow\r\nContent-Length: 100\r\n\r\n<Envelope")
+	r, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+
+	if r.StatusCode != http.StatusRequestTimeout {
+		t.Fatalf("got: %d, want: %d", r.StatusCode, http.StatusRequestTimeout)
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	t.Parallel()
+	block, entered := make(chan struct{}), make(chan struct{})
+	errDown := fmt.Errorf("database is down")
+	s := NewServer(ServerConfig{HealthPath: "/healthz", Health: func(ctx context.Context) error {
+		select {
+		case <-entered:
+			return nil
+		default:
+			return errDown
+		}
+	}})
+	s.Handle("block", func(ctx context.Context, r *ServerRequest) (interface{}, error) {
+		close(entered)
+		<-block
+		return response{Attr3: "value3"}, nil
+	})
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	health := func() int {
+		resp, err := http.Get(srv.URL + "/healthz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if got := health(); got != http.StatusServiceUnavailable {
+		t.Fatalf("got: %d, want: %d", got, http.StatusServiceUnavailable)
+	}
+
+	client := MustNewClient(srv.URL, Config{})
+	done := make(chan error)
+	go func() {
+		var resp response
+		err := client.Call(context.Background(), "block", request{}, &resp)
+		if err == nil && resp.Attr3 != "value3" {
+			err = fmt.Errorf("got: %s, want: value3", resp.Attr3)
+		}
+		done <- err
+	}()
+
+	<-entered
+	if got := health(); got != http.StatusOK {
+		t.Fatalf("got: %d, want: %d", got, http.StatusOK)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err == nil {
+		t.Fatal("want: error of the in-flight request")
+	}
+
+	if got := health(); got != http.StatusServiceUnavailable {
+		t.Fatalf("got: %d, want: %d", got, http.StatusServiceUnavailable)
+	}
+
+	if err := client.Call(context.Background(), "block", request{}, nil); err == nil || err.Error() != "soap: soapenv:Server: server is shutting down 503" {
+		t.Fatalf("got: %v, want: server is shutting down", err)
+	}
+
+	close(block)
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,159 @@
+package soap
+
+import (
+	"context"
+	"crypto"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errSignatureConfirmation = fmt.Errorf("soap: response does not confirm signatures of the request")
+
+// NewSignatureConfirmation returns middleware of WS-Security 1.1 signature confirmation: the call is failed
+// when wsse11:SignatureConfirmation elements of the response do not match ds:SignatureValue of the signed request
+// or are not signed by the response signature verified with the server key.
+// It must follow the signing middleware, e.g. SAML, responses of unsigned requests and faults are not checked.
+func NewSignatureConfirmation(serverKey crypto.PublicKey) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			values := signatureValues(r.Envelope)
+			resp, err := next(ctx, r)
+			if err != nil || len(values) == 0 || resp.StatusCode != http.StatusOK {
+				return resp, err
+			}
+
+			if err := checkSignatureConfirmation(resp.Body, values, serverKey); err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+	}
+}
+
+// signatureValues returns ds:SignatureValue of the signatures of the security header.
+func signatureValues(envelope []byte) []string {
+	root, err := parseDoc(trimDecl(envelope))
+	if err != nil {
+		return nil
+	}
+
+	security := root.find("Header", "Security")
+	if security == nil {
+		return nil
+	}
+
+	var values []string
+	for _, c := range security.children {
+		if n, ok := c.(*xnode); ok && n.name.Local == "Signature" && n.namespace(n.name.Space) == DSigNS {
+			values = append(values, strings.Join(strings.Fields(n.child("SignatureValue").text()), ""))
+		}
+	}
+	return values
+}
+
+// checkSignatureConfirmation checks that the response confirms each of the signature values and nothing else
+// by the confirmations signed with the key.
+func checkSignatureConfirmation(envelope []byte, values []string, key crypto.PublicKey) error {
+	root, err := parseDoc(trimDecl(envelope))
+	if err != nil {
+		return err
+	}
+
+	security := root.find("Header", "Security")
+	if security == nil {
+		return errSignatureConfirmation
+	}
+
+	signed, err := VerifySignature(trimDecl(envelope), key)
+	if err != nil {
+		return err
+	}
+
+	confirmed := make(map[string]bool)
+	for _, c := range security.children {
+		n, ok := c.(*xnode)
+		if !ok || n.name.Local != "SignatureConfirmation" || n.namespace(n.name.Space) != WSSE11NS {
+			continue
+		}
+
+		if !contains(signed, n.id()) {
+			return fmt.Errorf("soap: signature confirmation is not signed")
+		}
+
+		v, _ := attr(n, "Value")
+		if v = strings.Join(strings.Fields(v), ""); !contains(values, v) {
+			return fmt.Errorf("soap: response confirms signature which is not sent")
+		}
+		confirmed[v] = true
+	}
+
+	if len(confirmed) != len(values) {
+		return errSignatureConfirmation
+	}
+	return nil
+}
+
+// confirmationParts are signed parts of the response confirming signatures.
+var confirmationParts = []xml.Name{BodyPart, {Space: WSSE11NS, Local: "SignatureConfirmation"}}
+
+// writeConfirmation writes the response with the body and signature confirmations signed with the key.
+func writeConfirmation(w http.ResponseWriter, env Envelope, key crypto.Signer) {
+	b, err := xml.Marshal(env)
+	if err == nil {
+		var ids []string
+		if b, ids, err = withSignedParts(b, confirmationParts); err == nil {
+			b, err = signEnvelope(b, key, "", ids)
+		}
+	}
+
+	if err != nil {
+		writeEnvelope(w, http.StatusInternalServerError, Envelope{Body: Body{Fault: NewServerFault("response is not signed")}})
+		return
+	}
+
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// signatureConfirmations implements security header of the response confirming signature values
+// of the request, single confirmation without value is written for unsigned request.
+type signatureConfirmations []string
+
+// MarshalXML implements xml.Marshaler interface, names are written with literal prefixes.
+func (c signatureConfirmations) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	security := xml.StartElement{Name: xml.Name{Local: "wsse:Security"}, Attr: []xml.Attr{
+		{Name: xml.Name{Local: "xmlns:wsse"}, Value: WSSENS},
+		{Name: xml.Name{Local: "xmlns:wsse11"}, Value: WSSE11NS},
+		{Name: xml.Name{Local: "xmlns:wsu"}, Value: WSUNS},
+	}}
+	if err := e.EncodeToken(security); err != nil {
+		return err
+	}
+
+	values := []string(c)
+	if len(values) == 0 {
+		values = []string{""}
+	}
+
+	for i, v := range values {
+		se := xml.StartElement{Name: xml.Name{Local: "wsse11:SignatureConfirmation"}, Attr: []xml.Attr{
+			{Name: xml.Name{Local: "wsu:Id"}, Value: "SigConf-" + strconv.Itoa(i+1)},
+		}}
+		if v != "" {
+			se.Attr = append(se.Attr, xml.Attr{Name: xml.Name{Local: "Value"}, Value: v})
+		}
+
+		if err := e.EncodeToken(se); err != nil {
+			return err
+		}
+
+		if err := e.EncodeToken(se.End()); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(security.End())
+}
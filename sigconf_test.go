@@ -0,0 +1,169 @@
+package soap
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignatureConfirmation(t *testing.T) {
+	t.Parallel()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var unsigned []string
+	newServer := func(confirm bool, key crypto.Signer) *httptest.Server {
+		s := NewServer(ServerConfig{SignatureConfirmation: confirm, SignatureKey: key})
+		s.Handle("get", func(ctx context.Context, r *ServerRequest) (interface{}, error) {
+			if len(signatureValues(r.Envelope)) == 0 {
+				unsigned = append(unsigned, r.Action)
+			}
+			return response{Attr3: "value3"}, nil
+		})
+		return httptest.NewServer(s)
+	}
+
+	srv := newServer(true, serverKey)
+	defer srv.Close()
+
+	saml := &SAML{Assertion: []byte(samlAssertion), Key: key, TTL: time.Minute}
+	client := MustNewClient(srv.URL, Config{Middleware: []Middleware{saml.Middleware(), NewSignatureConfirmation(serverKey.Public())}})
+	resp := &response{}
+	if err := client.Call(context.Background(), "get", request{}, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Attr3 != "value3" || len(unsigned) != 0 {
+		t.Fatalf("got: %s %v, want: value3 []", resp.Attr3, unsigned)
+	}
+
+	// response is signed with other key
+	client = MustNewClient(srv.URL, Config{Middleware: []Middleware{saml.Middleware(), NewSignatureConfirmation(key.Public())}})
+	if err := client.Call(context.Background(), "get", request{}, &response{}); err != errSignature {
+		t.Fatalf("got: %v, want: %s", err, errSignature)
+	}
+
+	// bearer assertion is not signed
+	saml = &SAML{Assertion: []byte(samlAssertion)}
+	client = MustNewClient(srv.URL, Config{Middleware: []Middleware{saml.Middleware(), NewSignatureConfirmation(serverKey.Public())}})
+	if err := client.Call(context.Background(), "get", request{}, &response{}); err != nil {
+		t.Fatal(err)
+	}
+
+	noConfirm := newServer(false, nil)
+	defer noConfirm.Close()
+
+	saml = &SAML{Assertion: []byte(samlAssertion), Key: key}
+	client = MustNewClient(noConfirm.URL, Config{Middleware: []Middleware{saml.Middleware(), NewSignatureConfirmation(serverKey.Public())}})
+	if err := client.Call(context.Background(), "get", request{}, &response{}); err != errSignatureConfirmation {
+		t.Fatalf("got: %v, want: %s", err, errSignatureConfirmation)
+	}
+
+	unsignedConfirm := newServer(true, nil)
+	defer unsignedConfirm.Close()
+
+	client = MustNewClient(unsignedConfirm.URL, Config{Middleware: []Middleware{saml.Middleware(), NewSignatureConfirmation(serverKey.Public())}})
+	if err := client.Call(context.Background(), "get", request{}, &response{}); err != errSignature {
+		t.Fatalf("got: %v, want: %s", err, errSignature)
+	}
+}
+
+func TestSignatureConfirmation_Unsigned(t *testing.T) {
+	t.Parallel()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []struct {
+		parts []xml.Name
+		err   string
+	}{
+		{parts: confirmationParts},
+		{parts: []xml.Name{BodyPart}, err: "soap: signature confirmation is not signed"},
+	} {
+		b, err := xml.Marshal(Envelope{Header: &Header{Items: []interface{}{signatureConfirmations{"AAA"}}}, Body: Body{Content: response{Attr3: "value3"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, ids, err := withSignedParts(b, v.parts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if b, err = signEnvelope(b, key, "", ids); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := checkSignatureConfirmation(b, []string{"AAA"}, key.Public()); v.err == "" && err != nil || v.err != "" && (err == nil || err.Error() != v.err) {
+			t.Fatalf("%v got: %v, want: %s", v.parts, err, v.err)
+		}
+	}
+}
+
+func TestServer_SignatureConfirmation(t *testing.T) {
+	t.Parallel()
+	s := NewServer(ServerConfig{SignatureConfirmation: true})
+	s.Handle("get", func(ctx context.Context, r *ServerRequest) (interface{}, error) {
+		return response{Attr3: "value3"}, nil
+	})
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	for i, v := range []struct {
+		security string
+		want     []string
+	}{
+		{want: []string{""}},
+		{
+			security: `<wsse:Security xmlns:wsse="` + WSSENS + `"><ds:Signature xmlns:ds="` + DSigNS + `"><ds:SignatureValue>AAA
+BBB</ds:SignatureValue></ds:Signature><ds:Signature xmlns:ds="` + DSigNS + `"><ds:SignatureValue>CCC</ds:SignatureValue></ds:Signature></wsse:Security>`,
+			want: []string{"AAABBB", "CCC"},
+		},
+	} {
+		body := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Header>` + v.security +
+			`</soapenv:Header><soapenv:Body><Request xmlns="test:call"/></soapenv:Body></soapenv:Envelope>`
+		req, _ := http.NewRequest("POST", srv.URL, strings.NewReader(body))
+		req.Header.Set("SOAPAction", "get")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		n, err := ParseNode(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		confirmations := n.FindAll("Envelope/Header/Security/SignatureConfirmation")
+		if len(confirmations) != len(v.want) {
+			t.Fatalf("#%d got: %d, want: %d", i, len(confirmations), len(v.want))
+		}
+
+		for j, c := range confirmations {
+			if got, _ := c.Attr("Value"); got != v.want[j] {
+				t.Errorf("#%d got: %s, want: %s", i, got, v.want[j])
+			}
+		}
+	}
+}
@@ -3,18 +3,32 @@ package soap
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime"
 	"net"
 	"net/http"
+	"net/http/httptrace"
+	neturl "net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
+// Version is version of the package sent in the default User-Agent.
+const Version = "1.0.0"
+
 var (
 	errUnauthorized = fmt.Errorf("soap: unauthorized")
 	errBody         = fmt.Errorf("soap: body response is empty")
+	errPinning      = fmt.Errorf("soap: certificate chain does not match pinned fingerprints")
+	errClosed       = fmt.Errorf("soap: client is closed")
+	errDTD          = fmt.Errorf("soap: response must not contain DTD")
 )
 
 // Envelope implements soap envelope.
@@ -35,6 +49,9 @@ type Body struct {
 	XMLName xml.Name    `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
 	Fault   *Fault      `xml:",omitempty"`
 	Content interface{} `xml:",omitempty"`
+
+	// faultWithBody decodes content next to the fault
+	faultWithBody bool
 }
 
 // UnmarshalXML implements xml.Unmarshaler interface.
@@ -61,18 +78,41 @@ Loop:
 
 		switch se := token.(type) {
 		case xml.StartElement:
-			if consumed {
+			fault := se.Name.Space == "http://schemas.xmlsoap.org/soap/envelope/" && se.Name.Local == "Fault"
+			if consumed && !(fault && b.faultWithBody && b.Fault == nil) {
 				return xml.UnmarshalError("found multiple elements inside SOAP body; not wrapped-document/literal WS-I compliant")
-			} else if se.Name.Space == "http://schemas.xmlsoap.org/soap/envelope/" && se.Name.Local == "Fault" {
+			} else if fault {
 				b.Fault = &Fault{}
-				b.Content = nil
+				if !b.faultWithBody {
+					b.Content = nil
+				}
 
 				err = d.DecodeElement(b.Fault, &se)
 				if err != nil {
 					return err
 				}
 
+				consumed = consumed || !b.faultWithBody
+			} else if choice, ok := b.Content.(*Choice); ok {
+				if err = choice.decode(d, se); err != nil {
+					return err
+				}
+
+				consumed = true
+			} else if targets, ok := b.Content.(Targets); ok {
+				if err = targets.decode(d, se); err != nil {
+					return err
+				}
+			} else if selection, ok := b.Content.(*Selection); ok {
+				if err = selection.decode(d, se); err != nil {
+					return err
+				}
+
 				consumed = true
+			} else if stream, ok := b.Content.(Stream); ok {
+				if err = stream.decode(d, se); err != nil {
+					return err
+				}
 			} else {
 				if err = d.DecodeElement(b.Content, &se); err != nil {
 					return err
@@ -88,6 +128,28 @@ Loop:
 	return nil
 }
 
+// Targets implements response content of several body elements, each element is
+// decoded into the target keyed by its name, a key without namespace matches any namespace.
+// Elements without target are skipped.
+type Targets map[xml.Name]interface{}
+
+func (t Targets) decode(d *xml.Decoder, se xml.StartElement) error {
+	v, ok := t.match(se.Name)
+	if !ok {
+		return d.Skip()
+	}
+	return d.DecodeElement(v, &se)
+}
+
+// match returns target of the element name.
+func (t Targets) match(name xml.Name) (interface{}, bool) {
+	v, ok := t[name]
+	if !ok {
+		v, ok = t[xml.Name{Local: name.Local}]
+	}
+	return v, ok
+}
+
 type trimSpace string
 
 func (ts *trimSpace) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
@@ -112,6 +174,7 @@ type Fault struct {
 	Actor      trimSpace `xml:"faultactor,omitempty"`
 	Detail     trimSpace `xml:"detail,omitempty"`
 	HTTPStatus int       `xml:"-"`
+	CallInfo   `xml:"-"`
 }
 
 func (f *Fault) Error() string {
@@ -140,29 +203,332 @@ type Config struct {
 	BasicAuth           *BasicAuth
 	TLS                 *tls.Config
 	MaxIdleConnsPerHost int
+
+	// TLSPreset is applied on top of TLS.
+	TLSPreset TLSPreset
+	// CipherSuites and CurvePreferences override the preset values.
+	CipherSuites     []uint16
+	CurvePreferences []tls.CurveID
+	// DialTLSContext replaces crypto/tls for https endpoints.
+	DialTLSContext TLSDialer
+	// PinnedCertificates and PinnedSPKIHashes are SHA-256 fingerprints,
+	// the server certificate chain must match at least one of them.
+	PinnedCertificates [][]byte
+	PinnedSPKIHashes   [][]byte
+	// Retry is used for failed calls, by default calls are not retried.
+	Retry RetryPolicy
+	// Hedge enables hedging of idempotent calls.
+	Hedge *HedgePolicy
+	// Throttle limits rate of the calls by soap action, each attempt is limited,
+	// ThrottleDefault key limits actions without own rate. By default calls are not limited.
+	Throttle map[string]Rate
+	// Middleware wraps round trips, the first one is the outermost.
+	Middleware []Middleware
+	// MaxRequestBytes and MaxRequestDepth limit size and element depth of the request envelope.
+	MaxRequestBytes int
+	MaxRequestDepth int
+	// DecodeLimits protects response decoding against xml bombs, DTDs are always rejected.
+	DecodeLimits DecodeLimits
+	// HeaderLimits validates response header elements, *LimitError or *MustUnderstandError is returned when violated.
+	HeaderLimits HeaderLimits
+	// DecodeMode is Lenient by default, OnUnknownElement is called for response elements
+	// unknown to the response type in any mode.
+	DecodeMode       DecodeMode
+	OnUnknownElement UnknownElementFunc
+	// Namespaces are namespace uris of the response types, equivalent uris of the response
+	// differing by trailing slash or https scheme are decoded as them, the envelope namespace included.
+	Namespaces []string
+	// WrapTransport wraps http transport, e.g. for NTLM or Kerberos negotiation.
+	WrapTransport func(http.RoundTripper) http.RoundTripper
+	// KeepAlive reuses connections between calls, it is required by connection-bound
+	// authentication like NTLM. By default connection is closed after the call.
+	KeepAlive bool
+	// PreconnectConns is number of connections opened by Preconnect, one when zero.
+	// Idle connections above MaxIdleConnsPerHost are closed.
+	PreconnectConns int
+	// Jar keeps session cookies between calls.
+	Jar http.CookieJar
+	// WSSE adds WS-Security header to each request.
+	WSSE *WSSE
+	// Stats is called with stats of each call.
+	Stats func(st Stats)
+	// UserAgent overrides the default "itcomusic-soap/<version>" user agent.
+	UserAgent string
+	// Headers are sent with each request, headers of the middleware request take precedence.
+	Headers http.Header
+	// Accept is sent as Accept header unless it is set by headers, e.g. "application/soap+xml, multipart/related",
+	// since some servers vary the response format by it. By default the header is not sent.
+	Accept string
+	// Chunked sends request with chunked transfer encoding, by default the envelope
+	// is buffered and Content-Length is sent, since old servers reject chunked requests.
+	Chunked bool
+	// StrictContentType rejects responses which content type is not xml,
+	// by default the envelope is decoded regardless of the content type.
+	StrictContentType bool
+	// FaultWithBody decodes body element returned next to the fault by broken servers
+	// into the response, the fault is still returned as error.
+	FaultWithBody bool
+	// Escape rewrites character escaping of the request envelope for servers
+	// rejecting references emitted by encoding/xml, e.g. EscapeMinimal.
+	Escape EscapeFunc
+	// RawResponseTransformers repair the raw response body in order before it is decoded,
+	// e.g. ScrubInvalidChars or duplicate namespace declarations of partner responses.
+	RawResponseTransformers []RawResponseTransformer
+	// RawRequestTransformers rewrite the marshaled request envelope in order after Escape,
+	// e.g. ReplaceNamespace for servers expecting a legacy namespace.
+	RawRequestTransformers []RawRequestTransformer
+	// Timeout limits each http round trip in addition to the call context.
+	Timeout time.Duration
+	// Proxy is the http proxy url, by default requests are sent directly.
+	Proxy *neturl.URL
+	// SpoolMemory is size of spooled data, e.g. large envelope or streamed request body kept for replay,
+	// kept in memory, the rest is spooled to a temporary file in SpoolDir, 1 MiB when zero.
+	// Spools of the call are removed when it completes.
+	SpoolMemory int64
+	// SpoolDir is directory of the temporary files, os.TempDir when empty.
+	SpoolDir string
+	// Resolver resolves endpoint hosts, by default net.DefaultResolver is used.
+	Resolver *net.Resolver
+	// DNSCacheTTL enables caching of resolved addresses, the cached addresses
+	// are also used when the resolver fails after they expired.
+	DNSCacheTTL time.Duration
+	// IPFamily restricts dialed addresses, by default both families are dialed.
+	// FallbackDelay is delay of the dual-stack fallback, 300ms when zero, negative disables it.
+	IPFamily      IPFamily
+	FallbackDelay time.Duration
+	// StickyHeaders lists response header elements, e.g. SessionId of stateful session protocols,
+	// which are captured and sent with the subsequent requests of the client.
+	// A name without namespace matches any namespace.
+	StickyHeaders []xml.Name
+	// Credentials are fetched for each request instead of BasicAuth.
+	Credentials CredentialsProvider
+	// TimeFormat encodes time.Time fields of the request as xsd:dateTime of the format,
+	// by default they are encoded by encoding/xml.
+	TimeFormat *TimeFormat
+	// Recorder writes each request as curl command or .http file for debugging.
+	Recorder *Recorder
+
+	insecureSkipVerify bool
 }
 
-// Client implements soap client.
+// Client implements soap client, it is safe for concurrent use including
+// adding headers and replacing the endpoint while calls are in flight.
 type Client struct {
-	url        string
-	auth       *BasicAuth
-	headers    []interface{}
-	httpClient *http.Client
+	// mu guards url, auth, httpClient, headers and fault mappings which may be changed at runtime
+	mu        sync.RWMutex
+	url       string
+	auth      *BasicAuth
+	headers   []interface{}
+	headerFns []HeaderFunc
+	// actionHeaders are sent only with the soap action
+	actionHeaders map[string][]interface{}
+	// stickyHeaders are captured from responses
+	stickyHeaders map[xml.Name]RawElement
+	faultMaps     []FaultMapFunc
+	errorElements []errorElement
+	namespaces    namespaces
+	conns         connMetrics
+	throttle      *throttle
+	config        Config
+	httpClient    *http.Client
+	transport     RoundTripFunc
+	closed        context.Context
+	close         context.CancelFunc
+	// calls counts calls in progress, idle connections of the drained client are closed after the last one
+	calls struct {
+		sync.Mutex
+		n       int
+		drained bool
+	}
+}
+
+// NewClient creates soap client, the endpoint url, tls and auth options are validated.
+func NewClient(url string, c Config) (*Client, error) {
+	if err := c.validate(url); err != nil {
+		return nil, err
+	}
+
+	closed, close := context.WithCancel(context.Background())
+	s := &Client{
+		closed: closed,
+		close:  close,
+		url:    url,
+		auth:   c.BasicAuth,
+		config: c,
+		// namespaces are nil unless configured
+		namespaces: newNamespaces(c.Namespaces),
+		throttle:   newThrottle(c.Throttle),
+	}
+
+	s.httpClient = c.httpClient(&s.conns)
+	if c.WSSE != nil {
+		s.AddHeaderFunc(c.WSSE.Header)
+	}
+	s.transport = chain(s.roundTrip, c.Middleware)
+	return s, nil
+}
+
+// MustNewClient is like NewClient but panics on invalid config.
+func MustNewClient(url string, c Config) *Client {
+	s, err := NewClient(url, c)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// httpClient returns http client, connections of the transport are counted by the metrics.
+func (c Config) httpClient(m *connMetrics) *http.Client {
+	var proxy func(*http.Request) (*neturl.URL, error)
+	if c.Proxy != nil {
+		proxy = http.ProxyURL(c.Proxy)
+	}
+
+	var rt http.RoundTripper = &connTransport{m: m, rt: &http.Transport{
+		Proxy:               proxy,
+		TLSClientConfig:     c.tlsConfig(),
+		DialContext:         m.dial(c.dial()),
+		DialTLSContext:      m.dial(dialFunc(c.DialTLSContext)),
+		MaxIdleConnsPerHost: c.MaxIdleConnsPerHost,
+	}}
+	if c.WrapTransport != nil {
+		rt = c.WrapTransport(rt)
+	}
+	return &http.Client{Transport: rt, Jar: c.Jar, Timeout: c.Timeout}
+}
+
+// validate checks the endpoint and options which would fail calls later.
+func (c Config) validate(endpoint string) error {
+	if err := validateURL(endpoint); err != nil {
+		return err
+	}
+
+	if err := validateAuth(c.BasicAuth); err != nil {
+		return err
+	}
+
+	if err := validateThrottle(c.Throttle); err != nil {
+		return err
+	}
+
+	if c.BasicAuth != nil && c.Credentials != nil {
+		return fmt.Errorf("soap: basic auth must not be set with credentials provider")
+	}
+
+	if _, ok := tlsPresets[c.TLSPreset]; !ok {
+		return fmt.Errorf("soap: unknown tls preset %q", c.TLSPreset)
+	}
+
+	if c.Accept != "" {
+		for _, accept := range strings.Split(c.Accept, ",") {
+			if mediaType, _, err := mime.ParseMediaType(accept); err != nil || !strings.Contains(mediaType, "/") {
+				return fmt.Errorf("soap: accept %q is invalid", c.Accept)
+			}
+		}
+	}
+
+	if c.IPFamily != DualStack && c.IPFamily != IPv4Only && c.IPFamily != IPv6Only {
+		return fmt.Errorf("soap: unknown ip family %q", c.IPFamily)
+	}
+
+	if c.DialTLSContext != nil && c.tlsConfig() != nil {
+		return fmt.Errorf("soap: tls options are not applied with DialTLSContext")
+	}
+
+	for _, pin := range append(append([][]byte(nil), c.PinnedCertificates...), c.PinnedSPKIHashes...) {
+		if len(pin) != sha256.Size {
+			return fmt.Errorf("soap: pinned fingerprint must be SHA-256")
+		}
+	}
+	return nil
+}
+
+func validateURL(endpoint string) error {
+	u, err := neturl.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("soap: %s", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("soap: endpoint %q must be absolute http or https url", endpoint)
+	}
+	return nil
+}
+
+func validateAuth(auth *BasicAuth) error {
+	if auth != nil && auth.Username == "" {
+		return fmt.Errorf("soap: basic auth username is empty")
+	}
+	return nil
+}
+
+// SetURL replaces the endpoint url, calls in flight are not affected.
+func (s *Client) SetURL(url string) error {
+	if err := validateURL(url); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.url = url
+	s.mu.Unlock()
+	return nil
+}
+
+// SetBasicAuth replaces basic authorization credentials, nil disables basic authorization.
+// It is not used when credentials provider is configured.
+func (s *Client) SetBasicAuth(auth *BasicAuth) error {
+	if err := validateAuth(auth); err != nil {
+		return err
+	}
+
+	if auth != nil {
+		auth = &BasicAuth{Username: auth.Username, Password: auth.Password}
+	}
+
+	s.mu.Lock()
+	s.auth = auth
+	s.mu.Unlock()
+	return nil
 }
 
-// NewClient creates soap client.
-func NewClient(url string, c Config) *Client {
-	return &Client{
-		url:  url,
-		auth: c.BasicAuth,
-		httpClient: &http.Client{Transport: &http.Transport{
-			TLSClientConfig: c.TLS,
-			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-				return (&net.Dialer{}).DialContext(ctx, network, addr)
-			},
-			MaxIdleConnsPerHost: c.MaxIdleConnsPerHost,
-		}},
+// SetTLS replaces tls configuration, e.g. rotated client certificate. New connections
+// use the configuration, idle connections are closed and calls in flight are completed.
+func (s *Client) SetTLS(cfg *tls.Config) error {
+	c := s.config
+	c.TLS = cfg
+	if err := c.validate(s.endpoint()); err != nil {
+		return err
 	}
+
+	httpClient := c.httpClient(&s.conns)
+	s.mu.Lock()
+	prev := s.httpClient
+	s.httpClient = httpClient
+	s.mu.Unlock()
+
+	prev.CloseIdleConnections()
+	return nil
+}
+
+// endpoint returns the current endpoint url.
+func (s *Client) endpoint() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.url
+}
+
+// current returns basic authorization and http client used by the next round trip.
+func (s *Client) current() (*BasicAuth, *http.Client) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.auth, s.httpClient
+}
+
+func (c Config) userAgent() string {
+	if c.UserAgent != "" {
+		return c.UserAgent
+	}
+	return "itcomusic-soap/" + Version
 }
 
 // BasicAuth implements work with basic authorization.
@@ -171,11 +537,116 @@ type BasicAuth struct {
 	Password string
 }
 
+// HeaderFunc returns header computed at send time, nil header is skipped.
+type HeaderFunc func(ctx context.Context) (interface{}, error)
+
 // AddHeader adds header.
 func (s *Client) AddHeader(header interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.headers = append(s.headers, header)
 }
 
+// AddHeaderFor adds header sent only with requests of the soap action.
+func (s *Client) AddHeaderFor(soapAction string, header interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.actionHeaders == nil {
+		s.actionHeaders = make(map[string][]interface{})
+	}
+	s.actionHeaders[soapAction] = append(s.actionHeaders[soapAction], header)
+}
+
+// AddHeaderFunc adds header which is evaluated per request,
+// e.g. security headers with nonce and timestamp.
+func (s *Client) AddHeaderFunc(fn HeaderFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.headerFns = append(s.headerFns, fn)
+}
+
+func (s *Client) header(ctx context.Context, soapAction string) (*Header, error) {
+	// header funcs are called outside the lock, they may block
+	s.mu.RLock()
+	items := make([]interface{}, 0, len(s.headers)+len(s.actionHeaders[soapAction])+len(s.headerFns))
+	items = append(items, s.headers...)
+	items = append(items, s.actionHeaders[soapAction]...)
+	for _, name := range s.config.StickyHeaders {
+		if h, ok := s.stickyHeaders[name]; ok {
+			items = append(items, h)
+		}
+	}
+	fns := s.headerFns[:len(s.headerFns):len(s.headerFns)]
+	s.mu.RUnlock()
+
+	for _, fn := range fns {
+		h, err := fn(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("soap: header %s", err)
+		}
+
+		if h != nil {
+			items = append(items, h)
+		}
+	}
+
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return &Header{Items: items}, nil
+}
+
+// CloseIdleConnections closes connections which are not in use.
+func (s *Client) CloseIdleConnections() {
+	_, httpClient := s.current()
+	httpClient.CloseIdleConnections()
+}
+
+// Close cancels in-flight requests and closes idle connections, the client must not be used after.
+func (s *Client) Close() error {
+	s.close()
+	s.CloseIdleConnections()
+	return nil
+}
+
+// drain closes idle connections now and after the calls which are in progress,
+// so connections of the client which is no longer used are not kept open.
+func (s *Client) drain() {
+	s.calls.Lock()
+	s.calls.drained = true
+	s.calls.Unlock()
+	s.CloseIdleConnections()
+}
+
+// withClose returns context which is also canceled when the client is closed,
+// the call is counted until the context is canceled.
+func (s *Client) withClose(ctx context.Context) (context.Context, context.CancelFunc) {
+	s.calls.Lock()
+	s.calls.n++
+	s.calls.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(s.closed, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+
+		s.calls.Lock()
+		s.calls.n--
+		idle := s.calls.drained && s.calls.n == 0
+		s.calls.Unlock()
+		if idle {
+			// connections of the finished calls are returned to the idle pool
+			s.CloseIdleConnections()
+		}
+	}
+}
+
+// CallMulti sends soap request and decodes several response body elements into the targets.
+func (s *Client) CallMulti(ctx context.Context, soapAction string, request interface{}, targets Targets) error {
+	return s.Call(ctx, soapAction, request, targets)
+}
+
 // Call sends soap request.
 func (s *Client) Call(ctx context.Context, soapAction string, request, response interface{}) error {
 	// action may be empty
@@ -183,66 +654,341 @@ func (s *Client) Call(ctx context.Context, soapAction string, request, response
 		response = new(interface{})
 	}
 
-	var envelope Envelope
-	if s.headers != nil && len(s.headers) > 0 {
-		soapHeader := &Header{Items: make([]interface{}, len(s.headers))}
-		copy(soapHeader.Items, s.headers)
-		envelope.Header = soapHeader
+	if s.closed.Err() != nil {
+		return errClosed
+	}
+
+	ctx, cancel := s.withClose(ctx)
+	defer cancel()
+	return s.call(ctx, soapAction, request, response)
+}
+
+// call encodes the request with the extra headers and sends it with retries, stats and call info.
+func (s *Client) call(ctx context.Context, soapAction string, request, response interface{}, extra ...interface{}) error {
+	ctx, closeSpools := s.withSpools(ctx)
+	defer closeSpools()
+
+	st := &Stats{Action: soapAction}
+	start := time.Now()
+	envelope, body, err := s.encodeBody(ctx, soapAction, request, extra...)
+	st.MarshalDuration, st.RequestBytes = time.Since(start), len(envelope)
+	if body != nil {
+		st.RequestBytes = int(body.Size())
+	}
+	if err != nil {
+		return s.report(st, err)
 	}
 
-	envelope.Body.Content = request
+	endpoint := s.endpoint()
+	err = s.config.Retry.do(ctx, func(ctx context.Context) error {
+		st.Attempts++
+		return s.send(ctx, soapAction, envelope, body, response, st)
+	})
+	err = withCallInfo(withCause(ctx, err), CallInfo{action: soapAction, endpoint: endpoint, attempts: st.Attempts, elapsed: time.Since(start)})
+	return s.report(st, s.mapFault(err))
+}
+
+// encode encodes envelope of the request with the client and action headers followed by the extra headers.
+func (s *Client) encode(ctx context.Context, soapAction string, request interface{}, extra ...interface{}) ([]byte, error) {
 	buffer := new(bytes.Buffer)
+	if err := s.encodeTo(ctx, buffer, soapAction, request, extra...); err != nil {
+		return nil, err
+	}
+
+	data := buffer.Bytes()
+	var err error
+	if s.config.Escape != nil {
+		data = s.config.Escape(data)
+	}
+
+	for _, transform := range s.config.RawRequestTransformers {
+		if data, err = transform(ctx, soapAction, data); err != nil {
+			return nil, fmt.Errorf("soap: %s", err)
+		}
+	}
+
+	if err := checkDepth(bytes.NewReader(data), s.config.MaxRequestDepth); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// encodeBody encodes envelope of the call into the spool, so content streamed by Base64Reader is not kept
+// in memory: envelope exceeding SpoolMemory is returned as the spool sent as request body. Envelope is encoded
+// in memory when middleware, Escape or RawRequestTransformers of the client need it.
+func (s *Client) encodeBody(ctx context.Context, soapAction string, request interface{}, extra ...interface{}) ([]byte, *Spool, error) {
+	if len(s.config.Middleware) > 0 || s.config.Escape != nil || len(s.config.RawRequestTransformers) > 0 {
+		envelope, err := s.encode(ctx, soapAction, request, extra...)
+		return envelope, nil, err
+	}
+
+	sp := NewSpool(ctx)
+	if err := s.encodeTo(ctx, sp, soapAction, request, extra...); err != nil {
+		return nil, nil, err
+	}
+
+	if sp.file == nil {
+		data := sp.mem.Bytes()
+		if err := checkDepth(bytes.NewReader(data), s.config.MaxRequestDepth); err != nil {
+			return nil, nil, err
+		}
+		return data, nil, nil
+	}
 
-	encoder := xml.NewEncoder(buffer)
+	if err := checkDepth(sp.Reader(), s.config.MaxRequestDepth); err != nil {
+		return nil, nil, err
+	}
+	return nil, sp, nil
+}
+
+// encodeTo writes envelope of the request to the writer.
+func (s *Client) encodeTo(ctx context.Context, w io.Writer, soapAction string, request interface{}, extra ...interface{}) error {
+	header, err := s.header(ctx, soapAction)
+	if err != nil {
+		return err
+	}
+
+	request, lifted, err := liftHeaders(request)
+	if err != nil {
+		return err
+	}
+
+	if request, err = formatTimes(request, s.config.TimeFormat); err != nil {
+		return err
+	}
+
+	for i, h := range lifted {
+		if lifted[i], err = formatTimes(h, s.config.TimeFormat); err != nil {
+			return err
+		}
+	}
+	extra = append(lifted, extra...)
+
+	if len(extra) > 0 {
+		if header == nil {
+			header = &Header{}
+		}
+		header.Items = append(header.Items, extra...)
+	}
+
+	envelope := Envelope{Header: header, Body: Body{Content: request}}
+	encoder := xml.NewEncoder(&limitWriter{w: w, max: s.config.MaxRequestBytes})
 	//encoder.Indent("  ", "    ")
 	if err := encoder.Encode(envelope); err != nil {
-		return fmt.Errorf("soap: %s", err)
+		return encodeError(err)
 	}
 	if err := encoder.Flush(); err != nil {
-		return fmt.Errorf("soap: %s", err)
+		return encodeError(err)
 	}
+	return nil
+}
 
-	req, err := http.NewRequest("POST", s.url, buffer)
-	if err != nil {
-		return fmt.Errorf("soap: %s", err)
+// send sends encoded envelope or the spooled one and decodes the response.
+func (s *Client) send(ctx context.Context, soapAction string, envelope []byte, body *Spool, response interface{}, st *Stats) error {
+	// network phases are traced only when stats are reported
+	var tr *tracer
+	if s.config.Stats != nil {
+		tr = new(tracer)
+		ctx = httptrace.WithClientTrace(ctx, tr.trace())
 	}
-	if s.auth != nil {
-		req.SetBasicAuth(s.auth.Username, s.auth.Password)
+
+	if err := s.throttle.wait(ctx, soapAction); err != nil {
+		return err
 	}
-	req.Header.Add("Content-Type", "text/xml; charset=\"utf-8\"")
-	req.Header.Add("SOAPAction", soapAction)
-	req.Close = true
 
-	resp, err := s.httpClient.Do(req.WithContext(ctx))
+	req := s.newRequest(soapAction, envelope)
+	if body != nil {
+		req.Body = body.Reader()
+	}
+
+	start := time.Now()
+	resp, err := s.transport(s.withIdentity(ctx), req)
+	st.NetworkDuration += time.Since(start)
+	tr.add(st)
 	if err != nil {
-		return fmt.Errorf("soap: %s", err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	start = time.Now()
+	st.ResponseBytes = len(resp.Body)
+	if resp, err = s.transformResponse(ctx, soapAction, resp); err != nil {
+		return err
+	}
+
+	err = s.decodeErrors(resp, soapAction, response)
+	s.captureHeaders(resp.Body)
+	st.DecodeDuration += time.Since(start)
+	return err
+}
+
+// trimProlog removes UTF-8 BOM, whitespace and stray characters emitted by legacy servers
+// before the xml declaration or the root element.
+func trimProlog(body []byte) []byte {
+	body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+	if i := bytes.IndexByte(body, '<'); i >= 0 {
+		return body[i:]
+	}
+	return nil
+}
+
+func (s *Client) newRequest(soapAction string, envelope []byte) *Request {
+	return &Request{URL: s.endpoint(), Action: soapAction, Header: make(http.Header), Envelope: envelope}
+}
+
+// roundTrip sends encoded envelope, it is the innermost round trip of the middleware chain.
+func (s *Client) roundTrip(ctx context.Context, r *Request) (*Response, error) {
+	// streamed body is spooled once, so hedged requests and redirects replay it
+	var body *Spool
+	if r.Body != nil {
+		ctx, closeSpools := s.withSpools(ctx)
+		defer closeSpools()
+
+		sp, err := spoolBody(ctx, r.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		// the envelope is not sent instead of empty or consumed body
+		if sp.Size() == 0 {
+			return nil, errEmptyBody
+		}
+		body = sp
+	}
+
+	return s.config.Hedge.do(ctx, r.URL, r.Action, func(ctx context.Context, url string) (*Response, error) {
+		req, err := http.NewRequest("POST", url, bytes.NewReader(r.Envelope))
+		if err != nil {
+			return nil, fmt.Errorf("soap: %s", err)
+		}
+		if body != nil {
+			req.Body, req.ContentLength = body.Reader(), body.Size()
+			req.GetBody = func() (io.ReadCloser, error) {
+				return body.Reader(), nil
+			}
+		}
+		auth, httpClient := s.current()
+		if err := s.authorize(ctx, req, auth); err != nil {
+			return nil, err
+		}
+		for k, v := range s.config.Headers {
+			req.Header[k] = v
+		}
+		for k, v := range r.Header {
+			req.Header[k] = v
+		}
+		if req.Header.Get("User-Agent") == "" {
+			req.Header.Set("User-Agent", s.config.userAgent())
+		}
+		if req.Header.Get("Accept") == "" && s.config.Accept != "" {
+			req.Header.Set("Accept", s.config.Accept)
+		}
+		if r.Header.Get("Content-Type") == "" {
+			req.Header.Set("Content-Type", "text/xml; charset=\"utf-8\"")
+		}
+		req.Header.Set("SOAPAction", r.Action)
+		req.Close = !s.config.KeepAlive
+		if s.config.Chunked {
+			req.ContentLength = -1
+			req.TransferEncoding = []string{"chunked"}
+		}
+		s.config.Recorder.record(req)
+
+		resp, err := httpClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, &transportError{err: err}
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, &transportError{err: err}
+		}
+		return &Response{Status: resp.Status, StatusCode: resp.StatusCode, Header: resp.Header, Body: body}, nil
+	})
+}
+
+// isXMLContentType reports whether the media type may carry soap envelope.
+func isXMLContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
 	if err != nil {
-		return fmt.Errorf("soap: %s", err)
+		return false
+	}
+
+	switch mediaType {
+	case "text/xml", "application/xml", "application/soap+xml", "application/xop+xml", "multipart/related":
+		return true
 	}
+	return strings.HasSuffix(mediaType, "+xml")
+}
+
+// statusError returns error of the response which is not soap envelope, content type
+// is reported unless it is xml.
+func (s *Client) statusError(r *Response) error {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" && !isXMLContentType(contentType) {
+		return &statusError{status: r.Status, code: r.StatusCode, contentType: strconv.Quote(contentType)}
+	}
+	return &statusError{status: r.Status, code: r.StatusCode}
+}
 
+// decode decodes response envelope, fault is returned as error.
+func (s *Client) decode(r *Response, response interface{}) error {
 	// body must not be empty
-	if len(body) == 0 {
+	if len(r.Body) == 0 {
 		return errBody
 	}
 
-	if resp.StatusCode == 401 {
+	if r.StatusCode == 401 {
 		return errUnauthorized
 	}
 
-	respEnvelope := &Envelope{Body: Body{Content: response}}
-	err = xml.Unmarshal(body, respEnvelope)
-	if err != nil {
-		return fmt.Errorf("soap: %s (%d)", resp.Status, resp.StatusCode)
+	contentType := r.Header.Get("Content-Type")
+	if s.config.StrictContentType && !isXMLContentType(contentType) {
+		return &statusError{status: r.Status, code: r.StatusCode, contentType: strconv.Quote(contentType)}
+	}
+
+	// body without elements, e.g. plain text error of the load balancer, is reported by the status
+	body := trimProlog(r.Body)
+	if len(body) == 0 {
+		return s.statusError(r)
+	}
+
+	if err := s.config.HeaderLimits.check(body); err != nil {
+		return err
+	}
+
+	respEnvelope := &Envelope{Body: Body{Content: response, faultWithBody: s.config.FaultWithBody}}
+	var tr xml.TokenReader = s.config.DecodeLimits.decoder(body)
+	if s.namespaces != nil {
+		tr = &nsTokenReader{r: tr, ns: s.namespaces}
+	}
+
+	d, release := scopedDecoder(tr)
+	defer release()
+
+	if err := d.Decode(respEnvelope); err != nil {
+		if e, ok := err.(*LimitError); ok {
+			return e
+		}
+
+		if err == errDTD {
+			return err
+		}
+
+		if e, ok := err.(*streamError); ok {
+			return e.err
+		}
+
+		if e, ok := err.(*ChoiceError); ok {
+			return e
+		}
+
+		return s.statusError(r)
 	}
 
 	// check fault
 	if respEnvelope.Body.Fault != nil {
-		respEnvelope.Body.Fault.HTTPStatus = resp.StatusCode
+		respEnvelope.Body.Fault.HTTPStatus = r.StatusCode
 		return respEnvelope.Body.Fault
 	}
-	return nil
+	return s.config.checkDecodeMode(body, response)
 }
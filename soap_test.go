@@ -2,10 +2,16 @@ package soap
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/xml"
+	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -77,7 +83,7 @@ func TestClient_Call(t *testing.T) {
 	defer srv.Close()
 
 	var r response
-	if err := NewClient(srv.URL, Config{}).Call(context.Background(), "soap.action", request{
+	if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "soap.action", request{
 		Attr1: "value1",
 		Attr2: "value2",
 	}, &r); err != nil {
@@ -97,7 +103,7 @@ func TestClient_EmptyAction(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	if err := NewClient(srv.URL, Config{}).Call(context.Background(), "", request{}, nil); err != nil {
+	if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "", request{}, nil); err != nil {
 		t.Fatal(err)
 	}
 }
@@ -137,7 +143,7 @@ func TestClient_Fault(t *testing.T) {
 	defer srv.Close()
 
 	want := "soap: fault text 500"
-	if err := NewClient(srv.URL, Config{}).Call(context.Background(), "", request{}, nil); err.Error() != want {
+	if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "", request{}, nil); err.Error() != want {
 		t.Fatalf("got: %s, want: %s", err, want)
 	}
 }
@@ -147,7 +153,7 @@ func TestClient_EmptyBody(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
 	defer srv.Close()
 
-	if err := NewClient(srv.URL, Config{}).Call(context.Background(), "", request{}, nil); err != errBody {
+	if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "", request{}, nil); err != errBody {
 		t.Fatalf("got: %s, want: %s", err, errBody)
 	}
 }
@@ -169,7 +175,7 @@ func TestClient_BasicAuth(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	NewClient(srv.URL, Config{BasicAuth: &BasicAuth{Username: "user", Password: "pass"}}).Call(context.Background(), "", request{}, nil)
+	MustNewClient(srv.URL, Config{BasicAuth: &BasicAuth{Username: "user", Password: "pass"}}).Call(context.Background(), "", request{}, nil)
 }
 
 /*func TestClient_AddHeader(t *testing.T) {
@@ -181,7 +187,412 @@ func TestClient_BasicAuth(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	client := NewClient(srv.URL, Config{})
+	client := MustNewClient(srv.URL, Config{})
 	client.AddHeader(Header{})
 	client.Call("", request{}, nil)
 }*/
+
+func TestClient_CallMulti(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body>` +
+			`<Response xmlns="test:call"><attr3>value3</attr3></Response>` +
+			`<Unknown xmlns="test:call"/>` +
+			`<Out xmlns="test:other">value4</Out>` +
+			`</Body></Envelope>`))
+	}))
+	defer srv.Close()
+
+	var (
+		resp response
+		out  string
+	)
+	if err := MustNewClient(srv.URL, Config{}).CallMulti(context.Background(), "", request{}, Targets{
+		{Space: "test:call", Local: "Response"}: &resp,
+		{Local: "Out"}:                          &out,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "value3"; resp.Attr3 != want {
+		t.Fatalf("got: %s, want: %s", resp.Attr3, want)
+	}
+
+	if want := "value4"; out != want {
+		t.Fatalf("got: %s, want: %s", out, want)
+	}
+}
+
+type nonce struct {
+	XMLName xml.Name `xml:"test:call Nonce"`
+	Value   int      `xml:",chardata"`
+}
+
+func TestClient_AddHeaderFunc(t *testing.T) {
+	t.Parallel()
+	var got []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		n, err := ParseNode(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		v, _ := strconv.Atoi(n.Value("Envelope/Header/Nonce"))
+		got = append(got, v)
+
+		b, _ := xml.Marshal(Envelope{Body: Body{}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	var i int
+	client := MustNewClient(srv.URL, Config{})
+	client.AddHeaderFunc(func(ctx context.Context) (interface{}, error) {
+		i++
+		return nonce{Value: i}, nil
+	})
+
+	for range []int{1, 2} {
+		if err := client.Call(context.Background(), "", request{}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("got: %v, want: %v", got, []int{1, 2})
+	}
+
+	client.AddHeaderFunc(func(ctx context.Context) (interface{}, error) {
+		return nil, fmt.Errorf("failed")
+	})
+	if err := client.Call(context.Background(), "", request{}, nil); err == nil || err.Error() != "soap: header failed" {
+		t.Fatalf("got: %v, want: %s", err, "soap: header failed")
+	}
+}
+
+func TestClient_AddHeaderFor(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		n, err := ParseNode(body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		want := map[string]string{"urn:GetUser": "1 2", "urn:GetGroup": "1"}[r.Header.Get("SOAPAction")]
+		var got []string
+		for _, h := range n.FindAll("Envelope/Header/Nonce") {
+			got = append(got, h.Text())
+		}
+
+		if strings.Join(got, " ") != want {
+			t.Errorf("got: %v, want: %s", got, want)
+		}
+
+		b, _ := xml.Marshal(Envelope{Body: Body{}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{})
+	client.AddHeader(nonce{Value: 1})
+	client.AddHeaderFor("urn:GetUser", nonce{Value: 2})
+	for _, action := range []string{"urn:GetUser", "urn:GetGroup"} {
+		if err := client.Call(context.Background(), action, request{}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestClient_Close(t *testing.T) {
+	t.Parallel()
+	started := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- client.Call(context.Background(), "", request{}, nil)
+	}()
+
+	<-started
+	client.Close()
+	if err := <-errc; err == nil {
+		t.Fatal("want error of canceled request")
+	}
+
+	if err := client.Call(context.Background(), "", request{}, nil); err != errClosed {
+		t.Fatalf("got: %v, want: %s", err, errClosed)
+	}
+}
+
+func TestClient_Headers(t *testing.T) {
+	t.Parallel()
+	for i, v := range []struct {
+		config Config
+		ua     string
+		custom string
+		accept string
+	}{
+		{ua: "itcomusic-soap/" + Version},
+		{config: Config{UserAgent: "app/1.0", Headers: http.Header{"X-Custom": {"gopher"}}}, ua: "app/1.0", custom: "gopher"},
+		{config: Config{Headers: http.Header{"User-Agent": {"waf-friendly"}}}, ua: "waf-friendly"},
+		{config: Config{Accept: "application/soap+xml, multipart/related"}, ua: "itcomusic-soap/" + Version, accept: "application/soap+xml, multipart/related"},
+		{config: Config{Accept: "text/xml", Headers: http.Header{"Accept": {"*/*"}}}, ua: "itcomusic-soap/" + Version, accept: "*/*"},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("User-Agent"); got != v.ua {
+				t.Errorf("#%d got: %s, want: %s", i, got, v.ua)
+			}
+
+			if got := r.Header.Get("X-Custom"); got != v.custom {
+				t.Errorf("#%d got: %s, want: %s", i, got, v.custom)
+			}
+
+			if got := r.Header.Get("Accept"); got != v.accept {
+				t.Errorf("#%d got: %s, want: %s", i, got, v.accept)
+			}
+
+			b, _ := xml.Marshal(Envelope{Body: Body{}})
+			w.Write(b)
+		}))
+
+		if err := MustNewClient(srv.URL, v.config).Call(context.Background(), "", request{}, nil); err != nil {
+			t.Error(err)
+		}
+		srv.Close()
+	}
+}
+
+func TestClient_FaultWithBody(t *testing.T) {
+	t.Parallel()
+	for i, body := range []string{
+		`<Fault xmlns="http://schemas.xmlsoap.org/soap/envelope/"><faultcode>Server</faultcode><faultstring>partial</faultstring></Fault><Response xmlns="test:call"><attr3>value3</attr3></Response>`,
+		`<Response xmlns="test:call"><attr3>value3</attr3></Response><Fault xmlns="http://schemas.xmlsoap.org/soap/envelope/"><faultcode>Server</faultcode><faultstring>partial</faultstring></Fault>`,
+	} {
+		body := body
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(500)
+			w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body>` + body + `</Body></Envelope>`))
+		}))
+
+		var resp response
+		err := MustNewClient(srv.URL, Config{FaultWithBody: true}).Call(context.Background(), "", request{}, &resp)
+		if f, ok := err.(*Fault); !ok || f.Text != "partial" {
+			t.Errorf("#%d got: %v, want: fault", i, err)
+		}
+
+		if resp.Attr3 != "value3" {
+			t.Errorf("#%d got: %s, want: %s", i, resp.Attr3, "value3")
+		}
+
+		if _, ok := MustNewClient(srv.URL, Config{}).Call(context.Background(), "", request{}, &response{}).(*Fault); ok {
+			t.Errorf("#%d got: fault, want: decode error", i)
+		}
+		srv.Close()
+	}
+}
+
+func TestClient_ContentType(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		if r.URL.Query().Get("html") != "" {
+			w.Write([]byte("<html><body>maintenance</body></html>"))
+			return
+		}
+
+		b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	for i, v := range []struct {
+		query  string
+		strict bool
+		err    string
+	}{
+		{query: "?type=text/plain", strict: false},
+		{query: "?type=application/soap%2Bxml%3B+charset=utf-8", strict: true},
+		{query: "?type=application/octet-stream", strict: true, err: `soap: 200 OK (200) unexpected content type "application/octet-stream"`},
+		{query: "?type=text/html&html=1", strict: false, err: `soap: 200 OK (200) unexpected content type "text/html"`},
+	} {
+		var resp response
+		err := MustNewClient(srv.URL+v.query, Config{StrictContentType: v.strict}).Call(context.Background(), "", request{}, &resp)
+		if v.err == "" && (err != nil || resp.Attr3 != "value3") {
+			t.Errorf("#%d got: %v, want: value3", i, err)
+		}
+
+		if v.err != "" && (err == nil || err.Error() != v.err) {
+			t.Errorf("#%d got: %v, want: %s", i, err, v.err)
+		}
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	t.Parallel()
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) { return nil, nil }
+	for i, v := range []struct {
+		url string
+		c   Config
+		err string
+	}{
+		{url: "http://127.0.0.1/call", c: Config{TLS: &tls.Config{}}},
+		{url: "https://example.com", c: Config{DialTLSContext: dial}},
+		{url: "localhost/call", err: `soap: endpoint "localhost/call" must be absolute http or https url`},
+		{url: "ftp://example.com", err: `soap: endpoint "ftp://example.com" must be absolute http or https url`},
+		{url: "http://", err: `soap: endpoint "http://" must be absolute http or https url`},
+		{url: "http://example.com", c: Config{BasicAuth: &BasicAuth{Password: "test"}}, err: "soap: basic auth username is empty"},
+		{url: "http://example.com", c: Config{TLSPreset: "strict"}, err: `soap: unknown tls preset "strict"`},
+		{url: "http://example.com", c: Config{IPFamily: "udp"}, err: `soap: unknown ip family "udp"`},
+		{url: "http://example.com", c: Config{Accept: "text/xml, xml"}, err: `soap: accept "text/xml, xml" is invalid`},
+		{url: "http://example.com", c: Config{BasicAuth: &BasicAuth{Username: "user"}, Credentials: StaticCredentials{}}, err: "soap: basic auth must not be set with credentials provider"},
+		{url: "https://example.com", c: Config{DialTLSContext: dial, TLSPreset: TLSModern}, err: "soap: tls options are not applied with DialTLSContext"},
+		{url: "https://example.com", c: Config{PinnedSPKIHashes: [][]byte{[]byte("short")}}, err: "soap: pinned fingerprint must be SHA-256"},
+	} {
+		client, err := NewClient(v.url, v.c)
+		if v.err == "" && (err != nil || client == nil) {
+			t.Errorf("#%d got: %v, want: client", i, err)
+		}
+
+		if v.err != "" && (err == nil || err.Error() != v.err) {
+			t.Errorf("#%d got: %v, want: %s", i, err, v.err)
+		}
+	}
+}
+
+func TestClient_SetURL(t *testing.T) {
+	t.Parallel()
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if u, p, _ := r.BasicAuth(); u+":"+p != name {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte("unauthorized"))
+				return
+			}
+
+			b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: name}}})
+			w.Write(b)
+		})
+	}
+	primary := httptest.NewServer(handler("primary:1"))
+	defer primary.Close()
+	secondary := httptest.NewServer(handler("secondary:2"))
+	defer secondary.Close()
+
+	client := MustNewClient(primary.URL, Config{BasicAuth: &BasicAuth{Username: "primary", Password: "1"}})
+	var resp response
+	if err := client.Call(context.Background(), "", request{}, &resp); err != nil || resp.Attr3 != "primary:1" {
+		t.Fatalf("got: %v %s, want: primary:1", err, resp.Attr3)
+	}
+
+	if err := client.SetURL("secondary"); err == nil {
+		t.Fatal("relative url is accepted")
+	}
+
+	if err := client.SetURL(secondary.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Call(context.Background(), "", request{}, &resp); err != errUnauthorized {
+		t.Fatalf("got: %v, want: %s", err, errUnauthorized)
+	}
+
+	if err := client.SetBasicAuth(&BasicAuth{Username: "secondary", Password: "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Call(context.Background(), "", request{}, &resp); err != nil || resp.Attr3 != "secondary:2" {
+		t.Fatalf("got: %v %s, want: secondary:2", err, resp.Attr3)
+	}
+}
+
+// TestClient_Concurrent is meaningful with the race detector.
+func TestClient_Concurrent(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{KeepAlive: true})
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			client.AddHeader(nonce{Value: i})
+			client.AddHeaderFor("call", nonce{Value: i})
+			client.AddHeaderFunc(func(ctx context.Context) (interface{}, error) { return nil, nil })
+			client.SetBasicAuth(&BasicAuth{Username: strconv.Itoa(i)})
+			client.SetURL(srv.URL)
+			client.CloseIdleConnections()
+		}(i)
+
+		go func() {
+			defer wg.Done()
+			var resp response
+			if err := client.Call(context.Background(), "call", request{}, &resp); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestClient_Chunked(t *testing.T) {
+	t.Parallel()
+	for _, chunked := range []bool{false, true} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if got := len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked"; got != chunked {
+				t.Errorf("got: %v, want chunked: %t", r.TransferEncoding, chunked)
+			}
+
+			if got := r.ContentLength > 0; got == chunked {
+				t.Errorf("got: %d, want content length: %t", r.ContentLength, !chunked)
+			}
+
+			if _, err := ioutil.ReadAll(r.Body); err != nil {
+				t.Error(err)
+			}
+
+			b, _ := xml.Marshal(Envelope{Body: Body{}})
+			w.Write(b)
+		}))
+
+		if err := MustNewClient(srv.URL, Config{Chunked: chunked}).Call(context.Background(), "", request{Attr1: "value1"}, nil); err != nil {
+			t.Error(err)
+		}
+		srv.Close()
+	}
+}
+
+func TestClient_Prolog(t *testing.T) {
+	t.Parallel()
+	for i, prefix := range []string{"\xef\xbb\xbf", "\r\n  ", "\xef\xbb\xbf\n200 OK\n"} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+			w.Write([]byte(prefix + xml.Header))
+			w.Write(b)
+		}))
+
+		var r response
+		if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "", request{}, &r); err != nil {
+			t.Errorf("#%d %s", i, err)
+		} else if r.Attr3 != "value3" {
+			t.Errorf("#%d got: %s, want: %s", i, r.Attr3, "value3")
+		}
+		srv.Close()
+	}
+}
@@ -0,0 +1,225 @@
+// Package soapfmt formats soap envelopes for logs and bug reports: pretty-printing,
+// exclusive canonicalization and redaction of secrets of the standard headers.
+package soapfmt
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/itcomusic/soap"
+)
+
+// Redacted replaces text of the redacted elements.
+const Redacted = "***"
+
+// xencNS is namespace of xml encryption.
+const xencNS = "http://www.w3.org/2001/04/xmlenc#"
+
+// DefaultRedacted are elements carrying secrets: WS-Security passwords, nonces and tokens,
+// signature and cipher values.
+var DefaultRedacted = []xml.Name{
+	{Space: soap.WSSENS, Local: "Password"},
+	{Space: soap.WSSENS, Local: "Nonce"},
+	{Space: soap.WSSENS, Local: "BinarySecurityToken"},
+	{Space: soap.DSigNS, Local: "SignatureValue"},
+	{Space: xencNS, Local: "CipherValue"},
+}
+
+// Options implements formatting options.
+type Options struct {
+	// Canonical writes exclusive canonical form instead of the indented one.
+	Canonical bool
+	// Indent is indentation of the pretty-printed envelope, two spaces when empty.
+	Indent string
+	// Redact are elements which text is redacted, name without namespace matches any namespace.
+	Redact []xml.Name
+}
+
+// Format redacts the envelope and writes it pretty-printed or canonical.
+func Format(data []byte, o Options) ([]byte, error) {
+	data, err := Redact(data, o.Redact...)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Canonical {
+		return soap.Canonicalize(data)
+	}
+	return Pretty(data, o.Indent)
+}
+
+// Redact replaces text of the elements and their children, prefixes and formatting are kept.
+func Redact(data []byte, names ...xml.Name) ([]byte, error) {
+	if len(names) == 0 {
+		return data, nil
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var (
+		out   bytes.Buffer
+		last  int64
+		depth int
+	)
+	for {
+		offset := d.InputOffset()
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("soapfmt: %s", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth > 0 || match(t.Name, names) {
+				depth++
+			}
+		case xml.EndElement:
+			if depth > 0 {
+				depth--
+			}
+		case xml.CharData:
+			if depth == 0 || len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+
+			out.Write(data[last:offset])
+			out.WriteString(Redacted)
+			last = d.InputOffset()
+		}
+	}
+	out.Write(data[last:])
+	return out.Bytes(), nil
+}
+
+func match(name xml.Name, names []xml.Name) bool {
+	for _, n := range names {
+		if n.Local == name.Local && (n.Space == "" || n.Space == name.Space) {
+			return true
+		}
+	}
+	return false
+}
+
+// Pretty indents the document, prefixes, namespace declarations and comments are kept,
+// whitespace between elements is replaced.
+func Pretty(data []byte, indent string) ([]byte, error) {
+	if indent == "" {
+		indent = "  "
+	}
+
+	tokens, err := rawTokens(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		out   bytes.Buffer
+		depth int
+		// text is set when the element has text content written inline
+		text bool
+	)
+	newline := func() {
+		if out.Len() > 0 {
+			out.WriteByte('\n')
+		}
+		out.WriteString(strings.Repeat(indent, depth))
+	}
+
+	for i, token := range tokens {
+		switch t := token.(type) {
+		case xml.StartElement:
+			newline()
+			out.WriteString("<" + name(t.Name))
+			for _, a := range t.Attr {
+				out.WriteString(" " + name(a.Name) + `="`)
+				xml.EscapeText(&out, []byte(a.Value))
+				out.WriteByte('"')
+			}
+
+			if i+1 < len(tokens) {
+				if _, ok := tokens[i+1].(xml.EndElement); ok {
+					out.WriteString("/>")
+					// the end element is skipped
+					tokens[i+1] = nil
+					continue
+				}
+			}
+			out.WriteByte('>')
+			depth++
+			text = false
+		case xml.EndElement:
+			depth--
+			if !text {
+				newline()
+			}
+			out.WriteString("</" + name(t.Name) + ">")
+			text = false
+		case xml.CharData:
+			xml.EscapeText(&out, t)
+			text = true
+		case xml.Comment:
+			newline()
+			out.WriteString("<!--" + string(t) + "-->")
+		case xml.ProcInst:
+			newline()
+			out.WriteString("<?" + t.Target + " " + string(t.Inst) + "?>")
+		case xml.Directive:
+			newline()
+			out.WriteString("<!" + string(t) + ">")
+		}
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// rawTokens returns tokens of the well-formed document, whitespace is dropped and text next
+// to child elements is kept only when it is not whitespace.
+func rawTokens(data []byte) ([]xml.Token, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var (
+		tokens []xml.Token
+		stack  []xml.Name
+	)
+	for {
+		token, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("soapfmt: %s", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name)
+		case xml.EndElement:
+			if len(stack) == 0 || stack[len(stack)-1] != t.Name {
+				return nil, fmt.Errorf("soapfmt: element </%s> is not expected", name(t.Name))
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+		}
+		tokens = append(tokens, xml.CopyToken(token))
+	}
+
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("soapfmt: element <%s> is not closed", name(stack[len(stack)-1]))
+	}
+	return tokens, nil
+}
+
+// name returns raw name of the token, the space is the prefix.
+func name(n xml.Name) string {
+	if n.Space != "" {
+		return n.Space + ":" + n.Local
+	}
+	return n.Local
+}
@@ -0,0 +1,70 @@
+package soapfmt
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+const envelope = `<?xml version="1.0"?><soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Header>` +
+	`<wsse:Security xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"><wsse:UsernameToken>` +
+	`<wsse:Username>user</wsse:Username><wsse:Password>secret</wsse:Password></wsse:UsernameToken></wsse:Security></soapenv:Header>` +
+	`<soapenv:Body><m:Request xmlns:m="urn:m"><!-- id --><m:Password>kept</m:Password><m:empty/></m:Request></soapenv:Body></soapenv:Envelope>`
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	got, err := Format([]byte(envelope), Options{Redact: DefaultRedacted})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<?xml version="1.0"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+  <soapenv:Header>
+    <wsse:Security xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">
+      <wsse:UsernameToken>
+        <wsse:Username>user</wsse:Username>
+        <wsse:Password>***</wsse:Password>
+      </wsse:UsernameToken>
+    </wsse:Security>
+  </soapenv:Header>
+  <soapenv:Body>
+    <m:Request xmlns:m="urn:m">
+      <!-- id -->
+      <m:Password>kept</m:Password>
+      <m:empty/>
+    </m:Request>
+  </soapenv:Body>
+</soapenv:Envelope>
+`
+	if string(got) != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+
+	got, err = Format([]byte(envelope), Options{Canonical: true, Redact: []xml.Name{{Local: "Password"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Header>` +
+		`<wsse:Security xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"><wsse:UsernameToken>` +
+		`<wsse:Username>user</wsse:Username><wsse:Password>***</wsse:Password></wsse:UsernameToken></wsse:Security></soapenv:Header>` +
+		`<soapenv:Body><m:Request xmlns:m="urn:m"><m:Password>***</m:Password><m:empty></m:empty></m:Request></soapenv:Body></soapenv:Envelope>`
+	if string(got) != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+}
+
+func TestPretty_Invalid(t *testing.T) {
+	t.Parallel()
+	for i, v := range []struct {
+		in   string
+		want string
+	}{
+		{in: `<a><b></a>`, want: "soapfmt: element </a> is not expected"},
+		{in: `<a><b></b>`, want: "soapfmt: element <a> is not closed"},
+	} {
+		if _, err := Pretty([]byte(v.in), ""); err == nil || err.Error() != v.want {
+			t.Errorf("#%d got: %v, want: %s", i, err, v.want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package soaptest
+
+import "testing"
+
+// AssertEnvelopeEqual reports test error when envelopes differ semantically, see Diff.
+// It keeps request assertions stable when the encoder changes prefixes or attribute order.
+func AssertEnvelopeEqual(t testing.TB, want, got []byte) bool {
+	t.Helper()
+	if diff := Diff(want, got); diff != "" {
+		t.Errorf("envelope differs: %s\nwant: %s\ngot: %s", diff, want, got)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package soaptest
+
+import (
+	"fmt"
+	"testing"
+)
+
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertEnvelopeEqual(t *testing.T) {
+	t.Parallel()
+	want := []byte(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+  <soapenv:Body>
+    <urn:GetUser xmlns:urn="urn:users" id="1" active="true">
+      <urn:Name>alice</urn:Name>
+    </urn:GetUser>
+  </soapenv:Body>
+</soapenv:Envelope>`)
+
+	r := &recorder{TB: t}
+	same := `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><GetUser xmlns="urn:users" active="true" id="1"><Name>alice</Name></GetUser></Body></Envelope>`
+	if !AssertEnvelopeEqual(r, want, []byte(same)) || len(r.errors) != 0 {
+		t.Fatalf("got: %v, want: equal", r.errors)
+	}
+
+	other := `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><GetUser xmlns="urn:users" active="true" id="1"><Name>bob</Name></GetUser></Body></Envelope>`
+	if AssertEnvelopeEqual(r, want, []byte(other)) || len(r.errors) != 1 {
+		t.Fatalf("got: %v, want: one error", r.errors)
+	}
+}
@@ -0,0 +1,182 @@
+package soaptest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"testing"
+	"text/template"
+	"unicode"
+
+	"github.com/itcomusic/soap"
+)
+
+// ContractCase implements sample message of the operation which must round-trip
+// through the generated type.
+type ContractCase struct {
+	Operation string
+	// Message is "input" or "output".
+	Message string
+	Sample  []byte
+	// New returns pointer to the generated type of the message element.
+	New func() interface{}
+}
+
+// RunContract decodes each sample into its type, encodes it back and compares
+// the result with the sample ignoring prefixes, attribute order and insignificant whitespace.
+func RunContract(t *testing.T, cases []ContractCase) {
+	t.Helper()
+	for _, c := range cases {
+		c := c
+		t.Run(c.Operation+"/"+c.Message, func(t *testing.T) {
+			v := c.New()
+			if err := xml.Unmarshal(c.Sample, v); err != nil {
+				t.Fatalf("decode: %s", err)
+			}
+
+			b, err := xml.Marshal(v)
+			if err != nil {
+				t.Fatalf("encode: %s", err)
+			}
+
+			if diff := Diff(c.Sample, b); diff != "" {
+				t.Fatalf("round trip differs: %s\nsample: %s\ngot: %s", diff, c.Sample, b)
+			}
+		})
+	}
+}
+
+// Diff compares xml documents semantically: namespace prefixes, attribute order and
+// whitespace around elements are ignored. It returns description of the first difference.
+func Diff(want, got []byte) string {
+	w, err := soap.ParseNode(want)
+	if err != nil {
+		return fmt.Sprintf("want: %s", err)
+	}
+
+	g, err := soap.ParseNode(got)
+	if err != nil {
+		return fmt.Sprintf("got: %s", err)
+	}
+	return diffNodes(w, g, "/"+w.XMLName.Local)
+}
+
+func diffNodes(want, got *soap.Node, path string) string {
+	if want.XMLName != got.XMLName {
+		return fmt.Sprintf("%s: element %s, want %s", path, formatName(got.XMLName), formatName(want.XMLName))
+	}
+
+	if w, g := formatAttrs(want.Attrs), formatAttrs(got.Attrs); w != g {
+		return fmt.Sprintf("%s: attributes [%s], want [%s]", path, g, w)
+	}
+
+	if w, g := want.Text(), got.Text(); w != g {
+		return fmt.Sprintf("%s: text %q, want %q", path, g, w)
+	}
+
+	if len(want.Children) != len(got.Children) {
+		return fmt.Sprintf("%s: %d children, want %d", path, len(got.Children), len(want.Children))
+	}
+
+	for i := range want.Children {
+		if diff := diffNodes(want.Children[i], got.Children[i], path+"/"+want.Children[i].XMLName.Local); diff != "" {
+			return diff
+		}
+	}
+	return ""
+}
+
+func formatName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return "{" + n.Space + "}" + n.Local
+}
+
+func formatAttrs(attrs []xml.Attr) string {
+	s := make([]string, 0, len(attrs))
+	for _, a := range attrs {
+		s = append(s, formatName(a.Name)+"="+a.Value)
+	}
+	sort.Strings(s)
+	return strings.Join(s, " ")
+}
+
+var contractTemplate = template.Must(template.New("contract").Parse(`// Code generated by soapcontract. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"testing"
+
+	"github.com/itcomusic/soap/soaptest"
+)
+
+func TestContract(t *testing.T) {
+	soaptest.RunContract(t, []soaptest.ContractCase{
+{{- range .Cases}}
+		{
+			Operation: {{printf "%q" .Operation}},
+			Message:   {{printf "%q" .Message}},
+			Sample:    []byte({{printf "%q" .Data}}),
+			New:       func() interface{} { return new({{.Type}}) },
+		},
+{{- end}}
+	})
+}
+`))
+
+// GenerateContract generates go source of the table-driven contract test of the package,
+// each sample of the wsdl is decoded into the type named after its element as gowsdl does.
+func GenerateContract(pkg string, wsdl []byte) ([]byte, error) {
+	samples, err := Samples(wsdl)
+	if err != nil {
+		return nil, err
+	}
+
+	type contractCase struct {
+		Sample
+		Type string
+	}
+
+	cases := make([]contractCase, 0, len(samples))
+	for _, s := range samples {
+		cases = append(cases, contractCase{Sample: s, Type: typeName(s.Element.Local)})
+	}
+
+	var buf bytes.Buffer
+	if err := contractTemplate.Execute(&buf, struct {
+		Package string
+		Cases   []contractCase
+	}{Package: pkg, Cases: cases}); err != nil {
+		return nil, fmt.Errorf("soaptest: %s", err)
+	}
+
+	b, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("soaptest: %s", err)
+	}
+	return b, nil
+}
+
+// typeName returns exported go identifier of the element name.
+func typeName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
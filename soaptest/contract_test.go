@@ -0,0 +1,85 @@
+package soaptest
+
+import (
+	"encoding/xml"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+type GetOrder struct {
+	XMLName xml.Name `xml:"urn:orders getOrder"`
+	ID      int64    `xml:"id,omitempty"`
+}
+
+type GetOrderResponse struct {
+	XMLName xml.Name `xml:"urn:orders getOrderResponse"`
+	Status  string   `xml:"status,omitempty"`
+	Created string   `xml:"created,omitempty"`
+	Line    *struct {
+		Number   int    `xml:"number,attr,omitempty"`
+		SKU      string `xml:"sku,omitempty"`
+		Quantity int    `xml:"quantity,omitempty"`
+	} `xml:"line,omitempty"`
+}
+
+func TestSamples(t *testing.T) {
+	t.Parallel()
+	data, err := ioutil.ReadFile("testdata/service.wsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples, err := Samples(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) != 2 {
+		t.Fatalf("got: %d, want: 2", len(samples))
+	}
+
+	want := `<getOrderResponse xmlns="urn:orders"><status xmlns="urn:orders">open</status><created xmlns="urn:orders">2006-01-02T15:04:05Z</created><line xmlns="urn:orders" number="1"><sku xmlns="urn:orders">string</sku><quantity xmlns="urn:orders">1</quantity></line></getOrderResponse>`
+	if diff := Diff([]byte(want), samples[1].Data); diff != "" {
+		t.Fatalf("%s\ngot: %s, want: %s", diff, samples[1].Data, want)
+	}
+
+	RunContract(t, []ContractCase{
+		{Operation: samples[0].Operation, Message: samples[0].Message, Sample: samples[0].Data, New: func() interface{} { return new(GetOrder) }},
+		{Operation: samples[1].Operation, Message: samples[1].Message, Sample: samples[1].Data, New: func() interface{} { return new(GetOrderResponse) }},
+	})
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	if diff := Diff([]byte(`<a xmlns="urn:x" b="1" c="2"> <d>v</d> </a>`), []byte(`<x:a xmlns:x="urn:x" c="2" b="1"><x:d>v</x:d></x:a>`)); diff != "" {
+		t.Fatalf("got: %s, want: empty", diff)
+	}
+
+	if diff := Diff([]byte(`<a><d>v</d></a>`), []byte(`<a><d>w</d></a>`)); !strings.HasPrefix(diff, "/a/d: text") {
+		t.Fatalf("got: %s, want: text difference", diff)
+	}
+}
+
+func TestGenerateContract(t *testing.T) {
+	t.Parallel()
+	data, err := ioutil.ReadFile("testdata/service.wsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := GenerateContract("orders", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "contract_test.go", src, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(src), "new(GetOrderResponse)") {
+		t.Fatalf("got: %s, want: GetOrderResponse case", src)
+	}
+}
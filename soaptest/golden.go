@@ -0,0 +1,87 @@
+package soaptest
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/itcomusic/soap"
+)
+
+// Update rewrites golden files with the actual envelopes, it is set by SOAPTEST_UPDATE environment variable,
+// e.g. SOAPTEST_UPDATE=1 go test, or by the test of the package.
+var Update = os.Getenv("SOAPTEST_UPDATE") != ""
+
+// GoldenDir is directory of golden files relative to the package of the test.
+var GoldenDir = filepath.Join("testdata", "golden")
+
+// AssertGolden reports test error when the envelope differs byte by byte from the golden file
+// named after the test, the file is written when Update is set. Name distinguishes
+// several envelopes of the test and may be empty.
+func AssertGolden(t testing.TB, name string, got []byte) bool {
+	t.Helper()
+	file := goldenFile(t, name)
+	if Update {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(file, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return true
+	}
+
+	want, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Errorf("golden file: %s, run SOAPTEST_UPDATE=1 go test to create it", err)
+		return false
+	}
+
+	if !bytes.Equal(want, got) {
+		diff := Diff(want, got)
+		if diff == "" {
+			diff = "documents are equal semantically, formatting differs"
+		}
+		t.Errorf("envelope differs from %s: %s\nwant: %s\ngot: %s", file, diff, want, got)
+		return false
+	}
+	return true
+}
+
+// Golden returns middleware asserting each request envelope of the test with the golden files,
+// the n-th request of the test is compared with the file suffixed by n starting from 2.
+func Golden(t testing.TB) soap.Middleware {
+	var (
+		mu sync.Mutex
+		n  int
+	)
+	return func(next soap.RoundTripFunc) soap.RoundTripFunc {
+		return func(ctx context.Context, r *soap.Request) (*soap.Response, error) {
+			mu.Lock()
+			n++
+			name := ""
+			if n > 1 {
+				name = strconv.Itoa(n)
+			}
+			mu.Unlock()
+
+			AssertGolden(t, name, r.Envelope)
+			return next(ctx, r)
+		}
+	}
+}
+
+func goldenFile(t testing.TB, name string) string {
+	file := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	if name != "" {
+		file += "_" + name
+	}
+	return filepath.Join(GoldenDir, file+".xml")
+}
@@ -0,0 +1,31 @@
+package soaptest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/itcomusic/soap"
+)
+
+func TestGolden(t *testing.T) {
+	t.Parallel()
+	srv := NewServer()
+	defer srv.Close()
+	srv.Handle("", Behavior{Response: response{Attr3: "value3"}})
+
+	client := soap.MustNewClient(srv.URL, soap.Config{Middleware: []soap.Middleware{Golden(t)}})
+	for _, action := range []string{"get", "put"} {
+		if err := client.Call(context.Background(), action, request{}, &response{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if Update {
+		return
+	}
+
+	r := &recorder{TB: t}
+	if AssertGolden(r, "2", []byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"></Envelope>`)) || len(r.errors) != 1 {
+		t.Fatalf("got: %v, want: one error", r.errors)
+	}
+}
@@ -0,0 +1,158 @@
+// Package soaptest implements utilities for testing of soap clients.
+package soaptest
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/itcomusic/soap"
+)
+
+// MalformedBody is sent by Behavior with Malformed set.
+const MalformedBody = `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Response`
+
+// Behavior implements response of the fake server.
+type Behavior struct {
+	// Response is body content of the envelope.
+	Response interface{}
+	// Fault is sent instead of the response.
+	Fault *soap.Fault
+	// Raw is sent as is instead of the envelope.
+	Raw []byte
+	// Malformed sends truncated envelope.
+	Malformed bool
+	// Status is http status, 200 by default or 500 for the fault.
+	Status int
+	// Delay is applied before the response.
+	Delay time.Duration
+	// Drip writes the body byte by byte with the delay between bytes.
+	Drip time.Duration
+}
+
+// Server implements fake soap server injecting faults, delays, malformed xml,
+// error statuses and slow drips. Behaviors are registered by soap action.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	behaviors map[string][]Behavior
+	calls     map[string]int
+}
+
+// NewServer creates and starts fake server, it must be closed by the caller.
+func NewServer() *Server {
+	s := &Server{behaviors: make(map[string][]Behavior), calls: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Handle registers behaviors of the soap action, successive calls use successive behaviors
+// and the last one is repeated. Empty action matches any action without own behaviors.
+func (s *Server) Handle(action string, behaviors ...Behavior) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.behaviors[action] = behaviors
+	s.calls[action] = 0
+}
+
+// Calls returns number of calls of the soap action.
+func (s *Server) Calls(action string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[action]
+}
+
+func (s *Server) next(action string) (Behavior, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	behaviors, ok := s.behaviors[action]
+	if !ok {
+		if behaviors, ok = s.behaviors[""]; !ok {
+			return Behavior{}, false
+		}
+	}
+
+	n := s.calls[action]
+	s.calls[action]++
+	if n >= len(behaviors) {
+		n = len(behaviors) - 1
+	}
+
+	if n < 0 {
+		return Behavior{}, true
+	}
+	return behaviors[n], true
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	ioutil.ReadAll(r.Body)
+	b, ok := s.next(r.Header.Get("SOAPAction"))
+	if !ok {
+		http.Error(w, "soaptest: unknown action", http.StatusNotFound)
+		return
+	}
+
+	if b.Delay > 0 {
+		select {
+		case <-time.After(b.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	body, err := b.body()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status := b.Status
+	if status == 0 {
+		status = http.StatusOK
+		if b.Fault != nil {
+			status = http.StatusInternalServerError
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(status)
+	if b.Drip <= 0 {
+		w.Write(body)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	for i := range body {
+		if _, err := w.Write(body[i : i+1]); err != nil {
+			return
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		select {
+		case <-time.After(b.Drip):
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (b Behavior) body() ([]byte, error) {
+	switch {
+	case b.Raw != nil:
+		return b.Raw, nil
+	case b.Malformed:
+		return []byte(MalformedBody), nil
+	case b.Fault != nil:
+		return xml.Marshal(soap.Envelope{Body: soap.Body{Fault: b.Fault}})
+	default:
+		return xml.Marshal(soap.Envelope{Body: soap.Body{Content: b.Response}})
+	}
+}
@@ -0,0 +1,57 @@
+package soaptest
+
+import (
+	"context"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/itcomusic/soap"
+)
+
+type request struct {
+	XMLName xml.Name `xml:"test:call Request"`
+}
+
+type response struct {
+	XMLName xml.Name `xml:"test:call Response"`
+	Attr3   string   `xml:"attr3,omitempty"`
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+	srv := NewServer()
+	defer srv.Close()
+
+	srv.Handle("get",
+		Behavior{Fault: &soap.Fault{Code: "ServerBusy", Text: "busy"}},
+		Behavior{Malformed: true, Status: 503},
+		Behavior{Response: response{Attr3: "value3"}, Drip: time.Millisecond},
+	)
+	srv.Handle("slow", Behavior{Delay: time.Second})
+	srv.Handle("auth", Behavior{Status: 401, Raw: []byte("denied")})
+
+	var r response
+	client := soap.MustNewClient(srv.URL, soap.Config{Retry: soap.RetryPolicy{MaxAttempts: 3, Fault: soap.RetryFaultCodes("ServerBusy")}})
+	if err := client.Call(context.Background(), "get", request{}, &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Attr3 != "value3" || srv.Calls("get") != 3 {
+		t.Fatalf("got: %s after %d calls", r.Attr3, srv.Calls("get"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := client.Call(ctx, "slow", request{}, nil); err == nil {
+		t.Fatal("want timeout error")
+	}
+
+	if err := client.Call(context.Background(), "auth", request{}, nil); err == nil || err.Error() != "soap: unauthorized" {
+		t.Fatalf("got: %v, want: %s", err, "soap: unauthorized")
+	}
+
+	if err := client.Call(context.Background(), "unknown", request{}, nil); err == nil {
+		t.Fatal("want error of unknown action")
+	}
+}
@@ -0,0 +1,308 @@
+package soaptest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/itcomusic/soap"
+)
+
+// maxSampleDepth stops sample generation of recursive types.
+const maxSampleDepth = 8
+
+type wsdlDefinitions struct {
+	TargetNamespace string        `xml:"targetNamespace,attr"`
+	Schemas         []xsdSchema   `xml:"types>schema"`
+	Messages        []wsdlMessage `xml:"message"`
+	PortTypes       []struct {
+		Operations []struct {
+			Name   string `xml:"name,attr"`
+			Input  wsdlIO `xml:"input"`
+			Output wsdlIO `xml:"output"`
+		} `xml:"operation"`
+	} `xml:"portType"`
+}
+
+type wsdlIO struct {
+	Message string `xml:"message,attr"`
+}
+
+type wsdlMessage struct {
+	Name  string `xml:"name,attr"`
+	Parts []struct {
+		Name    string `xml:"name,attr"`
+		Element string `xml:"element,attr"`
+	} `xml:"part"`
+}
+
+type xsdSchema struct {
+	TargetNamespace string           `xml:"targetNamespace,attr"`
+	Elements        []xsdElement     `xml:"element"`
+	ComplexTypes    []xsdComplexType `xml:"complexType"`
+	SimpleTypes     []xsdSimpleType  `xml:"simpleType"`
+}
+
+type xsdElement struct {
+	Name        string          `xml:"name,attr"`
+	Type        string          `xml:"type,attr"`
+	Ref         string          `xml:"ref,attr"`
+	MinOccurs   string          `xml:"minOccurs,attr"`
+	ComplexType *xsdComplexType `xml:"complexType"`
+	SimpleType  *xsdSimpleType  `xml:"simpleType"`
+}
+
+type xsdComplexType struct {
+	Name       string       `xml:"name,attr"`
+	Sequence   []xsdElement `xml:"sequence>element"`
+	All        []xsdElement `xml:"all>element"`
+	Choice     []xsdElement `xml:"choice>element"`
+	Attributes []struct {
+		Name string `xml:"name,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"attribute"`
+	Extension *struct {
+		Base     string       `xml:"base,attr"`
+		Sequence []xsdElement `xml:"sequence>element"`
+	} `xml:"complexContent>extension"`
+}
+
+type xsdSimpleType struct {
+	Name        string `xml:"name,attr"`
+	Restriction struct {
+		Base         string `xml:"base,attr"`
+		Enumerations []struct {
+			Value string `xml:"value,attr"`
+		} `xml:"enumeration"`
+	} `xml:"restriction"`
+}
+
+// Sample implements generated sample message of the operation.
+type Sample struct {
+	Operation string
+	// Message is "input" or "output".
+	Message string
+	// Element is name of the message element.
+	Element xml.Name
+	Data    []byte
+}
+
+// Samples generates sample messages of all operations of the wsdl with inline schemas.
+// Document/literal messages are supported: each part refers to an element,
+// child elements are qualified with the schema namespace.
+func Samples(wsdl []byte) ([]Sample, error) {
+	var defs wsdlDefinitions
+	if err := xml.Unmarshal(wsdl, &defs); err != nil {
+		return nil, fmt.Errorf("soaptest: %s", err)
+	}
+
+	g := &generator{
+		namespaces:   prefixes(wsdl),
+		elements:     make(map[xml.Name]*xsdElement),
+		complexTypes: make(map[xml.Name]*xsdComplexType),
+		simpleTypes:  make(map[xml.Name]*xsdSimpleType),
+	}
+	for i := range defs.Schemas {
+		s := &defs.Schemas[i]
+		for j := range s.Elements {
+			g.elements[xml.Name{Space: s.TargetNamespace, Local: s.Elements[j].Name}] = &s.Elements[j]
+		}
+		for j := range s.ComplexTypes {
+			g.complexTypes[xml.Name{Space: s.TargetNamespace, Local: s.ComplexTypes[j].Name}] = &s.ComplexTypes[j]
+		}
+		for j := range s.SimpleTypes {
+			g.simpleTypes[xml.Name{Space: s.TargetNamespace, Local: s.SimpleTypes[j].Name}] = &s.SimpleTypes[j]
+		}
+	}
+
+	messages := make(map[string]wsdlMessage, len(defs.Messages))
+	for _, m := range defs.Messages {
+		messages[m.Name] = m
+	}
+
+	var samples []Sample
+	for _, pt := range defs.PortTypes {
+		for _, op := range pt.Operations {
+			for _, io := range []struct {
+				kind    string
+				message string
+			}{
+				{kind: "input", message: op.Input.Message},
+				{kind: "output", message: op.Output.Message},
+			} {
+				if io.message == "" {
+					continue
+				}
+
+				m, ok := messages[localPart(io.message)]
+				if !ok {
+					return nil, fmt.Errorf("soaptest: unknown message %s", io.message)
+				}
+
+				for _, part := range m.Parts {
+					name := resolve(part.Element, g.namespaces)
+					n, err := g.element(name, 0)
+					if err != nil {
+						return nil, err
+					}
+
+					b, err := xml.Marshal(n)
+					if err != nil {
+						return nil, fmt.Errorf("soaptest: %s", err)
+					}
+					samples = append(samples, Sample{Operation: op.Name, Message: io.kind, Element: name, Data: b})
+				}
+			}
+		}
+	}
+	return samples, nil
+}
+
+type generator struct {
+	elements     map[xml.Name]*xsdElement
+	complexTypes map[xml.Name]*xsdComplexType
+	simpleTypes  map[xml.Name]*xsdSimpleType
+	namespaces   map[string]string
+}
+
+func (g *generator) element(name xml.Name, depth int) (*soap.Node, error) {
+	e, ok := g.elements[name]
+	if !ok {
+		return nil, fmt.Errorf("soaptest: unknown element %s", formatName(name))
+	}
+	return g.build(name, e, depth)
+}
+
+func (g *generator) build(name xml.Name, e *xsdElement, depth int) (*soap.Node, error) {
+	n := &soap.Node{XMLName: name}
+	switch {
+	case e.ComplexType != nil:
+		return n, g.fill(n, e.ComplexType, depth)
+	case e.SimpleType != nil:
+		n.CharData = g.simpleValue(e.SimpleType)
+		return n, nil
+	}
+
+	typ := resolve(e.Type, g.namespaces)
+	if ct, ok := g.complexTypes[typ]; ok {
+		return n, g.fill(n, ct, depth)
+	}
+	n.CharData = g.value(typ)
+	return n, nil
+}
+
+func (g *generator) fill(n *soap.Node, ct *xsdComplexType, depth int) error {
+	if depth >= maxSampleDepth {
+		return nil
+	}
+
+	for _, a := range ct.Attributes {
+		n.SetAttr("", a.Name, g.value(resolve(a.Type, g.namespaces)))
+	}
+
+	children := append(append(append([]xsdElement(nil), ct.Sequence...), ct.All...), ct.Choice...)
+	if ct.Extension != nil {
+		if base, ok := g.complexTypes[resolve(ct.Extension.Base, g.namespaces)]; ok {
+			if err := g.fill(n, base, depth); err != nil {
+				return err
+			}
+		}
+		children = append(children, ct.Extension.Sequence...)
+	}
+
+	// choice contributes only the first element
+	if len(ct.Choice) > 0 {
+		children = children[:len(children)-len(ct.Choice)+1]
+	}
+
+	for i := range children {
+		c := &children[i]
+		var (
+			child *soap.Node
+			err   error
+		)
+		if c.Ref != "" {
+			child, err = g.element(resolve(c.Ref, g.namespaces), depth+1)
+		} else {
+			child, err = g.build(xml.Name{Space: n.XMLName.Space, Local: c.Name}, c, depth+1)
+		}
+
+		if err != nil {
+			return err
+		}
+		n.Add(child)
+	}
+	return nil
+}
+
+func (g *generator) simpleValue(st *xsdSimpleType) string {
+	if len(st.Restriction.Enumerations) > 0 {
+		return st.Restriction.Enumerations[0].Value
+	}
+	return g.value(resolve(st.Restriction.Base, g.namespaces))
+}
+
+// value returns sample value of the simple type.
+func (g *generator) value(typ xml.Name) string {
+	if st, ok := g.simpleTypes[typ]; ok {
+		return g.simpleValue(st)
+	}
+
+	switch typ.Local {
+	case "int", "integer", "long", "short", "byte", "unsignedInt", "unsignedLong", "unsignedShort", "unsignedByte", "positiveInteger", "nonNegativeInteger":
+		return "1"
+	case "boolean":
+		return "true"
+	case "decimal", "double", "float":
+		return "1.5"
+	case "dateTime":
+		return "2006-01-02T15:04:05Z"
+	case "date":
+		return "2006-01-02"
+	case "time":
+		return "15:04:05"
+	case "base64Binary":
+		return "Z29waGVy"
+	default:
+		return "string"
+	}
+}
+
+// prefixes returns namespace prefixes declared in the document.
+func prefixes(data []byte) map[string]string {
+	ns := make(map[string]string)
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := d.RawToken()
+		if err != nil {
+			return ns
+		}
+
+		if se, ok := token.(xml.StartElement); ok {
+			for _, a := range se.Attr {
+				if a.Name.Space == "xmlns" {
+					ns[a.Name.Local] = a.Value
+				}
+			}
+		}
+	}
+}
+
+// resolve resolves prefixed name, unknown prefix is kept as namespace.
+func resolve(qname string, namespaces map[string]string) xml.Name {
+	i := strings.IndexByte(qname, ':')
+	if i < 0 {
+		return xml.Name{Local: qname}
+	}
+
+	prefix := qname[:i]
+	if ns, ok := namespaces[prefix]; ok {
+		return xml.Name{Space: ns, Local: qname[i+1:]}
+	}
+	return xml.Name{Space: prefix, Local: qname[i+1:]}
+}
+
+func localPart(qname string) string {
+	return qname[strings.IndexByte(qname, ':')+1:]
+}
@@ -0,0 +1,167 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// defaultSpoolMemory is size of spooled data kept in memory.
+const defaultSpoolMemory = 1 << 20
+
+// Spool implements temporary storage of large messages: envelope of the call encoded with streamed
+// content, streamed request body replayed by retries and redirects or attachment decoded by Base64Writer.
+// Written data is kept in memory up to the limit and written to a temporary file beyond it, each reader
+// starts from the beginning. MTOM packages and responses are not spooled, the middleware chain passes
+// them in memory.
+type Spool struct {
+	memory int64
+	dir    string
+	mem    bytes.Buffer
+	file   *os.File
+	size   int64
+}
+
+// NewSpool returns spool configured by the client of the call, it is removed when the call completes.
+// Outside of the call the spool keeps 1 MiB in memory and it must be closed.
+func NewSpool(ctx context.Context) *Spool {
+	if sp, ok := ctx.Value(spoolsKey{}).(*spools); ok {
+		return sp.new()
+	}
+	return &Spool{memory: defaultSpoolMemory}
+}
+
+// Write implements io.Writer interface.
+func (s *Spool) Write(p []byte) (int, error) {
+	if s.file == nil && int64(s.mem.Len()+len(p)) <= s.memory {
+		n, _ := s.mem.Write(p)
+		s.size += int64(n)
+		return n, nil
+	}
+
+	if s.file == nil {
+		f, err := ioutil.TempFile(s.dir, "soap-spool-")
+		if err != nil {
+			return 0, fmt.Errorf("soap: %s", err)
+		}
+
+		s.file = f
+		if _, err := s.file.Write(s.mem.Bytes()); err != nil {
+			return 0, fmt.Errorf("soap: %s", err)
+		}
+		s.mem = bytes.Buffer{}
+	}
+
+	n, err := s.file.Write(p)
+	s.size += int64(n)
+	if err != nil {
+		return n, fmt.Errorf("soap: %s", err)
+	}
+	return n, nil
+}
+
+// Size returns size of the written data.
+func (s *Spool) Size() int64 {
+	return s.size
+}
+
+// Reader returns reader of the written data, it is valid until the spool is closed.
+func (s *Spool) Reader() io.ReadCloser {
+	if s.file == nil {
+		return &spoolReader{Reader: bytes.NewReader(s.mem.Bytes()), spool: s}
+	}
+	return &spoolReader{Reader: io.NewSectionReader(s.file, 0, s.size), spool: s}
+}
+
+// spoolReader implements reader of the spool, request body read from the spool is not spooled again.
+type spoolReader struct {
+	io.Reader
+	spool *Spool
+}
+
+// Close implements io.Closer interface, the spool is closed by its owner.
+func (r *spoolReader) Close() error {
+	return nil
+}
+
+// Close removes the temporary file.
+func (s *Spool) Close() error {
+	if s.file == nil {
+		return nil
+	}
+
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+type spoolsKey struct{}
+
+// spools implements spools of the call removed when it completes.
+type spools struct {
+	memory int64
+	dir    string
+
+	mu   sync.Mutex
+	list []*Spool
+}
+
+// spools returns spools configured by the client.
+func (s *Client) spools() *spools {
+	memory := s.config.SpoolMemory
+	if memory <= 0 {
+		memory = defaultSpoolMemory
+	}
+	return &spools{memory: memory, dir: s.config.SpoolDir}
+}
+
+func (sp *spools) new() *Spool {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	s := &Spool{memory: sp.memory, dir: sp.dir}
+	sp.list = append(sp.list, s)
+	return s
+}
+
+// close removes the spools.
+func (sp *spools) close() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	for _, s := range sp.list {
+		s.Close()
+	}
+	sp.list = nil
+}
+
+// withSpools returns context of the call with spools of the client, close must be called when the call completes.
+// The spools of the outer call are kept.
+func (s *Client) withSpools(ctx context.Context) (context.Context, func()) {
+	if _, ok := ctx.Value(spoolsKey{}).(*spools); ok {
+		return ctx, func() {}
+	}
+
+	sp := s.spools()
+	return context.WithValue(ctx, spoolsKey{}, sp), sp.close
+}
+
+var errEmptyBody = fmt.Errorf("soap: request body is empty")
+
+// spoolBody reads the streamed body into the spool of the call.
+func spoolBody(ctx context.Context, r io.Reader) (*Spool, error) {
+	if sr, ok := r.(*spoolReader); ok {
+		return sr.spool, nil
+	}
+
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	s := NewSpool(ctx)
+	if _, err := io.Copy(s, r); err != nil {
+		return nil, fmt.Errorf("soap: request body %s", err)
+	}
+	return s, nil
+}
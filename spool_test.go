@@ -0,0 +1,187 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSpool(t *testing.T) {
+	t.Parallel()
+	want := strings.Repeat("envelope", 10)
+	for _, limit := range []int64{0, 16} {
+		s := (&spools{memory: defaultSpoolMemory}).new()
+		if limit > 0 {
+			s.memory = limit
+		}
+
+		for i := 0; i < len(want); i += 8 {
+			if _, err := s.Write([]byte(want[i : i+8])); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for i := 0; i < 2; i++ {
+			got, err := ioutil.ReadAll(s.Reader())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != want || s.Size() != int64(len(want)) {
+				t.Fatalf("got: %s, want: %s", got, want)
+			}
+		}
+
+		if (s.file != nil) != (limit == 16) {
+			t.Fatalf("got: %v, want: spooled to file with limit %d", s.file != nil, limit)
+		}
+
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if s.file != nil {
+			if _, err := os.Stat(s.file.Name()); !os.IsNotExist(err) {
+				t.Fatalf("got: %v, want: file is removed", err)
+			}
+		}
+	}
+}
+
+func TestClient_SpoolCall(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body/></Envelope>`))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "soap-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var files int
+	spool := func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			s := NewSpool(ctx)
+			s.Write(bytes.Repeat([]byte("a"), 32))
+			list, _ := ioutil.ReadDir(dir)
+			files = len(list)
+			return next(ctx, r)
+		}
+	}
+
+	if err := MustNewClient(srv.URL, Config{SpoolMemory: 16, SpoolDir: dir, Middleware: []Middleware{spool}}).
+		Call(context.Background(), "", request{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if list, _ := ioutil.ReadDir(dir); files != 1 || len(list) != 0 {
+		t.Fatalf("got: %d files during call, %d after, want: 1 file removed after the call", files, len(list))
+	}
+}
+
+func TestClient_SpoolEnvelope(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "soap-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := bytes.Repeat([]byte("0123456789"), 100)
+	var files int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		list, _ := ioutil.ReadDir(dir)
+		files = len(list)
+
+		var got bytes.Buffer
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := xml.Unmarshal(body, &Envelope{Body: Body{Content: &uploaded{Content: Base64Writer{W: &got}}}}); err != nil || !bytes.Equal(got.Bytes(), file) {
+			t.Errorf("got: %d bytes %v, want: %d bytes", got.Len(), err, len(file))
+		}
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body/></Envelope>`))
+	}))
+	defer srv.Close()
+
+	// envelope exceeding the spool memory is sent from the spool file
+	if err := MustNewClient(srv.URL, Config{SpoolMemory: 64, SpoolDir: dir}).
+		Call(context.Background(), "", upload{Name: "file", Content: Base64Reader{R: bytes.NewReader(file)}}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if list, _ := ioutil.ReadDir(dir); files != 1 || len(list) != 0 {
+		t.Fatalf("got: %d files during call, %d after, want: 1 file removed after the call", files, len(list))
+	}
+}
+
+func TestClient_SpoolRedirect(t *testing.T) {
+	t.Parallel()
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if r.URL.Path == "/call" {
+			http.Redirect(w, r, "/moved", http.StatusTemporaryRedirect)
+			return
+		}
+
+		b, _ = xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	// streamed body without known length
+	stream := func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			r.Body = io.MultiReader(bytes.NewReader(r.Envelope))
+			return next(ctx, r)
+		}
+	}
+
+	var r response
+	if err := MustNewClient(srv.URL+"/call", Config{SpoolMemory: 16, Middleware: []Middleware{stream}}).
+		Call(context.Background(), "", request{}, &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bodies) != 2 || bodies[0] == "" || bodies[0] != bodies[1] {
+		t.Fatalf("got: %q, want: the same body sent twice", bodies)
+	}
+}
+
+func TestClient_SpoolEmpty(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer srv.Close()
+
+	// consumed body
+	stream := func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			body := bytes.NewReader(r.Envelope)
+			ioutil.ReadAll(body)
+			r.Body = body
+			return next(ctx, r)
+		}
+	}
+
+	if err := MustNewClient(srv.URL, Config{Middleware: []Middleware{stream}}).Call(context.Background(), "", request{}, &response{}); err != errEmptyBody {
+		t.Fatalf("got: %v, want: %s", err, errEmptyBody)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 0 {
+		t.Fatalf("got: %d, want: 0", got)
+	}
+}
@@ -0,0 +1,106 @@
+package soap
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Stats implements stats of the call, durations are summed over attempts.
+type Stats struct {
+	Action          string
+	Attempts        int
+	MarshalDuration time.Duration
+	NetworkDuration time.Duration
+	DecodeDuration  time.Duration
+	// DNSDuration, ConnectDuration and TLSDuration are spent establishing new connections,
+	// ServerDuration is time from the written request to the first response byte.
+	DNSDuration     time.Duration
+	ConnectDuration time.Duration
+	TLSDuration     time.Duration
+	ServerDuration  time.Duration
+	// ReusedConns counts attempts sent over kept-alive connections.
+	ReusedConns  int
+	RequestBytes int
+	// ResponseBytes is size of the last response.
+	ResponseBytes int
+	Err           error
+}
+
+// report passes stats of the finished call to the callback and returns the call error.
+func (s *Client) report(st *Stats, err error) error {
+	if s.config.Stats != nil {
+		st.Err = err
+		s.config.Stats(*st)
+	}
+	return err
+}
+
+// tracer implements httptrace hooks of the attempt, hooks of hedged requests may run concurrently.
+type tracer struct {
+	mu                                 sync.Mutex
+	dnsStart, connectStart, tlsStart   time.Time
+	wrote                              time.Time
+	dns, connect, handshake, firstByte time.Duration
+	reused                             int
+}
+
+func (t *tracer) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.start(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.done(&t.dnsStart, &t.dns) },
+		ConnectStart: func(network, addr string) {
+			t.start(&t.connectStart)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.done(&t.connectStart, &t.connect)
+		},
+		TLSHandshakeStart: func() { t.start(&t.tlsStart) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.done(&t.tlsStart, &t.handshake)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				t.mu.Lock()
+				t.reused++
+				t.mu.Unlock()
+			}
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.start(&t.wrote) },
+		GotFirstResponseByte: func() { t.done(&t.wrote, &t.firstByte) },
+	}
+}
+
+// start records start of the phase, concurrent dials of the same phase are measured from the first one.
+func (t *tracer) start(at *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if at.IsZero() {
+		*at = time.Now()
+	}
+}
+
+func (t *tracer) done(at *time.Time, d *time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !at.IsZero() {
+		*d += time.Since(*at)
+		*at = time.Time{}
+	}
+}
+
+// add adds durations of the attempt to the stats.
+func (t *tracer) add(st *Stats) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st.DNSDuration += t.dns
+	st.ConnectDuration += t.connect
+	st.TLSDuration += t.handshake
+	st.ServerDuration += t.firstByte
+	st.ReusedConns += t.reused
+}
@@ -0,0 +1,74 @@
+package soap
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_Stats(t *testing.T) {
+	t.Parallel()
+	b, _ := xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	var st Stats
+	if err := MustNewClient(srv.URL, Config{Stats: func(s Stats) {
+		st = s
+	}}).Call(context.Background(), "act", request{Attr1: "value1"}, &response{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if st.Action != "act" || st.Attempts != 1 || st.Err != nil {
+		t.Fatalf("invalid stats %+v", st)
+	}
+
+	if st.NetworkDuration < 10*time.Millisecond || st.MarshalDuration <= 0 || st.DecodeDuration <= 0 {
+		t.Fatalf("invalid durations %+v", st)
+	}
+
+	if st.RequestBytes == 0 || st.ResponseBytes != len(b) {
+		t.Fatalf("invalid sizes %+v", st)
+	}
+}
+
+func TestClient_StatsTrace(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		b, _ := xml.Marshal(Envelope{Body: Body{}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	var st Stats
+	client := MustNewClient(srv.URL, Config{TLS: &tls.Config{RootCAs: pool}, KeepAlive: true, Stats: func(s Stats) {
+		st = s
+	}})
+
+	if err := client.Call(context.Background(), "", request{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if st.ConnectDuration <= 0 || st.TLSDuration <= 0 || st.ServerDuration < 10*time.Millisecond || st.ReusedConns != 0 {
+		t.Fatalf("invalid new connection stats %+v", st)
+	}
+
+	if err := client.Call(context.Background(), "", request{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if st.ConnectDuration != 0 || st.TLSDuration != 0 || st.ServerDuration < 10*time.Millisecond || st.ReusedConns != 1 {
+		t.Fatalf("invalid reused connection stats %+v", st)
+	}
+}
@@ -0,0 +1,77 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// captureHeaders keeps response header elements configured as sticky, the latest
+// element of each name replaces the previous one.
+func (s *Client) captureHeaders(body []byte) {
+	if len(s.config.StickyHeaders) == 0 {
+		return
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(trimProlog(body)))
+	depth := 0
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 2 && t.Name.Local != "Header":
+				// body is not searched
+				return
+			case depth == 3:
+				name, ok := s.sticky(t.Name)
+				if !ok {
+					if err := d.Skip(); err != nil {
+						return
+					}
+					depth--
+					continue
+				}
+
+				var r RawElement
+				if err := d.DecodeElement(&r, &t); err != nil {
+					return
+				}
+				depth--
+
+				s.mu.Lock()
+				if s.stickyHeaders == nil {
+					s.stickyHeaders = make(map[xml.Name]RawElement)
+				}
+				s.stickyHeaders[name] = r
+				s.mu.Unlock()
+			}
+		case xml.EndElement:
+			depth--
+			if depth == 1 {
+				return
+			}
+		}
+	}
+}
+
+// sticky returns configured name matching the element, a name without namespace matches any namespace.
+func (s *Client) sticky(name xml.Name) (xml.Name, bool) {
+	for _, n := range s.config.StickyHeaders {
+		if n == name || n.Space == "" && n.Local == name.Local {
+			return n, true
+		}
+	}
+	return xml.Name{}, false
+}
+
+// ResetStickyHeaders forgets captured sticky headers, e.g. after the session is closed.
+func (s *Client) ResetStickyHeaders() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stickyHeaders = nil
+}
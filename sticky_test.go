@@ -0,0 +1,55 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestClient_StickyHeaders(t *testing.T) {
+	t.Parallel()
+	var (
+		calls    int
+		sessions []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		n, err := ParseNode(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions = append(sessions, n.Value("Envelope/Header/SessionId"))
+
+		calls++
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Header>` +
+			`<s:SessionId xmlns:s="urn:session" s:ttl="60">` + strconv.Itoa(calls) + `</s:SessionId><Other>x</Other>` +
+			`</Header><Body/></Envelope>`))
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{StickyHeaders: []xml.Name{{Local: "SessionId"}}})
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			client.ResetStickyHeaders()
+		}
+
+		if err := client.Call(context.Background(), "", request{}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := []string{"", "1", ""}; len(sessions) != 3 || sessions[0] != want[0] || sessions[1] != want[1] || sessions[2] != want[2] {
+		t.Fatalf("got: %q, want: %q", sessions, want)
+	}
+
+	client.mu.RLock()
+	got := string(client.stickyHeaders[xml.Name{Local: "SessionId"}].Raw)
+	client.mu.RUnlock()
+	if want := `<SessionId xmlns="urn:session" xmlns:_="urn:session" _:ttl="60">3</SessionId>`; got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+package soap
+
+import (
+	"encoding/xml"
+)
+
+// StreamFunc decodes the element, e.g. by d.DecodeElement(&item, &start).
+type StreamFunc func(d *xml.Decoder, start xml.StartElement) error
+
+// Stream implements response content decoded element by element: each element with
+// the registered name at any depth of the body is passed to its callback instead of
+// being materialized in a slice, a key without namespace matches any namespace.
+// The raw response is still buffered by the transport.
+type Stream map[xml.Name]StreamFunc
+
+// streamError implements error of the callback returned from Call as is.
+type streamError struct {
+	err error
+}
+
+func (e *streamError) Error() string {
+	return e.err.Error()
+}
+
+func (s Stream) decode(d *xml.Decoder, se xml.StartElement) error {
+	if fn, ok := s.match(se.Name); ok {
+		return s.call(fn, d, se)
+	}
+
+	for depth := 1; depth > 0; {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			fn, ok := s.match(t.Name)
+			if !ok {
+				depth++
+				continue
+			}
+
+			if err := s.call(fn, d, t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return nil
+}
+
+func (s Stream) match(name xml.Name) (StreamFunc, bool) {
+	fn, ok := s[name]
+	if !ok {
+		fn, ok = s[xml.Name{Local: name.Local}]
+	}
+	return fn, ok
+}
+
+func (s Stream) call(fn StreamFunc, d *xml.Decoder, start xml.StartElement) error {
+	if err := fn(d, start); err != nil {
+		return &streamError{err: err}
+	}
+	return nil
+}
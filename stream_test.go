@@ -0,0 +1,54 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type streamItem struct {
+	ID int `xml:"id"`
+}
+
+func TestClient_Stream(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var items strings.Builder
+		for i := 1; i <= 1000; i++ {
+			fmt.Fprintf(&items, "<item><id>%d</id></item>", i)
+		}
+		fmt.Fprintf(w, `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><ListResponse xmlns="test:call"><total>1000</total><items>%s</items></ListResponse></Body></Envelope>`, items.String())
+	}))
+	defer srv.Close()
+
+	var count, sum int
+	stream := Stream{{Local: "item"}: func(d *xml.Decoder, start xml.StartElement) error {
+		var item streamItem
+		if err := d.DecodeElement(&item, &start); err != nil {
+			return err
+		}
+
+		count++
+		sum += item.ID
+		return nil
+	}}
+
+	if err := MustNewClient(srv.URL, Config{DecodeMode: Strict}).Call(context.Background(), "list", request{}, stream); err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1000 || sum != 500500 {
+		t.Fatalf("got: %d %d, want: 1000 500500", count, sum)
+	}
+
+	stream[xml.Name{Local: "item"}] = func(d *xml.Decoder, start xml.StartElement) error {
+		return fmt.Errorf("stop")
+	}
+	if err := MustNewClient(srv.URL, Config{}).Call(context.Background(), "list", request{}, stream); err == nil || err.Error() != "stop" {
+		t.Fatalf("got: %v, want: stop", err)
+	}
+}
@@ -0,0 +1,230 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// DecodeMode implements handling of response elements unknown to the response type.
+type DecodeMode int
+
+// Decode modes.
+const (
+	// Lenient ignores unknown elements.
+	Lenient DecodeMode = iota
+	// Strict fails the call on unknown element, catching contract drift.
+	Strict
+)
+
+// UnknownElementFunc is called with slash separated path of the unknown response element,
+// the path starts with the body element.
+type UnknownElementFunc func(path string)
+
+var unmarshalerType = reflect.TypeOf((*xml.Unmarshaler)(nil)).Elem()
+
+// xmlFields implements expected child elements of the struct type.
+type xmlFields struct {
+	any      bool
+	children map[string]*xmlField
+}
+
+// xmlField implements expected element, it is either typed or holds nested elements of a>b tag.
+type xmlField struct {
+	typ    reflect.Type
+	nested *xmlFields
+}
+
+// fieldsOf returns expected child elements of the type, nil means any content is accepted.
+func fieldsOf(t reflect.Type) *xmlFields {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		t = t.Elem()
+	}
+
+	if t.Kind() == reflect.Interface || t.Implements(unmarshalerType) || reflect.PtrTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	if t.Kind() != reflect.Struct {
+		return &xmlFields{}
+	}
+
+	fields := &xmlFields{children: make(map[string]*xmlField)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Name == "XMLName" || f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		tag := f.Tag.Get("xml")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := tag, ""
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			name, opts = tag[:i], tag[i:]
+		}
+
+		switch {
+		case strings.Contains(opts, ",innerxml"), strings.Contains(opts, ",any"):
+			return nil
+		case strings.Contains(opts, ",attr"), strings.Contains(opts, ",chardata"), strings.Contains(opts, ",cdata"), strings.Contains(opts, ",comment"):
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			embedded := fieldsOf(f.Type)
+			if embedded == nil {
+				return nil
+			}
+
+			for k, v := range embedded.children {
+				fields.children[k] = v
+			}
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		// namespace is not checked
+		if i := strings.LastIndexByte(name, ' '); i >= 0 {
+			name = name[i+1:]
+		}
+
+		parents := strings.Split(name, ">")
+		cur := fields
+		for _, p := range parents[:len(parents)-1] {
+			field, ok := cur.children[p]
+			if !ok || field.nested == nil {
+				field = &xmlField{nested: &xmlFields{children: make(map[string]*xmlField)}}
+				cur.children[p] = field
+			}
+			cur = field.nested
+		}
+		cur.children[parents[len(parents)-1]] = &xmlField{typ: f.Type}
+	}
+	return fields
+}
+
+// checkUnknown reports elements of the response body which are not expected by the response type.
+func checkUnknown(body []byte, response interface{}, unknown UnknownElementFunc) error {
+	d := xml.NewDecoder(bytes.NewReader(body))
+	depth := 0
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			// envelope, header or body
+			if depth < 2 {
+				if depth == 1 && t.Name.Local != "Body" {
+					if err := d.Skip(); err != nil {
+						return err
+					}
+					continue
+				}
+
+				depth++
+				continue
+			}
+
+			if t.Name.Local == "Fault" {
+				return nil
+			}
+
+			switch response.(type) {
+			case Stream, *Selection:
+				return nil
+			}
+
+			target := response
+			if choice, ok := response.(*Choice); ok {
+				target = choice.Chosen
+			} else if targets, ok := response.(Targets); ok {
+				if target, ok = targets.match(t.Name); !ok {
+					if err := d.Skip(); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+
+			if err := walkUnknown(d, fieldsOf(reflect.TypeOf(target)), t.Name.Local, unknown); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+func walkUnknown(d *xml.Decoder, fields *xmlFields, path string, unknown UnknownElementFunc) error {
+	if fields == nil {
+		return d.Skip()
+	}
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			child := path + "/" + t.Name.Local
+			field, ok := fields.children[t.Name.Local]
+			if !ok {
+				unknown(child)
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+
+			next := field.nested
+			if next == nil {
+				next = fieldsOf(field.typ)
+			}
+
+			if err := walkUnknown(d, next, child, unknown); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// checkDecodeMode reports unknown elements to the callback and fails in strict mode.
+func (c Config) checkDecodeMode(body []byte, response interface{}) error {
+	if c.DecodeMode == Lenient && c.OnUnknownElement == nil {
+		return nil
+	}
+
+	var paths []string
+	if err := checkUnknown(body, response, func(path string) {
+		paths = append(paths, path)
+		if c.OnUnknownElement != nil {
+			c.OnUnknownElement(path)
+		}
+	}); err != nil {
+		return fmt.Errorf("soap: %s", err)
+	}
+
+	if c.DecodeMode == Strict && len(paths) > 0 {
+		return fmt.Errorf("soap: unknown elements %s", strings.Join(paths, ", "))
+	}
+	return nil
+}
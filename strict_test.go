@@ -0,0 +1,60 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type strictResponse struct {
+	XMLName xml.Name `xml:"test:call Response"`
+	ID      string   `xml:"id,attr"`
+	Attr3   string   `xml:"attr3"`
+	Items   []struct {
+		Value string `xml:"value"`
+	} `xml:"list>item"`
+	Extra *Node `xml:"extra"`
+}
+
+func TestClient_DecodeMode(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Header><Session/></Header><Body>` +
+			`<Response xmlns="test:call" id="1"><attr3>value3</attr3>` +
+			`<list><item><value>1</value><note/></item><count>1</count></list>` +
+			`<extra><any/></extra><attr4/></Response></Body></Envelope>`))
+	}))
+	defer srv.Close()
+
+	want := []string{"Response/list/item/note", "Response/list/count", "Response/attr4"}
+	var got []string
+	c := Config{OnUnknownElement: func(path string) {
+		got = append(got, path)
+	}}
+
+	var r strictResponse
+	if err := MustNewClient(srv.URL, c).Call(context.Background(), "", request{}, &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+
+	if r.Attr3 != "value3" {
+		t.Fatalf("got: %s, want: %s", r.Attr3, "value3")
+	}
+
+	c.DecodeMode = Strict
+	err := MustNewClient(srv.URL, c).Call(context.Background(), "", request{}, &strictResponse{})
+	if want := "soap: unknown elements Response/list/item/note, Response/list/count, Response/attr4"; err == nil || err.Error() != want {
+		t.Fatalf("got: %v, want: %s", err, want)
+	}
+
+	if err := MustNewClient(srv.URL, Config{DecodeMode: Strict}).Call(context.Background(), "", request{}, &Node{}); err != nil {
+		t.Fatal(err)
+	}
+}
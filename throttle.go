@@ -0,0 +1,118 @@
+package soap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ThrottleDefault is key of the rate of actions without own rate, each action is limited separately.
+const ThrottleDefault = "*"
+
+// Rate implements rate limit of the calls, e.g. Rate{Limit: 50, Per: time.Second}.
+type Rate struct {
+	// Limit calls are sent per the period, the calls are spaced evenly.
+	Limit int
+	Per   time.Duration
+	// Burst calls may be sent at once, one when zero.
+	Burst int
+}
+
+// interval returns spacing of the calls.
+func (r Rate) interval() time.Duration {
+	return r.Per / time.Duration(r.Limit)
+}
+
+// limiter implements rate limit of the action.
+type limiter struct {
+	rate Rate
+	mu   sync.Mutex
+	// next is time the next call is allowed when the burst is used up
+	next time.Time
+}
+
+// wait blocks until the call is allowed.
+func (l *limiter) wait(ctx context.Context) error {
+	interval := l.rate.interval()
+	burst := l.rate.Burst
+	if burst < 1 {
+		burst = 1
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	next := l.next
+	if next.Before(now) {
+		next = now
+	}
+	delay := next.Sub(now) - time.Duration(burst-1)*interval
+	l.next = next.Add(interval)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(delay)
+	select {
+	case <-ctx.Done():
+		t.Stop()
+		return &transportError{err: ctx.Err()}
+	case <-t.C:
+		return nil
+	}
+}
+
+// throttle implements rate limits of the actions.
+type throttle struct {
+	rates map[string]Rate
+
+	mu       sync.Mutex
+	limiters map[string]*limiter
+}
+
+func newThrottle(rates map[string]Rate) *throttle {
+	if len(rates) == 0 {
+		return nil
+	}
+	return &throttle{rates: rates, limiters: make(map[string]*limiter)}
+}
+
+// wait blocks until the call of the action is allowed.
+func (t *throttle) wait(ctx context.Context, action string) error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	l, ok := t.limiters[action]
+	if !ok {
+		rate, ok := t.rates[action]
+		if !ok {
+			rate, ok = t.rates[ThrottleDefault]
+		}
+
+		if ok {
+			l = &limiter{rate: rate}
+		}
+		// actions without rate are cached as nil
+		t.limiters[action] = l
+	}
+	t.mu.Unlock()
+
+	if l == nil {
+		return nil
+	}
+	return l.wait(ctx)
+}
+
+// validateThrottle checks the rates.
+func validateThrottle(rates map[string]Rate) error {
+	for action, r := range rates {
+		if r.Limit <= 0 || r.Per <= 0 || r.Burst < 0 {
+			return fmt.Errorf("soap: throttle of action %q is invalid", action)
+		}
+	}
+	return nil
+}
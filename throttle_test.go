@@ -0,0 +1,55 @@
+package soap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_Throttle(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body/></Envelope>`))
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{Throttle: map[string]Rate{
+		"login":         {Limit: 1, Per: 100 * time.Millisecond},
+		ThrottleDefault: {Limit: 1, Per: time.Hour, Burst: 3},
+	}})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := client.Call(context.Background(), "login", request{}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("got: %s, want: calls spaced by 100ms", elapsed)
+	}
+
+	// burst of the default rate is separate for each action
+	start = time.Now()
+	for _, action := range []string{"query", "query", "query", "search"} {
+		if err := client.Call(context.Background(), action, request{}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("got: %s, want: calls within the burst", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.Call(ctx, "query", request{}, nil); err == nil {
+		t.Fatal("got: nil, want: error of the canceled wait")
+	}
+
+	if _, err := NewClient(srv.URL, Config{Throttle: map[string]Rate{"login": {Per: time.Second}}}); err == nil {
+		t.Fatal("got: nil, want: error of invalid rate")
+	}
+}
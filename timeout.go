@@ -0,0 +1,40 @@
+package soap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Causes of the transport failures of the call, they are matched by errors.Is.
+var (
+	// ErrDeadline is returned when deadline of the call context is exceeded.
+	ErrDeadline = fmt.Errorf("soap: call deadline exceeded")
+	// ErrCanceled is returned when the call context is canceled by the caller or the client is closed.
+	ErrCanceled = fmt.Errorf("soap: call canceled")
+	// ErrTimeout is returned when the server is too slow for Config.Timeout or the attempt timeout
+	// of the retry budget while the call context is alive, e.g. the response body is read too long.
+	ErrTimeout = fmt.Errorf("soap: server timeout")
+)
+
+// withCause returns copy of the transport failure with cause by the context of the call.
+func withCause(ctx context.Context, err error) error {
+	e, ok := copyError(err).(*transportError)
+	if !ok {
+		return err
+	}
+
+	switch ctx.Err() {
+	case context.Canceled:
+		e.cause = ErrCanceled
+	case context.DeadlineExceeded:
+		e.cause = ErrDeadline
+	default:
+		var ne net.Error
+		if errors.As(e.err, &ne) && ne.Timeout() || errors.Is(e.err, context.DeadlineExceeded) {
+			e.cause = ErrTimeout
+		}
+	}
+	return e
+}
@@ -0,0 +1,56 @@
+package soap
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_TimeoutCause(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		// headers are sent and the body is delayed
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/">`))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	deadline, cancelDeadline := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelDeadline()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	for i, v := range []struct {
+		ctx    context.Context
+		config Config
+		want   error
+	}{
+		{ctx: context.Background(), config: Config{Timeout: 50 * time.Millisecond}, want: ErrTimeout},
+		{ctx: deadline, want: ErrDeadline},
+		{ctx: canceled, want: ErrCanceled},
+	} {
+		err := MustNewClient(srv.URL, v.config).Call(v.ctx, "", request{}, nil)
+		if !errors.Is(err, v.want) {
+			t.Errorf("#%d got: %v, want: %s", i, err, v.want)
+		}
+
+		if _, ok := CallInfoOf(err); !ok {
+			t.Errorf("#%d got: %v, want: error with call info", i, err)
+		}
+	}
+
+	err := MustNewClient(srv.URL, Config{}).Call(deadline, "", request{}, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got: %v, want: %s", err, context.DeadlineExceeded)
+	}
+}
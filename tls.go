@@ -0,0 +1,157 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+)
+
+// TLSPreset implements named tls configuration.
+type TLSPreset string
+
+// TLS presets.
+const (
+	// TLSDefault uses defaults of crypto/tls.
+	TLSDefault TLSPreset = ""
+	// TLSModern allows only TLS 1.3.
+	TLSModern TLSPreset = "modern"
+	// TLSCompatible allows TLS 1.2 and above with forward secret AEAD cipher suites.
+	TLSCompatible TLSPreset = "compatible"
+	// TLSLegacy allows TLS 1.0 and above with CBC cipher suites for outdated servers.
+	TLSLegacy TLSPreset = "legacy"
+)
+
+var tlsPresets = map[TLSPreset]func(c *tls.Config){
+	TLSDefault: func(c *tls.Config) {},
+	TLSModern: func(c *tls.Config) {
+		c.MinVersion = tls.VersionTLS13
+	},
+	TLSCompatible: func(c *tls.Config) {
+		c.MinVersion = tls.VersionTLS12
+		c.CipherSuites = []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		}
+	},
+	TLSLegacy: func(c *tls.Config) {
+		c.MinVersion = tls.VersionTLS10
+		c.CipherSuites = []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+		}
+	},
+}
+
+// TLSDialer implements dialing of tls connections by an external engine,
+// e.g. GOST TLS which is not supported by crypto/tls.
+type TLSDialer func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// DangerouslySkipVerify disables verification of the server certificate chain and host name.
+// Any certificate presented by the server is accepted, it must be used only for testing.
+func (c *Config) DangerouslySkipVerify() {
+	c.insecureSkipVerify = true
+}
+
+// tlsConfig returns tls configuration, the preset, custom cipher suites and curves
+// are applied on top of Config.TLS.
+func (c Config) tlsConfig() *tls.Config {
+	if c.TLS == nil && c.TLSPreset == TLSDefault && len(c.CipherSuites) == 0 && len(c.CurvePreferences) == 0 &&
+		len(c.PinnedCertificates) == 0 && len(c.PinnedSPKIHashes) == 0 && !c.insecureSkipVerify {
+		return nil
+	}
+
+	cfg := &tls.Config{}
+	if c.TLS != nil {
+		cfg = c.TLS.Clone()
+	}
+
+	if preset, ok := tlsPresets[c.TLSPreset]; ok {
+		preset(cfg)
+	}
+
+	if len(c.CipherSuites) > 0 {
+		cfg.CipherSuites = c.CipherSuites
+	}
+
+	if len(c.CurvePreferences) > 0 {
+		cfg.CurvePreferences = c.CurvePreferences
+	}
+
+	if c.insecureSkipVerify {
+		cfg.InsecureSkipVerify = true
+	}
+
+	if len(c.PinnedCertificates) > 0 || len(c.PinnedSPKIHashes) > 0 {
+		verify := cfg.VerifyConnection
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if verify != nil {
+				if err := verify(cs); err != nil {
+					return err
+				}
+			}
+			// pins are matched against the verified chains, the chain sent by the server
+			// may carry any pinned certificate
+			if cfg.InsecureSkipVerify {
+				if len(cs.PeerCertificates) == 0 {
+					return errPinning
+				}
+				return c.verifyPins(cs.PeerCertificates[:1])
+			}
+
+			for _, chain := range cs.VerifiedChains {
+				if c.verifyPins(chain) == nil {
+					return nil
+				}
+			}
+			return errPinning
+		}
+	}
+	return cfg
+}
+
+// CertificateHash returns SHA-256 fingerprint of the certificate.
+func CertificateHash(cert *x509.Certificate) []byte {
+	h := sha256.Sum256(cert.Raw)
+	return h[:]
+}
+
+// SPKIHash returns SHA-256 fingerprint of the certificate subject public key info.
+func SPKIHash(cert *x509.Certificate) []byte {
+	h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return h[:]
+}
+
+// verifyPins checks that any certificate of the chain matches the pinned fingerprints.
+func (c Config) verifyPins(chain []*x509.Certificate) error {
+	for _, cert := range chain {
+		for _, pin := range c.PinnedCertificates {
+			if bytes.Equal(pin, CertificateHash(cert)) {
+				return nil
+			}
+		}
+
+		for _, pin := range c.PinnedSPKIHashes {
+			if bytes.Equal(pin, SPKIHash(cert)) {
+				return nil
+			}
+		}
+	}
+	return errPinning
+}
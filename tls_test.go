@@ -0,0 +1,184 @@
+package soap
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/xml"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_TLSConfig(t *testing.T) {
+	t.Parallel()
+	if cfg := (Config{}).tlsConfig(); cfg != nil {
+		t.Fatalf("got: %v, want: nil", cfg)
+	}
+
+	c := Config{TLS: &tls.Config{ServerName: "example"}, TLSPreset: TLSCompatible, CurvePreferences: []tls.CurveID{tls.CurveP256}}
+	cfg := c.tlsConfig()
+	if cfg.ServerName != "example" || cfg.MinVersion != tls.VersionTLS12 || len(cfg.CipherSuites) == 0 {
+		t.Fatalf("preset is not applied: %+v", cfg)
+	}
+
+	if len(cfg.CurvePreferences) != 1 || cfg.CurvePreferences[0] != tls.CurveP256 {
+		t.Fatalf("got: %v, want: %v", cfg.CurvePreferences, []tls.CurveID{tls.CurveP256})
+	}
+
+	if c.TLS.MinVersion != 0 {
+		t.Fatal("tls config must not be modified")
+	}
+}
+
+func TestClient_DangerouslySkipVerify(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := xml.Marshal(Envelope{Body: Body{}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	if err := MustNewClient(srv.URL, Config{TLSPreset: TLSModern}).Call(context.Background(), "", request{}, nil); err == nil {
+		t.Fatal("want certificate error")
+	}
+
+	c := Config{TLSPreset: TLSModern}
+	c.DangerouslySkipVerify()
+	if err := MustNewClient(srv.URL, c).Call(context.Background(), "", request{}, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_DialTLSContext(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := xml.Marshal(Envelope{Body: Body{}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	var dialed bool
+	if err := MustNewClient(srv.URL, Config{DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = true
+		return tls.Dial(network, addr, &tls.Config{InsecureSkipVerify: true})
+	}}).Call(context.Background(), "", request{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !dialed {
+		t.Fatal("tls dialer is not used")
+	}
+}
+
+func TestClient_Pinning(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := xml.Marshal(Envelope{Body: Body{}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	for i, v := range []struct {
+		config Config
+		err    bool
+	}{
+		{config: Config{PinnedCertificates: [][]byte{CertificateHash(srv.Certificate())}}},
+		{config: Config{PinnedSPKIHashes: [][]byte{SPKIHash(srv.Certificate())}}},
+		{config: Config{PinnedSPKIHashes: [][]byte{make([]byte, 32)}}, err: true},
+	} {
+		v.config.TLS = &tls.Config{RootCAs: pool}
+		err := MustNewClient(srv.URL, v.config).Call(context.Background(), "", request{}, nil)
+		if v.err != (err != nil) {
+			t.Errorf("#%d got: %v, want error: %t", i, err, v.err)
+		}
+	}
+}
+
+func TestClient_PinningAppendedCertificate(t *testing.T) {
+	t.Parallel()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "pinned"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	pinned, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(pinned)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := xml.Marshal(Envelope{Body: Body{}})
+		w.Write(b)
+	}))
+	srv.StartTLS()
+	defer srv.Close()
+
+	// the server sends the pinned certificate which is not part of the verified chain
+	srv.TLS.Certificates[0].Certificate = append(srv.TLS.Certificates[0].Certificate, pinned)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	c := Config{TLS: &tls.Config{RootCAs: pool}, PinnedCertificates: [][]byte{CertificateHash(cert)}}
+	if err := MustNewClient(srv.URL, c).Call(context.Background(), "", request{}, nil); err == nil {
+		t.Fatal("got: nil, want: pinning error")
+	}
+
+	c = Config{PinnedSPKIHashes: [][]byte{SPKIHash(cert)}}
+	c.DangerouslySkipVerify()
+	if err := MustNewClient(srv.URL, c).Call(context.Background(), "", request{}, nil); err == nil {
+		t.Fatal("got: nil, want: pinning error")
+	}
+
+	c = Config{PinnedSPKIHashes: [][]byte{SPKIHash(srv.Certificate())}}
+	c.DangerouslySkipVerify()
+	if err := MustNewClient(srv.URL, c).Call(context.Background(), "", request{}, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_SetTLS(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := xml.Marshal(Envelope{Body: Body{}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{})
+	if err := client.Call(context.Background(), "", request{}, nil); err == nil {
+		t.Fatal("want certificate error")
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	if err := client.SetTLS(&tls.Config{RootCAs: pool}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Call(context.Background(), "", request{}, nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,63 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// RawRequestTransformer rewrites the marshaled request envelope before it is sent, e.g. last-mile
+// fixes demanded by broken servers, error fails the call.
+type RawRequestTransformer func(ctx context.Context, action string, envelope []byte) ([]byte, error)
+
+// ReplaceNamespace returns transformer replacing namespace uri of the declarations and attribute values.
+func ReplaceNamespace(old, new string) RawRequestTransformer {
+	return func(ctx context.Context, action string, envelope []byte) ([]byte, error) {
+		for _, q := range []string{`"`, `'`} {
+			envelope = bytes.Replace(envelope, []byte("="+q+old+q), []byte("="+q+new+q), -1)
+		}
+		return envelope, nil
+	}
+}
+
+// AddProcInst returns transformer adding the processing instruction before the envelope element,
+// after the xml declaration when it is present.
+func AddProcInst(target, inst string) RawRequestTransformer {
+	pi := []byte("<?" + target + " " + inst + "?>")
+	return func(ctx context.Context, action string, envelope []byte) ([]byte, error) {
+		i := 0
+		if bytes.HasPrefix(envelope, []byte("<?xml ")) {
+			if end := bytes.Index(envelope, []byte("?>")); end >= 0 {
+				i = end + len("?>")
+			}
+		}
+
+		out := make([]byte, 0, len(envelope)+len(pi))
+		out = append(out, envelope[:i]...)
+		out = append(out, pi...)
+		return append(out, envelope[i:]...), nil
+	}
+}
+
+// RawResponseTransformer repairs the raw response body before it is decoded, error fails the call.
+type RawResponseTransformer func(ctx context.Context, action string, body []byte) ([]byte, error)
+
+// transformResponse returns copy of the response with the body transformed, the response
+// may be shared by middleware, e.g. cached.
+func (s *Client) transformResponse(ctx context.Context, action string, resp *Response) (*Response, error) {
+	if len(s.config.RawResponseTransformers) == 0 || len(resp.Body) == 0 {
+		return resp, nil
+	}
+
+	body := resp.Body
+	for _, transform := range s.config.RawResponseTransformers {
+		var err error
+		if body, err = transform(ctx, action, body); err != nil {
+			return nil, fmt.Errorf("soap: %s", err)
+		}
+	}
+
+	r := *resp
+	r.Body = body
+	return &r, nil
+}
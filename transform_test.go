@@ -0,0 +1,122 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRawRequestTransformer(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		transform RawRequestTransformer
+		in        string
+		want      string
+	}{
+		{
+			name:      "namespace",
+			transform: ReplaceNamespace("test:new", "test:old"),
+			in:        `<a xmlns="test:new"><b xmlns:p='test:new'>test:new</b></a>`,
+			want:      `<a xmlns="test:old"><b xmlns:p='test:old'>test:new</b></a>`,
+		},
+		{
+			name:      "proc inst",
+			transform: AddProcInst("app", `version="1"`),
+			in:        `<a/>`,
+			want:      `<?app version="1"?><a/>`,
+		},
+		{
+			name:      "proc inst after declaration",
+			transform: AddProcInst("app", `version="1"`),
+			in:        `<?xml version="1.0"?><a/>`,
+			want:      `<?xml version="1.0"?><?app version="1"?><a/>`,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.transform(context.Background(), "", []byte(tt.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != tt.want {
+			t.Errorf("%s: got: %s, want: %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClient_RawRequestTransformers(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if !strings.HasPrefix(string(b), `<?app x?><Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/">`) {
+			t.Errorf("got: %s, want: transformed envelope", b)
+		}
+
+		b, _ = xml.Marshal(Envelope{Body: Body{Content: response{Attr3: "value3"}}})
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	var actions []string
+	client := MustNewClient(srv.URL, Config{RawRequestTransformers: []RawRequestTransformer{
+		func(ctx context.Context, action string, envelope []byte) ([]byte, error) {
+			actions = append(actions, action)
+			return envelope, nil
+		},
+		AddProcInst("app", "x"),
+	}})
+	if err := client.Call(context.Background(), "get", request{}, &response{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(actions) != 1 || actions[0] != "get" {
+		t.Fatalf("got: %v, want: [get]", actions)
+	}
+
+	client = MustNewClient(srv.URL, Config{RawRequestTransformers: []RawRequestTransformer{
+		func(ctx context.Context, action string, envelope []byte) ([]byte, error) {
+			return nil, fmt.Errorf("broken")
+		},
+	}})
+	if err := client.Call(context.Background(), "get", request{}, &response{}); err == nil || err.Error() != "soap: broken" {
+		t.Fatalf("got: %v, want: soap: broken", err)
+	}
+}
+
+func TestClient_RawResponseTransformers(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Response xmlns="test:call" xmlns="test:call"><attr3>value&nbsp;3</attr3></Response></Body></Envelope>`))
+	}))
+	defer srv.Close()
+
+	client := MustNewClient(srv.URL, Config{})
+	if err := client.Call(context.Background(), "get", request{}, &response{}); err == nil {
+		t.Fatal("got: nil, want: error")
+	}
+
+	client = MustNewClient(srv.URL, Config{RawResponseTransformers: []RawResponseTransformer{
+		func(ctx context.Context, action string, body []byte) ([]byte, error) {
+			return bytes.Replace(body, []byte(` xmlns="test:call" xmlns="test:call"`), []byte(` xmlns="test:call"`), 1), nil
+		},
+		func(ctx context.Context, action string, body []byte) ([]byte, error) {
+			return bytes.Replace(body, []byte("&nbsp;"), []byte(" "), -1), nil
+		},
+	}})
+	resp := &response{}
+	if err := client.Call(context.Background(), "get", request{}, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Attr3 != "value 3" {
+		t.Fatalf("got: %s, want: value 3", resp.Attr3)
+	}
+}
@@ -0,0 +1,177 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"strings"
+)
+
+// Limits of the wsdl retrieval.
+const (
+	maxWSDLDocuments = 100
+	maxWSDLBytes     = 10 << 20
+)
+
+// WSDL implements service description downloaded with its imports.
+type WSDL struct {
+	// URL is url of the service description.
+	URL string
+	// Documents maps absolute url to content of the description and the imported wsdl and xsd documents.
+	Documents map[string][]byte
+}
+
+// Definitions returns content of the service description.
+func (w *WSDL) Definitions() []byte {
+	return w.Documents[w.URL]
+}
+
+// FetchWSDL downloads description of the service by the endpoint url with ?wsdl query,
+// wsdl:import locations and xsd:import and xsd:include schema locations are followed.
+// Authorization, tls and proxy options of the client are applied, imports of other scheme or host
+// are downloaded without authorization and headers of the client.
+func (s *Client) FetchWSDL(ctx context.Context) (*WSDL, error) {
+	u, err := neturl.Parse(s.endpoint())
+	if err != nil {
+		return nil, fmt.Errorf("soap: %s", err)
+	}
+
+	if _, ok := u.Query()["wsdl"]; !ok {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += "wsdl"
+	}
+
+	w := &WSDL{URL: u.String(), Documents: make(map[string][]byte)}
+	for queue := []*neturl.URL{u}; len(queue) > 0; queue = queue[1:] {
+		doc := queue[0]
+		if _, ok := w.Documents[doc.String()]; ok {
+			continue
+		}
+
+		if len(w.Documents) == maxWSDLDocuments {
+			return nil, fmt.Errorf("soap: wsdl imports more than %d documents", maxWSDLDocuments)
+		}
+
+		b, err := s.get(ctx, doc.String(), sameOrigin(u, doc))
+		if err != nil {
+			return nil, err
+		}
+		w.Documents[doc.String()] = b
+
+		locations, err := importLocations(b)
+		if err != nil {
+			return nil, fmt.Errorf("soap: %s: %s", doc, err)
+		}
+
+		for _, l := range locations {
+			ref, err := doc.Parse(l)
+			if err != nil {
+				return nil, fmt.Errorf("soap: %s: %s", doc, err)
+			}
+
+			if ref.Scheme != "http" && ref.Scheme != "https" {
+				return nil, fmt.Errorf("soap: %s: import %q must be http or https url", doc, l)
+			}
+			ref.Fragment = ""
+			queue = append(queue, ref)
+		}
+	}
+	return w, nil
+}
+
+// FetchWSDL downloads description of the service by the endpoint url with the config.
+func FetchWSDL(ctx context.Context, url string, c Config) (*WSDL, error) {
+	s, err := NewClient(url, c)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.FetchWSDL(ctx)
+}
+
+// sameOrigin reports whether the urls have the same scheme and host.
+func sameOrigin(a, b *neturl.URL) bool {
+	return a.Scheme == b.Scheme && strings.EqualFold(a.Host, b.Host)
+}
+
+// get downloads the document, the client authorization is applied when authorized is true.
+func (s *Client) get(ctx context.Context, url string, authorized bool) ([]byte, error) {
+	resp, err := s.do(ctx, "GET", url, authorized)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxWSDLBytes+1))
+	if err != nil {
+		return nil, &transportError{err: err}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{status: resp.Status, code: resp.StatusCode}
+	}
+
+	if len(b) > maxWSDLBytes {
+		return nil, fmt.Errorf("soap: %s exceeds limit %d", url, maxWSDLBytes)
+	}
+	return b, nil
+}
+
+// do sends request without envelope, e.g. wsdl retrieval, with the client authorization and headers
+// when authorized is true.
+func (s *Client) do(ctx context.Context, method, url string, authorized bool) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("soap: %s", err)
+	}
+
+	auth, httpClient := s.current()
+	if authorized {
+		if err := s.authorize(ctx, req, auth); err != nil {
+			return nil, err
+		}
+		for k, v := range s.config.Headers {
+			req.Header[k] = v
+		}
+	}
+	req.Header.Set("User-Agent", s.config.userAgent())
+
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, &transportError{err: err}
+	}
+	return resp, nil
+}
+
+// importLocations returns locations of wsdl:import, xsd:import and xsd:include elements.
+func importLocations(doc []byte) ([]string, error) {
+	var locations []string
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return locations, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		t, ok := token.(xml.StartElement)
+		if !ok || t.Name.Local != "import" && t.Name.Local != "include" {
+			continue
+		}
+
+		for _, a := range t.Attr {
+			if a.Name.Space == "" && (a.Name.Local == "location" || a.Name.Local == "schemaLocation") && a.Value != "" {
+				locations = append(locations, a.Value)
+			}
+		}
+	}
+}
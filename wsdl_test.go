@@ -0,0 +1,94 @@
+package soap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_FetchWSDL(t *testing.T) {
+	t.Parallel()
+	docs := map[string]string{
+		"/service?wsdl": `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"><import location="common.wsdl"/>` +
+			`<types><schema xmlns="http://www.w3.org/2001/XMLSchema"><import schemaLocation="xsd/types.xsd"/></schema></types></definitions>`,
+		"/common.wsdl":   `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"/>`,
+		"/xsd/types.xsd": `<schema xmlns="http://www.w3.org/2001/XMLSchema"><include schemaLocation="base.xsd"/><include schemaLocation="/xsd/types.xsd"/></schema>`,
+		"/xsd/base.xsd":  `<schema xmlns="http://www.w3.org/2001/XMLSchema"/>`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, _ := r.BasicAuth(); r.Method != "GET" || u != "user" || p != "pass" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		doc, ok := docs[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(doc))
+	}))
+	defer srv.Close()
+
+	w, err := FetchWSDL(context.Background(), srv.URL+"/service", Config{BasicAuth: &BasicAuth{Username: "user", Password: "pass"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := w.URL, srv.URL+"/service?wsdl"; got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+
+	if got, want := len(w.Documents), len(docs); got != want {
+		t.Fatalf("got: %d, want: %d", got, want)
+	}
+
+	for path, doc := range docs {
+		if got := string(w.Documents[srv.URL+path]); got != doc {
+			t.Errorf("%s got: %s, want: %s", path, got, doc)
+		}
+	}
+
+	if string(w.Definitions()) != docs["/service?wsdl"] {
+		t.Fatalf("got: %s, want: service description", w.Definitions())
+	}
+
+	if _, err := FetchWSDL(context.Background(), srv.URL+"/service", Config{}); err == nil || err.Error() != "soap: 401 Unauthorized (401)" {
+		t.Fatalf("got: %v, want: unauthorized", err)
+	}
+}
+
+func TestClient_FetchWSDLCrossOrigin(t *testing.T) {
+	t.Parallel()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" || r.Header.Get("X-Api-Key") != "" {
+			http.Error(w, "credentials are leaked", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`<schema xmlns="http://www.w3.org/2001/XMLSchema"/>`))
+	}))
+	defer other.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, _ := r.BasicAuth(); u != "user" || p != "pass" || r.Header.Get("X-Api-Key") != "key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"><types><schema xmlns="http://www.w3.org/2001/XMLSchema">` +
+			`<import schemaLocation="` + other.URL + `/types.xsd"/></schema></types></definitions>`))
+	}))
+	defer srv.Close()
+
+	w, err := FetchWSDL(context.Background(), srv.URL+"/service", Config{
+		BasicAuth: &BasicAuth{Username: "user", Password: "pass"},
+		Headers:   http.Header{"X-Api-Key": {"key"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(w.Documents), 2; got != want {
+		t.Fatalf("got: %d, want: %d", got, want)
+	}
+}
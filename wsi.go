@@ -0,0 +1,213 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WSIViolation implements violated assertion of WS-I Basic Profile 1.1.
+type WSIViolation struct {
+	// Rule is id of the assertion, e.g. R1005.
+	Rule    string
+	Message string
+}
+
+func (v WSIViolation) String() string {
+	return v.Rule + ": " + v.Message
+}
+
+// WSIError is returned when the request or the response violates the profile.
+type WSIError struct {
+	// Response is set for violations of the response.
+	Response   bool
+	Violations []WSIViolation
+}
+
+func (e *WSIError) Error() string {
+	msg := "request"
+	if e.Response {
+		msg = "response"
+	}
+
+	rules := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		rules[i] = v.String()
+	}
+	return fmt.Sprintf("soap: %s violates WS-I Basic Profile: %s", msg, strings.Join(rules, "; "))
+}
+
+// WSI implements conformance checking of requests and responses with WS-I Basic Profile 1.1:
+// envelope structure, absence of encodingStyle, DTDs and processing instructions, fault placement and content.
+type WSI struct {
+	// Report is called with violations of each message, the call is failed with *WSIError when nil.
+	Report func(action string, response bool, violations []WSIViolation)
+}
+
+// Middleware returns middleware checking requests and responses.
+func (w *WSI) Middleware() Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, r *Request) (*Response, error) {
+			if err := w.check(r.Action, false, r.Envelope); err != nil {
+				return nil, err
+			}
+
+			resp, err := next(ctx, r)
+			if err != nil || len(resp.Body) == 0 || !isXMLContentType(resp.Header.Get("Content-Type")) {
+				return resp, err
+			}
+
+			if err := w.check(r.Action, true, resp.Body); err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+	}
+}
+
+func (w *WSI) check(action string, response bool, envelope []byte) error {
+	violations := CheckWSI(envelope)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	if w.Report != nil {
+		w.Report(action, response, violations)
+		return nil
+	}
+	return &WSIError{Response: response, Violations: violations}
+}
+
+// faultChildren are allowed unqualified children of the fault.
+var faultChildren = map[string]bool{"faultcode": true, "faultstring": true, "faultactor": true, "detail": true}
+
+// CheckWSI returns violations of WS-I Basic Profile 1.1 by the envelope.
+func CheckWSI(envelope []byte) []WSIViolation {
+	var violations []WSIViolation
+	report := func(rule, format string, args ...interface{}) {
+		violations = append(violations, WSIViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(trimProlog(envelope)))
+	// declared encoding is reported instead of failing the decoder
+	d.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var (
+		depth int
+		// inBody and inFault are set inside the body and the fault which is the body child
+		inBody, inFault bool
+		seenBody        bool
+		bodyElements    int
+	)
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			report("R9980", "envelope is not well-formed: %s", err)
+			break
+		}
+
+		switch t := token.(type) {
+		case xml.ProcInst:
+			if t.Target != "xml" {
+				report("R1009", "processing instruction %s is not allowed", t.Target)
+			} else if enc := procInstEncoding(t.Inst); enc != "" && !strings.EqualFold(enc, "utf-8") && !strings.EqualFold(enc, "utf-16") {
+				report("R1012", "encoding %s is not UTF-8 or UTF-16", enc)
+			}
+		case xml.Directive:
+			report("R1008", "DTD is not allowed")
+		case xml.StartElement:
+			for _, a := range t.Attr {
+				if a.Name.Space != envelopeNS || a.Name.Local != "encodingStyle" {
+					continue
+				}
+
+				switch {
+				case t.Name.Space == envelopeNS:
+					report("R1005", "encodingStyle is not allowed on soap:%s", t.Name.Local)
+				case inBody && depth == 2:
+					report("R1006", "encodingStyle is not allowed on body element %s", t.Name.Local)
+				case inBody:
+					report("R1007", "encodingStyle is not allowed on element %s of the body", t.Name.Local)
+				}
+			}
+
+			switch {
+			case depth == 0:
+				if t.Name.Space != envelopeNS || t.Name.Local != "Envelope" {
+					report("R1015", "document element {%s}%s is not soap 1.1 envelope", t.Name.Space, t.Name.Local)
+					return violations
+				}
+			case depth == 1:
+				if seenBody {
+					report("R1011", "element %s follows the body", t.Name.Local)
+				} else if t.Name.Space != envelopeNS || t.Name.Local != "Header" && t.Name.Local != "Body" {
+					report("R9980", "element %s is not allowed in the envelope", t.Name.Local)
+				}
+				inBody = t.Name.Space == envelopeNS && t.Name.Local == "Body" && !seenBody
+				seenBody = seenBody || inBody
+			case depth == 2 && inBody:
+				if bodyElements++; bodyElements == 2 {
+					report("R9981", "body has more than one element")
+				}
+
+				if t.Name.Space == "" {
+					report("R1014", "body element %s is not namespace qualified", t.Name.Local)
+				}
+				inFault = t.Name.Space == envelopeNS && t.Name.Local == "Fault"
+			case depth == 3 && inFault:
+				if t.Name.Space != "" {
+					report("R1001", "fault element %s is not unqualified", t.Name.Local)
+				} else if !faultChildren[t.Name.Local] {
+					report("R1000", "element %s is not allowed in the fault", t.Name.Local)
+				} else if t.Name.Local == "faultcode" {
+					var code string
+					if err := d.DecodeElement(&code, &t); err != nil {
+						report("R9980", "envelope is not well-formed: %s", err)
+						return violations
+					}
+
+					if code = strings.TrimSpace(code); strings.IndexByte(code, ':') < 0 {
+						report("R1004", "faultcode %s is not namespace qualified", code)
+					}
+					// the end element is consumed
+					continue
+				}
+			case t.Name.Space == envelopeNS && t.Name.Local == "Fault":
+				report("R9980", "fault is not child of the body")
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			switch depth {
+			case 1:
+				inBody = false
+			case 2:
+				inFault = false
+			}
+		}
+	}
+	return violations
+}
+
+// procInstEncoding returns encoding of the xml declaration.
+func procInstEncoding(inst []byte) string {
+	s := string(inst)
+	i := strings.Index(s, "encoding=")
+	if i < 0 || len(s) < i+len("encoding=")+2 {
+		return ""
+	}
+
+	s = s[i+len("encoding="):]
+	if j := strings.IndexByte(s[1:], s[0]); j >= 0 {
+		return s[1 : j+1]
+	}
+	return ""
+}
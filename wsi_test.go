@@ -0,0 +1,71 @@
+package soap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckWSI(t *testing.T) {
+	t.Parallel()
+	const env = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">`
+	for i, v := range []struct {
+		envelope string
+		want     string
+	}{
+		{envelope: env + `<soap:Header><h xmlns="test:call"/></soap:Header><soap:Body><m:Response xmlns:m="test:call"><a/></m:Response></soap:Body></soap:Envelope>`},
+		{envelope: env + `<soap:Body><soap:Fault><faultcode>soap:Server</faultcode><faultstring>error</faultstring><detail><e xmlns="test:call"/></detail></soap:Fault></soap:Body></soap:Envelope>`},
+		{envelope: `<?xml version="1.0" encoding="ISO-8859-1"?>` + env + `<soap:Body/></soap:Envelope>`, want: "R1012"},
+		{envelope: `<?xml version="1.0"?><!DOCTYPE x>` + env + `<soap:Body/></soap:Envelope>`, want: "R1008"},
+		{envelope: env + `<?pi x?><soap:Body/></soap:Envelope>`, want: "R1009"},
+		{envelope: `<Envelope xmlns="http://www.w3.org/2003/05/soap-envelope"><Body/></Envelope>`, want: "R1015"},
+		{envelope: env + `<soap:Body/><soap:Trailer/></soap:Envelope>`, want: "R1011"},
+		{envelope: env + `<soap:Body><Response/></soap:Body></soap:Envelope>`, want: "R1014"},
+		{envelope: env + `<soap:Body><a xmlns="test:call"/><b xmlns="test:call"/></soap:Body></soap:Envelope>`, want: "R9981"},
+		{envelope: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" soap:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><soap:Body/></soap:Envelope>`, want: "R1005"},
+		{envelope: env + `<soap:Body><a xmlns="test:call" soap:encodingStyle="x"/></soap:Body></soap:Envelope>`, want: "R1006"},
+		{envelope: env + `<soap:Body><a xmlns="test:call"><b soap:encodingStyle="x"/></a></soap:Body></soap:Envelope>`, want: "R1007"},
+		{envelope: env + `<soap:Body><soap:Fault><faultcode>Server</faultcode></soap:Fault></soap:Body></soap:Envelope>`, want: "R1004"},
+		{envelope: env + `<soap:Body><soap:Fault><faultcode>soap:Server</faultcode><reason/></soap:Fault></soap:Body></soap:Envelope>`, want: "R1000"},
+		{envelope: env + `<soap:Body><soap:Fault><soap:faultcode>soap:Server</soap:faultcode></soap:Fault></soap:Body></soap:Envelope>`, want: "R1001"},
+		{envelope: env + `<soap:Header><soap:Fault/></soap:Header><soap:Body/></soap:Envelope>`, want: "R9980"},
+		{envelope: env + `<soap:Body>`, want: "R9980"},
+	} {
+		var rules []string
+		for _, violation := range CheckWSI([]byte(v.envelope)) {
+			rules = append(rules, violation.Rule)
+		}
+
+		if got := strings.Join(rules, " "); got != v.want {
+			t.Errorf("#%d got: %s, want: %s", i, got, v.want)
+		}
+	}
+}
+
+func TestWSI_Middleware(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Response xmlns=""/></Body></Envelope>`))
+	}))
+	defer srv.Close()
+
+	err := MustNewClient(srv.URL, Config{Middleware: []Middleware{(&WSI{}).Middleware()}}).Call(context.Background(), "", request{}, nil)
+	if e, ok := err.(*WSIError); !ok || !e.Response || e.Violations[0].Rule != "R1014" {
+		t.Fatalf("got: %v, want: R1014 violation of the response", err)
+	}
+
+	var reported []string
+	w := &WSI{Report: func(action string, response bool, violations []WSIViolation) {
+		reported = append(reported, action+" "+violations[0].Rule)
+	}}
+	if err := MustNewClient(srv.URL, Config{Middleware: []Middleware{w.Middleware()}}).Call(context.Background(), "call", request{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := strings.Join(reported, ","), "call R1014"; got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+}
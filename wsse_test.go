@@ -0,0 +1,72 @@
+package soap
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_PasswordDigestOf(t *testing.T) {
+	t.Parallel()
+	if got, want := PasswordDigestOf([]byte("nonce"), "2020-01-02T03:04:05.000Z", "pass"), "AA3ny1DEiCTt4jllSIYYQvXou/8="; got != want {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+}
+
+func TestClient_WSSE(t *testing.T) {
+	t.Parallel()
+	for _, digest := range []bool{false, true} {
+		var got string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			got = string(body)
+			b, _ := xml.Marshal(Envelope{Body: Body{}})
+			w.Write(b)
+		}))
+
+		wsse := NewWSSE("user", "pass")
+		wsse.Digest = digest
+		err := MustNewClient(srv.URL, Config{WSSE: wsse}).Call(context.Background(), "", request{}, nil)
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n, err := ParseNode([]byte(got))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		security := n.Find("Envelope/Header/Security")
+		if security == nil || security.XMLName.Space != WSSENS || !strings.Contains(got, "<wsse:Security ") || !strings.Contains(got, `soapenv:mustUnderstand="1"`) {
+			t.Fatalf("invalid security header: %s", got)
+		}
+
+		created, expires := security.Value("Security/Timestamp/Created"), security.Value("Security/Timestamp/Expires")
+		c, _ := time.Parse(wsuTime, created)
+		e, _ := time.Parse(wsuTime, expires)
+		if e.Sub(c) != 5*time.Minute {
+			t.Fatalf("invalid timestamp %s - %s", created, expires)
+		}
+
+		password := security.Find("Security/UsernameToken/Password")
+		passwordType, _ := password.Attr("Type")
+		if !digest {
+			if password.Text() != "pass" || passwordType != PasswordText {
+				t.Fatalf("invalid password %s", got)
+			}
+			continue
+		}
+
+		nonce, _ := base64.StdEncoding.DecodeString(security.Value("Security/UsernameToken/Nonce"))
+		if want := PasswordDigestOf(nonce, security.Value("Security/UsernameToken/Created"), "pass"); password.Text() != want || passwordType != PasswordDigest {
+			t.Fatalf("got: %s, want: %s", password.Text(), want)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package soap
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationRe = regexp.MustCompile(`^(-)?P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)(?:\.(\d+))?S)?)?$`)
+
+// Duration implements xsd:duration, years and months are kept apart from days and time,
+// since their length depends on the date the duration is added to.
+type Duration struct {
+	Negative bool
+	Years    int
+	Months   int
+	Days     int
+	// Time is hours, minutes and seconds of the duration.
+	Time time.Duration
+}
+
+// ParseDuration parses ISO 8601 duration, e.g. P1Y2M3DT4H5M6.5S, fraction of seconds
+// is truncated to nanoseconds.
+func ParseDuration(s string) (Duration, error) {
+	s = strings.TrimSpace(s)
+	m := durationRe.FindStringSubmatch(s)
+	if m == nil || s == "P" || s == "-P" || strings.HasSuffix(s, "T") {
+		return Duration{}, fmt.Errorf("soap: duration %q is invalid", s)
+	}
+
+	var (
+		d   = Duration{Negative: m[1] != ""}
+		err error
+	)
+	for i, p := range []*int{&d.Years, &d.Months, &d.Days} {
+		if m[i+2] == "" {
+			continue
+		}
+
+		if *p, err = strconv.Atoi(m[i+2]); err != nil {
+			return Duration{}, fmt.Errorf("soap: duration %q is invalid", s)
+		}
+	}
+
+	for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		if m[i+5] == "" {
+			continue
+		}
+
+		n, err := strconv.ParseInt(m[i+5], 10, 64)
+		if err != nil || n > int64(math.MaxInt64/unit) || d.Time > math.MaxInt64-time.Duration(n)*unit {
+			return Duration{}, fmt.Errorf("soap: duration %q is out of range", s)
+		}
+		d.Time += time.Duration(n) * unit
+	}
+
+	if frac := m[8]; frac != "" {
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+
+		ns, _ := strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		if d.Time > math.MaxInt64-time.Duration(ns) {
+			return Duration{}, fmt.Errorf("soap: duration %q is out of range", s)
+		}
+		d.Time += time.Duration(ns)
+	}
+	return d, nil
+}
+
+// String returns ISO 8601 duration, zero duration is PT0S.
+func (d Duration) String() string {
+	var b strings.Builder
+	if d.Negative {
+		b.WriteByte('-')
+	}
+	b.WriteByte('P')
+
+	for _, v := range []struct {
+		n    int
+		unit byte
+	}{{d.Years, 'Y'}, {d.Months, 'M'}, {d.Days, 'D'}} {
+		if v.n != 0 {
+			b.WriteString(strconv.Itoa(v.n))
+			b.WriteByte(v.unit)
+		}
+	}
+
+	if d.Time == 0 && (d.Years != 0 || d.Months != 0 || d.Days != 0) {
+		return b.String()
+	}
+
+	b.WriteByte('T')
+	t := d.Time
+	if h := t / time.Hour; h != 0 {
+		b.WriteString(strconv.FormatInt(int64(h), 10) + "H")
+		t -= h * time.Hour
+	}
+
+	if m := t / time.Minute; m != 0 {
+		b.WriteString(strconv.FormatInt(int64(m), 10) + "M")
+		t -= m * time.Minute
+	}
+
+	if t != 0 || d.Time == 0 {
+		b.WriteString(strconv.FormatInt(int64(t/time.Second), 10))
+		if ns := t % time.Second; ns != 0 {
+			b.WriteString("." + strings.TrimRight(fmt.Sprintf("%09d", ns), "0"))
+		}
+		b.WriteByte('S')
+	}
+	return b.String()
+}
+
+// AddTo returns the time with the duration added.
+func (d Duration) AddTo(t time.Time) time.Time {
+	if d.Negative {
+		return t.AddDate(-d.Years, -d.Months, -d.Days).Add(-d.Time)
+	}
+	return t.AddDate(d.Years, d.Months, d.Days).Add(d.Time)
+}
+
+// MarshalText implements encoding.TextMarshaler interface.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler interface.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = v
+	return nil
+}
+
+var decimalRe = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)$`)
+
+// Decimal implements xsd:decimal of arbitrary precision, so amounts are not rounded as float64.
+// Scale of the parsed value is kept, e.g. 1.50 is encoded back as 1.50. Zero value is 0.
+type Decimal struct {
+	// value is unscaled value, nil is zero
+	value *big.Int
+	// scale is number of fraction digits
+	scale int
+}
+
+// NewDecimal returns decimal of the unscaled value and number of fraction digits, e.g. NewDecimal(150, 2) is 1.50.
+func NewDecimal(unscaled int64, scale int) Decimal {
+	if scale < 0 {
+		return Decimal{value: new(big.Int).Mul(big.NewInt(unscaled), pow10(-scale))}
+	}
+	return Decimal{value: big.NewInt(unscaled), scale: scale}
+}
+
+// ParseDecimal parses xsd:decimal, exponent is not allowed.
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	if !decimalRe.MatchString(s) {
+		return Decimal{}, fmt.Errorf("soap: decimal %q is invalid", s)
+	}
+
+	digits := s
+	scale := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		digits, scale = s[:i]+s[i+1:], len(s)-i-1
+	}
+
+	v, ok := new(big.Int).SetString(strings.TrimPrefix(digits, "+"), 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("soap: decimal %q is invalid", s)
+	}
+	return Decimal{value: v, scale: scale}, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func (d Decimal) unscaled() *big.Int {
+	if d.value == nil {
+		return new(big.Int)
+	}
+	return d.value
+}
+
+// rescale returns unscaled value of the decimal with greater scale.
+func (d Decimal) rescale(scale int) *big.Int {
+	if scale == d.scale {
+		return d.unscaled()
+	}
+	return new(big.Int).Mul(d.unscaled(), pow10(scale-d.scale))
+}
+
+// Scale returns number of fraction digits.
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Sign returns -1, 0 or 1.
+func (d Decimal) Sign() int {
+	return d.unscaled().Sign()
+}
+
+// Cmp compares the decimals regardless of the scale, it returns -1, 0 or 1.
+func (d Decimal) Cmp(o Decimal) int {
+	scale := d.scale
+	if o.scale > scale {
+		scale = o.scale
+	}
+	return d.rescale(scale).Cmp(o.rescale(scale))
+}
+
+// Add returns d + o, scale is the greater one.
+func (d Decimal) Add(o Decimal) Decimal {
+	scale := d.scale
+	if o.scale > scale {
+		scale = o.scale
+	}
+	return Decimal{value: new(big.Int).Add(d.rescale(scale), o.rescale(scale)), scale: scale}
+}
+
+// Sub returns d - o, scale is the greater one.
+func (d Decimal) Sub(o Decimal) Decimal {
+	return d.Add(o.Neg())
+}
+
+// Mul returns d * o, scale is the sum of the scales.
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{value: new(big.Int).Mul(d.unscaled(), o.unscaled()), scale: d.scale + o.scale}
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{value: new(big.Int).Neg(d.unscaled()), scale: d.scale}
+}
+
+// Round returns the decimal rounded half away from zero to the number of fraction digits,
+// a decimal with less digits is returned with the scale extended.
+func (d Decimal) Round(scale int) Decimal {
+	if scale < 0 {
+		scale = 0
+	}
+
+	if d.scale <= scale {
+		return Decimal{value: d.rescale(scale), scale: scale}
+	}
+
+	div := pow10(d.scale - scale)
+	q, r := new(big.Int).QuoRem(new(big.Int).Abs(d.unscaled()), div, new(big.Int))
+	if r.Lsh(r, 1).Cmp(div) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+
+	if d.Sign() < 0 {
+		q.Neg(q)
+	}
+	return Decimal{value: q, scale: scale}
+}
+
+// Quo returns d / o rounded half away from zero to the number of fraction digits,
+// it panics when o is zero.
+func (d Decimal) Quo(o Decimal, scale int) Decimal {
+	if scale < 0 {
+		scale = 0
+	}
+
+	// one extra digit is kept for rounding
+	q := new(big.Int).Quo(d.rescale(d.scale+o.scale+scale+1), o.rescale(o.scale+d.scale))
+	return Decimal{value: q, scale: scale + 1}.Round(scale)
+}
+
+// Rat returns the decimal as rational number.
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.unscaled(), pow10(d.scale))
+}
+
+// String returns the decimal without exponent.
+func (d Decimal) String() string {
+	s := new(big.Int).Abs(d.unscaled()).String()
+	if d.scale > 0 {
+		if len(s) <= d.scale {
+			s = strings.Repeat("0", d.scale-len(s)+1) + s
+		}
+		s = s[:len(s)-d.scale] + "." + s[len(s)-d.scale:]
+	}
+
+	if d.Sign() < 0 {
+		return "-" + s
+	}
+	return s
+}
+
+// MarshalText implements encoding.TextMarshaler interface.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler interface.
+func (d *Decimal) UnmarshalText(text []byte) error {
+	v, err := ParseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = v
+	return nil
+}
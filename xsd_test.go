@@ -0,0 +1,153 @@
+package soap
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	t.Parallel()
+	for i, v := range []struct {
+		in   string
+		want Duration
+		str  string
+		err  bool
+	}{
+		{in: "P1Y2M3DT4H5M6.5S", want: Duration{Years: 1, Months: 2, Days: 3, Time: 4*time.Hour + 5*time.Minute + 6500*time.Millisecond}, str: "P1Y2M3DT4H5M6.5S"},
+		{in: "-P10D", want: Duration{Negative: true, Days: 10}, str: "-P10D"},
+		{in: " PT36H ", want: Duration{Time: 36 * time.Hour}, str: "PT36H"},
+		{in: "PT0S", want: Duration{}, str: "PT0S"},
+		{in: "PT0.0000000019S", want: Duration{Time: 1}, str: "PT0.000000001S"},
+		{in: "P", err: true},
+		{in: "PT", err: true},
+		{in: "P1DT", err: true},
+		{in: "P1H", err: true},
+		{in: "1D", err: true},
+		{in: "PT9999999999999H", err: true},
+	} {
+		got, err := ParseDuration(v.in)
+		if (err != nil) != v.err {
+			t.Errorf("#%d got: %v, want error: %t", i, err, v.err)
+			continue
+		}
+
+		if got != v.want {
+			t.Errorf("#%d got: %+v, want: %+v", i, got, v.want)
+		}
+
+		if !v.err && got.String() != v.str {
+			t.Errorf("#%d got: %s, want: %s", i, got, v.str)
+		}
+	}
+}
+
+func TestDuration_AddTo(t *testing.T) {
+	t.Parallel()
+	d, _ := ParseDuration("P1M1DT1H")
+	tm := time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)
+	if got, want := d.AddTo(tm), time.Date(2020, 3, 3, 1, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("got: %s, want: %s", got, want)
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	t.Parallel()
+	for i, v := range []struct {
+		in, want string
+		err      bool
+	}{
+		{in: "1.50", want: "1.50"},
+		{in: "+0.1", want: "0.1"},
+		{in: "-.5", want: "-0.5"},
+		{in: "5.", want: "5"},
+		{in: "-0.000001", want: "-0.000001"},
+		{in: "123456789012345678901234567890.123456789", want: "123456789012345678901234567890.123456789"},
+		{in: "1e5", err: true},
+		{in: ".", err: true},
+		{in: "", err: true},
+		{in: "1.2.3", err: true},
+	} {
+		got, err := ParseDecimal(v.in)
+		if (err != nil) != v.err {
+			t.Errorf("#%d got: %v, want error: %t", i, err, v.err)
+			continue
+		}
+
+		if !v.err && got.String() != v.want {
+			t.Errorf("#%d got: %s, want: %s", i, got, v.want)
+		}
+	}
+}
+
+func TestDecimal_Arithmetic(t *testing.T) {
+	t.Parallel()
+	d := func(s string) Decimal {
+		v, err := ParseDecimal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	for i, v := range []struct {
+		got  Decimal
+		want string
+	}{
+		{got: d("0.1").Add(d("0.2")), want: "0.3"},
+		{got: d("1.50").Sub(d("2")), want: "-0.50"},
+		{got: d("19.99").Mul(d("3")), want: "59.97"},
+		{got: d("2.675").Round(2), want: "2.68"},
+		{got: d("-2.675").Round(2), want: "-2.68"},
+		{got: d("2.674").Round(2), want: "2.67"},
+		{got: d("1.5").Round(3), want: "1.500"},
+		{got: d("10").Quo(d("3"), 2), want: "3.33"},
+		{got: d("-2").Quo(d("3"), 2), want: "-0.67"},
+		{got: d("1.25").Quo(d("0.5"), 1), want: "2.5"},
+		{got: NewDecimal(150, 2), want: "1.50"},
+		{got: NewDecimal(-5, 3), want: "-0.005"},
+		{got: NewDecimal(5, -2), want: "500"},
+		{got: Decimal{}, want: "0"},
+	} {
+		if v.got.String() != v.want {
+			t.Errorf("#%d got: %s, want: %s", i, v.got, v.want)
+		}
+	}
+
+	if d("1.50").Cmp(d("1.5")) != 0 || d("1.49").Cmp(d("1.5")) != -1 {
+		t.Error("got: unequal, want: decimals compared regardless of scale")
+	}
+
+	if got, want := d("0.75").Rat().String(), "3/4"; got != want {
+		t.Errorf("got: %s, want: %s", got, want)
+	}
+}
+
+func TestXSD_XML(t *testing.T) {
+	t.Parallel()
+	type payment struct {
+		Amount  Decimal  `xml:"amount"`
+		Fee     Decimal  `xml:"fee,attr"`
+		Period  Duration `xml:"period"`
+		Missing *Decimal `xml:"missing,omitempty"`
+	}
+
+	in := `<payment fee="0.10"><amount>1234.50</amount><period>P1M</period></payment>`
+	var p payment
+	if err := xml.Unmarshal([]byte(in), &p); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := xml.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(out) != in {
+		t.Fatalf("got: %s, want: %s", out, in)
+	}
+
+	if err := xml.Unmarshal([]byte(`<payment><amount>1.2e3</amount></payment>`), &p); err == nil {
+		t.Fatal("got: nil, want: error of invalid decimal")
+	}
+}